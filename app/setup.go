@@ -2,14 +2,19 @@ package app
 
 import (
 	"os"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/ptmmeiningen/schichtplaner/backup"
 	"github.com/ptmmeiningen/schichtplaner/config"
 	"github.com/ptmmeiningen/schichtplaner/database"
+	"github.com/ptmmeiningen/schichtplaner/notifications"
 	"github.com/ptmmeiningen/schichtplaner/router"
+	"github.com/ptmmeiningen/schichtplaner/trash"
+	"github.com/ptmmeiningen/schichtplaner/webhooks"
 )
 
 // SetupAndRunApp initialisiert und startet die Anwendung
@@ -24,11 +29,31 @@ func SetupAndRunApp() (*fiber.App, error) {
 		return nil, err
 	}
 
-	// Datenbank-Migrationen durchführen
-	if err := database.AutoMigrate(); err != nil {
+	// Schemaversion prüfen, Migrationen laufen über cmd/migrate
+	if err := database.CheckSchemaVersion(); err != nil {
 		return nil, err
 	}
 
+	// Benachrichtigungskanäle registrieren und Postausgang verarbeiten
+	notifications.Setup()
+	notifications.StartWorker(time.Minute)
+
+	// Ereignisse an Webhook-Abonnenten weiterleiten
+	webhooks.Setup()
+	webhooks.StartWorker(10 * time.Second)
+
+	// Automatische Sicherungen der SQLite-Datenbank
+	if err := backup.Setup(); err != nil {
+		return nil, err
+	}
+	backup.StartScheduler()
+
+	// Abgelaufene Datensätze aus dem Papierkorb endgültig löschen
+	if err := trash.Setup(); err != nil {
+		return nil, err
+	}
+	trash.StartWorker(time.Hour)
+
 	// Fiber-App mit Basiskonfiguration erstellen
 	app := fiber.New(fiber.Config{
 		AppName:      "Schichtplaner",
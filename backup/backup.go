@@ -0,0 +1,423 @@
+package backup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/ptmmeiningen/schichtplaner/database"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const (
+	filePrefix = "schichtplaner-"
+	fileSuffix = ".db"
+	timeLayout = "20060102-150405"
+)
+
+var (
+	ErrNotSupported = errors.New("Sicherungen werden nur für SQLite unterstützt")
+	ErrNotFound     = errors.New("Sicherung nicht gefunden")
+)
+
+// Config steuert Ablageort, Zeitplan und Aufbewahrung der Sicherungen
+type Config struct {
+	Dir      string
+	Interval time.Duration
+	KeepLast int
+	MaxAge   time.Duration
+}
+
+// Snapshot beschreibt eine abgelegte Sicherung
+type Snapshot struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+	Checksum  string    `json:"checksum"`
+}
+
+var (
+	config = Config{Dir: "backups", KeepLast: 14}
+	mu     sync.Mutex
+
+	// gate hält Restore an, bis alle laufenden Anfragen beendet sind, und
+	// weist neue Anfragen ab, solange Restore wartet oder läuft
+	gate sync.RWMutex
+)
+
+// Setup liest die Konfiguration aus den Umgebungsvariablen:
+//
+//	BACKUP_DIR            Verzeichnis der Sicherungen (Standard: backups)
+//	BACKUP_INTERVAL       Abstand automatischer Sicherungen, z.B. 6h (leer: aus)
+//	BACKUP_KEEP_LAST      Anzahl aufzubewahrender Sicherungen (Standard: 14)
+//	BACKUP_MAX_AGE_DAYS   ältere Sicherungen werden gelöscht (0: unbegrenzt)
+func Setup() error {
+	if dir := os.Getenv("BACKUP_DIR"); dir != "" {
+		config.Dir = dir
+	}
+	if value := os.Getenv("BACKUP_INTERVAL"); value != "" {
+		interval, err := time.ParseDuration(value)
+		if err != nil {
+			return errors.New("ungültiges BACKUP_INTERVAL: " + value)
+		}
+		config.Interval = interval
+	}
+	if value := os.Getenv("BACKUP_KEEP_LAST"); value != "" {
+		keep, err := strconv.Atoi(value)
+		if err != nil || keep < 1 {
+			return errors.New("ungültiges BACKUP_KEEP_LAST: " + value)
+		}
+		config.KeepLast = keep
+	}
+	if value := os.Getenv("BACKUP_MAX_AGE_DAYS"); value != "" {
+		days, err := strconv.Atoi(value)
+		if err != nil || days < 0 {
+			return errors.New("ungültiges BACKUP_MAX_AGE_DAYS: " + value)
+		}
+		config.MaxAge = time.Duration(days) * 24 * time.Hour
+	}
+	return nil
+}
+
+// GetConfig liefert die aktive Konfiguration
+func GetConfig() Config {
+	return config
+}
+
+// StartScheduler legt im konfigurierten Intervall Sicherungen an und räumt alte auf
+func StartScheduler() {
+	if config.Interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(config.Interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if _, err := Create(); err != nil {
+				log.Printf("Fehler beim Anlegen der Sicherung: %v", err)
+				continue
+			}
+			if _, err := Prune(); err != nil {
+				log.Printf("Fehler beim Aufräumen der Sicherungen: %v", err)
+			}
+		}
+	}()
+}
+
+// Create legt per VACUUM INTO eine konsistente Sicherung der laufenden Datenbank an
+func Create() (*Snapshot, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	return create()
+}
+
+func create() (*Snapshot, error) {
+	if _, ok := database.SQLitePath(); !ok {
+		return nil, ErrNotSupported
+	}
+	if err := os.MkdirAll(config.Dir, 0o750); err != nil {
+		return nil, err
+	}
+
+	name := filePrefix + time.Now().Format(timeLayout) + fileSuffix
+	path := filepath.Join(config.Dir, name)
+	if _, err := os.Stat(path); err == nil {
+		return nil, fmt.Errorf("Sicherung %s existiert bereits", name)
+	}
+
+	if err := database.GetDB().Exec("VACUUM INTO ?", path).Error; err != nil {
+		return nil, errors.New("Fehler beim Anlegen der Sicherung: " + err.Error())
+	}
+	if err := checkIntegrity(path); err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+
+	checksum, err := fileChecksum(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path+".sha256", []byte(checksum+"  "+name+"\n"), 0o640); err != nil {
+		return nil, err
+	}
+
+	return snapshotInfo(name)
+}
+
+// List liefert alle Sicherungen, die neueste zuerst
+func List() ([]Snapshot, error) {
+	entries, err := os.ReadDir(config.Dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []Snapshot{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := []Snapshot{}
+	for _, entry := range entries {
+		if !validName(entry.Name()) {
+			continue
+		}
+		snapshot, err := snapshotInfo(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, *snapshot)
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].CreatedAt.After(snapshots[j].CreatedAt)
+	})
+	return snapshots, nil
+}
+
+// Prune löscht Sicherungen außerhalb der Aufbewahrungsregeln. Die neueste
+// Sicherung bleibt immer erhalten.
+func Prune() ([]string, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	snapshots, err := List()
+	if err != nil {
+		return nil, err
+	}
+
+	removed := []string{}
+	for i, snapshot := range snapshots {
+		if i == 0 {
+			continue
+		}
+		expired := config.MaxAge > 0 && time.Since(snapshot.CreatedAt) > config.MaxAge
+		if i < config.KeepLast && !expired {
+			continue
+		}
+
+		path := filepath.Join(config.Dir, snapshot.Name)
+		if err := os.Remove(path); err != nil {
+			return removed, err
+		}
+		os.Remove(path + ".sha256")
+		removed = append(removed, snapshot.Name)
+	}
+	return removed, nil
+}
+
+// Verify prüft Prüfsumme, Integrität und Schemaversion einer Sicherung
+func Verify(name string) error {
+	if !validName(name) {
+		return ErrNotFound
+	}
+	path := filepath.Join(config.Dir, name)
+	if _, err := os.Stat(path); err != nil {
+		return ErrNotFound
+	}
+
+	expected, err := os.ReadFile(path + ".sha256")
+	if err != nil {
+		return errors.New("Prüfsumme der Sicherung fehlt")
+	}
+	checksum, err := fileChecksum(path)
+	if err != nil {
+		return err
+	}
+	if fields := strings.Fields(string(expected)); len(fields) == 0 || fields[0] != checksum {
+		return errors.New("Prüfsumme der Sicherung stimmt nicht überein")
+	}
+
+	if err := checkIntegrity(path); err != nil {
+		return err
+	}
+
+	snapshotVersion, err := schemaVersion(path)
+	if err != nil {
+		return err
+	}
+	expectedVersion, err := database.ExpectedSchemaVersion()
+	if err != nil {
+		return err
+	}
+	if snapshotVersion > expectedVersion {
+		return fmt.Errorf("Sicherung hat Schemaversion %d, diese Programmversion kennt nur %d", snapshotVersion, expectedVersion)
+	}
+	return nil
+}
+
+// Enter meldet eine Anfrage an, die die Datenbank benutzt. Während eine
+// Sicherung zurückgespielt wird, liefert Enter false. Sonst muss release
+// nach der Anfrage aufgerufen werden.
+func Enter() (release func(), ok bool) {
+	if !gate.TryRLock() {
+		return nil, false
+	}
+	return gate.RUnlock, true
+}
+
+// Restore spielt eine geprüfte Sicherung zurück. Vorher wird der aktuelle
+// Stand gesichert, danach werden fehlende Migrationen angewendet. Der
+// Austausch wartet auf alle mit Enter angemeldeten Anfragen, neue werden bis
+// zum Ende abgewiesen.
+func Restore(name string) (*Snapshot, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	dbPath, ok := database.SQLitePath()
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	if err := Verify(name); err != nil {
+		return nil, err
+	}
+
+	safety, err := create()
+	if err != nil {
+		return nil, err
+	}
+
+	tmpPath := dbPath + ".restore"
+	if err := copyFile(filepath.Join(config.Dir, name), tmpPath); err != nil {
+		return nil, err
+	}
+
+	gate.Lock()
+	defer gate.Unlock()
+
+	database.CloseDB()
+	os.Remove(dbPath + "-wal")
+	os.Remove(dbPath + "-shm")
+	renameErr := os.Rename(tmpPath, dbPath)
+
+	if err := database.StartDB(); err != nil {
+		return nil, err
+	}
+	if renameErr != nil {
+		os.Remove(tmpPath)
+		return nil, errors.New("Fehler beim Zurückspielen der Sicherung: " + renameErr.Error())
+	}
+	if err := database.Migrate(); err != nil {
+		return nil, err
+	}
+	return safety, nil
+}
+
+func validName(name string) bool {
+	return filepath.Base(name) == name &&
+		strings.HasPrefix(name, filePrefix) &&
+		strings.HasSuffix(name, fileSuffix)
+}
+
+func snapshotInfo(name string) (*Snapshot, error) {
+	path := filepath.Join(config.Dir, name)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	createdAt, err := time.ParseInLocation(timeLayout, strings.TrimSuffix(strings.TrimPrefix(name, filePrefix), fileSuffix), time.Local)
+	if err != nil {
+		createdAt = info.ModTime()
+	}
+
+	snapshot := &Snapshot{Name: name, Size: info.Size(), CreatedAt: createdAt}
+	if content, err := os.ReadFile(path + ".sha256"); err == nil {
+		if fields := strings.Fields(string(content)); len(fields) > 0 {
+			snapshot.Checksum = fields[0]
+		}
+	}
+	return snapshot, nil
+}
+
+func openSnapshot(path string) (*gorm.DB, func(), error) {
+	snapshotDB, err := gorm.Open(sqlite.Open(path), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		return nil, nil, err
+	}
+	closeFn := func() {
+		if sqlDB, err := snapshotDB.DB(); err == nil {
+			sqlDB.Close()
+		}
+	}
+	return snapshotDB, closeFn, nil
+}
+
+func checkIntegrity(path string) error {
+	snapshotDB, closeFn, err := openSnapshot(path)
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+
+	var result string
+	if err := snapshotDB.Raw("PRAGMA integrity_check").Scan(&result).Error; err != nil {
+		return err
+	}
+	if result != "ok" {
+		return errors.New("Integritätsprüfung der Sicherung fehlgeschlagen: " + result)
+	}
+	return nil
+}
+
+func schemaVersion(path string) (uint, error) {
+	snapshotDB, closeFn, err := openSnapshot(path)
+	if err != nil {
+		return 0, err
+	}
+	defer closeFn()
+
+	if !snapshotDB.Migrator().HasTable(&database.SchemaMigration{}) {
+		return 0, nil
+	}
+	var version uint
+	err = snapshotDB.Model(&database.SchemaMigration{}).Select("COALESCE(MAX(version), 0)").Scan(&version).Error
+	return version, err
+}
+
+func fileChecksum(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
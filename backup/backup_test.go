@@ -0,0 +1,139 @@
+package backup
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ptmmeiningen/schichtplaner/database"
+	"github.com/ptmmeiningen/schichtplaner/database/databasetest"
+	"github.com/ptmmeiningen/schichtplaner/models"
+	"gorm.io/gorm/logger"
+)
+
+// setupSQLite öffnet eine migrierte SQLite-Datenbank im temporären
+// Verzeichnis. DATABASE_URL zeigt auf dieselbe Datei, damit Restore sie
+// wieder öffnet.
+func setupSQLite(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	url := "sqlite://" + filepath.Join(dir, "schichtplaner.db")
+	t.Setenv("DATABASE_URL", url)
+	t.Setenv("SQLITE_DB_PATH", "")
+
+	if err := database.Open(url, "", logger.Silent); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(database.CloseDB)
+	if err := database.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+
+	previous := config
+	config = Config{Dir: filepath.Join(dir, "backups"), KeepLast: 14}
+	t.Cleanup(func() { config = previous })
+}
+
+func shiftTypeNames(t *testing.T) []string {
+	t.Helper()
+	var names []string
+	if err := database.GetDB().Model(&models.ShiftType{}).Order("name").Pluck("name", &names).Error; err != nil {
+		t.Fatal(err)
+	}
+	return names
+}
+
+// nextSecond wartet bis zur nächsten Sekunde, weil Sicherungen sekundengenau
+// benannt werden
+func nextSecond() {
+	time.Sleep(time.Until(time.Now().Truncate(time.Second).Add(time.Second)))
+}
+
+func TestRestore(t *testing.T) {
+	setupSQLite(t)
+	databasetest.ShiftType(t, "Früh", "06:00", "14:00")
+
+	snapshot, err := Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(snapshot.Name); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+
+	databasetest.ShiftType(t, "Spät", "06:00", "14:00")
+	nextSecond()
+
+	safety, err := Restore(snapshot.Name)
+	if err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if names := shiftTypeNames(t); len(names) != 1 || names[0] != "Früh" {
+		t.Fatalf("Schichttypen nach Restore %v, erwartet [Früh]", names)
+	}
+	if err := database.CheckSchemaVersion(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Die vorher angelegte Sicherung enthält den überschriebenen Stand
+	nextSecond()
+	if _, err := Restore(safety.Name); err != nil {
+		t.Fatalf("Restore der Sicherheitskopie: %v", err)
+	}
+	if names := shiftTypeNames(t); len(names) != 2 {
+		t.Fatalf("Schichttypen nach Restore der Sicherheitskopie %v, erwartet [Früh Spät]", names)
+	}
+
+	// Nach dem Austausch ist die Datenbank wieder beschreibbar
+	databasetest.ShiftType(t, "Nacht", "06:00", "14:00")
+}
+
+func TestRestoreWaitsForRequests(t *testing.T) {
+	setupSQLite(t)
+	databasetest.ShiftType(t, "Früh", "06:00", "14:00")
+	snapshot, err := Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	nextSecond()
+
+	release, ok := Enter()
+	if !ok {
+		t.Fatal("Anfrage ohne laufendes Restore abgewiesen")
+	}
+
+	done := make(chan error)
+	go func() {
+		_, err := Restore(snapshot.Name)
+		done <- err
+	}()
+
+	// Solange die Anfrage läuft, wartet Restore und neue Anfragen werden abgewiesen
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		other, ok := Enter()
+		if !ok {
+			break
+		}
+		other()
+		if time.Now().After(deadline) {
+			t.Fatal("Anfragen werden während Restore nicht abgewiesen")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case err := <-done:
+		t.Fatalf("Restore vor Ende der laufenden Anfrage beendet: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	release()
+	if err := <-done; err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	release, ok = Enter()
+	if !ok {
+		t.Fatal("Anfrage nach Restore abgewiesen")
+	}
+	release()
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/ptmmeiningen/schichtplaner/backup"
+	"github.com/ptmmeiningen/schichtplaner/config"
+	"github.com/ptmmeiningen/schichtplaner/database"
+)
+
+const usage = `Verwendung: backup <befehl>
+
+Befehle:
+  create          Sicherung der laufenden Datenbank anlegen
+  list            vorhandene Sicherungen anzeigen
+  prune           Sicherungen außerhalb der Aufbewahrungsregeln löschen
+  verify <name>   Prüfsumme, Integrität und Schemaversion prüfen
+  restore <name>  Sicherung zurückspielen`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+
+	if err := config.LoadENV(); err != nil {
+		log.Fatal(err)
+	}
+	if err := backup.Setup(); err != nil {
+		log.Fatal(err)
+	}
+	if err := database.StartDB(); err != nil {
+		log.Fatal(err)
+	}
+	defer database.CloseDB()
+
+	switch os.Args[1] {
+	case "create":
+		snapshot, err := backup.Create()
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Sicherung angelegt: %s (%d Bytes)\n", snapshot.Name, snapshot.Size)
+
+	case "list":
+		snapshots, err := backup.List()
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, snapshot := range snapshots {
+			fmt.Printf("%-36s %12d  %s\n", snapshot.Name, snapshot.Size, snapshot.CreatedAt.Format("2006-01-02 15:04:05"))
+		}
+
+	case "prune":
+		removed, err := backup.Prune()
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, name := range removed {
+			fmt.Println("gelöscht:", name)
+		}
+
+	case "verify":
+		if err := backup.Verify(argName()); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println("Sicherung ist in Ordnung")
+
+	case "restore":
+		safety, err := backup.Restore(argName())
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Sicherung zurückgespielt, vorheriger Stand gesichert als %s\n", safety.Name)
+
+	default:
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+}
+
+func argName() string {
+	if len(os.Args) < 3 {
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+	return os.Args[2]
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/ptmmeiningen/schichtplaner/database"
+)
+
+const usage = `Verwendung: migrate <befehl>
+
+Befehle:
+  up           alle ausstehenden Migrationen anwenden
+  down [n]     die letzten n Migrationen zurücknehmen (Standard: 1)
+  status       Stand aller Migrationen anzeigen`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+
+	if err := database.StartDB(); err != nil {
+		log.Fatal(err)
+	}
+	defer database.CloseDB()
+
+	switch os.Args[1] {
+	case "up":
+		if err := database.Migrate(); err != nil {
+			log.Fatal(err)
+		}
+		printVersion()
+
+	case "down":
+		steps := 1
+		if len(os.Args) > 2 {
+			n, err := strconv.Atoi(os.Args[2])
+			if err != nil || n < 1 {
+				log.Fatal("Ungültige Anzahl an Schritten: " + os.Args[2])
+			}
+			steps = n
+		}
+		if err := database.MigrateDown(steps); err != nil {
+			log.Fatal(err)
+		}
+		printVersion()
+
+	case "status":
+		states, err := database.MigrationStatus()
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, state := range states {
+			applied := "ausstehend"
+			if state.Applied {
+				applied = "angewendet am " + state.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d  %-30s %s\n", state.Version, state.Name, applied)
+		}
+		printVersion()
+
+	default:
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+}
+
+func printVersion() {
+	version, err := database.SchemaVersion()
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Schemaversion: %d\n", version)
+}
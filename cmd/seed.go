@@ -14,7 +14,13 @@ func main() {
 	}
 	defer database.CloseDB()
 
-	if err := database.AutoMigrate(); err != nil {
+	if err := database.Migrate(); err != nil {
+		log.Fatal(err)
+	}
+
+	// Die Migration legt den Standardstandort an
+	var location models.Location
+	if err := database.GetDB().Order("id").First(&location).Error; err != nil {
 		log.Fatal(err)
 	}
 
@@ -28,6 +34,7 @@ func main() {
 	}
 
 	for _, dept := range departments {
+		dept.LocationID = location.ID
 		database.GetDB().Create(&dept)
 	}
 
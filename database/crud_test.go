@@ -0,0 +1,90 @@
+package database_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ptmmeiningen/schichtplaner/database"
+	"github.com/ptmmeiningen/schichtplaner/database/databasetest"
+	"github.com/ptmmeiningen/schichtplaner/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func TestCRUD(t *testing.T) {
+	databasetest.Setup(t)
+	db := database.GetDB()
+
+	department := databasetest.Department(t, "Produktion")
+	employee := databasetest.Employee(t, "anna@example.org", department.ID)
+	shiftType := databasetest.ShiftType(t, "Früh", "06:00", "14:00")
+	shiftWeek := models.ShiftWeek{Year: 2030, CalendarWeek: 3, Status: models.StatusDraft, DepartmentID: &department.ID}
+	databasetest.Create(t, &shiftWeek)
+	date := time.Date(2030, 1, 14, 0, 0, 0, 0, time.UTC)
+	shiftDay := models.ShiftDay{Date: date, ShiftWeekID: &shiftWeek.ID, ShiftTypeID: shiftType.ID, EmployeeID: &employee.ID}
+	databasetest.Create(t, &shiftDay)
+
+	// Lesen samt Beziehungen
+	var loaded models.ShiftDay
+	if err := db.Preload("Employee").Preload("ShiftType").First(&loaded, shiftDay.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Employee.Email != employee.Email || loaded.ShiftType.Name != shiftType.Name {
+		t.Fatalf("Beziehungen nicht geladen: %+v", loaded)
+	}
+	if !loaded.Date.Equal(date) {
+		t.Fatalf("Datum %v, erwartet %v", loaded.Date, date)
+	}
+
+	// Ändern
+	if err := db.Model(&employee).Update("last_name", "Amsel").Error; err != nil {
+		t.Fatal(err)
+	}
+	var renamed models.Employee
+	if err := db.First(&renamed, employee.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if renamed.LastName != "Amsel" {
+		t.Fatalf("Nachname %q nach Update", renamed.LastName)
+	}
+
+	// Eindeutige E-Mail unter aktiven Mitarbeitern
+	duplicate := models.Employee{FirstName: "Anne", LastName: "Adler", Email: employee.Email, Password: "geheim", Color: "#ff0000"}
+	if err := db.Omit(clause.Associations).Create(&duplicate).Error; err == nil {
+		t.Fatal("doppelte E-Mail angelegt")
+	}
+
+	// Löschen mit RESTRICT, SET NULL und CASCADE
+	var blocked *database.ReferenceError
+	err := db.Transaction(func(tx *gorm.DB) error { return database.SoftDelete(tx, "shift_types", shiftType.ID) })
+	if !errors.As(err, &blocked) {
+		t.Fatalf("Schichttyp trotz Schichttag gelöscht: %v", err)
+	}
+	if err := db.Transaction(func(tx *gorm.DB) error { return database.SoftDelete(tx, "employees", employee.ID) }); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.First(&loaded, shiftDay.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if loaded.EmployeeID != nil {
+		t.Fatalf("Schichttag verweist nach dem Löschen weiter auf Mitarbeiter %d", *loaded.EmployeeID)
+	}
+	if err := db.Transaction(func(tx *gorm.DB) error { return database.SoftDelete(tx, "shift_weeks", shiftWeek.ID) }); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.First(&loaded, shiftDay.ID).Error; !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("Schichttag nach Löschen der Woche: %v", err)
+	}
+
+	// Wiederherstellen bringt die mitgelöschten Schichttage zurück
+	if err := db.Transaction(func(tx *gorm.DB) error { return database.Restore(tx, "shift_weeks", shiftWeek.ID) }); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.First(&loaded, shiftDay.ID).Error; err != nil {
+		t.Fatalf("Schichttag nach Wiederherstellen der Woche: %v", err)
+	}
+
+	// Die gelöschte E-Mail ist wieder frei
+	databasetest.Create(t, &duplicate)
+}
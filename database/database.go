@@ -0,0 +1,126 @@
+package database
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/plugin/dbresolver"
+)
+
+var (
+	db      *gorm.DB
+	readers *dbresolver.DBResolver
+)
+
+func GetDB() *gorm.DB {
+	return db
+}
+
+func SetDB(database *gorm.DB) {
+	db = database
+}
+
+func ValidateConnection() error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Ping()
+}
+
+// StartDB öffnet die Datenbank aus DATABASE_URL. Ohne DATABASE_URL wird
+// wie bisher die SQLite-Datei aus SQLITE_DB_PATH verwendet.
+func StartDB() error {
+	return Open(os.Getenv("DATABASE_URL"), os.Getenv("SQLITE_DB_PATH"), logger.Info)
+}
+
+// Open öffnet die Datenbank unter url wie StartDB, aber mit eigener URL und
+// Protokollstufe, etwa für Testdatenbanken
+func Open(url, sqlitePath string, logLevel logger.LogLevel) error {
+	dialector, err := Dialector(url, sqlitePath)
+	if err != nil {
+		return err
+	}
+
+	config := &gorm.Config{
+		Logger: logger.Default.LogMode(logLevel),
+		NowFunc: func() time.Time {
+			return time.Now().Local()
+		},
+	}
+
+	db, err = gorm.Open(dialector, config)
+	if err != nil {
+		return errors.New("Fehler beim Öffnen der Datenbank: " + err.Error())
+	}
+
+	if err := RegisterTenantScope(db); err != nil {
+		return err
+	}
+
+	if sqliteDialector, ok := dialector.(*sqlite.Dialector); ok {
+		return configureSQLite(sqliteDialector.DSN)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+
+	// Performance-Optimierungen
+	sqlDB.SetMaxIdleConns(10)
+	sqlDB.SetMaxOpenConns(100)
+	sqlDB.SetConnMaxLifetime(time.Hour)
+
+	return nil
+}
+
+// configureSQLite trennt Lese- und Schreibzugriffe: SQLite erlaubt nur einen
+// Schreiber gleichzeitig, deshalb laufen Schreibvorgänge und Transaktionen
+// über eine einzige Verbindung, während Abfragen einen eigenen Pool nutzen.
+func configureSQLite(dsn string) error {
+	readers = dbresolver.Register(dbresolver.Config{
+		Replicas: []gorm.Dialector{sqlite.Open(dsn)},
+	}).
+		SetMaxOpenConns(sqliteReadConns).
+		SetMaxIdleConns(sqliteReadConns).
+		SetConnMaxLifetime(time.Hour)
+
+	if err := db.Use(readers); err != nil {
+		return errors.New("Fehler beim Einrichten des Lese-Pools: " + err.Error())
+	}
+
+	writer, err := db.DB()
+	if err != nil {
+		return err
+	}
+	writer.SetMaxOpenConns(1)
+	writer.SetMaxIdleConns(1)
+	writer.SetConnMaxLifetime(0)
+
+	return nil
+}
+
+func CloseDB() {
+	if readers != nil {
+		readers.Call(func(pool gorm.ConnPool) error {
+			if closer, ok := pool.(interface{ Close() error }); ok {
+				closer.Close()
+			}
+			return nil
+		})
+		readers = nil
+		return
+	}
+
+	if db != nil {
+		sqlDB, err := db.DB()
+		if err == nil {
+			sqlDB.Close()
+		}
+	}
+}
@@ -0,0 +1,44 @@
+// Package databasetest stellt Tests anderer Pakete eine leere Datenbank mit
+// dem aktuellen Schema bereit
+package databasetest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ptmmeiningen/schichtplaner/database"
+	"gorm.io/gorm/logger"
+)
+
+// Setup öffnet die Testdatenbank, spielt alle Migrationen ein und setzt sie
+// als Datenbank der Anwendung. Mit TEST_DATABASE_URL, etwa einer lokalen
+// PostgreSQL-Instanz, wird deren Schema public vorher geleert. Sonst wird
+// eine SQLite-Datei im temporären Verzeichnis des Tests angelegt.
+func Setup(t testing.TB) *fiber.App {
+	t.Helper()
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		url = "sqlite://" + filepath.Join(t.TempDir(), "schichtplaner_test.db")
+	}
+
+	if err := database.Open(url, "", logger.Silent); err != nil {
+		t.Fatalf("Fehler beim Verbinden zur Testdatenbank: %v", err)
+	}
+	t.Cleanup(database.CloseDB)
+
+	if database.Dialect() == database.DialectPostgres {
+		for _, statement := range []string{"DROP SCHEMA public CASCADE", "CREATE SCHEMA public"} {
+			if err := database.GetDB().Exec(statement).Error; err != nil {
+				t.Fatalf("Fehler beim Leeren der Testdatenbank: %v", err)
+			}
+		}
+	}
+	if err := database.Migrate(); err != nil {
+		t.Fatalf("Fehler beim Migrieren der Testdatenbank: %v", err)
+	}
+
+	return fiber.New()
+}
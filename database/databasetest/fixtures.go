@@ -0,0 +1,62 @@
+package databasetest
+
+import (
+	"testing"
+
+	"github.com/ptmmeiningen/schichtplaner/database"
+	"github.com/ptmmeiningen/schichtplaner/models"
+	"gorm.io/gorm/clause"
+)
+
+// Create legt value ohne Beziehungen direkt in der Datenbank an
+func Create(t testing.TB, value interface{}) {
+	t.Helper()
+	if err := database.GetDB().Omit(clause.Associations).Create(value).Error; err != nil {
+		t.Fatalf("Anlegen von %T: %v", value, err)
+	}
+}
+
+// Save speichert value ohne Beziehungen direkt in der Datenbank
+func Save(t testing.TB, value interface{}) {
+	t.Helper()
+	if err := database.GetDB().Omit(clause.Associations).Save(value).Error; err != nil {
+		t.Fatalf("Speichern von %T: %v", value, err)
+	}
+}
+
+// Location liefert den Standort, den Migration 0011 anlegt
+func Location(t testing.TB) models.Location {
+	t.Helper()
+	var location models.Location
+	if err := database.GetDB().Order("id").First(&location).Error; err != nil {
+		t.Fatalf("Standort aus Migration 0011: %v", err)
+	}
+	return location
+}
+
+// Department legt eine Abteilung am Standort aus Migration 0011 an
+func Department(t testing.TB, name string) models.Department {
+	t.Helper()
+	department := models.Department{Name: name, Color: "#0000ff", LocationID: Location(t).ID}
+	Create(t, &department)
+	return department
+}
+
+// ShiftType legt einen Schichttyp von start bis end an
+func ShiftType(t testing.TB, name, start, end string) models.ShiftType {
+	t.Helper()
+	shiftType := models.ShiftType{Name: name, Color: "#00ff00", StartTime: start, EndTime: end}
+	Create(t, &shiftType)
+	return shiftType
+}
+
+// Employee legt einen Mitarbeiter an, mit departmentID 0 ohne Abteilung
+func Employee(t testing.TB, email string, departmentID uint) models.Employee {
+	t.Helper()
+	employee := models.Employee{FirstName: "Anna", LastName: "Adler", Email: email, Password: "geheim123", Color: "#ff0000"}
+	if departmentID != 0 {
+		employee.DepartmentID = &departmentID
+	}
+	Create(t, &employee)
+	return employee
+}
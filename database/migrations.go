@@ -0,0 +1,325 @@
+package database
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+//go:embed migrations/*/*.sql
+var migrationFiles embed.FS
+
+// noForeignKeysMarker kennzeichnet Migrationen, die unter SQLite ohne
+// Fremdschlüsselprüfung laufen müssen
+const noForeignKeysMarker = "-- migrate:no-foreign-keys"
+
+// SchemaMigration protokolliert eine angewendete Migration
+type SchemaMigration struct {
+	Version   uint      `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"size:255;not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+// Migration ist eine nummerierte Schemaänderung mit Hin- und Rückweg
+type Migration struct {
+	Version uint
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationState beschreibt den Stand einer Migration in der Datenbank
+type MigrationState struct {
+	Migration
+	Applied   bool
+	AppliedAt *time.Time
+}
+
+// Migrations liefert alle eingebetteten Migrationen des aktiven Dialekts
+// aufsteigend sortiert. Jeder Dialekt hat ein eigenes Verzeichnis unter
+// migrations/, Dateinamen folgen dem Muster NNNN_name.up.sql bzw.
+// NNNN_name.down.sql. Beide Verzeichnisse müssen dieselben Versionen führen.
+func Migrations() ([]Migration, error) {
+	dir := path.Join("migrations", Dialect())
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, fmt.Errorf("keine Migrationen für Datenbank %s vorhanden", Dialect())
+	}
+
+	byVersion := map[uint]*Migration{}
+	for _, entry := range entries {
+		name := entry.Name()
+		var direction string
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(name, ".down.sql"):
+			direction = "down"
+		default:
+			continue
+		}
+
+		base := strings.TrimSuffix(name, "."+direction+".sql")
+		number, label, found := strings.Cut(base, "_")
+		if !found {
+			return nil, fmt.Errorf("ungültiger Migrationsdateiname: %s", name)
+		}
+		version, err := strconv.ParseUint(number, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("ungültige Migrationsversion in %s", name)
+		}
+
+		content, err := migrationFiles.ReadFile(path.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[uint(version)]
+		if !ok {
+			m = &Migration{Version: uint(version), Name: label}
+			byVersion[uint(version)] = m
+		}
+		if direction == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("Migration %04d_%s hat keine up-Datei", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// ExpectedSchemaVersion ist die höchste eingebettete Migrationsversion
+func ExpectedSchemaVersion() (uint, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return 0, err
+	}
+	if len(migrations) == 0 {
+		return 0, nil
+	}
+	return migrations[len(migrations)-1].Version, nil
+}
+
+// SchemaVersion liefert die höchste angewendete Migrationsversion
+func SchemaVersion() (uint, error) {
+	if !db.Migrator().HasTable(&SchemaMigration{}) {
+		return 0, nil
+	}
+	var version uint
+	err := db.Model(&SchemaMigration{}).Select("COALESCE(MAX(version), 0)").Scan(&version).Error
+	return version, err
+}
+
+// Migrate wendet alle ausstehenden Migrationen in aufsteigender Reihenfolge an
+func Migrate() error {
+	if err := db.AutoMigrate(&SchemaMigration{}); err != nil {
+		return errors.New("Fehler beim Anlegen der Migrationstabelle: " + err.Error())
+	}
+
+	migrations, err := Migrations()
+	if err != nil {
+		return err
+	}
+	applied, err := appliedMigrations()
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		err := runScript(m.Up, func(tx *gorm.DB) error {
+			return tx.Create(&SchemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("Datenbank Migrationsfehler in %04d_%s: %s", m.Version, m.Name, err.Error())
+		}
+	}
+	return nil
+}
+
+// MigrateDown nimmt die letzten steps angewendeten Migrationen zurück
+func MigrateDown(steps int) error {
+	if !db.Migrator().HasTable(&SchemaMigration{}) {
+		return nil
+	}
+
+	migrations, err := Migrations()
+	if err != nil {
+		return err
+	}
+	known := make(map[uint]Migration, len(migrations))
+	for _, m := range migrations {
+		known[m.Version] = m
+	}
+
+	var applied []SchemaMigration
+	if err := db.Order("version DESC").Limit(steps).Find(&applied).Error; err != nil {
+		return err
+	}
+
+	for _, record := range applied {
+		m, ok := known[record.Version]
+		if !ok {
+			return fmt.Errorf("Migration %04d ist nicht in dieser Version enthalten", record.Version)
+		}
+		if m.Down == "" {
+			return fmt.Errorf("Migration %04d_%s kann nicht zurückgenommen werden", m.Version, m.Name)
+		}
+		err := runScript(m.Down, func(tx *gorm.DB) error {
+			return tx.Delete(&SchemaMigration{}, m.Version).Error
+		})
+		if err != nil {
+			return fmt.Errorf("Fehler beim Zurücknehmen von %04d_%s: %s", m.Version, m.Name, err.Error())
+		}
+	}
+	return nil
+}
+
+// MigrationStatus liefert alle bekannten Migrationen mit ihrem Anwendungsstand
+func MigrationStatus() ([]MigrationState, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	states := make([]MigrationState, 0, len(migrations))
+	for _, m := range migrations {
+		state := MigrationState{Migration: m}
+		if record, ok := applied[m.Version]; ok {
+			state.Applied = true
+			state.AppliedAt = &record.AppliedAt
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+// CheckSchemaVersion stellt sicher, dass das Datenbankschema genau dem
+// Stand entspricht, den diese Programmversion erwartet
+func CheckSchemaVersion() error {
+	expected, err := ExpectedSchemaVersion()
+	if err != nil {
+		return err
+	}
+	current, err := SchemaVersion()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case current < expected:
+		return fmt.Errorf("Datenbankschema ist veraltet (Version %d, erwartet %d) - bitte 'migrate up' ausführen", current, expected)
+	case current > expected:
+		return fmt.Errorf("Datenbankschema ist neuer als diese Programmversion (Version %d, erwartet %d)", current, expected)
+	}
+	return nil
+}
+
+func appliedMigrations() (map[uint]SchemaMigration, error) {
+	applied := map[uint]SchemaMigration{}
+	if !db.Migrator().HasTable(&SchemaMigration{}) {
+		return applied, nil
+	}
+
+	var records []SchemaMigration
+	if err := db.Find(&records).Error; err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
+
+// runScript führt eine Migrationsdatei samt Protokolleintrag in einer
+// Transaktion aus. Enthält die Datei die Zeile noForeignKeysMarker, läuft sie
+// unter SQLite ohne Fremdschlüsselprüfung, wie es SQLite für den Neuaufbau
+// referenzierter Tabellen vorsieht. Vor dem Commit wird PRAGMA
+// foreign_key_check ausgeführt.
+func runScript(script string, record func(tx *gorm.DB) error) error {
+	run := func(tx *gorm.DB) error {
+		if err := execScript(tx, script); err != nil {
+			return err
+		}
+		return record(tx)
+	}
+
+	if !strings.Contains(script, noForeignKeysMarker) || Dialect() != DialectSQLite {
+		return db.Transaction(run)
+	}
+
+	// Das PRAGMA wirkt nur außerhalb einer Transaktion und nur auf die eigene
+	// Verbindung. Es wird direkt auf der Verbindung ausgeführt, damit der
+	// Lese-/Schreib-Resolver es nicht auf eine andere Verbindung umleitet.
+	return db.Connection(func(conn *gorm.DB) error {
+		ctx := context.Background()
+		pool := conn.Statement.ConnPool
+		if _, err := pool.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+			return err
+		}
+		defer pool.ExecContext(ctx, "PRAGMA foreign_keys = ON")
+
+		return conn.Transaction(func(tx *gorm.DB) error {
+			if err := run(tx); err != nil {
+				return err
+			}
+			var violations []map[string]interface{}
+			if err := tx.Raw("PRAGMA foreign_key_check").Scan(&violations).Error; err != nil {
+				return err
+			}
+			if len(violations) > 0 {
+				return fmt.Errorf("%d Fremdschlüsselverletzungen nach der Migration", len(violations))
+			}
+			return nil
+		})
+	})
+}
+
+// execScript führt die durch Semikolon getrennten Anweisungen einer
+// Migrationsdatei nacheinander aus
+func execScript(tx *gorm.DB, script string) error {
+	var lines []string
+	for _, line := range strings.Split(script, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "--") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	for _, statement := range strings.Split(strings.Join(lines, "\n"), ";") {
+		statement = strings.TrimSpace(statement)
+		if statement == "" {
+			continue
+		}
+		if err := tx.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhook_subscriptions;
+DROP TABLE IF EXISTS notification_preferences;
+DROP TABLE IF EXISTS notifications;
+DROP TABLE IF EXISTS shift_days;
+DROP TABLE IF EXISTS shift_weeks;
+DROP TABLE IF EXISTS shift_types;
+DROP TABLE IF EXISTS employees;
+DROP TABLE IF EXISTS departments;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS shift_template_days;
+DROP TABLE IF EXISTS shift_templates;
@@ -0,0 +1,19 @@
+ALTER TABLE employees DROP CONSTRAINT IF EXISTS fk_departments_employees;
+ALTER TABLE employees ADD CONSTRAINT fk_departments_employees FOREIGN KEY (department_id) REFERENCES departments(id);
+ALTER TABLE shift_weeks DROP CONSTRAINT IF EXISTS fk_departments_shift_weeks;
+ALTER TABLE shift_weeks ADD CONSTRAINT fk_departments_shift_weeks FOREIGN KEY (department_id) REFERENCES departments(id);
+ALTER TABLE shift_templates DROP CONSTRAINT IF EXISTS fk_shift_templates_department;
+ALTER TABLE shift_templates ADD CONSTRAINT fk_shift_templates_department FOREIGN KEY (department_id) REFERENCES departments(id);
+ALTER TABLE shift_days DROP CONSTRAINT IF EXISTS fk_employees_shift_days;
+ALTER TABLE shift_days ADD CONSTRAINT fk_employees_shift_days FOREIGN KEY (employee_id) REFERENCES employees(id);
+ALTER TABLE shift_days DROP CONSTRAINT IF EXISTS fk_shift_weeks_shift_days;
+ALTER TABLE shift_days ADD CONSTRAINT fk_shift_weeks_shift_days FOREIGN KEY (shift_week_id) REFERENCES shift_weeks(id);
+ALTER TABLE shift_days DROP CONSTRAINT IF EXISTS fk_shift_types_shift_days;
+ALTER TABLE shift_days ADD CONSTRAINT fk_shift_types_shift_days FOREIGN KEY (shift_type_id) REFERENCES shift_types(id);
+ALTER TABLE shift_template_days DROP CONSTRAINT IF EXISTS fk_shift_templates_shift_days;
+ALTER TABLE shift_template_days ADD CONSTRAINT fk_shift_templates_shift_days FOREIGN KEY (shift_template_id) REFERENCES shift_templates(id);
+ALTER TABLE shift_template_days DROP CONSTRAINT IF EXISTS fk_shift_template_days_shift_type;
+ALTER TABLE shift_template_days ADD CONSTRAINT fk_shift_template_days_shift_type FOREIGN KEY (shift_type_id) REFERENCES shift_types(id);
+ALTER TABLE notifications DROP CONSTRAINT IF EXISTS fk_notifications_employee;
+ALTER TABLE notification_preferences DROP CONSTRAINT IF EXISTS fk_notification_preferences_employee;
+ALTER TABLE webhook_deliveries DROP CONSTRAINT IF EXISTS fk_webhook_deliveries_subscription;
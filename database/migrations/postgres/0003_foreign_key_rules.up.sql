@@ -0,0 +1,38 @@
+-- Fremdschlüssel mit ausdrücklichen ON DELETE-Regeln. Die Anwendung löscht
+-- weich und wendet dieselben Regeln in database/references.go an.
+
+-- Verwaiste Verweise bereinigen
+UPDATE employees SET department_id = NULL WHERE department_id IS NOT NULL AND department_id NOT IN (SELECT id FROM departments);
+UPDATE shift_weeks SET department_id = NULL WHERE department_id IS NOT NULL AND department_id NOT IN (SELECT id FROM departments);
+DELETE FROM shift_templates WHERE department_id IS NOT NULL AND department_id NOT IN (SELECT id FROM departments);
+UPDATE shift_days SET employee_id = NULL WHERE employee_id IS NOT NULL AND employee_id NOT IN (SELECT id FROM employees);
+UPDATE shift_days SET shift_week_id = NULL WHERE shift_week_id IS NOT NULL AND shift_week_id NOT IN (SELECT id FROM shift_weeks);
+DELETE FROM shift_days WHERE shift_type_id IS NOT NULL AND shift_type_id NOT IN (SELECT id FROM shift_types);
+DELETE FROM shift_template_days WHERE shift_template_id IS NOT NULL AND shift_template_id NOT IN (SELECT id FROM shift_templates);
+DELETE FROM shift_template_days WHERE shift_type_id IS NOT NULL AND shift_type_id NOT IN (SELECT id FROM shift_types);
+DELETE FROM notifications WHERE employee_id IS NOT NULL AND employee_id NOT IN (SELECT id FROM employees);
+DELETE FROM notification_preferences WHERE employee_id IS NOT NULL AND employee_id NOT IN (SELECT id FROM employees);
+DELETE FROM webhook_deliveries WHERE subscription_id IS NOT NULL AND subscription_id NOT IN (SELECT id FROM webhook_subscriptions);
+
+ALTER TABLE employees DROP CONSTRAINT IF EXISTS fk_departments_employees;
+ALTER TABLE employees ADD CONSTRAINT fk_departments_employees FOREIGN KEY (department_id) REFERENCES departments(id) ON DELETE SET NULL;
+ALTER TABLE shift_weeks DROP CONSTRAINT IF EXISTS fk_departments_shift_weeks;
+ALTER TABLE shift_weeks ADD CONSTRAINT fk_departments_shift_weeks FOREIGN KEY (department_id) REFERENCES departments(id) ON DELETE CASCADE;
+ALTER TABLE shift_templates DROP CONSTRAINT IF EXISTS fk_shift_templates_department;
+ALTER TABLE shift_templates ADD CONSTRAINT fk_shift_templates_department FOREIGN KEY (department_id) REFERENCES departments(id) ON DELETE CASCADE;
+ALTER TABLE shift_days DROP CONSTRAINT IF EXISTS fk_employees_shift_days;
+ALTER TABLE shift_days ADD CONSTRAINT fk_employees_shift_days FOREIGN KEY (employee_id) REFERENCES employees(id) ON DELETE SET NULL;
+ALTER TABLE shift_days DROP CONSTRAINT IF EXISTS fk_shift_weeks_shift_days;
+ALTER TABLE shift_days ADD CONSTRAINT fk_shift_weeks_shift_days FOREIGN KEY (shift_week_id) REFERENCES shift_weeks(id) ON DELETE CASCADE;
+ALTER TABLE shift_days DROP CONSTRAINT IF EXISTS fk_shift_types_shift_days;
+ALTER TABLE shift_days ADD CONSTRAINT fk_shift_types_shift_days FOREIGN KEY (shift_type_id) REFERENCES shift_types(id) ON DELETE RESTRICT;
+ALTER TABLE shift_template_days DROP CONSTRAINT IF EXISTS fk_shift_templates_shift_days;
+ALTER TABLE shift_template_days ADD CONSTRAINT fk_shift_templates_shift_days FOREIGN KEY (shift_template_id) REFERENCES shift_templates(id) ON DELETE CASCADE;
+ALTER TABLE shift_template_days DROP CONSTRAINT IF EXISTS fk_shift_template_days_shift_type;
+ALTER TABLE shift_template_days ADD CONSTRAINT fk_shift_template_days_shift_type FOREIGN KEY (shift_type_id) REFERENCES shift_types(id) ON DELETE RESTRICT;
+ALTER TABLE notifications DROP CONSTRAINT IF EXISTS fk_notifications_employee;
+ALTER TABLE notifications ADD CONSTRAINT fk_notifications_employee FOREIGN KEY (employee_id) REFERENCES employees(id) ON DELETE CASCADE;
+ALTER TABLE notification_preferences DROP CONSTRAINT IF EXISTS fk_notification_preferences_employee;
+ALTER TABLE notification_preferences ADD CONSTRAINT fk_notification_preferences_employee FOREIGN KEY (employee_id) REFERENCES employees(id) ON DELETE CASCADE;
+ALTER TABLE webhook_deliveries DROP CONSTRAINT IF EXISTS fk_webhook_deliveries_subscription;
+ALTER TABLE webhook_deliveries ADD CONSTRAINT fk_webhook_deliveries_subscription FOREIGN KEY (subscription_id) REFERENCES webhook_subscriptions(id) ON DELETE CASCADE;
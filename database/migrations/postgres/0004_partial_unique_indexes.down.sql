@@ -0,0 +1,13 @@
+-- Schlägt fehl, falls gelöschte und aktive Datensätze denselben Wert haben.
+
+DROP INDEX IF EXISTS idx_shift_date_employee;
+CREATE UNIQUE INDEX idx_shift_date_employee ON shift_days(date, employee_id);
+
+DROP INDEX IF EXISTS idx_shift_types_name;
+ALTER TABLE shift_types ADD CONSTRAINT uni_shift_types_name UNIQUE (name);
+
+DROP INDEX IF EXISTS idx_employees_email;
+ALTER TABLE employees ADD CONSTRAINT uni_employees_email UNIQUE (email);
+
+DROP INDEX IF EXISTS idx_departments_name;
+CREATE UNIQUE INDEX idx_departments_name ON departments(name);
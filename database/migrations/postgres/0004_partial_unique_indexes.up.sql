@@ -0,0 +1,15 @@
+-- Eindeutigkeit nur noch für nicht gelöschte Datensätze, damit gelöschte
+-- Abteilungen, Mitarbeiter und Schichttypen im Papierkorb keine neuen
+-- Einträge mit gleichem Namen bzw. gleicher E-Mail blockieren.
+
+DROP INDEX IF EXISTS idx_departments_name;
+CREATE UNIQUE INDEX idx_departments_name ON departments(name) WHERE deleted_at IS NULL;
+
+ALTER TABLE employees DROP CONSTRAINT IF EXISTS uni_employees_email;
+CREATE UNIQUE INDEX idx_employees_email ON employees(email) WHERE deleted_at IS NULL;
+
+ALTER TABLE shift_types DROP CONSTRAINT IF EXISTS uni_shift_types_name;
+CREATE UNIQUE INDEX idx_shift_types_name ON shift_types(name) WHERE deleted_at IS NULL;
+
+DROP INDEX IF EXISTS idx_shift_date_employee;
+CREATE UNIQUE INDEX idx_shift_date_employee ON shift_days(date, employee_id) WHERE deleted_at IS NULL;
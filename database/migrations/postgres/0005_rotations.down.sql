@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_shift_days_rotation_id;
+ALTER TABLE shift_days DROP COLUMN IF EXISTS rotation_id;
+
+DROP TABLE IF EXISTS rotation_crew_members;
+DROP TABLE IF EXISTS rotation_crews;
+DROP TABLE IF EXISTS rotation_slots;
+DROP TABLE IF EXISTS rotations;
@@ -0,0 +1,72 @@
+-- Rotationen: ein Muster über mehrere Wochen, das feste Schichtgruppen mit
+-- Versatz durchlaufen. Erzeugte Schichttage verweisen auf ihre Rotation.
+
+CREATE TABLE rotations (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at timestamptz,
+    created_by bigint DEFAULT NULL,
+    updated_by bigint DEFAULT NULL,
+    version integer DEFAULT 1,
+    name varchar(100) NOT NULL,
+    description text,
+    department_id bigint NOT NULL,
+    cycle_weeks integer NOT NULL CHECK (cycle_weeks >= 1),
+    start_date timestamptz NOT NULL,
+    CONSTRAINT fk_rotations_department FOREIGN KEY (department_id) REFERENCES departments(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_rotations_deleted_at ON rotations(deleted_at);
+
+CREATE TABLE rotation_slots (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at timestamptz,
+    created_by bigint DEFAULT NULL,
+    updated_by bigint DEFAULT NULL,
+    version integer DEFAULT 1,
+    rotation_id bigint NOT NULL,
+    week integer NOT NULL CHECK (week >= 0),
+    week_day integer NOT NULL CHECK (week_day >= 0 AND week_day <= 6),
+    shift_type_id bigint NOT NULL,
+    CONSTRAINT fk_rotations_slots FOREIGN KEY (rotation_id) REFERENCES rotations(id) ON DELETE CASCADE,
+    CONSTRAINT fk_rotation_slots_shift_type FOREIGN KEY (shift_type_id) REFERENCES shift_types(id) ON DELETE RESTRICT
+);
+CREATE INDEX idx_rotation_slots_deleted_at ON rotation_slots(deleted_at);
+CREATE INDEX idx_rotation_slots_rotation_id ON rotation_slots(rotation_id);
+
+CREATE TABLE rotation_crews (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at timestamptz,
+    created_by bigint DEFAULT NULL,
+    updated_by bigint DEFAULT NULL,
+    version integer DEFAULT 1,
+    rotation_id bigint NOT NULL,
+    name varchar(100) NOT NULL,
+    start_offset integer NOT NULL DEFAULT 0 CHECK (start_offset >= 0),
+    CONSTRAINT fk_rotations_crews FOREIGN KEY (rotation_id) REFERENCES rotations(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_rotation_crews_deleted_at ON rotation_crews(deleted_at);
+CREATE INDEX idx_rotation_crews_rotation_id ON rotation_crews(rotation_id);
+
+CREATE TABLE rotation_crew_members (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at timestamptz,
+    created_by bigint DEFAULT NULL,
+    updated_by bigint DEFAULT NULL,
+    version integer DEFAULT 1,
+    rotation_crew_id bigint NOT NULL,
+    employee_id bigint NOT NULL,
+    CONSTRAINT fk_rotation_crews_members FOREIGN KEY (rotation_crew_id) REFERENCES rotation_crews(id) ON DELETE CASCADE,
+    CONSTRAINT fk_rotation_crew_members_employee FOREIGN KEY (employee_id) REFERENCES employees(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_rotation_crew_members_deleted_at ON rotation_crew_members(deleted_at);
+CREATE INDEX idx_rotation_crew_members_rotation_crew_id ON rotation_crew_members(rotation_crew_id);
+
+ALTER TABLE shift_days ADD COLUMN rotation_id bigint DEFAULT NULL REFERENCES rotations(id) ON DELETE SET NULL;
+CREATE INDEX idx_shift_days_rotation_id ON shift_days(rotation_id);
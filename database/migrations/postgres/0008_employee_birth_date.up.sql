@@ -0,0 +1,3 @@
+-- Geburtsdatum für den Jugendarbeitsschutz
+
+ALTER TABLE employees ADD COLUMN birth_date timestamptz;
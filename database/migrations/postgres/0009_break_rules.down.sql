@@ -0,0 +1,3 @@
+ALTER TABLE shift_days DROP COLUMN break_minutes;
+ALTER TABLE shift_types DROP COLUMN breaks_paid;
+ALTER TABLE shift_types DROP COLUMN break_minutes;
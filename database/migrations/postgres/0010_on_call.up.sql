@@ -0,0 +1,40 @@
+-- Rufbereitschaften mit Einsätzen. Sie liegen neben den Schichttagen, weil
+-- sie sich mit Schichten überschneiden dürfen.
+
+CREATE TABLE on_call_assignments (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at timestamptz,
+    created_by bigint DEFAULT NULL,
+    updated_by bigint DEFAULT NULL,
+    version integer DEFAULT 1,
+    employee_id bigint NOT NULL,
+    department_id bigint NOT NULL,
+    starts_at timestamptz NOT NULL,
+    ends_at timestamptz NOT NULL,
+    compensation_class varchar(20) NOT NULL,
+    notes text,
+    CONSTRAINT fk_on_call_assignments_employee FOREIGN KEY (employee_id) REFERENCES employees(id) ON DELETE CASCADE,
+    CONSTRAINT fk_on_call_assignments_department FOREIGN KEY (department_id) REFERENCES departments(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_on_call_assignments_deleted_at ON on_call_assignments(deleted_at);
+CREATE INDEX idx_on_call_assignments_employee_id ON on_call_assignments(employee_id);
+CREATE INDEX idx_on_call_assignments_department_id ON on_call_assignments(department_id);
+
+CREATE TABLE on_call_callouts (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at timestamptz,
+    created_by bigint DEFAULT NULL,
+    updated_by bigint DEFAULT NULL,
+    version integer DEFAULT 1,
+    on_call_assignment_id bigint NOT NULL,
+    started_at timestamptz NOT NULL,
+    ended_at timestamptz NOT NULL,
+    description text,
+    CONSTRAINT fk_on_call_assignments_callouts FOREIGN KEY (on_call_assignment_id) REFERENCES on_call_assignments(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_on_call_callouts_deleted_at ON on_call_callouts(deleted_at);
+CREATE INDEX idx_on_call_callouts_on_call_assignment_id ON on_call_callouts(on_call_assignment_id);
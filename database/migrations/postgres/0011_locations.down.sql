@@ -0,0 +1,7 @@
+-- Schlägt fehl, falls an mehreren Standorten gleichnamige Abteilungen aktiv sind.
+
+DROP INDEX IF EXISTS idx_departments_location_name;
+CREATE UNIQUE INDEX idx_departments_name ON departments(name) WHERE deleted_at IS NULL;
+
+ALTER TABLE departments DROP COLUMN location_id;
+DROP TABLE IF EXISTS locations;
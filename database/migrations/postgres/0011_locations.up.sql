@@ -0,0 +1,32 @@
+-- Standorte oberhalb der Abteilungen. Bestehende Abteilungen kommen in einen
+-- Standardstandort ohne Landesfeiertage, damit sich Berichte nicht ändern.
+-- Abteilungsnamen sind nur noch je Standort eindeutig.
+
+CREATE TABLE locations (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at timestamptz,
+    created_by bigint DEFAULT NULL,
+    updated_by bigint DEFAULT NULL,
+    version integer DEFAULT 1,
+    name text NOT NULL,
+    street text,
+    postal_code varchar(10),
+    city text,
+    timezone varchar(64) NOT NULL DEFAULT 'Europe/Berlin',
+    holiday_state varchar(2)
+);
+CREATE INDEX idx_locations_deleted_at ON locations(deleted_at);
+CREATE UNIQUE INDEX idx_locations_name ON locations(name) WHERE deleted_at IS NULL;
+
+INSERT INTO locations (name) VALUES ('Hauptstandort');
+
+ALTER TABLE departments ADD COLUMN location_id bigint;
+UPDATE departments SET location_id = (SELECT MIN(id) FROM locations);
+ALTER TABLE departments ALTER COLUMN location_id SET NOT NULL;
+ALTER TABLE departments ADD CONSTRAINT fk_locations_departments FOREIGN KEY (location_id) REFERENCES locations(id) ON DELETE RESTRICT;
+CREATE INDEX idx_departments_location_id ON departments(location_id);
+
+DROP INDEX IF EXISTS idx_departments_name;
+CREATE UNIQUE INDEX idx_departments_location_name ON departments(location_id, name) WHERE deleted_at IS NULL;
@@ -0,0 +1,67 @@
+-- Mandanten für Schwesterfirmen. Alle bestehenden Daten gehören zum
+-- Standardmandanten 1. Mandanten werden nie gelöscht, nur deaktiviert,
+-- deshalb verweist tenant_id ohne Fremdschlüssel auf tenants.
+-- Eindeutige Namen und E-Mail-Adressen gelten nur noch je Mandant.
+
+CREATE TABLE tenants (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    name text NOT NULL,
+    slug varchar(63) NOT NULL,
+    token_hash varchar(64),
+    active boolean NOT NULL DEFAULT true
+);
+CREATE UNIQUE INDEX idx_tenants_slug ON tenants(slug);
+CREATE UNIQUE INDEX idx_tenants_token_hash ON tenants(token_hash);
+
+INSERT INTO tenants (id, name, slug) VALUES (1, 'Standard', 'default');
+SELECT setval('tenants_id_seq', 1);
+
+ALTER TABLE webhook_deliveries ADD COLUMN tenant_id bigint NOT NULL DEFAULT 1;
+CREATE INDEX idx_webhook_deliveries_tenant_id ON webhook_deliveries(tenant_id);
+ALTER TABLE notification_preferences ADD COLUMN tenant_id bigint NOT NULL DEFAULT 1;
+CREATE INDEX idx_notification_preferences_tenant_id ON notification_preferences(tenant_id);
+ALTER TABLE notifications ADD COLUMN tenant_id bigint NOT NULL DEFAULT 1;
+CREATE INDEX idx_notifications_tenant_id ON notifications(tenant_id);
+ALTER TABLE shift_template_days ADD COLUMN tenant_id bigint NOT NULL DEFAULT 1;
+CREATE INDEX idx_shift_template_days_tenant_id ON shift_template_days(tenant_id);
+ALTER TABLE shift_preferences ADD COLUMN tenant_id bigint NOT NULL DEFAULT 1;
+CREATE INDEX idx_shift_preferences_tenant_id ON shift_preferences(tenant_id);
+ALTER TABLE contracts ADD COLUMN tenant_id bigint NOT NULL DEFAULT 1;
+CREATE INDEX idx_contracts_tenant_id ON contracts(tenant_id);
+ALTER TABLE on_call_callouts ADD COLUMN tenant_id bigint NOT NULL DEFAULT 1;
+CREATE INDEX idx_on_call_callouts_tenant_id ON on_call_callouts(tenant_id);
+ALTER TABLE on_call_assignments ADD COLUMN tenant_id bigint NOT NULL DEFAULT 1;
+CREATE INDEX idx_on_call_assignments_tenant_id ON on_call_assignments(tenant_id);
+ALTER TABLE rotation_crew_members ADD COLUMN tenant_id bigint NOT NULL DEFAULT 1;
+CREATE INDEX idx_rotation_crew_members_tenant_id ON rotation_crew_members(tenant_id);
+ALTER TABLE rotation_crews ADD COLUMN tenant_id bigint NOT NULL DEFAULT 1;
+CREATE INDEX idx_rotation_crews_tenant_id ON rotation_crews(tenant_id);
+ALTER TABLE rotation_slots ADD COLUMN tenant_id bigint NOT NULL DEFAULT 1;
+CREATE INDEX idx_rotation_slots_tenant_id ON rotation_slots(tenant_id);
+ALTER TABLE shift_days ADD COLUMN tenant_id bigint NOT NULL DEFAULT 1;
+CREATE INDEX idx_shift_days_tenant_id ON shift_days(tenant_id);
+ALTER TABLE shift_templates ADD COLUMN tenant_id bigint NOT NULL DEFAULT 1;
+CREATE INDEX idx_shift_templates_tenant_id ON shift_templates(tenant_id);
+ALTER TABLE shift_weeks ADD COLUMN tenant_id bigint NOT NULL DEFAULT 1;
+CREATE INDEX idx_shift_weeks_tenant_id ON shift_weeks(tenant_id);
+ALTER TABLE rotations ADD COLUMN tenant_id bigint NOT NULL DEFAULT 1;
+CREATE INDEX idx_rotations_tenant_id ON rotations(tenant_id);
+ALTER TABLE shift_types ADD COLUMN tenant_id bigint NOT NULL DEFAULT 1;
+CREATE INDEX idx_shift_types_tenant_id ON shift_types(tenant_id);
+ALTER TABLE employees ADD COLUMN tenant_id bigint NOT NULL DEFAULT 1;
+CREATE INDEX idx_employees_tenant_id ON employees(tenant_id);
+ALTER TABLE webhook_subscriptions ADD COLUMN tenant_id bigint NOT NULL DEFAULT 1;
+CREATE INDEX idx_webhook_subscriptions_tenant_id ON webhook_subscriptions(tenant_id);
+ALTER TABLE departments ADD COLUMN tenant_id bigint NOT NULL DEFAULT 1;
+CREATE INDEX idx_departments_tenant_id ON departments(tenant_id);
+ALTER TABLE locations ADD COLUMN tenant_id bigint NOT NULL DEFAULT 1;
+CREATE INDEX idx_locations_tenant_id ON locations(tenant_id);
+
+DROP INDEX idx_employees_email;
+CREATE UNIQUE INDEX idx_employees_email ON employees(tenant_id, email) WHERE deleted_at IS NULL;
+DROP INDEX idx_shift_types_name;
+CREATE UNIQUE INDEX idx_shift_types_name ON shift_types(tenant_id, name) WHERE deleted_at IS NULL;
+DROP INDEX idx_locations_name;
+CREATE UNIQUE INDEX idx_locations_name ON locations(tenant_id, name) WHERE deleted_at IS NULL;
@@ -0,0 +1,7 @@
+DROP INDEX idx_shift_weeks_team_id;
+ALTER TABLE shift_weeks DROP COLUMN team_id;
+
+DROP INDEX idx_employees_team_id;
+ALTER TABLE employees DROP COLUMN team_id;
+
+DROP TABLE teams;
@@ -0,0 +1 @@
+ALTER TABLE notification_preferences ADD COLUMN decisions boolean NOT NULL DEFAULT true;
@@ -0,0 +1,181 @@
+-- Ausgangsschema, entspricht dem bisher per AutoMigrate erzeugten Stand.
+-- IF NOT EXISTS erlaubt es, bestehende Datenbanken ohne Datenverlust zu übernehmen.
+
+CREATE TABLE IF NOT EXISTS departments (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at datetime,
+    created_by integer DEFAULT NULL,
+    updated_by integer DEFAULT NULL,
+    version integer DEFAULT 1,
+    name text NOT NULL,
+    color text NOT NULL,
+    description text
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_departments_name ON departments(name);
+CREATE INDEX IF NOT EXISTS idx_departments_deleted_at ON departments(deleted_at);
+
+CREATE TABLE IF NOT EXISTS employees (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at datetime,
+    created_by integer DEFAULT NULL,
+    updated_by integer DEFAULT NULL,
+    version integer DEFAULT 1,
+    first_name text NOT NULL,
+    last_name text NOT NULL,
+    email text NOT NULL,
+    password text NOT NULL,
+    color text NOT NULL,
+    is_admin numeric DEFAULT false,
+    department_id integer,
+    CONSTRAINT fk_departments_employees FOREIGN KEY (department_id) REFERENCES departments(id),
+    CONSTRAINT uni_employees_email UNIQUE (email)
+);
+CREATE INDEX IF NOT EXISTS idx_employees_deleted_at ON employees(deleted_at);
+
+CREATE TABLE IF NOT EXISTS shift_types (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at datetime,
+    created_by integer DEFAULT NULL,
+    updated_by integer DEFAULT NULL,
+    version integer DEFAULT 1,
+    name text NOT NULL,
+    description text,
+    color text NOT NULL,
+    start_time text NOT NULL,
+    end_time text NOT NULL,
+    CONSTRAINT uni_shift_types_name UNIQUE (name)
+);
+CREATE INDEX IF NOT EXISTS idx_shift_types_deleted_at ON shift_types(deleted_at);
+
+CREATE TABLE IF NOT EXISTS shift_weeks (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at datetime,
+    created_by integer DEFAULT NULL,
+    updated_by integer DEFAULT NULL,
+    version integer DEFAULT 1,
+    calendar_week integer NOT NULL,
+    year integer NOT NULL,
+    department_id integer,
+    status varchar(20) DEFAULT 'draft',
+    notes text,
+    published_at datetime,
+    CONSTRAINT fk_departments_shift_weeks FOREIGN KEY (department_id) REFERENCES departments(id)
+);
+CREATE INDEX IF NOT EXISTS idx_shift_weeks_calendar_week ON shift_weeks(calendar_week);
+CREATE INDEX IF NOT EXISTS idx_shift_weeks_year ON shift_weeks(year);
+CREATE INDEX IF NOT EXISTS idx_shift_weeks_deleted_at ON shift_weeks(deleted_at);
+
+CREATE TABLE IF NOT EXISTS shift_days (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at datetime,
+    created_by integer DEFAULT NULL,
+    updated_by integer DEFAULT NULL,
+    version integer DEFAULT 1,
+    date datetime NOT NULL,
+    shift_week_id integer,
+    shift_type_id integer NOT NULL,
+    employee_id integer,
+    notes text,
+    status varchar(20) DEFAULT 'planned',
+    CONSTRAINT fk_employees_shift_days FOREIGN KEY (employee_id) REFERENCES employees(id),
+    CONSTRAINT fk_shift_weeks_shift_days FOREIGN KEY (shift_week_id) REFERENCES shift_weeks(id),
+    CONSTRAINT fk_shift_types_shift_days FOREIGN KEY (shift_type_id) REFERENCES shift_types(id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_shift_date_employee ON shift_days(date, employee_id);
+CREATE INDEX IF NOT EXISTS idx_shift_days_deleted_at ON shift_days(deleted_at);
+
+CREATE TABLE IF NOT EXISTS notifications (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at datetime,
+    created_by integer DEFAULT NULL,
+    updated_by integer DEFAULT NULL,
+    version integer DEFAULT 1,
+    employee_id integer NOT NULL,
+    channel varchar(20) NOT NULL,
+    kind varchar(30) NOT NULL,
+    recipient text NOT NULL,
+    subject text NOT NULL,
+    body_text text,
+    body_html text,
+    dedupe_key text,
+    status varchar(20) DEFAULT 'pending',
+    attempts integer DEFAULT 0,
+    next_attempt_at datetime,
+    last_error text,
+    sent_at datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_notifications_dedupe_key ON notifications(dedupe_key);
+CREATE INDEX IF NOT EXISTS idx_notifications_employee_id ON notifications(employee_id);
+CREATE INDEX IF NOT EXISTS idx_notifications_status ON notifications(status);
+CREATE INDEX IF NOT EXISTS idx_notifications_next_attempt_at ON notifications(next_attempt_at);
+CREATE INDEX IF NOT EXISTS idx_notifications_deleted_at ON notifications(deleted_at);
+
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at datetime,
+    created_by integer DEFAULT NULL,
+    updated_by integer DEFAULT NULL,
+    version integer DEFAULT 1,
+    employee_id integer NOT NULL,
+    email_enabled numeric NOT NULL,
+    week_published numeric NOT NULL,
+    shift_changed numeric NOT NULL,
+    decisions numeric NOT NULL,
+    reminders numeric NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_notification_preferences_employee_id ON notification_preferences(employee_id);
+CREATE INDEX IF NOT EXISTS idx_notification_preferences_deleted_at ON notification_preferences(deleted_at);
+
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at datetime,
+    created_by integer DEFAULT NULL,
+    updated_by integer DEFAULT NULL,
+    version integer DEFAULT 1,
+    url text NOT NULL,
+    secret text NOT NULL,
+    event_types text,
+    description text,
+    active numeric NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_subscriptions_deleted_at ON webhook_subscriptions(deleted_at);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at datetime,
+    created_by integer DEFAULT NULL,
+    updated_by integer DEFAULT NULL,
+    version integer DEFAULT 1,
+    subscription_id integer NOT NULL,
+    event_type varchar(50) NOT NULL,
+    payload text NOT NULL,
+    status varchar(20) DEFAULT 'pending',
+    attempts integer DEFAULT 0,
+    next_attempt_at datetime,
+    response_status integer,
+    response_body text,
+    last_error text,
+    delivered_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_subscription_id ON webhook_deliveries(subscription_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_status ON webhook_deliveries(status);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_next_attempt_at ON webhook_deliveries(next_attempt_at);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_deleted_at ON webhook_deliveries(deleted_at);
@@ -0,0 +1,36 @@
+-- Schichtvorlagen wurden von AutoMigrate wegen des enum-Typs nie angelegt.
+
+CREATE TABLE IF NOT EXISTS shift_templates (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at datetime,
+    created_by integer DEFAULT NULL,
+    updated_by integer DEFAULT NULL,
+    version integer DEFAULT 1,
+    name varchar(100) NOT NULL,
+    description text,
+    department_id integer NOT NULL,
+    status varchar(20) DEFAULT 'draft' CHECK (status IN ('draft', 'active', 'inactive')),
+    valid_from datetime NOT NULL,
+    valid_until datetime NOT NULL,
+    CONSTRAINT fk_shift_templates_department FOREIGN KEY (department_id) REFERENCES departments(id)
+);
+CREATE INDEX IF NOT EXISTS idx_shift_templates_deleted_at ON shift_templates(deleted_at);
+
+CREATE TABLE IF NOT EXISTS shift_template_days (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at datetime,
+    created_by integer DEFAULT NULL,
+    updated_by integer DEFAULT NULL,
+    version integer DEFAULT 1,
+    shift_template_id integer NOT NULL,
+    shift_type_id integer NOT NULL,
+    week_day integer NOT NULL CHECK (week_day >= 0 AND week_day <= 6),
+    notes text,
+    CONSTRAINT fk_shift_templates_shift_days FOREIGN KEY (shift_template_id) REFERENCES shift_templates(id),
+    CONSTRAINT fk_shift_template_days_shift_type FOREIGN KEY (shift_type_id) REFERENCES shift_types(id)
+);
+CREATE INDEX IF NOT EXISTS idx_shift_template_days_deleted_at ON shift_template_days(deleted_at);
@@ -0,0 +1,222 @@
+-- Fremdschlüssel mit ausdrücklichen ON DELETE-Regeln. Die Anwendung löscht
+-- weich und wendet dieselben Regeln in database/references.go an.
+--
+-- SQLite kann Fremdschlüssel nicht nachträglich ändern, daher werden die
+-- betroffenen Tabellen neu aufgebaut. Eltern vor Kindern, damit beim
+-- Neuaufbau keine neuen Löschregeln ausgelöst werden. Die Prüfung der
+-- Fremdschlüssel wird bis zum Ende der Transaktion aufgeschoben.
+PRAGMA defer_foreign_keys = ON;
+
+-- Verwaiste Verweise aus der Zeit ohne Fremdschlüsselprüfung bereinigen
+UPDATE employees SET department_id = NULL WHERE department_id IS NOT NULL AND department_id NOT IN (SELECT id FROM departments);
+UPDATE shift_weeks SET department_id = NULL WHERE department_id IS NOT NULL AND department_id NOT IN (SELECT id FROM departments);
+DELETE FROM shift_templates WHERE department_id IS NOT NULL AND department_id NOT IN (SELECT id FROM departments);
+UPDATE shift_days SET employee_id = NULL WHERE employee_id IS NOT NULL AND employee_id NOT IN (SELECT id FROM employees);
+UPDATE shift_days SET shift_week_id = NULL WHERE shift_week_id IS NOT NULL AND shift_week_id NOT IN (SELECT id FROM shift_weeks);
+DELETE FROM shift_days WHERE shift_type_id IS NOT NULL AND shift_type_id NOT IN (SELECT id FROM shift_types);
+DELETE FROM shift_template_days WHERE shift_template_id IS NOT NULL AND shift_template_id NOT IN (SELECT id FROM shift_templates);
+DELETE FROM shift_template_days WHERE shift_type_id IS NOT NULL AND shift_type_id NOT IN (SELECT id FROM shift_types);
+DELETE FROM notifications WHERE employee_id IS NOT NULL AND employee_id NOT IN (SELECT id FROM employees);
+DELETE FROM notification_preferences WHERE employee_id IS NOT NULL AND employee_id NOT IN (SELECT id FROM employees);
+DELETE FROM webhook_deliveries WHERE subscription_id IS NOT NULL AND subscription_id NOT IN (SELECT id FROM webhook_subscriptions);
+
+CREATE TEMP TABLE employees_backup AS SELECT * FROM employees;
+DROP TABLE employees;
+CREATE TABLE employees (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at datetime,
+    created_by integer DEFAULT NULL,
+    updated_by integer DEFAULT NULL,
+    version integer DEFAULT 1,
+    first_name text NOT NULL,
+    last_name text NOT NULL,
+    email text NOT NULL,
+    password text NOT NULL,
+    color text NOT NULL,
+    is_admin numeric DEFAULT false,
+    department_id integer,
+    CONSTRAINT fk_departments_employees FOREIGN KEY (department_id) REFERENCES departments(id) ON DELETE SET NULL,
+    CONSTRAINT uni_employees_email UNIQUE (email)
+);
+INSERT INTO employees SELECT * FROM employees_backup;
+DROP TABLE employees_backup;
+CREATE INDEX idx_employees_deleted_at ON employees(deleted_at);
+
+CREATE TEMP TABLE shift_weeks_backup AS SELECT * FROM shift_weeks;
+DROP TABLE shift_weeks;
+CREATE TABLE shift_weeks (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at datetime,
+    created_by integer DEFAULT NULL,
+    updated_by integer DEFAULT NULL,
+    version integer DEFAULT 1,
+    calendar_week integer NOT NULL,
+    year integer NOT NULL,
+    department_id integer,
+    status varchar(20) DEFAULT 'draft',
+    notes text,
+    published_at datetime,
+    CONSTRAINT fk_departments_shift_weeks FOREIGN KEY (department_id) REFERENCES departments(id) ON DELETE CASCADE
+);
+INSERT INTO shift_weeks SELECT * FROM shift_weeks_backup;
+DROP TABLE shift_weeks_backup;
+CREATE INDEX idx_shift_weeks_calendar_week ON shift_weeks(calendar_week);
+CREATE INDEX idx_shift_weeks_year ON shift_weeks(year);
+CREATE INDEX idx_shift_weeks_deleted_at ON shift_weeks(deleted_at);
+
+CREATE TEMP TABLE shift_templates_backup AS SELECT * FROM shift_templates;
+DROP TABLE shift_templates;
+CREATE TABLE shift_templates (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at datetime,
+    created_by integer DEFAULT NULL,
+    updated_by integer DEFAULT NULL,
+    version integer DEFAULT 1,
+    name varchar(100) NOT NULL,
+    description text,
+    department_id integer NOT NULL,
+    status varchar(20) DEFAULT 'draft' CHECK (status IN ('draft', 'active', 'inactive')),
+    valid_from datetime NOT NULL,
+    valid_until datetime NOT NULL,
+    CONSTRAINT fk_shift_templates_department FOREIGN KEY (department_id) REFERENCES departments(id) ON DELETE CASCADE
+);
+INSERT INTO shift_templates SELECT * FROM shift_templates_backup;
+DROP TABLE shift_templates_backup;
+CREATE INDEX idx_shift_templates_deleted_at ON shift_templates(deleted_at);
+
+CREATE TEMP TABLE shift_days_backup AS SELECT * FROM shift_days;
+DROP TABLE shift_days;
+CREATE TABLE shift_days (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at datetime,
+    created_by integer DEFAULT NULL,
+    updated_by integer DEFAULT NULL,
+    version integer DEFAULT 1,
+    date datetime NOT NULL,
+    shift_week_id integer,
+    shift_type_id integer NOT NULL,
+    employee_id integer,
+    notes text,
+    status varchar(20) DEFAULT 'planned',
+    CONSTRAINT fk_employees_shift_days FOREIGN KEY (employee_id) REFERENCES employees(id) ON DELETE SET NULL,
+    CONSTRAINT fk_shift_weeks_shift_days FOREIGN KEY (shift_week_id) REFERENCES shift_weeks(id) ON DELETE CASCADE,
+    CONSTRAINT fk_shift_types_shift_days FOREIGN KEY (shift_type_id) REFERENCES shift_types(id) ON DELETE RESTRICT
+);
+INSERT INTO shift_days SELECT * FROM shift_days_backup;
+DROP TABLE shift_days_backup;
+CREATE UNIQUE INDEX idx_shift_date_employee ON shift_days(date, employee_id);
+CREATE INDEX idx_shift_days_deleted_at ON shift_days(deleted_at);
+
+CREATE TEMP TABLE shift_template_days_backup AS SELECT * FROM shift_template_days;
+DROP TABLE shift_template_days;
+CREATE TABLE shift_template_days (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at datetime,
+    created_by integer DEFAULT NULL,
+    updated_by integer DEFAULT NULL,
+    version integer DEFAULT 1,
+    shift_template_id integer NOT NULL,
+    shift_type_id integer NOT NULL,
+    week_day integer NOT NULL CHECK (week_day >= 0 AND week_day <= 6),
+    notes text,
+    CONSTRAINT fk_shift_templates_shift_days FOREIGN KEY (shift_template_id) REFERENCES shift_templates(id) ON DELETE CASCADE,
+    CONSTRAINT fk_shift_template_days_shift_type FOREIGN KEY (shift_type_id) REFERENCES shift_types(id) ON DELETE RESTRICT
+);
+INSERT INTO shift_template_days SELECT * FROM shift_template_days_backup;
+DROP TABLE shift_template_days_backup;
+CREATE INDEX idx_shift_template_days_deleted_at ON shift_template_days(deleted_at);
+
+CREATE TEMP TABLE notifications_backup AS SELECT * FROM notifications;
+DROP TABLE notifications;
+CREATE TABLE notifications (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at datetime,
+    created_by integer DEFAULT NULL,
+    updated_by integer DEFAULT NULL,
+    version integer DEFAULT 1,
+    employee_id integer NOT NULL,
+    channel varchar(20) NOT NULL,
+    kind varchar(30) NOT NULL,
+    recipient text NOT NULL,
+    subject text NOT NULL,
+    body_text text,
+    body_html text,
+    dedupe_key text,
+    status varchar(20) DEFAULT 'pending',
+    attempts integer DEFAULT 0,
+    next_attempt_at datetime,
+    last_error text,
+    sent_at datetime,
+    CONSTRAINT fk_notifications_employee FOREIGN KEY (employee_id) REFERENCES employees(id) ON DELETE CASCADE
+);
+INSERT INTO notifications SELECT * FROM notifications_backup;
+DROP TABLE notifications_backup;
+CREATE UNIQUE INDEX idx_notifications_dedupe_key ON notifications(dedupe_key);
+CREATE INDEX idx_notifications_employee_id ON notifications(employee_id);
+CREATE INDEX idx_notifications_status ON notifications(status);
+CREATE INDEX idx_notifications_next_attempt_at ON notifications(next_attempt_at);
+CREATE INDEX idx_notifications_deleted_at ON notifications(deleted_at);
+
+CREATE TEMP TABLE notification_preferences_backup AS SELECT * FROM notification_preferences;
+DROP TABLE notification_preferences;
+CREATE TABLE notification_preferences (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at datetime,
+    created_by integer DEFAULT NULL,
+    updated_by integer DEFAULT NULL,
+    version integer DEFAULT 1,
+    employee_id integer NOT NULL,
+    email_enabled numeric NOT NULL,
+    week_published numeric NOT NULL,
+    shift_changed numeric NOT NULL,
+    decisions numeric NOT NULL,
+    reminders numeric NOT NULL,
+    CONSTRAINT fk_notification_preferences_employee FOREIGN KEY (employee_id) REFERENCES employees(id) ON DELETE CASCADE
+);
+INSERT INTO notification_preferences SELECT * FROM notification_preferences_backup;
+DROP TABLE notification_preferences_backup;
+CREATE UNIQUE INDEX idx_notification_preferences_employee_id ON notification_preferences(employee_id);
+CREATE INDEX idx_notification_preferences_deleted_at ON notification_preferences(deleted_at);
+
+CREATE TEMP TABLE webhook_deliveries_backup AS SELECT * FROM webhook_deliveries;
+DROP TABLE webhook_deliveries;
+CREATE TABLE webhook_deliveries (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at datetime,
+    created_by integer DEFAULT NULL,
+    updated_by integer DEFAULT NULL,
+    version integer DEFAULT 1,
+    subscription_id integer NOT NULL,
+    event_type varchar(50) NOT NULL,
+    payload text NOT NULL,
+    status varchar(20) DEFAULT 'pending',
+    attempts integer DEFAULT 0,
+    next_attempt_at datetime,
+    response_status integer,
+    response_body text,
+    last_error text,
+    delivered_at datetime,
+    CONSTRAINT fk_webhook_deliveries_subscription FOREIGN KEY (subscription_id) REFERENCES webhook_subscriptions(id) ON DELETE CASCADE
+);
+INSERT INTO webhook_deliveries SELECT * FROM webhook_deliveries_backup;
+DROP TABLE webhook_deliveries_backup;
+CREATE INDEX idx_webhook_deliveries_subscription_id ON webhook_deliveries(subscription_id);
+CREATE INDEX idx_webhook_deliveries_status ON webhook_deliveries(status);
+CREATE INDEX idx_webhook_deliveries_next_attempt_at ON webhook_deliveries(next_attempt_at);
+CREATE INDEX idx_webhook_deliveries_deleted_at ON webhook_deliveries(deleted_at);
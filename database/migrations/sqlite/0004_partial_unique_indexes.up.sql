@@ -0,0 +1,54 @@
+-- migrate:no-foreign-keys
+-- Eindeutigkeit nur noch für nicht gelöschte Datensätze, damit gelöschte
+-- Abteilungen, Mitarbeiter und Schichttypen im Papierkorb keine neuen
+-- Einträge mit gleichem Namen bzw. gleicher E-Mail blockieren.
+-- SQLite kann UNIQUE-Constraints nur durch Neuaufbau der Tabelle entfernen.
+
+DROP INDEX IF EXISTS idx_departments_name;
+CREATE UNIQUE INDEX idx_departments_name ON departments(name) WHERE deleted_at IS NULL;
+
+CREATE TABLE employees_new (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at datetime,
+    created_by integer DEFAULT NULL,
+    updated_by integer DEFAULT NULL,
+    version integer DEFAULT 1,
+    first_name text NOT NULL,
+    last_name text NOT NULL,
+    email text NOT NULL,
+    password text NOT NULL,
+    color text NOT NULL,
+    is_admin numeric DEFAULT false,
+    department_id integer,
+    CONSTRAINT fk_departments_employees FOREIGN KEY (department_id) REFERENCES departments(id) ON DELETE SET NULL
+);
+INSERT INTO employees_new SELECT * FROM employees;
+DROP TABLE employees;
+ALTER TABLE employees_new RENAME TO employees;
+CREATE INDEX idx_employees_deleted_at ON employees(deleted_at);
+CREATE UNIQUE INDEX idx_employees_email ON employees(email) WHERE deleted_at IS NULL;
+
+CREATE TABLE shift_types_new (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at datetime,
+    created_by integer DEFAULT NULL,
+    updated_by integer DEFAULT NULL,
+    version integer DEFAULT 1,
+    name text NOT NULL,
+    description text,
+    color text NOT NULL,
+    start_time text NOT NULL,
+    end_time text NOT NULL
+);
+INSERT INTO shift_types_new SELECT * FROM shift_types;
+DROP TABLE shift_types;
+ALTER TABLE shift_types_new RENAME TO shift_types;
+CREATE INDEX idx_shift_types_deleted_at ON shift_types(deleted_at);
+CREATE UNIQUE INDEX idx_shift_types_name ON shift_types(name) WHERE deleted_at IS NULL;
+
+DROP INDEX IF EXISTS idx_shift_date_employee;
+CREATE UNIQUE INDEX idx_shift_date_employee ON shift_days(date, employee_id) WHERE deleted_at IS NULL;
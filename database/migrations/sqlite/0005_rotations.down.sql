@@ -0,0 +1,34 @@
+-- migrate:no-foreign-keys
+-- SQLite kann eine Spalte mit Fremdschlüssel nur durch Neuaufbau der Tabelle entfernen.
+
+CREATE TABLE shift_days_new (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at datetime,
+    created_by integer DEFAULT NULL,
+    updated_by integer DEFAULT NULL,
+    version integer DEFAULT 1,
+    date datetime NOT NULL,
+    shift_week_id integer,
+    shift_type_id integer NOT NULL,
+    employee_id integer,
+    notes text,
+    status varchar(20) DEFAULT 'planned',
+    CONSTRAINT fk_employees_shift_days FOREIGN KEY (employee_id) REFERENCES employees(id) ON DELETE SET NULL,
+    CONSTRAINT fk_shift_weeks_shift_days FOREIGN KEY (shift_week_id) REFERENCES shift_weeks(id) ON DELETE CASCADE,
+    CONSTRAINT fk_shift_types_shift_days FOREIGN KEY (shift_type_id) REFERENCES shift_types(id) ON DELETE RESTRICT
+);
+INSERT INTO shift_days_new
+    SELECT id, created_at, updated_at, deleted_at, created_by, updated_by, version,
+           date, shift_week_id, shift_type_id, employee_id, notes, status
+    FROM shift_days;
+DROP TABLE shift_days;
+ALTER TABLE shift_days_new RENAME TO shift_days;
+CREATE UNIQUE INDEX idx_shift_date_employee ON shift_days(date, employee_id) WHERE deleted_at IS NULL;
+CREATE INDEX idx_shift_days_deleted_at ON shift_days(deleted_at);
+
+DROP TABLE rotation_crew_members;
+DROP TABLE rotation_crews;
+DROP TABLE rotation_slots;
+DROP TABLE rotations;
@@ -0,0 +1,22 @@
+-- Schichtwünsche der Mitarbeiter
+
+CREATE TABLE shift_preferences (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at datetime,
+    created_by integer DEFAULT NULL,
+    updated_by integer DEFAULT NULL,
+    version integer DEFAULT 1,
+    employee_id integer NOT NULL,
+    strength varchar(10) NOT NULL DEFAULT 'soft' CHECK (strength IN ('hard', 'soft')),
+    kind varchar(10) NOT NULL CHECK (kind IN ('avoid', 'prefer')),
+    week_day integer CHECK (week_day >= 0 AND week_day <= 6),
+    date datetime,
+    shift_type_id integer,
+    note text,
+    CONSTRAINT fk_shift_preferences_employee FOREIGN KEY (employee_id) REFERENCES employees(id) ON DELETE CASCADE,
+    CONSTRAINT fk_shift_preferences_shift_type FOREIGN KEY (shift_type_id) REFERENCES shift_types(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_shift_preferences_deleted_at ON shift_preferences(deleted_at);
+CREATE INDEX idx_shift_preferences_employee_id ON shift_preferences(employee_id);
@@ -0,0 +1,27 @@
+-- Vertragshistorie der Mitarbeiter. Bestehende Mitarbeiter erhalten einen
+-- unbefristeten Vollzeitvertrag ab ihrer Anlage bzw. ihrer ersten Schicht.
+
+CREATE TABLE contracts (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at datetime,
+    created_by integer DEFAULT NULL,
+    updated_by integer DEFAULT NULL,
+    version integer DEFAULT 1,
+    employee_id integer NOT NULL,
+    employment_type varchar(20) NOT NULL CHECK (employment_type IN ('full_time', 'part_time', 'mini_job', 'apprentice')),
+    valid_from datetime NOT NULL,
+    valid_to datetime,
+    weekly_hours real NOT NULL CHECK (weekly_hours >= 0),
+    max_days_per_week integer NOT NULL DEFAULT 0 CHECK (max_days_per_week >= 0 AND max_days_per_week <= 7),
+    CONSTRAINT fk_contracts_employee FOREIGN KEY (employee_id) REFERENCES employees(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_contracts_deleted_at ON contracts(deleted_at);
+CREATE INDEX idx_contracts_employee_id ON contracts(employee_id);
+
+INSERT INTO contracts (employee_id, employment_type, valid_from, weekly_hours, max_days_per_week, deleted_at)
+SELECT employees.id, 'full_time',
+       COALESCE(MIN(employees.created_at, (SELECT MIN(shift_days.date) FROM shift_days WHERE shift_days.employee_id = employees.id)), employees.created_at),
+       40, 0, employees.deleted_at
+FROM employees;
@@ -0,0 +1,6 @@
+-- Pausenregeln: Pausenlänge und bezahlte Pausen je Schichttyp, abweichende
+-- Pausenlänge je Schichttag
+
+ALTER TABLE shift_types ADD COLUMN break_minutes integer CHECK (break_minutes >= 0);
+ALTER TABLE shift_types ADD COLUMN breaks_paid numeric NOT NULL DEFAULT false;
+ALTER TABLE shift_days ADD COLUMN break_minutes integer CHECK (break_minutes >= 0);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS on_call_callouts;
+DROP TABLE IF EXISTS on_call_assignments;
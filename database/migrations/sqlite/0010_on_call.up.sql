@@ -0,0 +1,40 @@
+-- Rufbereitschaften mit Einsätzen. Sie liegen neben den Schichttagen, weil
+-- sie sich mit Schichten überschneiden dürfen.
+
+CREATE TABLE on_call_assignments (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at datetime,
+    created_by integer DEFAULT NULL,
+    updated_by integer DEFAULT NULL,
+    version integer DEFAULT 1,
+    employee_id integer NOT NULL,
+    department_id integer NOT NULL,
+    starts_at datetime NOT NULL,
+    ends_at datetime NOT NULL,
+    compensation_class varchar(20) NOT NULL,
+    notes text,
+    CONSTRAINT fk_on_call_assignments_employee FOREIGN KEY (employee_id) REFERENCES employees(id) ON DELETE CASCADE,
+    CONSTRAINT fk_on_call_assignments_department FOREIGN KEY (department_id) REFERENCES departments(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_on_call_assignments_deleted_at ON on_call_assignments(deleted_at);
+CREATE INDEX idx_on_call_assignments_employee_id ON on_call_assignments(employee_id);
+CREATE INDEX idx_on_call_assignments_department_id ON on_call_assignments(department_id);
+
+CREATE TABLE on_call_callouts (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at datetime,
+    created_by integer DEFAULT NULL,
+    updated_by integer DEFAULT NULL,
+    version integer DEFAULT 1,
+    on_call_assignment_id integer NOT NULL,
+    started_at datetime NOT NULL,
+    ended_at datetime NOT NULL,
+    description text,
+    CONSTRAINT fk_on_call_assignments_callouts FOREIGN KEY (on_call_assignment_id) REFERENCES on_call_assignments(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_on_call_callouts_deleted_at ON on_call_callouts(deleted_at);
+CREATE INDEX idx_on_call_callouts_on_call_assignment_id ON on_call_callouts(on_call_assignment_id);
@@ -0,0 +1,24 @@
+-- migrate:no-foreign-keys
+-- Schlägt fehl, falls an mehreren Standorten gleichnamige Abteilungen aktiv sind.
+
+DROP INDEX IF EXISTS idx_departments_location_name;
+DROP INDEX IF EXISTS idx_departments_location_id;
+CREATE TABLE departments_new (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at datetime,
+    created_by integer DEFAULT NULL,
+    updated_by integer DEFAULT NULL,
+    version integer DEFAULT 1,
+    name text NOT NULL,
+    color text NOT NULL,
+    description text
+);
+INSERT INTO departments_new SELECT id, created_at, updated_at, deleted_at, created_by, updated_by, version, name, color, description FROM departments;
+DROP TABLE departments;
+ALTER TABLE departments_new RENAME TO departments;
+CREATE INDEX idx_departments_deleted_at ON departments(deleted_at);
+CREATE UNIQUE INDEX idx_departments_name ON departments(name) WHERE deleted_at IS NULL;
+
+DROP TABLE IF EXISTS locations;
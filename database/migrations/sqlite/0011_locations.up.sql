@@ -0,0 +1,46 @@
+-- migrate:no-foreign-keys
+-- Standorte oberhalb der Abteilungen. Bestehende Abteilungen kommen in einen
+-- Standardstandort ohne Landesfeiertage, damit sich Berichte nicht ändern.
+-- Abteilungsnamen sind nur noch je Standort eindeutig.
+
+CREATE TABLE locations (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at datetime,
+    created_by integer DEFAULT NULL,
+    updated_by integer DEFAULT NULL,
+    version integer DEFAULT 1,
+    name text NOT NULL,
+    street text,
+    postal_code varchar(10),
+    city text,
+    timezone varchar(64) NOT NULL DEFAULT 'Europe/Berlin',
+    holiday_state varchar(2)
+);
+CREATE INDEX idx_locations_deleted_at ON locations(deleted_at);
+CREATE UNIQUE INDEX idx_locations_name ON locations(name) WHERE deleted_at IS NULL;
+
+INSERT INTO locations (name) VALUES ('Hauptstandort');
+
+DROP INDEX IF EXISTS idx_departments_name;
+CREATE TABLE departments_new (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at datetime,
+    created_by integer DEFAULT NULL,
+    updated_by integer DEFAULT NULL,
+    version integer DEFAULT 1,
+    name text NOT NULL,
+    color text NOT NULL,
+    description text,
+    location_id integer NOT NULL,
+    CONSTRAINT fk_locations_departments FOREIGN KEY (location_id) REFERENCES locations(id) ON DELETE RESTRICT
+);
+INSERT INTO departments_new SELECT *, (SELECT MIN(id) FROM locations) FROM departments;
+DROP TABLE departments;
+ALTER TABLE departments_new RENAME TO departments;
+CREATE INDEX idx_departments_deleted_at ON departments(deleted_at);
+CREATE INDEX idx_departments_location_id ON departments(location_id);
+CREATE UNIQUE INDEX idx_departments_location_name ON departments(location_id, name) WHERE deleted_at IS NULL;
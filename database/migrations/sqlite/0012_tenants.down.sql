@@ -0,0 +1,52 @@
+-- Schlägt fehl, wenn mehrere Mandanten dieselben Namen oder E-Mail-Adressen
+-- verwenden
+
+DROP INDEX idx_employees_email;
+CREATE UNIQUE INDEX idx_employees_email ON employees(email) WHERE deleted_at IS NULL;
+DROP INDEX idx_shift_types_name;
+CREATE UNIQUE INDEX idx_shift_types_name ON shift_types(name) WHERE deleted_at IS NULL;
+DROP INDEX idx_locations_name;
+CREATE UNIQUE INDEX idx_locations_name ON locations(name) WHERE deleted_at IS NULL;
+
+DROP INDEX idx_locations_tenant_id;
+ALTER TABLE locations DROP COLUMN tenant_id;
+DROP INDEX idx_departments_tenant_id;
+ALTER TABLE departments DROP COLUMN tenant_id;
+DROP INDEX idx_webhook_subscriptions_tenant_id;
+ALTER TABLE webhook_subscriptions DROP COLUMN tenant_id;
+DROP INDEX idx_employees_tenant_id;
+ALTER TABLE employees DROP COLUMN tenant_id;
+DROP INDEX idx_shift_types_tenant_id;
+ALTER TABLE shift_types DROP COLUMN tenant_id;
+DROP INDEX idx_rotations_tenant_id;
+ALTER TABLE rotations DROP COLUMN tenant_id;
+DROP INDEX idx_shift_weeks_tenant_id;
+ALTER TABLE shift_weeks DROP COLUMN tenant_id;
+DROP INDEX idx_shift_templates_tenant_id;
+ALTER TABLE shift_templates DROP COLUMN tenant_id;
+DROP INDEX idx_shift_days_tenant_id;
+ALTER TABLE shift_days DROP COLUMN tenant_id;
+DROP INDEX idx_rotation_slots_tenant_id;
+ALTER TABLE rotation_slots DROP COLUMN tenant_id;
+DROP INDEX idx_rotation_crews_tenant_id;
+ALTER TABLE rotation_crews DROP COLUMN tenant_id;
+DROP INDEX idx_rotation_crew_members_tenant_id;
+ALTER TABLE rotation_crew_members DROP COLUMN tenant_id;
+DROP INDEX idx_on_call_assignments_tenant_id;
+ALTER TABLE on_call_assignments DROP COLUMN tenant_id;
+DROP INDEX idx_on_call_callouts_tenant_id;
+ALTER TABLE on_call_callouts DROP COLUMN tenant_id;
+DROP INDEX idx_contracts_tenant_id;
+ALTER TABLE contracts DROP COLUMN tenant_id;
+DROP INDEX idx_shift_preferences_tenant_id;
+ALTER TABLE shift_preferences DROP COLUMN tenant_id;
+DROP INDEX idx_shift_template_days_tenant_id;
+ALTER TABLE shift_template_days DROP COLUMN tenant_id;
+DROP INDEX idx_notifications_tenant_id;
+ALTER TABLE notifications DROP COLUMN tenant_id;
+DROP INDEX idx_notification_preferences_tenant_id;
+ALTER TABLE notification_preferences DROP COLUMN tenant_id;
+DROP INDEX idx_webhook_deliveries_tenant_id;
+ALTER TABLE webhook_deliveries DROP COLUMN tenant_id;
+
+DROP TABLE tenants;
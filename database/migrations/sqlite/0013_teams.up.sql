@@ -0,0 +1,30 @@
+-- Teams innerhalb einer Abteilung, z.B. Schichtgruppen mit eigener
+-- Teamleitung. Mitarbeiter können einem Team ihrer Abteilung angehören,
+-- Schichtwochen gelten für die ganze Abteilung oder für ein Team.
+
+CREATE TABLE teams (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at datetime,
+    created_by integer DEFAULT NULL,
+    updated_by integer DEFAULT NULL,
+    version integer DEFAULT 1,
+    tenant_id integer NOT NULL DEFAULT 1,
+    department_id integer NOT NULL,
+    name text NOT NULL,
+    color text,
+    lead_id integer,
+    CONSTRAINT fk_departments_teams FOREIGN KEY (department_id) REFERENCES departments(id) ON DELETE CASCADE,
+    CONSTRAINT fk_teams_lead FOREIGN KEY (lead_id) REFERENCES employees(id) ON DELETE SET NULL
+);
+CREATE INDEX idx_teams_deleted_at ON teams(deleted_at);
+CREATE INDEX idx_teams_tenant_id ON teams(tenant_id);
+CREATE INDEX idx_teams_department_id ON teams(department_id);
+CREATE UNIQUE INDEX idx_teams_department_name ON teams(department_id, name) WHERE deleted_at IS NULL;
+
+ALTER TABLE employees ADD COLUMN team_id integer CONSTRAINT fk_teams_employees REFERENCES teams(id) ON DELETE SET NULL;
+CREATE INDEX idx_employees_team_id ON employees(team_id);
+
+ALTER TABLE shift_weeks ADD COLUMN team_id integer CONSTRAINT fk_teams_shift_weeks REFERENCES teams(id) ON DELETE CASCADE;
+CREATE INDEX idx_shift_weeks_team_id ON shift_weeks(team_id);
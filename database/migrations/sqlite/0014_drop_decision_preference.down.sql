@@ -0,0 +1 @@
+ALTER TABLE notification_preferences ADD COLUMN decisions numeric NOT NULL DEFAULT 1;
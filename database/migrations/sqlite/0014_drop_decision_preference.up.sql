@@ -0,0 +1,4 @@
+-- Tausch- und Urlaubsanträge gibt es nicht, die Einstellung für
+-- Benachrichtigungen über ihre Entscheidung entfällt
+
+ALTER TABLE notification_preferences DROP COLUMN decisions;
@@ -0,0 +1,8 @@
+DROP INDEX idx_notifications_dedupe_key;
+CREATE UNIQUE INDEX idx_notifications_dedupe_key ON notifications(dedupe_key);
+DROP INDEX idx_shift_date_employee;
+CREATE UNIQUE INDEX idx_shift_date_employee ON shift_days(date, employee_id) WHERE deleted_at IS NULL;
+DROP INDEX idx_teams_department_name;
+CREATE UNIQUE INDEX idx_teams_department_name ON teams(department_id, name) WHERE deleted_at IS NULL;
+DROP INDEX idx_departments_location_name;
+CREATE UNIQUE INDEX idx_departments_location_name ON departments(location_id, name) WHERE deleted_at IS NULL;
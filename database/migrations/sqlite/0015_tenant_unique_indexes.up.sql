@@ -0,0 +1,12 @@
+-- Alle eindeutigen Indizes beginnen mit tenant_id, auch wo der übergeordnete
+-- Datensatz den Mandanten schon festlegt. Benachrichtigungen werden je
+-- Mandant dedupliziert.
+
+DROP INDEX idx_departments_location_name;
+CREATE UNIQUE INDEX idx_departments_location_name ON departments(tenant_id, location_id, name) WHERE deleted_at IS NULL;
+DROP INDEX idx_teams_department_name;
+CREATE UNIQUE INDEX idx_teams_department_name ON teams(tenant_id, department_id, name) WHERE deleted_at IS NULL;
+DROP INDEX idx_shift_date_employee;
+CREATE UNIQUE INDEX idx_shift_date_employee ON shift_days(tenant_id, date, employee_id) WHERE deleted_at IS NULL;
+DROP INDEX idx_notifications_dedupe_key;
+CREATE UNIQUE INDEX idx_notifications_dedupe_key ON notifications(tenant_id, dedupe_key);
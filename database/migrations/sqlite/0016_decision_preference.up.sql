@@ -0,0 +1,4 @@
+-- Abwesenheiten eines Schichttags gelten als Entscheidung über Urlaub oder
+-- Ausfall und werden dem Mitarbeiter gemeldet, sofern er das nicht abbestellt
+
+ALTER TABLE notification_preferences ADD COLUMN decisions numeric NOT NULL DEFAULT 1;
@@ -0,0 +1,179 @@
+package database_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ptmmeiningen/schichtplaner/database"
+	"github.com/ptmmeiningen/schichtplaner/database/databasetest"
+	"github.com/ptmmeiningen/schichtplaner/models"
+)
+
+// Die Tests laufen gegen SQLite oder, mit TEST_DATABASE_URL, gegen
+// PostgreSQL (make test-postgres)
+
+func requireSchemaVersion(t *testing.T, want uint) {
+	t.Helper()
+	version, err := database.SchemaVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != want {
+		t.Fatalf("Schemaversion %d, erwartet %d", version, want)
+	}
+}
+
+func TestMigrationsRoundTrip(t *testing.T) {
+	databasetest.Setup(t)
+
+	if err := database.CheckSchemaVersion(); err != nil {
+		t.Fatalf("nach Migrate: %v", err)
+	}
+	migrations, err := database.Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := migrations[len(migrations)-1].Version
+
+	if err := database.MigrateDown(len(migrations)); err != nil {
+		t.Fatalf("MigrateDown: %v", err)
+	}
+	requireSchemaVersion(t, 0)
+	for _, table := range []string{"employees", "shift_days", "tenants", "teams"} {
+		if database.GetDB().Migrator().HasTable(table) {
+			t.Errorf("Tabelle %s besteht nach MigrateDown weiter", table)
+		}
+	}
+	if err := database.CheckSchemaVersion(); err == nil {
+		t.Fatal("veraltetes Schema nicht erkannt")
+	}
+
+	if err := database.Migrate(); err != nil {
+		t.Fatalf("erneutes Migrate: %v", err)
+	}
+	requireSchemaVersion(t, expected)
+	if err := database.CheckSchemaVersion(); err != nil {
+		t.Fatalf("nach erneutem Migrate: %v", err)
+	}
+
+	states, err := database.MigrationStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, state := range states {
+		if !state.Applied {
+			t.Errorf("Migration %04d_%s nicht angewendet", state.Version, state.Name)
+		}
+	}
+}
+
+// Jede Migration muss sich einzeln zurücknehmen lassen und danach samt
+// aller späteren wieder anwenden
+func TestMigrationsStepwise(t *testing.T) {
+	databasetest.Setup(t)
+
+	migrations, err := database.Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := len(migrations) - 1; i >= 0; i-- {
+		m := migrations[i]
+		if err := database.MigrateDown(1); err != nil {
+			t.Fatalf("%04d_%s zurücknehmen: %v", m.Version, m.Name, err)
+		}
+		var previous uint
+		if i > 0 {
+			previous = migrations[i-1].Version
+		}
+		requireSchemaVersion(t, previous)
+
+		if err := database.Migrate(); err != nil {
+			t.Fatalf("%04d_%s erneut anwenden: %v", m.Version, m.Name, err)
+		}
+		if err := database.MigrateDown(len(migrations) - i); err != nil {
+			t.Fatalf("%04d_%s erneut zurücknehmen: %v", m.Version, m.Name, err)
+		}
+		requireSchemaVersion(t, previous)
+	}
+	requireSchemaVersion(t, 0)
+
+	if err := database.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.CheckSchemaVersion(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Migration 0011 legt den Standardstandort an und verschiebt alle
+// bestehenden Abteilungen dorthin. Abteilungsnamen sind danach nur je
+// Standort eindeutig, Standorte mit Abteilungen lassen sich nicht löschen.
+func TestLocationsMigration(t *testing.T) {
+	databasetest.Setup(t)
+
+	migrations, err := database.Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	steps := 0
+	for _, m := range migrations {
+		if m.Version > 10 {
+			steps++
+		}
+	}
+	if err := database.MigrateDown(steps); err != nil {
+		t.Fatal(err)
+	}
+	requireSchemaVersion(t, 10)
+
+	now := time.Now()
+	for _, name := range []string{"Produktion", "Verwaltung"} {
+		if err := database.GetDB().Exec("INSERT INTO departments (name, color, created_at, updated_at) VALUES (?, ?, ?, ?)", name, "#0000ff", now, now).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := database.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+
+	location := databasetest.Location(t)
+	if location.Name != "Hauptstandort" {
+		t.Fatalf("standardstandort %q", location.Name)
+	}
+	var departments []models.Department
+	database.GetDB().Order("name").Find(&departments)
+	if len(departments) != 2 {
+		t.Fatalf("%d abteilungen nach der migration", len(departments))
+	}
+	for _, department := range departments {
+		if department.LocationID != location.ID {
+			t.Errorf("%s am standort %d, erwartet %d", department.Name, department.LocationID, location.ID)
+		}
+	}
+
+	t.Run("name je standort eindeutig", func(t *testing.T) {
+		duplicate := models.Department{Name: "Produktion", Color: "#00ff00", LocationID: location.ID}
+		if err := database.GetDB().Create(&duplicate).Error; err == nil {
+			t.Fatal("doppelter name am selben standort angelegt")
+		}
+
+		branch := models.Location{Name: "Zweigwerk"}
+		databasetest.Create(t, &branch)
+		databasetest.Create(t, &models.Department{Name: "Produktion", Color: "#00ff00", LocationID: branch.ID})
+
+		// Gelöschte Abteilungen geben den Namen frei
+		if err := database.GetDB().Delete(&departments[0]).Error; err != nil {
+			t.Fatal(err)
+		}
+		databasetest.Create(t, &models.Department{Name: "Produktion", Color: "#00ff00", LocationID: location.ID})
+	})
+
+	t.Run("standort mit abteilungen", func(t *testing.T) {
+		err := database.SoftDelete(database.GetDB(), "locations", location.ID)
+		var refErr *database.ReferenceError
+		if !errors.As(err, &refErr) || len(refErr.References) != 1 || refErr.References[0].Table != "departments" || refErr.References[0].Count != 2 {
+			t.Fatalf("löschen: %v", err)
+		}
+	})
+}
@@ -0,0 +1,182 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Löschregeln, wie sie auch als ON DELETE an den Fremdschlüsseln stehen
+const (
+	OnDeleteRestrict = "RESTRICT"
+	OnDeleteSetNull  = "SET NULL"
+	OnDeleteCascade  = "CASCADE"
+)
+
+// maxBlockingIDs begrenzt die Anzahl der IDs je blockierender Tabelle in der Antwort
+const maxBlockingIDs = 50
+
+// Reference beschreibt einen Fremdschlüssel von Table.Column auf Parent.id
+type Reference struct {
+	Table    string
+	Column   string
+	Parent   string
+	OnDelete string
+}
+
+// References spiegelt die Fremdschlüssel der Migrationen. Da die Anwendung
+// weich löscht, greifen die Regeln der Datenbank nicht von selbst und werden
+// von SoftDelete nachgebildet. Beide Stellen müssen zusammenpassen.
+var References = []Reference{
+	{Table: "departments", Column: "location_id", Parent: "locations", OnDelete: OnDeleteRestrict},
+	{Table: "employees", Column: "department_id", Parent: "departments", OnDelete: OnDeleteSetNull},
+	{Table: "shift_weeks", Column: "department_id", Parent: "departments", OnDelete: OnDeleteCascade},
+	{Table: "shift_templates", Column: "department_id", Parent: "departments", OnDelete: OnDeleteCascade},
+	{Table: "shift_days", Column: "employee_id", Parent: "employees", OnDelete: OnDeleteSetNull},
+	{Table: "shift_days", Column: "shift_week_id", Parent: "shift_weeks", OnDelete: OnDeleteCascade},
+	{Table: "shift_days", Column: "shift_type_id", Parent: "shift_types", OnDelete: OnDeleteRestrict},
+	{Table: "shift_template_days", Column: "shift_template_id", Parent: "shift_templates", OnDelete: OnDeleteCascade},
+	{Table: "shift_template_days", Column: "shift_type_id", Parent: "shift_types", OnDelete: OnDeleteRestrict},
+	{Table: "notifications", Column: "employee_id", Parent: "employees", OnDelete: OnDeleteCascade},
+	{Table: "notification_preferences", Column: "employee_id", Parent: "employees", OnDelete: OnDeleteCascade},
+	{Table: "webhook_deliveries", Column: "subscription_id", Parent: "webhook_subscriptions", OnDelete: OnDeleteCascade},
+	{Table: "rotations", Column: "department_id", Parent: "departments", OnDelete: OnDeleteCascade},
+	{Table: "rotation_slots", Column: "rotation_id", Parent: "rotations", OnDelete: OnDeleteCascade},
+	{Table: "rotation_slots", Column: "shift_type_id", Parent: "shift_types", OnDelete: OnDeleteRestrict},
+	{Table: "rotation_crews", Column: "rotation_id", Parent: "rotations", OnDelete: OnDeleteCascade},
+	{Table: "rotation_crew_members", Column: "rotation_crew_id", Parent: "rotation_crews", OnDelete: OnDeleteCascade},
+	{Table: "rotation_crew_members", Column: "employee_id", Parent: "employees", OnDelete: OnDeleteCascade},
+	{Table: "shift_days", Column: "rotation_id", Parent: "rotations", OnDelete: OnDeleteSetNull},
+	{Table: "shift_preferences", Column: "employee_id", Parent: "employees", OnDelete: OnDeleteCascade},
+	{Table: "shift_preferences", Column: "shift_type_id", Parent: "shift_types", OnDelete: OnDeleteCascade},
+	{Table: "contracts", Column: "employee_id", Parent: "employees", OnDelete: OnDeleteCascade},
+	{Table: "on_call_assignments", Column: "employee_id", Parent: "employees", OnDelete: OnDeleteCascade},
+	{Table: "on_call_assignments", Column: "department_id", Parent: "departments", OnDelete: OnDeleteCascade},
+	{Table: "on_call_callouts", Column: "on_call_assignment_id", Parent: "on_call_assignments", OnDelete: OnDeleteCascade},
+	{Table: "teams", Column: "department_id", Parent: "departments", OnDelete: OnDeleteCascade},
+	{Table: "teams", Column: "lead_id", Parent: "employees", OnDelete: OnDeleteSetNull},
+	{Table: "employees", Column: "team_id", Parent: "teams", OnDelete: OnDeleteSetNull},
+	{Table: "shift_weeks", Column: "team_id", Parent: "teams", OnDelete: OnDeleteCascade},
+}
+
+// BlockingReference listet Datensätze, die das Löschen verhindern
+type BlockingReference struct {
+	Table  string `json:"table" example:"shift_days"`
+	Column string `json:"column" example:"shift_type_id"`
+	Count  int64  `json:"count" example:"12"`
+	IDs    []uint `json:"ids"`
+}
+
+// ReferenceError wird zurückgegeben, wenn noch Verweise mit RESTRICT bestehen
+type ReferenceError struct {
+	Table      string
+	ID         uint
+	References []BlockingReference
+}
+
+func (e *ReferenceError) Error() string {
+	return fmt.Sprintf("Datensatz %d in %s wird noch verwendet", e.ID, e.Table)
+}
+
+// SoftDelete löscht einen Datensatz weich: Erst werden alle RESTRICT-Verweise
+// geprüft (auch die der kaskadierend gelöschten Datensätze), danach SET NULL
+// und CASCADE angewendet. Der Datensatz und alle kaskadierend gelöschten
+// erhalten denselben Löschzeitpunkt, über den Restore sie wiederfindet.
+// Sollte in einer Transaktion laufen.
+func SoftDelete(tx *gorm.DB, table string, id uint) error {
+	var blocking []BlockingReference
+	if err := collectBlocking(tx, table, []uint{id}, &blocking); err != nil {
+		return err
+	}
+	if len(blocking) > 0 {
+		return &ReferenceError{Table: table, ID: id, References: blocking}
+	}
+
+	now := tx.NowFunc()
+	if err := applyRules(tx, table, []uint{id}, now); err != nil {
+		return err
+	}
+	return tx.Table(table).Where("id = ?", id).Update("deleted_at", now).Error
+}
+
+func referencesTo(parent string) []Reference {
+	var result []Reference
+	for _, ref := range References {
+		if ref.Parent == parent {
+			result = append(result, ref)
+		}
+	}
+	return result
+}
+
+// children liefert die IDs der nicht gelöschten Datensätze, die auf ids verweisen
+func children(tx *gorm.DB, ref Reference, ids []uint) ([]uint, error) {
+	var childIDs []uint
+	err := tx.Table(ref.Table).
+		Where(ref.Column+" IN ? AND deleted_at IS NULL", ids).
+		Order("id").
+		Pluck("id", &childIDs).Error
+	return childIDs, err
+}
+
+func collectBlocking(tx *gorm.DB, table string, ids []uint, blocking *[]BlockingReference) error {
+	for _, ref := range referencesTo(table) {
+		childIDs, err := children(tx, ref, ids)
+		if err != nil {
+			return err
+		}
+		if len(childIDs) == 0 {
+			continue
+		}
+
+		switch ref.OnDelete {
+		case OnDeleteRestrict:
+			listed := childIDs
+			if len(listed) > maxBlockingIDs {
+				listed = listed[:maxBlockingIDs]
+			}
+			*blocking = append(*blocking, BlockingReference{
+				Table:  ref.Table,
+				Column: ref.Column,
+				Count:  int64(len(childIDs)),
+				IDs:    listed,
+			})
+		case OnDeleteCascade:
+			if err := collectBlocking(tx, ref.Table, childIDs, blocking); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func applyRules(tx *gorm.DB, table string, ids []uint, now time.Time) error {
+	for _, ref := range referencesTo(table) {
+		switch ref.OnDelete {
+		case OnDeleteSetNull:
+			if err := tx.Table(ref.Table).
+				Where(ref.Column+" IN ? AND deleted_at IS NULL", ids).
+				Update(ref.Column, nil).Error; err != nil {
+				return err
+			}
+		case OnDeleteCascade:
+			childIDs, err := children(tx, ref, ids)
+			if err != nil {
+				return err
+			}
+			if len(childIDs) == 0 {
+				continue
+			}
+			if err := applyRules(tx, ref.Table, childIDs, now); err != nil {
+				return err
+			}
+			if err := tx.Table(ref.Table).
+				Where("id IN ?", childIDs).
+				Update("deleted_at", now).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
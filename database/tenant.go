@@ -0,0 +1,174 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"strconv"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// ErrTenantReference wird gemeldet, wenn ein Fremdschlüssel auf einen
+// Datensatz zeigt, den der Mandant nicht sehen darf
+var ErrTenantReference = errors.New("Verweis auf einen Datensatz, der nicht existiert oder zu einem anderen Mandanten gehört")
+
+type tenantKey struct{}
+
+// tenantTables sind alle Tabellen mit tenant_id
+var tenantTables = func() map[string]bool {
+	tables := map[string]bool{}
+	for _, table := range TrashTables {
+		tables[table] = true
+	}
+	return tables
+}()
+
+// MultiTenant gibt an, ob Anfragen über Token oder Subdomain einem Mandanten
+// zugeordnet werden (MULTI_TENANT=true). Sonst gehört jede Anfrage zum
+// Standardmandanten.
+func MultiTenant() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv("MULTI_TENANT"))
+	return enabled
+}
+
+// WithTenant legt den Mandanten im Kontext ab. Abfragen mit diesem Kontext
+// sehen und ändern nur Datensätze des Mandanten.
+func WithTenant(ctx context.Context, tenantID uint) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantID)
+}
+
+// TenantFromContext liefert den Mandanten aus dem Kontext
+func TenantFromContext(ctx context.Context) (uint, bool) {
+	if ctx == nil {
+		return 0, false
+	}
+	tenantID, ok := ctx.Value(tenantKey{}).(uint)
+	return tenantID, ok
+}
+
+// ForTenant liefert die Datenbank eingeschränkt auf den Mandanten im Kontext.
+// Ohne Mandant im Kontext, etwa in Workern, gilt die Abfrage für alle
+// Mandanten.
+func ForTenant(ctx context.Context) *gorm.DB {
+	return db.WithContext(ctx)
+}
+
+// RegisterTenantScope hängt an jede Abfrage, Änderung und Löschung einer
+// Mandantentabelle die Bedingung tenant_id = Mandant aus dem Kontext an und
+// setzt den Mandanten beim Anlegen. Upserts überschreiben keine Datensätze
+// anderer Mandanten, Fremdschlüssel müssen auf Datensätze desselben
+// Mandanten zeigen.
+func RegisterTenantScope(database *gorm.DB) error {
+	callbacks := database.Callback()
+	if err := callbacks.Create().Before("gorm:create").Register("tenant:create", tenantCreate); err != nil {
+		return err
+	}
+	if err := callbacks.Query().Before("gorm:query").Register("tenant:query", tenantWhere); err != nil {
+		return err
+	}
+	if err := callbacks.Row().Before("gorm:row").Register("tenant:row", tenantWhere); err != nil {
+		return err
+	}
+	if err := callbacks.Update().Before("gorm:update").Register("tenant:update", tenantUpdate); err != nil {
+		return err
+	}
+	return callbacks.Delete().Before("gorm:delete").Register("tenant:delete", tenantWhere)
+}
+
+func tenantWhere(tx *gorm.DB) {
+	tenantID, ok := TenantFromContext(tx.Statement.Context)
+	if !ok || !tenantTables[tx.Statement.Table] {
+		return
+	}
+	tx.Statement.AddClause(clause.Where{Exprs: []clause.Expression{tenantColumn(tx, tenantID)}})
+}
+
+func tenantUpdate(tx *gorm.DB) {
+	tenantWhere(tx)
+	tenantReferences(tx)
+}
+
+func tenantCreate(tx *gorm.DB) {
+	tenantID, ok := TenantFromContext(tx.Statement.Context)
+	if !ok || !tenantTables[tx.Statement.Table] {
+		return
+	}
+
+	if tx.Statement.Schema != nil {
+		if field := tx.Statement.Schema.LookUpField("TenantID"); field != nil {
+			tx.Statement.SetColumn(field.DBName, tenantID, true)
+		}
+	}
+
+	// Save legt Datensätze mit gesetzter ID per Upsert an. Gehört die ID
+	// einem anderen Mandanten, darf der Upsert sie nicht überschreiben.
+	if c, ok := tx.Statement.Clauses["ON CONFLICT"]; ok {
+		if onConflict, ok := c.Expression.(clause.OnConflict); ok && (onConflict.UpdateAll || len(onConflict.DoUpdates) > 0) {
+			onConflict.Where.Exprs = append(onConflict.Where.Exprs, tenantColumn(tx, tenantID))
+			tx.Statement.AddClause(onConflict)
+		}
+	}
+
+	tenantReferences(tx)
+}
+
+// tenantReferences prüft die Fremdschlüssel aller Belongs-To-Beziehungen der
+// geschriebenen Datensätze. Änderungen per Map werden nicht geprüft.
+func tenantReferences(tx *gorm.DB) {
+	stmt := tx.Statement
+	if _, ok := TenantFromContext(stmt.Context); !ok || stmt.Schema == nil || tx.Error != nil {
+		return
+	}
+
+	var records []reflect.Value
+	switch stmt.ReflectValue.Kind() {
+	case reflect.Struct:
+		records = append(records, stmt.ReflectValue)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < stmt.ReflectValue.Len(); i++ {
+			records = append(records, reflect.Indirect(stmt.ReflectValue.Index(i)))
+		}
+	}
+
+	for _, rel := range stmt.Schema.Relationships.Relations {
+		if rel.Type != schema.BelongsTo || len(rel.References) != 1 || !tenantTables[rel.FieldSchema.Table] {
+			continue
+		}
+		ref := rel.References[0]
+
+		ids := map[interface{}]bool{}
+		for _, record := range records {
+			if value, zero := ref.ForeignKey.ValueOf(stmt.Context, record); !zero {
+				ids[reflect.Indirect(reflect.ValueOf(value)).Interface()] = true
+			}
+		}
+		if len(ids) == 0 {
+			continue
+		}
+		keys := make([]interface{}, 0, len(ids))
+		for id := range ids {
+			keys = append(keys, id)
+		}
+
+		var count int64
+		if err := tx.Session(&gorm.Session{NewDB: true}).
+			Table(rel.FieldSchema.Table).
+			Where(ref.PrimaryKey.DBName+" IN ?", keys).
+			Count(&count).Error; err != nil {
+			tx.AddError(err)
+			return
+		}
+		if count != int64(len(keys)) {
+			tx.AddError(ErrTenantReference)
+			return
+		}
+	}
+}
+
+func tenantColumn(tx *gorm.DB, tenantID uint) clause.Expression {
+	return clause.Eq{Column: clause.Column{Table: tx.Statement.Table, Name: "tenant_id"}, Value: tenantID}
+}
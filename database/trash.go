@@ -0,0 +1,173 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrNotDeleted      = errors.New("Datensatz ist nicht gelöscht")
+	ErrRestoreConflict = errors.New("ein aktiver Datensatz mit denselben eindeutigen Werten existiert bereits")
+)
+
+// ParentDeletedError wird zurückgegeben, wenn ein Datensatz nicht
+// wiederhergestellt werden kann, weil übergeordnete Datensätze gelöscht sind
+type ParentDeletedError struct {
+	Table   string
+	ID      uint
+	Parents []BlockingReference
+}
+
+func (e *ParentDeletedError) Error() string {
+	return fmt.Sprintf("Datensatz %d in %s verweist auf gelöschte Datensätze", e.ID, e.Table)
+}
+
+// TrashTables sind alle weich gelöschten Tabellen, Kindtabellen vor ihren
+// Elterntabellen. Purge löscht in dieser Reihenfolge endgültig.
+var TrashTables = []string{
+	"webhook_deliveries",
+	"notification_preferences",
+	"notifications",
+	"shift_template_days",
+	"shift_preferences",
+	"contracts",
+	"on_call_callouts",
+	"on_call_assignments",
+	"rotation_crew_members",
+	"rotation_crews",
+	"rotation_slots",
+	"shift_days",
+	"shift_templates",
+	"shift_weeks",
+	"rotations",
+	"shift_types",
+	"employees",
+	"teams",
+	"webhook_subscriptions",
+	"departments",
+	"locations",
+}
+
+// Restore stellt einen weich gelöschten Datensatz wieder her, zusammen mit
+// allen Datensätzen, die beim Löschen per CASCADE mitgelöscht wurden.
+// Gelöschte Eltern über CASCADE oder RESTRICT verhindern die
+// Wiederherstellung, Verweise auf gelöschte Eltern mit SET NULL werden
+// geleert. Sollte in einer Transaktion laufen.
+func Restore(tx *gorm.DB, table string, id uint) error {
+	var row struct{ DeletedAt *time.Time }
+	if err := tx.Table(table).Select("deleted_at").Where("id = ?", id).Take(&row).Error; err != nil {
+		return err
+	}
+	if row.DeletedAt == nil {
+		return ErrNotDeleted
+	}
+
+	var missing []BlockingReference
+	for _, ref := range References {
+		if ref.Table != table {
+			continue
+		}
+		var parentIDs []uint
+		if err := tx.Table(ref.Parent).
+			Where("id = (SELECT "+ref.Column+" FROM "+table+" WHERE id = ?) AND deleted_at IS NOT NULL", id).
+			Pluck("id", &parentIDs).Error; err != nil {
+			return err
+		}
+		if len(parentIDs) == 0 {
+			continue
+		}
+
+		if ref.OnDelete == OnDeleteSetNull {
+			if err := tx.Table(table).Where("id = ?", id).Update(ref.Column, nil).Error; err != nil {
+				return err
+			}
+			continue
+		}
+		missing = append(missing, BlockingReference{
+			Table:  ref.Parent,
+			Column: ref.Column,
+			Count:  int64(len(parentIDs)),
+			IDs:    parentIDs,
+		})
+	}
+	if len(missing) > 0 {
+		return &ParentDeletedError{Table: table, ID: id, Parents: missing}
+	}
+
+	return translateRestoreError(tx, restoreRows(tx, table, []uint{id}, *row.DeletedAt))
+}
+
+// restoreRows hebt das Löschen von ids auf und folgt den CASCADE-Verweisen
+// zu allen Kindern, die zum selben Zeitpunkt gelöscht wurden
+func restoreRows(tx *gorm.DB, table string, ids []uint, deletedAt time.Time) error {
+	if err := tx.Table(table).Where("id IN ?", ids).Update("deleted_at", nil).Error; err != nil {
+		return err
+	}
+
+	for _, ref := range referencesTo(table) {
+		if ref.OnDelete != OnDeleteCascade {
+			continue
+		}
+		var childIDs []uint
+		if err := tx.Table(ref.Table).
+			Where(ref.Column+" IN ? AND deleted_at = ?", ids, deletedAt).
+			Order("id").
+			Pluck("id", &childIDs).Error; err != nil {
+			return err
+		}
+		if len(childIDs) == 0 {
+			continue
+		}
+		if err := restoreRows(tx, ref.Table, childIDs, deletedAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// translateRestoreError meldet Verstöße gegen eindeutige Indizes, z.B. eine
+// inzwischen neu angelegte Abteilung gleichen Namens, als ErrRestoreConflict
+func translateRestoreError(tx *gorm.DB, err error) error {
+	if err == nil {
+		return nil
+	}
+	if translator, ok := tx.Dialector.(gorm.ErrorTranslator); ok {
+		if errors.Is(translator.Translate(err), gorm.ErrDuplicatedKey) {
+			return ErrRestoreConflict
+		}
+	}
+	return err
+}
+
+// Purge löscht alle Datensätze endgültig, die vor cutoff weich gelöscht
+// wurden. Datensätze, auf die noch Zeilen per CASCADE oder RESTRICT
+// verweisen, bleiben erhalten, damit die Datenbank keine aktiven Kinder
+// mitlöscht. Liefert die Anzahl gelöschter Zeilen je Tabelle.
+func Purge(cutoff time.Time) (map[string]int64, error) {
+	purged := map[string]int64{}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, table := range TrashTables {
+			conditions := []string{"deleted_at IS NOT NULL", "deleted_at < ?"}
+			for _, ref := range referencesTo(table) {
+				if ref.OnDelete == OnDeleteSetNull {
+					continue
+				}
+				conditions = append(conditions, "NOT EXISTS (SELECT 1 FROM "+ref.Table+" WHERE "+ref.Table+"."+ref.Column+" = "+table+".id)")
+			}
+
+			result := tx.Exec("DELETE FROM "+table+" WHERE "+strings.Join(conditions, " AND "), cutoff)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected > 0 {
+				purged[table] = result.RowsAffected
+			}
+		}
+		return nil
+	})
+	return purged, err
+}
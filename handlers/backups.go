@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ptmmeiningen/schichtplaner/backup"
+	"github.com/ptmmeiningen/schichtplaner/pkg/responses"
+)
+
+// MaintenanceGate weist Anfragen mit 503 ab, während eine Sicherung
+// zurückgespielt wird. Das Zurückspielen wartet auf laufende Anfragen.
+func MaintenanceGate(c *fiber.Ctx) error {
+	release, ok := backup.Enter()
+	if !ok {
+		c.Set(fiber.HeaderRetryAfter, "5")
+		return c.Status(503).JSON(responses.ErrorResponse(responses.ErrMaintenance))
+	}
+	defer release()
+	return c.Next()
+}
+
+// @Summary Alle Sicherungen abrufen
+// @Description Listet alle Sicherungen der SQLite-Datenbank, die neueste zuerst. Benötigt ADMIN_TOKEN.
+// @Tags admin
+// @Produce json
+// @Success 200 {object} responses.APIResponse{data=[]backup.Snapshot}
+// @Failure 403,500 {object} responses.APIResponse
+// @Router /api/v1/admin/backups [get]
+func HandleAllBackups(c *fiber.Ctx) error {
+	snapshots, err := backup.List()
+	if err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	return c.JSON(responses.SuccessResponse(responses.MsgSuccessGet, snapshots))
+}
+
+// @Summary Sicherung anlegen
+// @Description Legt im laufenden Betrieb eine konsistente Sicherung der SQLite-Datenbank an. Benötigt ADMIN_TOKEN.
+// @Tags admin
+// @Produce json
+// @Success 201 {object} responses.APIResponse{data=backup.Snapshot}
+// @Failure 400,403,500 {object} responses.APIResponse
+// @Router /api/v1/admin/backups [post]
+func HandleCreateBackup(c *fiber.Ctx) error {
+	snapshot, err := backup.Create()
+	if errors.Is(err, backup.ErrNotSupported) {
+		return c.Status(400).JSON(responses.ErrorResponse(err.Error()))
+	}
+	if err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	return c.Status(201).JSON(responses.SuccessResponse("Sicherung erfolgreich angelegt", snapshot))
+}
+
+// @Summary Sicherungen aufräumen
+// @Description Löscht Sicherungen außerhalb der Aufbewahrungsregeln (BACKUP_KEEP_LAST, BACKUP_MAX_AGE_DAYS). Benötigt ADMIN_TOKEN.
+// @Tags admin
+// @Produce json
+// @Success 200 {object} responses.APIResponse{data=[]string}
+// @Failure 403,500 {object} responses.APIResponse
+// @Router /api/v1/admin/backups/prune [post]
+func HandlePruneBackups(c *fiber.Ctx) error {
+	removed, err := backup.Prune()
+	if err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	return c.JSON(responses.SuccessResponse("Sicherungen erfolgreich aufgeräumt", removed))
+}
+
+// @Summary Sicherung zurückspielen
+// @Description Prüft die Sicherung (Prüfsumme, Integrität, Schemaversion), sichert den aktuellen Stand und spielt sie zurück. Wartet auf laufende Anfragen, bis zum Ende werden alle anderen mit 503 abgewiesen. Benötigt ADMIN_TOKEN.
+// @Tags admin
+// @Produce json
+// @Param name path string true "Dateiname der Sicherung"
+// @Success 200 {object} responses.APIResponse{data=backup.Snapshot} "Vor dem Zurückspielen angelegte Sicherung"
+// @Failure 400,403,404,500 {object} responses.APIResponse
+// @Router /api/v1/admin/backups/{name}/restore [post]
+func HandleRestoreBackup(c *fiber.Ctx) error {
+	safety, err := backup.Restore(c.Params("name"))
+	switch {
+	case errors.Is(err, backup.ErrNotFound):
+		return c.Status(404).JSON(responses.ErrorResponse(err.Error()))
+	case errors.Is(err, backup.ErrNotSupported):
+		return c.Status(400).JSON(responses.ErrorResponse(err.Error()))
+	case err != nil:
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	return c.JSON(responses.SuccessResponse("Sicherung erfolgreich zurückgespielt", safety))
+}
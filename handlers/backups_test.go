@@ -0,0 +1,47 @@
+package handlers_test
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ptmmeiningen/schichtplaner/database/databasetest"
+	"github.com/ptmmeiningen/schichtplaner/router"
+)
+
+func TestBackupRoutesRequireAdminToken(t *testing.T) {
+	app := databasetest.Setup(t)
+	router.SetupRoutes(app)
+
+	tests := []struct {
+		name   string
+		env    string
+		token  string
+		method string
+		path   string
+		status int
+	}{
+		{name: "ohne ADMIN_TOKEN gesperrt", method: "GET", path: "/api/v1/admin/backups", status: 403},
+		{name: "ohne ADMIN_TOKEN kein Zurückspielen", method: "POST", path: "/api/v1/admin/backups/schichtplaner-20250106-020000.db/restore", status: 403},
+		{name: "ohne Token", env: "geheim", method: "GET", path: "/api/v1/admin/backups", status: 403},
+		{name: "falscher Token", env: "geheim", token: "falsch", method: "POST", path: "/api/v1/admin/backups/prune", status: 403},
+		{name: "falscher Token beim Zurückspielen", env: "geheim", token: "falsch", method: "POST", path: "/api/v1/admin/backups/schichtplaner-20250106-020000.db/restore", status: 403},
+		{name: "gültiger Token", env: "geheim", token: "geheim", method: "GET", path: "/api/v1/admin/backups", status: 200},
+		{name: "Mandanten ohne Mandantenbetrieb", method: "GET", path: "/api/v1/admin/tenants", status: 200},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv("ADMIN_TOKEN", test.env)
+			req := httptest.NewRequest(test.method, test.path, nil)
+			if test.token != "" {
+				req.Header.Set("Authorization", "Bearer "+test.token)
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != test.status {
+				t.Fatalf("status %d, erwartet %d", resp.StatusCode, test.status)
+			}
+		})
+	}
+}
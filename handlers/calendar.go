@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"sort"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ptmmeiningen/schichtplaner/models"
+	"github.com/ptmmeiningen/schichtplaner/pkg/responses"
+)
+
+// Arten von Kalendereinträgen
+const (
+	CalendarShift  = "shift"
+	CalendarOnCall = "on_call"
+)
+
+// CalendarEntry ist ein Eintrag im Kalender eines Mitarbeiters, entweder ein
+// Schichttag oder eine Rufbereitschaft
+type CalendarEntry struct {
+	Kind               string                 `json:"kind" example:"shift"`
+	StartsAt           time.Time              `json:"starts_at"`
+	EndsAt             time.Time              `json:"ends_at"`
+	Title              string                 `json:"title" example:"Frühschicht"`
+	Status             string                 `json:"status,omitempty" example:"planned"`
+	ShiftDayID         uint                   `json:"shift_day_id,omitempty"`
+	ShiftWeekStatus    string                 `json:"shift_week_status,omitempty" example:"published"`
+	PaidHours          float64                `json:"paid_hours,omitempty"`
+	OnCallAssignmentID uint                   `json:"on_call_assignment_id,omitempty"`
+	CompensationClass  string                 `json:"compensation_class,omitempty"`
+	Callouts           []models.OnCallCallout `json:"callouts,omitempty"`
+}
+
+// @Summary Kalender eines Mitarbeiters abrufen
+// @Description Liefert Schichten und Rufbereitschaften eines Mitarbeiters im Zeitraum, nach Beginn sortiert. Rufbereitschaften können sich mit Schichten überschneiden. Schichtzeiten gelten in der Zeitzone des Standorts der Abteilung.
+// @Tags employees
+// @Produce json
+// @Param id path int true "Mitarbeiter-ID"
+// @Param from query string true "Beginn (YYYY-MM-DD)"
+// @Param to query string true "Ende einschließlich (YYYY-MM-DD)"
+// @Success 200 {object} responses.APIResponse{data=[]CalendarEntry}
+// @Failure 400,404,500 {object} responses.APIResponse
+// @Router /api/v1/employees/{id}/calendar [get]
+func HandleEmployeeCalendar(c *fiber.Ctx) error {
+	var employee models.Employee
+	if err := tenantDB(c).First(&employee, c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
+	}
+
+	from, to, err := parseReportRange(c)
+	if err != nil {
+		return c.Status(400).JSON(responses.ErrorResponse(err.Error()))
+	}
+	until := to.AddDate(0, 0, 1)
+
+	var shiftDays []models.ShiftDay
+	if err := tenantDB(c).
+		Preload("ShiftWeek.Department.Location").
+		Where("employee_id = ? AND date >= ? AND date < ?", employee.ID, from, until).
+		Find(&shiftDays).Error; err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	shiftTypes, err := shiftTypesByID(tenantDB(c))
+	if err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	var assignments []models.OnCallAssignment
+	if err := preloadOnCall(tenantDB(c)).
+		Where("employee_id = ? AND starts_at < ? AND ends_at > ?", employee.ID, until, from).
+		Find(&assignments).Error; err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	entries := []CalendarEntry{}
+	for _, day := range shiftDays {
+		shiftType := shiftTypes[day.ShiftTypeID]
+		begin, end := localInterval(&shiftType, day.Date, day.ShiftWeek.Department.Location)
+		entry := CalendarEntry{
+			Kind:            CalendarShift,
+			StartsAt:        begin,
+			EndsAt:          end,
+			Title:           shiftType.Name,
+			Status:          day.Status,
+			ShiftDayID:      day.ID,
+			ShiftWeekStatus: day.ShiftWeek.Status,
+		}
+		if !isAbsence(day.Status) {
+			entry.PaidHours = day.Duration(&shiftType).Paid.Hours()
+		}
+		entries = append(entries, entry)
+	}
+	for _, assignment := range assignments {
+		entries = append(entries, CalendarEntry{
+			Kind:               CalendarOnCall,
+			StartsAt:           assignment.StartsAt,
+			EndsAt:             assignment.EndsAt,
+			Title:              "Rufbereitschaft",
+			OnCallAssignmentID: assignment.ID,
+			CompensationClass:  assignment.CompensationClass,
+			Callouts:           assignment.Callouts,
+		})
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].StartsAt.Before(entries[j].StartsAt)
+	})
+
+	return c.JSON(responses.SuccessResponse(responses.MsgSuccessGet, entries))
+}
+
+// localInterval legt Beginn und Ende einer Schicht in die Zeitzone des
+// Standorts. Ohne Standort bleibt es bei UTC.
+func localInterval(shiftType *models.ShiftType, date time.Time, location *models.Location) (time.Time, time.Time) {
+	begin, end := shiftType.Interval(date)
+	if location == nil {
+		return begin, end
+	}
+	local := time.Date(begin.Year(), begin.Month(), begin.Day(), begin.Hour(), begin.Minute(), 0, 0, location.TimeLocation())
+	return local, local.Add(end.Sub(begin))
+}
@@ -0,0 +1,68 @@
+package handlers_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ptmmeiningen/schichtplaner/database/databasetest"
+	"github.com/ptmmeiningen/schichtplaner/handlers"
+	"github.com/ptmmeiningen/schichtplaner/models"
+)
+
+func TestEmployeeCalendar(t *testing.T) {
+	app := setupApp(t)
+	department := databasetest.Department(t, "Produktion")
+	early := databasetest.ShiftType(t, "Früh", "06:00", "14:00")
+	anna := databasetest.Employee(t, "anna@example.org", department.ID)
+	bert := databasetest.Employee(t, "bert@example.org", department.ID)
+
+	monday := time.Date(2030, 1, 7, 0, 0, 0, 0, time.UTC)
+	week := models.ShiftWeek{Year: 2030, CalendarWeek: 2, Status: models.StatusPublished, DepartmentID: &department.ID}
+	databasetest.Create(t, &week)
+	shift := func(employee models.Employee, day int, status string) {
+		databasetest.Create(t, &models.ShiftDay{
+			Date: monday.AddDate(0, 0, day), ShiftWeekID: &week.ID, ShiftTypeID: early.ID, EmployeeID: &employee.ID, Status: status,
+		})
+	}
+	shift(anna, 0, models.ShiftDayPlanned)
+	shift(anna, 2, models.ShiftDaySick)
+	shift(anna, 3, models.ShiftDayPlanned)
+	shift(bert, 1, models.ShiftDayPlanned)
+
+	// Die Rufbereitschaft überschneidet sich mit der Schicht am Montag
+	at := func(day, hour int) time.Time { return time.Date(2030, 1, day, hour, 0, 0, 0, time.UTC) }
+	onCall := createOnCall(t, app, anna, department.ID, "standard", at(7, 12), at(8, 6))
+	addCallout(t, app, onCall, at(7, 22), at(7, 23))
+	createOnCall(t, app, bert, department.ID, "standard", at(7, 0), at(7, 6))
+
+	status, resp := call(t, app, "GET", fmt.Sprintf("/api/v1/employees/%d/calendar?from=2030-01-07&to=2030-01-09", anna.ID), "", nil)
+	if status != 200 {
+		t.Fatalf("status %d: %s", status, resp.Error)
+	}
+	var entries []handlers.CalendarEntry
+	decode(t, resp, &entries)
+	if len(entries) != 3 {
+		t.Fatalf("einträge %+v", entries)
+	}
+
+	// Schichtzeiten in der Zeitzone des Standorts
+	local := databasetest.Location(t)
+	start := time.Date(2030, 1, 7, 6, 0, 0, 0, local.TimeLocation())
+	if e := entries[0]; e.Kind != handlers.CalendarShift || !e.StartsAt.Equal(start) || !e.EndsAt.Equal(start.Add(8*time.Hour)) ||
+		e.Title != "Früh" || e.ShiftWeekStatus != models.StatusPublished || e.PaidHours != 7.5 {
+		t.Errorf("montag %+v", e)
+	}
+	if e := entries[1]; e.Kind != handlers.CalendarOnCall || e.OnCallAssignmentID != onCall.ID || !e.StartsAt.Equal(at(7, 12)) ||
+		e.CompensationClass != "standard" || len(e.Callouts) != 1 {
+		t.Errorf("rufbereitschaft %+v", e)
+	}
+	if e := entries[2]; e.Kind != handlers.CalendarShift || e.Status != models.ShiftDaySick || e.PaidHours != 0 {
+		t.Errorf("mittwoch %+v", e)
+	}
+
+	status, _ = call(t, app, "GET", fmt.Sprintf("/api/v1/employees/%d/calendar?from=2030-01-09&to=2030-01-07", anna.ID), "", nil)
+	if status != 400 {
+		t.Errorf("to vor from: status %d", status)
+	}
+}
@@ -0,0 +1,131 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ptmmeiningen/schichtplaner/database"
+	"github.com/ptmmeiningen/schichtplaner/database/databasetest"
+	"github.com/ptmmeiningen/schichtplaner/models"
+	"github.com/ptmmeiningen/schichtplaner/router"
+)
+
+const (
+	loadEmployees   = 30
+	loadWeeks       = 2
+	loadConcurrency = 50
+)
+
+// seedLoad legt Abteilung, Schichttyp, Wochen und Mitarbeiter an und liefert
+// für jeden Mitarbeiter und Tag den Body einer Anfrage sowie die Wochen
+func seedLoad(t *testing.T) ([][]byte, []models.ShiftWeek) {
+	t.Helper()
+	department := databasetest.Department(t, "Lasttest")
+	shiftType := databasetest.ShiftType(t, "Früh", "06:00", "14:00")
+	employees := make([]models.Employee, loadEmployees)
+	for i := range employees {
+		employees[i] = databasetest.Employee(t, fmt.Sprintf("lasttest%d@example.com", i+1), department.ID)
+	}
+
+	monday := time.Date(2030, 1, 7, 0, 0, 0, 0, time.UTC)
+	var bodies [][]byte
+	var weeks []models.ShiftWeek
+	for w := 0; w < loadWeeks; w++ {
+		weekStart := monday.AddDate(0, 0, 7*w)
+		year, calendarWeek := weekStart.ISOWeek()
+		week := models.ShiftWeek{CalendarWeek: calendarWeek, Year: year, DepartmentID: &department.ID, Status: models.StatusDraft}
+		databasetest.Create(t, &week)
+		weeks = append(weeks, week)
+
+		for d := 0; d < 7; d++ {
+			for _, employee := range employees {
+				body, err := json.Marshal(map[string]interface{}{
+					"date":          weekStart.AddDate(0, 0, d),
+					"shift_week_id": week.ID,
+					"shift_type_id": shiftType.ID,
+					"employee_id":   employee.ID,
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				bodies = append(bodies, body)
+			}
+		}
+	}
+	return bodies, weeks
+}
+
+// Parallele Schreibzugriffe dürfen unter SQLite nicht an "database is
+// locked" scheitern: Schreibvorgänge teilen sich eine Verbindung, Lesezugriffe
+// laufen gleichzeitig über den Pool des Resolvers.
+func TestConcurrentShiftDayWrites(t *testing.T) {
+	app := databasetest.Setup(t)
+	router.SetupRoutes(app)
+	bodies, weeks := seedLoad(t)
+
+	type job struct {
+		method, path string
+		body         []byte
+		status       int
+	}
+	jobs := make(chan job)
+	var (
+		mu       sync.Mutex
+		failures = map[string]int{}
+		wg       sync.WaitGroup
+	)
+
+	for i := 0; i < loadConcurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				req := httptest.NewRequest(j.method, j.path, bytes.NewReader(j.body))
+				req.Header.Set("Content-Type", "application/json")
+				resp, err := app.Test(req, -1)
+
+				var failure string
+				switch {
+				case err != nil:
+					failure = err.Error()
+				case resp.StatusCode != j.status:
+					message, _ := io.ReadAll(resp.Body)
+					failure = fmt.Sprintf("%s %s: %d %s", j.method, j.path, resp.StatusCode, message)
+				}
+				if failure != "" {
+					mu.Lock()
+					failures[failure]++
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for i, body := range bodies {
+		jobs <- job{method: "POST", path: "/api/v1/shiftdays", body: body, status: 201}
+		if i%5 == 0 {
+			week := weeks[i%len(weeks)]
+			jobs <- job{method: "GET", path: fmt.Sprintf("/api/v1/shiftdays/week/%d", week.ID), status: 200}
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	for message, count := range failures {
+		t.Errorf("%dx fehlgeschlagen: %s", count, message)
+	}
+
+	var created int64
+	if err := database.GetDB().Model(&models.ShiftDay{}).Count(&created).Error; err != nil {
+		t.Fatal(err)
+	}
+	if created != int64(len(bodies)) {
+		t.Fatalf("%d Schichttage angelegt, erwartet %d", created, len(bodies))
+	}
+}
@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ptmmeiningen/schichtplaner/database"
+	"github.com/ptmmeiningen/schichtplaner/models"
+	"github.com/ptmmeiningen/schichtplaner/pkg/responses"
+	"gorm.io/gorm"
+)
+
+// @Summary Verträge eines Mitarbeiters abrufen
+// @Description Ruft die Vertragshistorie eines Mitarbeiters ab, älteste zuerst
+// @Tags contracts
+// @Accept json
+// @Produce json
+// @Param id path int true "Mitarbeiter-ID"
+// @Success 200 {object} responses.APIResponse{data=[]models.Contract}
+// @Failure 404,500 {object} responses.APIResponse
+// @Router /api/v1/employees/{id}/contracts [get]
+func HandleAllContracts(c *fiber.Ctx) error {
+	var employee models.Employee
+	if err := tenantDB(c).First(&employee, c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
+	}
+
+	var contracts []models.Contract
+	if err := tenantDB(c).Where("employee_id = ?", employee.ID).Order("valid_from, id").Find(&contracts).Error; err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	return c.JSON(responses.SuccessResponse(responses.MsgSuccessGet, contracts))
+}
+
+// @Summary Vertrag erstellen
+// @Description Legt einen Vertrag für einen Mitarbeiter an. Verträge eines Mitarbeiters dürfen sich nicht überschneiden. Sobald ein Mitarbeiter Verträge hat, kann er nur an Tagen mit gültigem Vertrag eingeplant werden. Mitarbeiter ohne Verträge werden nicht geprüft.
+// @Tags contracts
+// @Accept json
+// @Produce json
+// @Param id path int true "Mitarbeiter-ID"
+// @Param contract body models.Contract true "Vertragsdaten"
+// @Success 201 {object} responses.APIResponse{data=models.Contract}
+// @Failure 400,404,500 {object} responses.APIResponse
+// @Failure 422 {object} responses.APIResponse{data=[]models.FieldError}
+// @Router /api/v1/employees/{id}/contracts [post]
+func HandleCreateContract(c *fiber.Ctx) error {
+	var employee models.Employee
+	if err := tenantDB(c).First(&employee, c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
+	}
+
+	contract := new(models.Contract)
+	if err := c.BodyParser(contract); err != nil {
+		return c.Status(400).JSON(responses.ErrorResponse(responses.ErrInvalidInput))
+	}
+	contract.EmployeeID = employee.ID
+
+	if err := validateContract(tenantDB(c), contract); err != nil {
+		return validationErrorResponse(c, err)
+	}
+
+	if err := tenantDB(c).Omit("Employee").Create(contract).Error; err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	return c.Status(201).JSON(responses.SuccessResponse(responses.MsgSuccessCreate, contract))
+}
+
+// @Summary Vertrag aktualisieren
+// @Description Aktualisiert einen Vertrag, z.B. um ihn mit valid_to zu beenden
+// @Tags contracts
+// @Accept json
+// @Produce json
+// @Param id path int true "Mitarbeiter-ID"
+// @Param contractId path int true "Vertrags-ID"
+// @Param contract body models.Contract true "Aktualisierte Vertragsdaten"
+// @Success 200 {object} responses.APIResponse{data=models.Contract}
+// @Failure 400,404,500 {object} responses.APIResponse
+// @Failure 422 {object} responses.APIResponse{data=[]models.FieldError}
+// @Router /api/v1/employees/{id}/contracts/{contractId} [put]
+func HandleUpdateContract(c *fiber.Ctx) error {
+	var contract models.Contract
+	if err := tenantDB(c).
+		Where("employee_id = ?", c.Params("id")).
+		First(&contract, c.Params("contractId")).Error; err != nil {
+		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
+	}
+
+	employeeID := contract.EmployeeID
+	contract.ValidTo = nil
+	if err := c.BodyParser(&contract); err != nil {
+		return c.Status(400).JSON(responses.ErrorResponse(responses.ErrInvalidInput))
+	}
+	contract.EmployeeID = employeeID
+
+	if err := validateContract(tenantDB(c), &contract); err != nil {
+		return validationErrorResponse(c, err)
+	}
+
+	if err := tenantDB(c).Omit("Employee").Save(&contract).Error; err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	return c.JSON(responses.SuccessResponse(responses.MsgSuccessUpdate, contract))
+}
+
+// @Summary Vertrag löschen
+// @Description Löscht einen Vertrag aus der Vertragshistorie
+// @Tags contracts
+// @Accept json
+// @Produce json
+// @Param id path int true "Mitarbeiter-ID"
+// @Param contractId path int true "Vertrags-ID"
+// @Success 200 {object} responses.APIResponse
+// @Failure 404,500 {object} responses.APIResponse
+// @Router /api/v1/employees/{id}/contracts/{contractId} [delete]
+func HandleDeleteContract(c *fiber.Ctx) error {
+	var contract models.Contract
+	if err := tenantDB(c).
+		Where("employee_id = ?", c.Params("id")).
+		First(&contract, c.Params("contractId")).Error; err != nil {
+		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
+	}
+
+	tx := tenantDB(c).Begin()
+
+	if err := database.SoftDelete(tx, "contracts", contract.ID); err != nil {
+		tx.Rollback()
+		return deleteErrorResponse(c, err)
+	}
+
+	tx.Commit()
+
+	return c.JSON(responses.SuccessResponse(responses.MsgSuccessDelete, nil))
+}
+
+func validateContract(db *gorm.DB, contract *models.Contract) error {
+	if !contract.ValidFrom.IsZero() {
+		contract.ValidFrom = truncateDay(contract.ValidFrom)
+	}
+	if contract.ValidTo != nil {
+		validTo := truncateDay(*contract.ValidTo)
+		contract.ValidTo = &validTo
+	}
+	errs := models.ValidateModel(contract)
+	if errs.Has("valid_from") || errs.Has("valid_to") {
+		return errs.Err()
+	}
+
+	// Verträge überschneiden sich, wenn jeder vor dem Ende des anderen beginnt
+	overlap := db.Model(&models.Contract{}).Where("employee_id = ? AND id != ?", contract.EmployeeID, contract.ID)
+	if contract.ValidTo != nil {
+		overlap = overlap.Where("valid_from <= ?", *contract.ValidTo)
+	}
+	var count int64
+	if err := overlap.Where("valid_to IS NULL OR valid_to >= ?", contract.ValidFrom).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		errs.Add("valid_from", models.CodeConflict, "vertrag überschneidet sich mit einem anderen vertrag des mitarbeiters")
+	}
+
+	return errs.Err()
+}
+
+// validateContractForShift prüft, ob der Mitarbeiter am Tag der Schicht einen
+// gültigen Vertrag hat und die vereinbarten Arbeitstage pro Woche einhält.
+// Mitarbeiter ohne Vertragshistorie werden bewusst nicht geprüft, damit
+// Bestände ohne erfasste Verträge weiter eingeplant werden können. Mit dem
+// ersten Vertrag gilt die Prüfung für alle Tage, auch vor Vertragsbeginn.
+func validateContractForShift(db *gorm.DB, shiftDay *models.ShiftDay) error {
+	var contracts []models.Contract
+	if err := db.Where("employee_id = ?", *shiftDay.EmployeeID).Find(&contracts).Error; err != nil {
+		return err
+	}
+	if len(contracts) == 0 {
+		return nil
+	}
+
+	contract := models.ContractOn(contracts, shiftDay.Date)
+	if contract == nil {
+		return models.NewValidationError("employee_id", models.CodeNotAllowed, "mitarbeiter hat an diesem tag keinen gültigen vertrag")
+	}
+
+	if contract.MaxDaysPerWeek == 0 || isAbsence(shiftDay.Status) {
+		return nil
+	}
+	monday := isoWeekStart(shiftDay.Date.ISOWeek())
+	var days int64
+	if err := db.Model(&models.ShiftDay{}).
+		Where("employee_id = ? AND id != ? AND date >= ? AND date < ?", *shiftDay.EmployeeID, shiftDay.ID, monday, monday.AddDate(0, 0, 7)).
+		Where("status NOT IN ?", models.AbsenceStatuses).
+		Count(&days).Error; err != nil {
+		return err
+	}
+	if int(days) >= contract.MaxDaysPerWeek {
+		return models.NewValidationError("employee_id", models.CodeNotAllowed, fmt.Sprintf("mitarbeiter darf laut vertrag höchstens %d tage pro woche arbeiten", contract.MaxDaysPerWeek))
+	}
+	return nil
+}
+
+// isAbsence gibt an, ob der Status eines Schichttags eine Abwesenheit ist
+func isAbsence(status string) bool {
+	for _, absence := range models.AbsenceStatuses {
+		if status == absence {
+			return true
+		}
+	}
+	return false
+}
+
+// contractsByEmployee lädt die Verträge der Mitarbeiter, gruppiert nach Mitarbeiter
+func contractsByEmployee(db *gorm.DB, employeeIDs []uint) (map[uint][]models.Contract, error) {
+	var contracts []models.Contract
+	if err := db.Where("employee_id IN ?", employeeIDs).Order("valid_from").Find(&contracts).Error; err != nil {
+		return nil, err
+	}
+	byEmployee := map[uint][]models.Contract{}
+	for _, contract := range contracts {
+		byEmployee[contract.EmployeeID] = append(byEmployee[contract.EmployeeID], contract)
+	}
+	return byEmployee, nil
+}
+
+// targetHours liefert die Sollstunden der Woche ab monday laut dem an jedem
+// Tag gültigen Vertrag. Ohne Vertragshistorie gilt standardWeeklyHours.
+func targetHours(contracts []models.Contract, monday time.Time) float64 {
+	if len(contracts) == 0 {
+		return standardWeeklyHours
+	}
+	hours := 0.0
+	for i := 0; i < 7; i++ {
+		if contract := models.ContractOn(contracts, monday.AddDate(0, 0, i)); contract != nil {
+			hours += contract.WeeklyHours / 7
+		}
+	}
+	return hours
+}
@@ -0,0 +1,58 @@
+package handlers_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ptmmeiningen/schichtplaner/database/databasetest"
+	"github.com/ptmmeiningen/schichtplaner/models"
+)
+
+func TestShiftDayContractCheck(t *testing.T) {
+	app := setupApp(t)
+	department := databasetest.Department(t, "Produktion")
+	shiftType := databasetest.ShiftType(t, "Früh", "06:00", "14:00")
+	week := models.ShiftWeek{Year: 2030, CalendarWeek: 2, Status: models.StatusDraft, DepartmentID: &department.ID}
+	databasetest.Create(t, &week)
+
+	monday := time.Date(2030, 1, 7, 0, 0, 0, 0, time.UTC)
+	createShiftDay := func(employee models.Employee, date time.Time) (int, apiResponse) {
+		return call(t, app, "POST", "/api/v1/shiftdays", "", map[string]interface{}{
+			"date": date, "shift_week_id": week.ID, "shift_type_id": shiftType.ID, "employee_id": employee.ID,
+		})
+	}
+
+	t.Run("ohne Vertrag nicht geprüft", func(t *testing.T) {
+		employee := databasetest.Employee(t, "ohne@example.org", department.ID)
+		for d := 0; d < 7; d++ {
+			if status, resp := createShiftDay(employee, monday.AddDate(0, 0, d)); status != 201 {
+				t.Fatalf("tag %d: status %d, %s %s", d, status, resp.Error, resp.Data)
+			}
+		}
+	})
+
+	t.Run("mit Vertrag", func(t *testing.T) {
+		employee := databasetest.Employee(t, "mit@example.org", department.ID)
+		databasetest.Create(t, &models.Contract{
+			EmployeeID:     employee.ID,
+			EmploymentType: models.EmploymentPartTime,
+			ValidFrom:      monday.AddDate(0, 0, 2),
+			WeeklyHours:    16,
+			MaxDaysPerWeek: 2,
+		})
+
+		// Vor Vertragsbeginn
+		status, resp := createShiftDay(employee, monday)
+		expectFieldErrors(t, status, resp, models.FieldError{Field: "employee_id", Code: models.CodeNotAllowed})
+
+		for d := 2; d < 4; d++ {
+			if status, resp := createShiftDay(employee, monday.AddDate(0, 0, d)); status != 201 {
+				t.Fatalf("tag %d: status %d, %s %s", d, status, resp.Error, resp.Data)
+			}
+		}
+
+		// Mehr Arbeitstage als vereinbart
+		status, resp = createShiftDay(employee, monday.AddDate(0, 0, 4))
+		expectFieldErrors(t, status, resp, models.FieldError{Field: "employee_id", Code: models.CodeNotAllowed})
+	})
+}
@@ -0,0 +1,389 @@
+package handlers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ptmmeiningen/schichtplaner/models"
+	"github.com/ptmmeiningen/schichtplaner/pkg/responses"
+	"gorm.io/gorm"
+)
+
+// standardWeeklyHours ist die Wochenarbeitszeit für Mitarbeiter ohne
+// Vertragshistorie, ab der Stunden als Überstunden zählen
+const standardWeeklyHours = 40
+
+// defaultStatsWeeks ist der Zeitraum ohne from/to: die aktuelle und die
+// folgenden drei Wochen
+const defaultStatsWeeks = 4
+
+// StaffingStats sind die Besetzungskennzahlen eines Tages, einer Woche oder
+// des ganzen Zeitraums. Coverage und AbsenceRate sind Prozentwerte und
+// fehlen, wenn nichts zu berechnen ist.
+type StaffingStats struct {
+	Required      int      `json:"required"`
+	Planned       int      `json:"planned"`
+	Open          int      `json:"open_shifts"`
+	Absent        int      `json:"absent"`
+	Coverage      *float64 `json:"coverage"`
+	AbsenceRate   *float64 `json:"absence_rate"`
+	Hours         float64  `json:"hours"`
+	OvertimeHours float64  `json:"overtime_hours"`
+
+	covered int
+}
+
+// DayStats sind die Besetzungskennzahlen eines Tages
+type DayStats struct {
+	Date string `json:"date" example:"2025-01-06"`
+	StaffingStats
+}
+
+// WeekStats sind die Besetzungskennzahlen einer Kalenderwoche
+type WeekStats struct {
+	Year         int `json:"year"`
+	CalendarWeek int `json:"calendar_week"`
+	StaffingStats
+}
+
+// DepartmentStats sind die Statistiken einer Abteilung im Zeitraum
+type DepartmentStats struct {
+	EmployeeCount    int64         `json:"employeeCount"`
+	ShiftWeekCount   int64         `json:"shiftWeekCount"`
+	ActiveShiftWeeks int64         `json:"activeShiftWeeks"`
+	From             string        `json:"from"`
+	To               string        `json:"to"`
+	Summary          StaffingStats `json:"summary"`
+	Days             []DayStats    `json:"days"`
+	Weeks            []WeekStats   `json:"weeks"`
+	Teams            []TeamStats   `json:"teams,omitempty"`
+}
+
+// TeamStats ist die Zusammenfassung eines Teams in den Abteilungsstatistiken
+type TeamStats struct {
+	TeamID        uint          `json:"team_id"`
+	Name          string        `json:"name"`
+	EmployeeCount int64         `json:"employee_count"`
+	Summary       StaffingStats `json:"summary"`
+}
+
+// add zählt die Kennzahlen eines Tages oder einer Woche hinzu
+func (s *StaffingStats) add(other StaffingStats) {
+	s.Required += other.Required
+	s.Planned += other.Planned
+	s.Open += other.Open
+	s.Absent += other.Absent
+	s.Hours += other.Hours
+	s.OvertimeHours += other.OvertimeHours
+	s.covered += other.covered
+}
+
+// finish berechnet Abdeckung und Abwesenheitsquote. Ein Tag gilt bis zum
+// Bedarf als abgedeckt, Überbesetzung gleicht keine anderen Tage aus.
+func (s *StaffingStats) finish() {
+	if s.Required > 0 {
+		coverage := 100 * float64(s.covered) / float64(s.Required)
+		s.Coverage = &coverage
+	}
+	if assigned := s.Planned + s.Absent; assigned > 0 {
+		rate := 100 * float64(s.Absent) / float64(assigned)
+		s.AbsenceRate = &rate
+	}
+}
+
+// @Summary Abteilungsstatistiken abrufen
+// @Description Ruft statistische Daten einer Abteilung für einen Zeitraum ab: Abdeckung, geplante gegenüber benötigter Besetzung je Tag, Abwesenheitsquote, Stunden, Überstunden über den Sollstunden laut Vertrag (für angeschnittene Wochen über die ganze Woche gerechnet), offene Schichten und Verlauf je Woche. Der Bedarf ergibt sich aus den aktiven Schichtvorlagen. Die Abteilung umfasst die Schichtwochen aller Teams, teams fasst jedes Team zusammen. Ohne from/to werden die aktuelle und die drei folgenden Wochen ausgewertet.
+// @Tags departments
+// @Accept json
+// @Produce json
+// @Param id path int true "Abteilungs-ID"
+// @Param from query string false "Beginn (YYYY-MM-DD)"
+// @Param to query string false "Ende einschließlich (YYYY-MM-DD)"
+// @Success 200 {object} responses.APIResponse{data=DepartmentStats}
+// @Failure 400,404,500 {object} responses.APIResponse
+// @Router /api/v1/departments/{id}/stats [get]
+func HandleDepartmentStats(c *fiber.Ctx) error {
+	id := c.Params("id")
+	var department models.Department
+
+	if err := tenantDB(c).First(&department, id).Error; err != nil {
+		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
+	}
+
+	from, to, err := parseStatsRange(c)
+	if err != nil {
+		return c.Status(400).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	stats, err := departmentStats(tenantDB(c), department.ID, 0, from, to)
+	if err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	var teams []models.Team
+	if err := tenantDB(c).Where("department_id = ?", department.ID).Order("name").Find(&teams).Error; err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+	for _, team := range teams {
+		teamStats, err := departmentStats(tenantDB(c), department.ID, team.ID, from, to)
+		if err != nil {
+			return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+		}
+		stats.Teams = append(stats.Teams, TeamStats{
+			TeamID:        team.ID,
+			Name:          team.Name,
+			EmployeeCount: teamStats.EmployeeCount,
+			Summary:       teamStats.Summary,
+		})
+	}
+
+	return c.JSON(responses.SuccessResponse("Statistiken erfolgreich abgerufen", stats))
+}
+
+// parseStatsRange liest from/to, ohne Angabe die aktuelle und die drei
+// folgenden Wochen
+func parseStatsRange(c *fiber.Ctx) (time.Time, time.Time, error) {
+	from := isoWeekStart(time.Now().ISOWeek())
+	to := from.AddDate(0, 0, 7*defaultStatsWeeks-1)
+	if c.Query("from") != "" || c.Query("to") != "" {
+		return parseReportRange(c)
+	}
+	return from, to, nil
+}
+
+// departmentStats berechnet die Statistiken einer Abteilung im Zeitraum, mit
+// teamID > 0 nur die des Teams. Die Schichtvorlagen gelten für die ganze
+// Abteilung, für ein Team bleibt der Bedarf daher leer.
+func departmentStats(db *gorm.DB, departmentID, teamID uint, from, to time.Time) (*DepartmentStats, error) {
+	stats := DepartmentStats{
+		From:  from.Format("2006-01-02"),
+		To:    to.Format("2006-01-02"),
+		Days:  []DayStats{},
+		Weeks: []WeekStats{},
+	}
+
+	if err := db.Model(&models.Employee{}).Scopes(inTeam("employees", teamID)).Where("department_id = ?", departmentID).Count(&stats.EmployeeCount).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Model(&models.ShiftWeek{}).Scopes(inTeam("shift_weeks", teamID)).Where("department_id = ?", departmentID).Count(&stats.ShiftWeekCount).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Model(&models.ShiftWeek{}).Scopes(inTeam("shift_weeks", teamID)).Where("department_id = ? AND status = ?", departmentID, models.StatusPublished).Count(&stats.ActiveShiftWeeks).Error; err != nil {
+		return nil, err
+	}
+
+	days, err := departmentDayStats(db, departmentID, teamID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	overtime, err := departmentOvertime(db, departmentID, teamID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	weekIndex := map[string]int{}
+	for date := from; !date.After(to); date = date.AddDate(0, 0, 1) {
+		day := DayStats{Date: date.Format("2006-01-02")}
+		if aggregated := days[day.Date]; aggregated != nil {
+			day.StaffingStats = *aggregated
+		}
+		day.finish()
+		stats.Days = append(stats.Days, day)
+
+		year, calendarWeek := date.ISOWeek()
+		key := fmt.Sprintf("%d-W%02d", year, calendarWeek)
+		index, ok := weekIndex[key]
+		if !ok {
+			week := WeekStats{Year: year, CalendarWeek: calendarWeek}
+			week.OvertimeHours = overtime[key]
+			index = len(stats.Weeks)
+			weekIndex[key] = index
+			stats.Weeks = append(stats.Weeks, week)
+		}
+		stats.Weeks[index].add(day.StaffingStats)
+	}
+
+	for i := range stats.Weeks {
+		stats.Weeks[i].finish()
+		stats.Summary.add(stats.Weeks[i].StaffingStats)
+	}
+	stats.Summary.finish()
+
+	return &stats, nil
+}
+
+// departmentDayStats zählt Bedarf, Besetzung, offene Schichten, Abwesenheiten
+// und Stunden je Tag per SQL-Aggregation
+func departmentDayStats(db *gorm.DB, departmentID, teamID uint, from, to time.Time) (map[string]*StaffingStats, error) {
+	shiftTypes, err := shiftTypesByID(db)
+	if err != nil {
+		return nil, err
+	}
+
+	days := map[string]*StaffingStats{}
+	dayStats := func(date time.Time) *StaffingStats {
+		key := date.Format("2006-01-02")
+		if days[key] == nil {
+			days[key] = &StaffingStats{}
+		}
+		return days[key]
+	}
+
+	var shifts []struct {
+		Date         time.Time
+		ShiftTypeID  uint
+		BreakMinutes *int
+		Total        int
+		Assigned     int
+		Absent       int
+	}
+	if err := db.Table("shift_days").
+		Select("shift_days.date AS date, shift_days.shift_type_id AS shift_type_id, shift_days.break_minutes AS break_minutes, COUNT(*) AS total, COUNT(shift_days.employee_id) AS assigned, "+
+			"SUM(CASE WHEN shift_days.employee_id IS NOT NULL AND shift_days.status IN ? THEN 1 ELSE 0 END) AS absent", models.AbsenceStatuses).
+		Joins("JOIN shift_weeks ON shift_weeks.id = shift_days.shift_week_id AND shift_weeks.deleted_at IS NULL").
+		Where("shift_weeks.department_id = ? AND shift_days.deleted_at IS NULL AND shift_days.date >= ? AND shift_days.date < ?", departmentID, from, to.AddDate(0, 0, 1)).
+		Scopes(inTeam("shift_weeks", teamID)).
+		Group("shift_days.date, shift_days.shift_type_id, shift_days.break_minutes").
+		Scan(&shifts).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range shifts {
+		day := dayStats(row.Date)
+		planned := row.Assigned - row.Absent
+		day.Planned += planned
+		day.Open += row.Total - row.Assigned
+		day.Absent += row.Absent
+		day.Hours += float64(planned) * paidHours(shiftTypes, row.ShiftTypeID, row.BreakMinutes)
+	}
+
+	if teamID > 0 {
+		return days, nil
+	}
+
+	// Jeder Tag einer aktiven Vorlage ist eine Schicht, die besetzt werden muss
+	var requirements []struct {
+		ValidFrom  time.Time
+		ValidUntil time.Time
+		WeekDay    int
+		Required   int
+	}
+	if err := db.Table("shift_template_days").
+		Select("shift_templates.valid_from AS valid_from, shift_templates.valid_until AS valid_until, shift_template_days.week_day AS week_day, COUNT(*) AS required").
+		Joins("JOIN shift_templates ON shift_templates.id = shift_template_days.shift_template_id AND shift_templates.deleted_at IS NULL").
+		Where("shift_templates.department_id = ? AND shift_templates.status = ? AND shift_template_days.deleted_at IS NULL", departmentID, "active").
+		Where("shift_templates.valid_from < ? AND shift_templates.valid_until >= ?", to.AddDate(0, 0, 1), from).
+		Group("shift_templates.id, shift_templates.valid_from, shift_templates.valid_until, shift_template_days.week_day").
+		Scan(&requirements).Error; err != nil {
+		return nil, err
+	}
+	for date := from; !date.After(to); date = date.AddDate(0, 0, 1) {
+		weekDay := (int(date.Weekday()) + 6) % 7
+		for _, requirement := range requirements {
+			if requirement.WeekDay == weekDay && !date.Before(truncateDay(requirement.ValidFrom)) && !date.After(requirement.ValidUntil) {
+				dayStats(date).Required += requirement.Required
+			}
+		}
+	}
+
+	for _, day := range days {
+		day.covered = min(day.Planned, day.Required)
+	}
+	return days, nil
+}
+
+// departmentOvertime summiert je Kalenderwoche die Stunden, die Mitarbeiter
+// über die Sollstunden ihres Vertrags hinaus in der Abteilung eingeplant sind.
+// Die Sollstunden gelten für die ganze Woche, daher zählen auch die Tage der
+// angeschnittenen Wochen vor from und nach to mit.
+func departmentOvertime(db *gorm.DB, departmentID, teamID uint, from, to time.Time) (map[string]float64, error) {
+	shiftTypes, err := shiftTypesByID(db)
+	if err != nil {
+		return nil, err
+	}
+	weekFrom := isoWeekStart(from.ISOWeek())
+	weekTo := isoWeekStart(to.ISOWeek()).AddDate(0, 0, 7)
+
+	var rows []struct {
+		EmployeeID   uint
+		Year         int
+		CalendarWeek int
+		ShiftTypeID  uint
+		BreakMinutes *int
+		Shifts       int
+	}
+	if err := db.Table("shift_days").
+		Select("shift_days.employee_id AS employee_id, shift_weeks.year AS year, shift_weeks.calendar_week AS calendar_week, shift_days.shift_type_id AS shift_type_id, shift_days.break_minutes AS break_minutes, COUNT(*) AS shifts").
+		Joins("JOIN shift_weeks ON shift_weeks.id = shift_days.shift_week_id AND shift_weeks.deleted_at IS NULL").
+		Where("shift_weeks.department_id = ? AND shift_days.deleted_at IS NULL AND shift_days.employee_id IS NOT NULL AND shift_days.status NOT IN ?", departmentID, models.AbsenceStatuses).
+		Where("shift_days.date >= ? AND shift_days.date < ?", weekFrom, weekTo).
+		Scopes(inTeam("shift_weeks", teamID)).
+		Group("shift_days.employee_id, shift_weeks.year, shift_weeks.calendar_week, shift_days.shift_type_id, shift_days.break_minutes").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	type employeeWeek struct {
+		employeeID uint
+		year, week int
+	}
+	hours := map[employeeWeek]float64{}
+	employeeIDs := []uint{}
+	for _, row := range rows {
+		key := employeeWeek{row.EmployeeID, row.Year, row.CalendarWeek}
+		if _, ok := hours[key]; !ok {
+			employeeIDs = append(employeeIDs, row.EmployeeID)
+		}
+		hours[key] += float64(row.Shifts) * paidHours(shiftTypes, row.ShiftTypeID, row.BreakMinutes)
+	}
+
+	contracts, err := contractsByEmployee(db, employeeIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	overtime := map[string]float64{}
+	for key, worked := range hours {
+		target := targetHours(contracts[key.employeeID], isoWeekStart(key.year, key.week))
+		if worked > target {
+			overtime[fmt.Sprintf("%d-W%02d", key.year, key.week)] += worked - target
+		}
+	}
+	return overtime, nil
+}
+
+// inTeam schränkt eine Tabelle mit team_id auf das Team ein, teamID 0 steht
+// für die ganze Abteilung
+func inTeam(table string, teamID uint) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if teamID == 0 {
+			return db
+		}
+		return db.Where(table+".team_id = ?", teamID)
+	}
+}
+
+// shiftTypesByID lädt alle Schichttypen, auch gelöschte
+func shiftTypesByID(db *gorm.DB) (map[uint]models.ShiftType, error) {
+	var shiftTypes []models.ShiftType
+	if err := db.Unscoped().Find(&shiftTypes).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]models.ShiftType, len(shiftTypes))
+	for _, shiftType := range shiftTypes {
+		byID[shiftType.ID] = shiftType
+	}
+	return byID, nil
+}
+
+// paidHours sind die bezahlten Stunden einer Schicht mit abweichender Pause
+func paidHours(shiftTypes map[uint]models.ShiftType, shiftTypeID uint, breakMinutes *int) float64 {
+	shiftType := shiftTypes[shiftTypeID]
+	shiftDay := models.ShiftDay{ShiftTypeID: shiftTypeID, BreakMinutes: breakMinutes}
+	return shiftDay.Duration(&shiftType).Paid.Hours()
+}
+
+// truncateDay schneidet die Uhrzeit ab
+func truncateDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
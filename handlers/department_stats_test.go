@@ -0,0 +1,123 @@
+package handlers_test
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ptmmeiningen/schichtplaner/database/databasetest"
+	"github.com/ptmmeiningen/schichtplaner/handlers"
+	"github.com/ptmmeiningen/schichtplaner/models"
+)
+
+func TestDepartmentStats(t *testing.T) {
+	app := setupApp(t)
+	department := databasetest.Department(t, "Produktion")
+	team := models.Team{DepartmentID: department.ID, Name: "Linie A"}
+	databasetest.Create(t, &team)
+	// Früh: 8 Stunden mit 30 Minuten Pause, Spät mit 45 Minuten am Tag
+	early := databasetest.ShiftType(t, "Früh", "06:00", "14:00")
+	late := databasetest.ShiftType(t, "Spät", "14:00", "22:00")
+	anna := databasetest.Employee(t, "anna@example.org", department.ID)
+	anna.TeamID = &team.ID
+	databasetest.Save(t, &anna)
+	bert := databasetest.Employee(t, "bert@example.org", department.ID)
+
+	// Bedarf: montags zwei, dienstags eine Frühschicht
+	template := models.ShiftTemplate{
+		Name: "Standard", DepartmentID: department.ID, Status: "active",
+		ValidFrom: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC), ValidUntil: time.Date(2030, 12, 31, 0, 0, 0, 0, time.UTC),
+	}
+	databasetest.Create(t, &template)
+	for _, weekDay := range []int{0, 0, 1} {
+		databasetest.Create(t, &models.ShiftTemplateDay{ShiftTemplateID: template.ID, ShiftTypeID: early.ID, WeekDay: weekDay})
+	}
+
+	// KW 2 plant das Team, KW 3 die ganze Abteilung
+	monday := time.Date(2030, 1, 7, 0, 0, 0, 0, time.UTC)
+	teamWeek := models.ShiftWeek{Year: 2030, CalendarWeek: 2, Status: models.StatusPublished, DepartmentID: &department.ID, TeamID: &team.ID}
+	databasetest.Create(t, &teamWeek)
+	departmentWeek := models.ShiftWeek{Year: 2030, CalendarWeek: 3, Status: models.StatusPublished, DepartmentID: &department.ID}
+	databasetest.Create(t, &departmentWeek)
+	shift := func(week models.ShiftWeek, employee *models.Employee, shiftType models.ShiftType, day int, apply func(*models.ShiftDay)) {
+		shiftDay := models.ShiftDay{Date: monday.AddDate(0, 0, day), ShiftWeekID: &week.ID, ShiftTypeID: shiftType.ID, Status: models.ShiftDayPlanned}
+		if employee != nil {
+			shiftDay.EmployeeID = &employee.ID
+		}
+		if apply != nil {
+			apply(&shiftDay)
+		}
+		databasetest.Create(t, &shiftDay)
+	}
+	// Anna arbeitet in KW 2 sechs Tage, 45 Stunden bei 40 Sollstunden
+	for day := 0; day < 6; day++ {
+		shift(teamWeek, &anna, early, day, nil)
+	}
+	shift(teamWeek, nil, early, 4, nil)
+	breakMinutes := 45
+	shift(departmentWeek, &anna, late, 7, func(d *models.ShiftDay) { d.BreakMinutes = &breakMinutes })
+	shift(departmentWeek, &bert, early, 7, nil)
+	shift(departmentWeek, &bert, early, 8, func(d *models.ShiftDay) { d.Status = models.ShiftDaySick })
+	shift(departmentWeek, nil, early, 8, nil)
+
+	// Von Donnerstag in KW 2 bis Dienstag in KW 3
+	status, resp := call(t, app, "GET", fmt.Sprintf("/api/v1/departments/%d/stats?from=2030-01-10&to=2030-01-15", department.ID), "", nil)
+	if status != 200 {
+		t.Fatalf("status %d: %s", status, resp.Error)
+	}
+	var stats handlers.DepartmentStats
+	decode(t, resp, &stats)
+
+	type staffing struct {
+		required, planned, open, absent int
+		hours, overtime                 float64
+		coverage, absenceRate           float64 // -1 fehlt
+	}
+	check := func(name string, got handlers.StaffingStats, want staffing) {
+		t.Helper()
+		percent := func(value *float64) float64 {
+			if value == nil {
+				return -1
+			}
+			return math.Round(*value*100) / 100
+		}
+		if got.Required != want.required || got.Planned != want.planned || got.Open != want.open || got.Absent != want.absent ||
+			math.Abs(got.Hours-want.hours) > 1e-9 || math.Abs(got.OvertimeHours-want.overtime) > 1e-9 ||
+			percent(got.Coverage) != want.coverage || percent(got.AbsenceRate) != want.absenceRate {
+			t.Errorf("%s: %+v (abdeckung %v, abwesenheit %v), erwartet %+v", name, got, percent(got.Coverage), percent(got.AbsenceRate), want)
+		}
+	}
+
+	wantDays := []staffing{
+		{planned: 1, hours: 7.5, coverage: -1, absenceRate: 0},
+		{planned: 1, open: 1, hours: 7.5, coverage: -1, absenceRate: 0},
+		{planned: 1, hours: 7.5, coverage: -1, absenceRate: 0},
+		{coverage: -1, absenceRate: -1},
+		{required: 2, planned: 2, hours: 7.5 + 7.25, coverage: 100, absenceRate: 0},
+		{required: 1, open: 1, absent: 1, coverage: 0, absenceRate: 100},
+	}
+	if len(stats.Days) != len(wantDays) || stats.Days[0].Date != "2030-01-10" {
+		t.Fatalf("tage %+v", stats.Days)
+	}
+	for i, want := range wantDays {
+		check(stats.Days[i].Date, stats.Days[i].StaffingStats, want)
+	}
+
+	// Die Überstunden rechnen mit der ganzen KW 2, nicht nur ab Donnerstag
+	if len(stats.Weeks) != 2 || stats.Weeks[0].CalendarWeek != 2 || stats.Weeks[1].CalendarWeek != 3 {
+		t.Fatalf("wochen %+v", stats.Weeks)
+	}
+	check("kw 2", stats.Weeks[0].StaffingStats, staffing{planned: 3, open: 1, hours: 22.5, overtime: 5, coverage: -1, absenceRate: 0})
+	check("kw 3", stats.Weeks[1].StaffingStats, staffing{required: 3, planned: 2, open: 1, absent: 1, hours: 14.75, coverage: 66.67, absenceRate: 33.33})
+	check("gesamt", stats.Summary, staffing{required: 3, planned: 5, open: 2, absent: 1, hours: 37.25, overtime: 5, coverage: 66.67, absenceRate: 16.67})
+	if stats.EmployeeCount != 2 || stats.ShiftWeekCount != 2 || stats.ActiveShiftWeeks != 2 {
+		t.Errorf("zähler %d, %d, %d", stats.EmployeeCount, stats.ShiftWeekCount, stats.ActiveShiftWeeks)
+	}
+
+	// Das Team umfasst nur seine eigenen Wochen und hat keinen Bedarf
+	if len(stats.Teams) != 1 || stats.Teams[0].TeamID != team.ID || stats.Teams[0].EmployeeCount != 1 {
+		t.Fatalf("teams %+v", stats.Teams)
+	}
+	check("team", stats.Teams[0].Summary, staffing{planned: 3, open: 1, hours: 22.5, overtime: 5, coverage: -1, absenceRate: 0})
+}
@@ -1,51 +1,101 @@
 package handlers
 
 import (
-	"fmt"
-
 	"github.com/gofiber/fiber/v2"
 	"github.com/ptmmeiningen/schichtplaner/database"
 	"github.com/ptmmeiningen/schichtplaner/models"
+	"github.com/ptmmeiningen/schichtplaner/pkg/events"
+	"github.com/ptmmeiningen/schichtplaner/pkg/query"
 	"github.com/ptmmeiningen/schichtplaner/pkg/responses"
 	"gorm.io/gorm"
 )
 
+// departmentQuery legt die Filter und Sortierungen für Abteilungs-Listen fest
+var departmentQuery = query.Config{
+	Table:    "departments",
+	Resource: "department",
+	Fields:   departmentFields,
+	Filters: map[string]query.Filter{
+		"name":        {Condition: "departments.name = ?", Parse: query.String},
+		"location_id": {Condition: "departments.location_id = ?", Parse: query.Int},
+	},
+	Sorts: map[string]string{
+		"name":       "departments.name",
+		"created_at": "departments.created_at",
+	},
+	DefaultSort: "name",
+	Includes: map[string]query.Include{
+		"employees": {
+			Preload:    "Employees",
+			Resource:   "employee",
+			Fields:     employeeFields,
+			ForeignKey: "department_id",
+			HasMany:    true,
+			Order:      "last_name, first_name",
+		},
+		"teams": {
+			Preload:    "Teams",
+			Resource:   "team",
+			Fields:     teamFields,
+			ForeignKey: "department_id",
+			HasMany:    true,
+			Order:      "name",
+		},
+		"shift_weeks": {
+			Preload:    "ShiftWeeks",
+			Resource:   "shift_week",
+			Fields:     shiftWeekFields,
+			ForeignKey: "department_id",
+			HasMany:    true,
+			Order:      "year DESC, calendar_week DESC",
+			Includes: map[string]query.Include{
+				"shift_days": shiftDaysInclude("shift_week_id", "date"),
+			},
+		},
+	},
+	DefaultIncludes: []string{"employees", "shift_weeks"},
+}
+
 // @Summary Alle Abteilungen abrufen
-// @Description Ruft alle Abteilungen mit zugehörigen Mitarbeitern, Beschreibungen und Schichtwochen ab
+// @Description Ruft alle Abteilungen mit zugehörigen Mitarbeitern, Beschreibungen und Schichtwochen ab, paginiert und sortiert
 // @Tags departments
 // @Accept json
 // @Produce json
+// @Param page query int false "Seite (ab 1)"
+// @Param page_size query int false "Einträge pro Seite (max. 200)"
+// @Param cursor query string false "Cursor für die nächste Seite (statt page)"
+// @Param name query string false "Name"
+// @Param location_id query int false "Standort-ID"
+// @Param sort query string false "Sortierung, z.B. name"
+// @Param include query string false "Beziehungen, z.B. employees,teams,shift_weeks"
+// @Param fields[department] query string false "Felder der Abteilung, z.B. id,name,color"
+// @Param fields[employee] query string false "Felder der Mitarbeiter, z.B. id,first_name,last_name"
 // @Success 200 {object} responses.APIResponse{data=[]models.Department}
-// @Failure 500 {object} responses.APIResponse
+// @Failure 400,500 {object} responses.APIResponse
 // @Router /api/v1/departments [get]
 func HandleAllDepartments(c *fiber.Ctx) error {
-	var departments []models.Department
-	result := database.GetDB().
-		Preload("Employees", func(db *gorm.DB) *gorm.DB {
-			return db.Select("id, first_name, last_name, email, color, department_id, is_admin").
-				Order("last_name, first_name")
-		}).
-		Preload("ShiftWeeks", func(db *gorm.DB) *gorm.DB {
-			return db.Select("id, calendar_week, year, department_id, status").
-				Order("year DESC, calendar_week DESC")
-		}).
-		Order("name").
-		Find(&departments)
+	q, err := query.Parse(c, departmentQuery)
+	if err != nil {
+		return c.Status(400).JSON(responses.ErrorResponse(err.Error()))
+	}
 
-	if result.Error != nil {
-		return c.Status(500).JSON(responses.ErrorResponse(result.Error.Error()))
+	var departments []models.Department
+	meta, err := q.Find(tenantDB(c), &departments)
+	if err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
 	}
-	return c.JSON(responses.SuccessResponse(responses.MsgSuccessGet, departments))
+	return c.JSON(responses.PaginatedResponse(responses.MsgSuccessGet, q.Shape(departments), meta))
 }
 
 // @Summary Abteilung erstellen
-// @Description Erstellt eine neue Abteilung mit Name, Farbe, Beschreibung und weiteren Daten
+// @Description Erstellt eine neue Abteilung mit Name, Farbe, Beschreibung und Standort. Ohne location_id kommt die Abteilung an den ältesten Standort. Der Name muss je Standort eindeutig sein.
 // @Tags departments
 // @Accept json
 // @Produce json
 // @Param department body models.Department true "Abteilungsdaten inkl. Beschreibung"
 // @Success 201 {object} responses.APIResponse{data=models.Department}
 // @Failure 400 {object} responses.APIResponse
+// @Failure 422 {object} responses.APIResponse{data=[]models.FieldError}
 // @Router /api/v1/departments [post]
 func HandleCreateDepartment(c *fiber.Ctx) error {
 	department := new(models.Department)
@@ -53,20 +103,22 @@ func HandleCreateDepartment(c *fiber.Ctx) error {
 		return c.Status(400).JSON(responses.ErrorResponse(responses.ErrInvalidInput))
 	}
 
-	if err := validateDepartment(department); err != nil {
-		return c.Status(400).JSON(responses.ErrorResponse(err.Error()))
+	if err := validateDepartment(tenantDB(c), department); err != nil {
+		return validationErrorResponse(c, err)
 	}
 
-	result := database.GetDB().Create(&department)
+	result := tenantDB(c).Create(&department)
 	if result.Error != nil {
 		return c.Status(500).JSON(responses.ErrorResponse(result.Error.Error()))
 	}
 
-	database.GetDB().
+	tenantDB(c).
 		Preload("Employees").
 		Preload("ShiftWeeks").
 		First(&department, department.ID)
 
+	events.Publish(tenantID(c), events.DepartmentCreated, &department.ID, department)
+
 	return c.Status(201).JSON(responses.SuccessResponse(responses.MsgSuccessCreate, department))
 }
 
@@ -76,23 +128,29 @@ func HandleCreateDepartment(c *fiber.Ctx) error {
 // @Accept json
 // @Produce json
 // @Param id path int true "Abteilungs-ID"
+// @Param include query string false "Beziehungen, z.B. employees,shift_weeks.shift_days.shift_type"
+// @Param fields[department] query string false "Felder der Abteilung, z.B. id,name,color"
 // @Success 200 {object} responses.APIResponse{data=models.Department}
-// @Failure 404 {object} responses.APIResponse
+// @Failure 400,404 {object} responses.APIResponse
 // @Router /api/v1/departments/{id} [get]
 func HandleGetOneDepartment(c *fiber.Ctx) error {
 	id := c.Params("id")
 	var department models.Department
 
-	result := database.GetDB().
-		Preload("Employees").
-		Preload("ShiftWeeks.ShiftDays.ShiftType").
-		First(&department, id)
+	config := departmentQuery
+	config.DefaultIncludes = []string{"employees", "shift_weeks.shift_days.shift_type"}
+	q, err := query.Parse(c, config)
+	if err != nil {
+		return c.Status(400).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	result := q.Preload(tenantDB(c)).First(&department, id)
 
 	if result.Error != nil {
 		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
 	}
 
-	return c.JSON(responses.SuccessResponse(responses.MsgSuccessGet, department))
+	return c.JSON(responses.SuccessResponse(responses.MsgSuccessGet, q.Shape(department)))
 }
 
 // @Summary Abteilung aktualisieren
@@ -104,12 +162,13 @@ func HandleGetOneDepartment(c *fiber.Ctx) error {
 // @Param department body models.Department true "Aktualisierte Abteilungsdaten inkl. Beschreibung"
 // @Success 200 {object} responses.APIResponse{data=models.Department}
 // @Failure 400,404 {object} responses.APIResponse
+// @Failure 422 {object} responses.APIResponse{data=[]models.FieldError}
 // @Router /api/v1/departments/{id} [put]
 func HandleUpdateDepartment(c *fiber.Ctx) error {
 	id := c.Params("id")
 	var department models.Department
 
-	if err := database.GetDB().First(&department, id).Error; err != nil {
+	if err := tenantDB(c).First(&department, id).Error; err != nil {
 		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
 	}
 
@@ -117,117 +176,84 @@ func HandleUpdateDepartment(c *fiber.Ctx) error {
 		return c.Status(400).JSON(responses.ErrorResponse(responses.ErrInvalidInput))
 	}
 
-	if err := validateDepartment(&department); err != nil {
-		return c.Status(400).JSON(responses.ErrorResponse(err.Error()))
+	if err := validateDepartment(tenantDB(c), &department); err != nil {
+		return validationErrorResponse(c, err)
 	}
 
-	if err := database.GetDB().Save(&department).Error; err != nil {
+	if err := tenantDB(c).Save(&department).Error; err != nil {
 		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
 	}
 
-	database.GetDB().
+	tenantDB(c).
 		Preload("Employees").
 		Preload("ShiftWeeks").
 		First(&department, id)
 
+	events.Publish(tenantID(c), events.DepartmentUpdated, &department.ID, department)
+
 	return c.JSON(responses.SuccessResponse(responses.MsgSuccessUpdate, department))
 }
 
 // @Summary Abteilung löschen
-// @Description Löscht eine Abteilung und ihre Beziehungen
+// @Description Löscht eine Abteilung samt Teams, Schichtwochen und Vorlagen, Mitarbeiter werden keiner Abteilung mehr zugeordnet
 // @Tags departments
 // @Accept json
 // @Produce json
 // @Param id path int true "Abteilungs-ID"
 // @Success 200 {object} responses.APIResponse
 // @Failure 404,500 {object} responses.APIResponse
+// @Failure 409 {object} responses.APIResponse{data=[]database.BlockingReference}
 // @Router /api/v1/departments/{id} [delete]
 func HandleDeleteDepartment(c *fiber.Ctx) error {
 	id := c.Params("id")
 	var department models.Department
 
-	if err := database.GetDB().
+	if err := tenantDB(c).
 		Preload("Employees").
 		Preload("ShiftWeeks").
 		First(&department, id).Error; err != nil {
 		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
 	}
 
-	tx := database.GetDB().Begin()
-
-	if err := tx.Model(&models.Employee{}).Where("department_id = ?", id).Update("department_id", nil).Error; err != nil {
-		tx.Rollback()
-		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
-	}
+	tx := tenantDB(c).Begin()
 
-	if err := tx.Where("department_id = ?", id).Delete(&models.ShiftWeek{}).Error; err != nil {
+	if err := database.SoftDelete(tx, "departments", department.ID); err != nil {
 		tx.Rollback()
-		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
-	}
-
-	if err := tx.Delete(&department).Error; err != nil {
-		tx.Rollback()
-		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+		return deleteErrorResponse(c, err)
 	}
 
 	tx.Commit()
 
+	events.Publish(tenantID(c), events.DepartmentDeleted, &department.ID, fiber.Map{"id": department.ID})
+
 	return c.JSON(responses.SuccessResponse(responses.MsgSuccessDelete, nil))
 }
 
-// @Summary Abteilungsstatistiken abrufen
-// @Description Ruft statistische Daten einer Abteilung ab
-// @Tags departments
-// @Accept json
-// @Produce json
-// @Param id path int true "Abteilungs-ID"
-// @Success 200 {object} responses.APIResponse{data=map[string]interface{}}
-// @Failure 404 {object} responses.APIResponse
-// @Router /api/v1/departments/{id}/stats [get]
-func HandleDepartmentStats(c *fiber.Ctx) error {
-	id := c.Params("id")
-	var department models.Department
-
-	result := database.GetDB().
-		Preload("ShiftWeeks").
-		Preload("Employees").
-		First(&department, id)
+func validateDepartment(db *gorm.DB, department *models.Department) error {
+	errs := models.ValidateModel(department)
 
-	if result.Error != nil {
-		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
-	}
-
-	stats := map[string]interface{}{
-		"employeeCount":    len(department.Employees),
-		"shiftWeekCount":   len(department.ShiftWeeks),
-		"activeShiftWeeks": countActiveShiftWeeks(department.ShiftWeeks),
-	}
-
-	return c.JSON(responses.SuccessResponse("Statistiken erfolgreich abgerufen", stats))
-}
-
-func validateDepartment(department *models.Department) error {
-	if department.Name == "" {
-		return fmt.Errorf("name ist erforderlich")
-	}
-	if department.Color == "" {
-		return fmt.Errorf("farbe ist erforderlich")
-	}
-	if len(department.Name) < 2 {
-		return fmt.Errorf("name muss mindestens 2 Zeichen lang sein")
-	}
-	if len(department.Name) > 50 {
-		return fmt.Errorf("name darf maximal 50 Zeichen lang sein")
+	// Bestehende Clients kennen keine Standorte
+	if department.LocationID == 0 {
+		var location models.Location
+		if err := db.Order("id").First(&location).Error; err != nil {
+			errs.Add("location_id", models.CodeRequired, "es ist noch kein standort angelegt")
+		}
+		department.LocationID = location.ID
+	} else if err := db.First(&models.Location{}, department.LocationID).Error; err != nil {
+		errs.Add("location_id", models.CodeNotFound, "standort nicht gefunden")
 	}
-	return nil
-}
-
-func countActiveShiftWeeks(weeks []models.ShiftWeek) int {
-	count := 0
-	for _, week := range weeks {
-		if week.Status == models.StatusPublished {
-			count++
+	department.Location = nil
+
+	if department.Name != "" && department.LocationID != 0 {
+		var count int64
+		if err := db.Model(&models.Department{}).
+			Where("location_id = ? AND name = ? AND id != ?", department.LocationID, department.Name, department.ID).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			errs.Add("name", models.CodeDuplicate, "name ist an diesem standort bereits vergeben")
 		}
 	}
-	return count
+	return errs.Err()
 }
@@ -1,45 +1,74 @@
 package handlers
 
 import (
-	"fmt"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/ptmmeiningen/schichtplaner/database"
 	"github.com/ptmmeiningen/schichtplaner/models"
+	"github.com/ptmmeiningen/schichtplaner/pkg/events"
+	"github.com/ptmmeiningen/schichtplaner/pkg/query"
 	"github.com/ptmmeiningen/schichtplaner/pkg/responses"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
+// employeeQuery legt die Filter und Sortierungen für Mitarbeiter-Listen fest
+var employeeQuery = query.Config{
+	Table:    "employees",
+	Resource: "employee",
+	Fields:   employeeFields,
+	Filters: map[string]query.Filter{
+		"department_id": {Condition: "employees.department_id = ?", Parse: query.Int},
+		"email":         {Condition: "employees.email = ?", Parse: query.String},
+		"location_id":   {Condition: "employees.department_id IN (SELECT id FROM departments WHERE location_id = ? AND deleted_at IS NULL)", Parse: query.Int},
+		"team_id":       {Condition: "employees.team_id = ?", Parse: query.Int},
+	},
+	Sorts: map[string]string{
+		"first_name": "employees.first_name",
+		"last_name":  "employees.last_name",
+		"email":      "employees.email",
+		"created_at": "employees.created_at",
+	},
+	DefaultSort: "first_name",
+	Includes: map[string]query.Include{
+		"department": departmentInclude(),
+		"team":       teamInclude(),
+		"shift_days": shiftDaysInclude("employee_id", "date DESC"),
+	},
+	DefaultIncludes: []string{"department", "shift_days.shift_type"},
+}
+
 // @Summary Alle Mitarbeiter abrufen
-// @Description Ruft alle Mitarbeiter mit ihren Berechtigungen und Schichten ab
+// @Description Ruft alle Mitarbeiter mit ihren Berechtigungen und Schichten ab, paginiert, gefiltert und sortiert
 // @Tags employees
 // @Accept json
 // @Produce json
+// @Param page query int false "Seite (ab 1)"
+// @Param page_size query int false "Einträge pro Seite (max. 200)"
+// @Param cursor query string false "Cursor für die nächste Seite (statt page)"
+// @Param department_id query int false "Abteilungs-ID"
+// @Param location_id query int false "Standort-ID"
+// @Param team_id query int false "Team-ID"
+// @Param email query string false "E-Mail-Adresse"
+// @Param sort query string false "Sortierung, z.B. last_name,first_name"
+// @Param include query string false "Beziehungen, z.B. department,shift_days.shift_type"
+// @Param fields[employee] query string false "Felder des Mitarbeiters, z.B. id,first_name,last_name"
 // @Success 200 {object} responses.APIResponse{data=[]models.Employee}
-// @Failure 500 {object} responses.APIResponse
+// @Failure 400,500 {object} responses.APIResponse
 // @Router /api/v1/employees [get]
 func HandleAllEmployees(c *fiber.Ctx) error {
-	var employees []models.Employee
-	result := database.GetDB().
-		Preload("Department", func(db *gorm.DB) *gorm.DB {
-			return db.Select("id, name, color").
-				Order("name")
-		}).
-		Preload("ShiftDays", func(db *gorm.DB) *gorm.DB {
-			return db.Select("id, date, shift_type_id, employee_id, shift_week_id").
-				Order("date DESC")
-		}).
-		Preload("ShiftDays.ShiftType").
-		// Nach Vornamen sortieren
-		Order("first_name").
-		Find(&employees)
+	q, err := query.Parse(c, employeeQuery)
+	if err != nil {
+		return c.Status(400).JSON(responses.ErrorResponse(err.Error()))
+	}
 
-	if result.Error != nil {
-		return c.Status(500).JSON(responses.ErrorResponse(result.Error.Error()))
+	var employees []models.Employee
+	meta, err := q.Find(tenantDB(c), &employees)
+	if err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
 	}
-	return c.JSON(responses.SuccessResponse(responses.MsgSuccessGet, employees))
+	return c.JSON(responses.PaginatedResponse(responses.MsgSuccessGet, q.Shape(employees), meta))
 }
 
 // @Summary Mitarbeiter erstellen
@@ -50,6 +79,7 @@ func HandleAllEmployees(c *fiber.Ctx) error {
 // @Param employee body models.Employee true "Mitarbeiter-Daten"
 // @Success 201 {object} responses.APIResponse{data=models.Employee}
 // @Failure 400 {object} responses.APIResponse
+// @Failure 422 {object} responses.APIResponse{data=[]models.FieldError}
 // @Router /api/v1/employees [post]
 func HandleCreateEmployee(c *fiber.Ctx) error {
 	employee := new(models.Employee)
@@ -57,8 +87,8 @@ func HandleCreateEmployee(c *fiber.Ctx) error {
 		return c.Status(400).JSON(responses.ErrorResponse(responses.ErrInvalidInput))
 	}
 
-	if err := validateEmployee(employee); err != nil {
-		return c.Status(400).JSON(responses.ErrorResponse(err.Error()))
+	if err := validateEmployee(tenantDB(c), employee); err != nil {
+		return validationErrorResponse(c, err)
 	}
 
 	hashedPassword, err := hashPassword(employee.Password)
@@ -67,16 +97,18 @@ func HandleCreateEmployee(c *fiber.Ctx) error {
 	}
 	employee.Password = hashedPassword
 
-	result := database.GetDB().Create(&employee)
+	result := tenantDB(c).Create(&employee)
 	if result.Error != nil {
 		return c.Status(500).JSON(responses.ErrorResponse(result.Error.Error()))
 	}
 
-	database.GetDB().
+	tenantDB(c).
 		Preload("Department").
 		Preload("ShiftDays.ShiftType").
 		First(&employee, employee.ID)
 
+	events.Publish(tenantID(c), events.EmployeeCreated, employee.DepartmentID, employee)
+
 	return c.Status(201).JSON(responses.SuccessResponse(responses.MsgSuccessCreate, employee))
 }
 
@@ -86,23 +118,27 @@ func HandleCreateEmployee(c *fiber.Ctx) error {
 // @Accept json
 // @Produce json
 // @Param id path int true "Mitarbeiter-ID"
+// @Param include query string false "Beziehungen, z.B. department,shift_days.shift_type"
+// @Param fields[employee] query string false "Felder des Mitarbeiters, z.B. id,first_name,last_name"
 // @Success 200 {object} responses.APIResponse{data=models.Employee}
-// @Failure 404 {object} responses.APIResponse
+// @Failure 400,404 {object} responses.APIResponse
 // @Router /api/v1/employees/{id} [get]
 func HandleGetOneEmployee(c *fiber.Ctx) error {
 	id := c.Params("id")
 	var employee models.Employee
 
-	result := database.GetDB().
-		Preload("Department").
-		Preload("ShiftDays.ShiftType").
-		First(&employee, id)
+	q, err := query.Parse(c, employeeQuery)
+	if err != nil {
+		return c.Status(400).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	result := q.Preload(tenantDB(c)).First(&employee, id)
 
 	if result.Error != nil {
 		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
 	}
 
-	return c.JSON(responses.SuccessResponse(responses.MsgSuccessGet, employee))
+	return c.JSON(responses.SuccessResponse(responses.MsgSuccessGet, q.Shape(employee)))
 }
 
 // @Summary Mitarbeiter aktualisieren
@@ -114,12 +150,13 @@ func HandleGetOneEmployee(c *fiber.Ctx) error {
 // @Param employee body models.Employee true "Aktualisierte Mitarbeiter-Daten"
 // @Success 200 {object} responses.APIResponse{data=models.Employee}
 // @Failure 400,404 {object} responses.APIResponse
+// @Failure 422 {object} responses.APIResponse{data=[]models.FieldError}
 // @Router /api/v1/employees/{id} [put]
 func HandleUpdateEmployee(c *fiber.Ctx) error {
 	id := c.Params("id")
 	var employee models.Employee
 
-	if err := database.GetDB().First(&employee, id).Error; err != nil {
+	if err := tenantDB(c).First(&employee, id).Error; err != nil {
 		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
 	}
 
@@ -127,8 +164,8 @@ func HandleUpdateEmployee(c *fiber.Ctx) error {
 		return c.Status(400).JSON(responses.ErrorResponse(responses.ErrInvalidInput))
 	}
 
-	if err := validateEmployee(&employee); err != nil {
-		return c.Status(400).JSON(responses.ErrorResponse(err.Error()))
+	if err := validateEmployee(tenantDB(c), &employee); err != nil {
+		return validationErrorResponse(c, err)
 	}
 
 	if employee.Password != "" {
@@ -139,15 +176,17 @@ func HandleUpdateEmployee(c *fiber.Ctx) error {
 		employee.Password = hashedPassword
 	}
 
-	if err := database.GetDB().Save(&employee).Error; err != nil {
+	if err := tenantDB(c).Save(&employee).Error; err != nil {
 		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
 	}
 
-	database.GetDB().
+	tenantDB(c).
 		Preload("Department").
 		Preload("ShiftDays.ShiftType").
 		First(&employee, id)
 
+	events.Publish(tenantID(c), events.EmployeeUpdated, employee.DepartmentID, employee)
+
 	return c.JSON(responses.SuccessResponse(responses.MsgSuccessUpdate, employee))
 }
 
@@ -164,24 +203,21 @@ func HandleDeleteEmployee(c *fiber.Ctx) error {
 	id := c.Params("id")
 	var employee models.Employee
 
-	if err := database.GetDB().First(&employee, id).Error; err != nil {
+	if err := tenantDB(c).First(&employee, id).Error; err != nil {
 		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
 	}
 
-	tx := database.GetDB().Begin()
+	tx := tenantDB(c).Begin()
 
-	if err := tx.Model(&models.ShiftDay{}).Where("employee_id = ?", id).Update("employee_id", nil).Error; err != nil {
+	if err := database.SoftDelete(tx, "employees", employee.ID); err != nil {
 		tx.Rollback()
-		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
-	}
-
-	if err := tx.Delete(&employee).Error; err != nil {
-		tx.Rollback()
-		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+		return deleteErrorResponse(c, err)
 	}
 
 	tx.Commit()
 
+	events.Publish(tenantID(c), events.EmployeeDeleted, employee.DepartmentID, fiber.Map{"id": employee.ID})
+
 	return c.JSON(responses.SuccessResponse(responses.MsgSuccessDelete, nil))
 }
 
@@ -201,10 +237,13 @@ func HandleGetAvailableEmployees(c *fiber.Ctx) error {
 		return c.Status(400).JSON(responses.ErrorResponse("Ungültiges Datumsformat. Bitte YYYY-MM-DD verwenden"))
 	}
 
+	busy := tenantDB(c).Model(&models.ShiftDay{}).
+		Select("employee_id").
+		Where("date = ? AND employee_id IS NOT NULL", date)
+
 	var availableEmployees []models.Employee
-	result := database.GetDB().
-		Joins("LEFT JOIN shift_days ON employees.id = shift_days.employee_id AND shift_days.date = ?", date).
-		Where("shift_days.id IS NULL").
+	result := tenantDB(c).
+		Where("employees.id NOT IN (?)", busy).
 		Preload("Department").
 		Find(&availableEmployees)
 
@@ -228,7 +267,7 @@ func HandleEmployeeShifts(c *fiber.Ctx) error {
 	id := c.Params("id")
 	var employee models.Employee
 
-	result := database.GetDB().
+	result := tenantDB(c).
 		Preload("ShiftDays.ShiftType").
 		Preload("ShiftDays.ShiftWeek").
 		First(&employee, id)
@@ -246,49 +285,71 @@ func HandleEmployeeShifts(c *fiber.Ctx) error {
 // @Accept json
 // @Produce json
 // @Param id path int true "Abteilungs-ID"
+// @Param page query int false "Seite (ab 1)"
+// @Param page_size query int false "Einträge pro Seite (max. 200)"
+// @Param cursor query string false "Cursor für die nächste Seite (statt page)"
+// @Param sort query string false "Sortierung, Standard: last_name,first_name"
+// @Param include query string false "Beziehungen, z.B. department,shift_days.shift_type"
+// @Param fields[employee] query string false "Felder des Mitarbeiters, z.B. id,first_name,last_name"
 // @Success 200 {object} responses.APIResponse{data=[]models.Employee}
-// @Failure 404 {object} responses.APIResponse
+// @Failure 400,500 {object} responses.APIResponse
 // @Router /api/v1/employees/department/{id} [get]
 func HandleGetDepartmentEmployees(c *fiber.Ctx) error {
 	departmentID := c.Params("id")
-	var employees []models.Employee
 
-	result := database.GetDB().
-		Where("department_id = ?", departmentID).
-		Preload("ShiftDays", func(db *gorm.DB) *gorm.DB {
-			return db.Order("date DESC")
-		}).
-		Preload("ShiftDays.ShiftType").
-		Order("last_name, first_name").
-		Find(&employees)
+	config := employeeQuery
+	config.DefaultSort = "last_name,first_name"
+	config.DefaultIncludes = []string{"shift_days.shift_type"}
+	q, err := query.Parse(c, config)
+	if err != nil {
+		return c.Status(400).JSON(responses.ErrorResponse(err.Error()))
+	}
 
-	if result.Error != nil {
-		return c.Status(500).JSON(responses.ErrorResponse(result.Error.Error()))
+	var employees []models.Employee
+	meta, err := q.Find(tenantDB(c).Where("employees.department_id = ?", departmentID), &employees)
+	if err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
 	}
 
-	return c.JSON(responses.SuccessResponse(responses.MsgSuccessGet, employees))
+	return c.JSON(responses.PaginatedResponse(responses.MsgSuccessGet, q.Shape(employees), meta))
 }
 
-func validateEmployee(employee *models.Employee) error {
-	if employee.FirstName == "" {
-		return fmt.Errorf("vorname ist erforderlich")
+func validateEmployee(db *gorm.DB, employee *models.Employee) error {
+	if employee.BirthDate != nil {
+		birthDate := truncateDay(*employee.BirthDate)
+		employee.BirthDate = &birthDate
 	}
-	if employee.LastName == "" {
-		return fmt.Errorf("nachname ist erforderlich")
-	}
-	if employee.Email == "" {
-		return fmt.Errorf("e-mail ist erforderlich")
+	errs := models.ValidateModel(employee)
+
+	if employee.DepartmentID != nil {
+		if err := db.First(&models.Department{}, *employee.DepartmentID).Error; err != nil {
+			errs.Add("department_id", models.CodeNotFound, "abteilung nicht gefunden")
+		}
 	}
-	if employee.Color == "" {
-		return fmt.Errorf("farbe ist erforderlich")
+	if employee.TeamID != nil {
+		var team models.Team
+		if err := db.First(&team, *employee.TeamID).Error; err != nil {
+			errs.Add("team_id", models.CodeNotFound, "team nicht gefunden")
+		} else if employee.DepartmentID == nil {
+			// Ohne Abteilung kommt der Mitarbeiter in die Abteilung des Teams
+			employee.DepartmentID = &team.DepartmentID
+		} else if *employee.DepartmentID != team.DepartmentID {
+			errs.Add("team_id", models.CodeMismatch, "team gehört nicht zur abteilung des mitarbeiters")
+		}
 	}
+	employee.Team = nil
 
-	var existingEmployee models.Employee
-	if err := database.GetDB().Where("email = ? AND id != ?", employee.Email, employee.ID).First(&existingEmployee).Error; err == nil {
-		return fmt.Errorf("e-mail wird bereits verwendet")
+	if employee.Email != "" {
+		var count int64
+		if err := db.Model(&models.Employee{}).Where("email = ? AND id != ?", employee.Email, employee.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			errs.Add("email", models.CodeDuplicate, "e-mail wird bereits verwendet")
+		}
 	}
 
-	return nil
+	return errs.Err()
 }
 
 func hashPassword(password string) (string, error) {
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ptmmeiningen/schichtplaner/pkg/events"
+	"github.com/ptmmeiningen/schichtplaner/pkg/responses"
+)
+
+// @Summary Ereignisstrom abonnieren
+// @Description Liefert Änderungen an Schichttagen und Schichtwochen des eigenen Mandanten als Server-Sent Events
+// @Tags events
+// @Produce text/event-stream
+// @Param department_id query int false "Nur Ereignisse dieser Abteilung"
+// @Success 200 {object} events.Event
+// @Failure 400 {object} responses.APIResponse
+// @Router /api/v1/events [get]
+func HandleEvents(c *fiber.Ctx) error {
+	var departmentID uint
+	if c.Query("department_id") != "" {
+		id := c.QueryInt("department_id", 0)
+		if id <= 0 {
+			return c.Status(400).JSON(responses.ErrorResponse(responses.ErrInvalidInput))
+		}
+		departmentID = uint(id)
+	}
+
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	tenant := tenantID(c)
+	stream, unsubscribe := events.GetBroker().Subscribe()
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer unsubscribe()
+
+		heartbeat := time.NewTicker(30 * time.Second)
+		defer heartbeat.Stop()
+
+		// Verbindung sofort bestätigen
+		fmt.Fprint(w, ": verbunden\n\n")
+		if err := w.Flush(); err != nil {
+			return
+		}
+
+		for {
+			select {
+			case event, ok := <-stream:
+				if !ok {
+					return
+				}
+				if event.TenantID != tenant {
+					continue
+				}
+				if departmentID != 0 && (event.DepartmentID == nil || *event.DepartmentID != departmentID) {
+					continue
+				}
+
+				payload, err := json.Marshal(event)
+				if err != nil {
+					continue
+				}
+				fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, payload)
+			case <-heartbeat.C:
+				fmt.Fprint(w, ": ping\n\n")
+			}
+
+			// Flush schlägt fehl, sobald der Client die Verbindung getrennt hat
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	})
+
+	return nil
+}
@@ -0,0 +1,4 @@
+package handlers
+
+// Gini ist für die Tests in handlers_test exportiert
+var Gini = gini
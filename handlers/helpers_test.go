@@ -0,0 +1,90 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ptmmeiningen/schichtplaner/database/databasetest"
+	"github.com/ptmmeiningen/schichtplaner/models"
+	"github.com/ptmmeiningen/schichtplaner/router"
+)
+
+// setupApp liefert die App mit allen Routes auf einer leeren Testdatenbank
+func setupApp(t *testing.T) *fiber.App {
+	t.Helper()
+	app := databasetest.Setup(t)
+	router.SetupRoutes(app)
+	return app
+}
+
+// apiResponse ist responses.APIResponse mit unverarbeitetem data
+type apiResponse struct {
+	Success bool            `json:"success"`
+	Message string          `json:"message"`
+	Error   string          `json:"error"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// call schickt body als JSON an die App, mit token als Bearer-Token, sofern
+// angegeben, und liefert Status und Antwort
+func call(t *testing.T, app *fiber.App, method, path, token string, body interface{}) (int, apiResponse) {
+	t.Helper()
+	var reader io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(raw)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result apiResponse
+	if len(raw) > 0 && json.Unmarshal(raw, &result) != nil {
+		t.Fatalf("%s %s: keine JSON-Antwort: %s", method, path, raw)
+	}
+	return resp.StatusCode, result
+}
+
+// decode liest data einer Antwort in value
+func decode(t *testing.T, resp apiResponse, value interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(resp.Data, value); err != nil {
+		t.Fatalf("data %s: %v", resp.Data, err)
+	}
+}
+
+// expectFieldErrors prüft, dass die Antwort ein 422 mit genau den Feldern
+// und Codes in want ist
+func expectFieldErrors(t *testing.T, status int, resp apiResponse, want ...models.FieldError) {
+	t.Helper()
+	if status != 422 {
+		t.Fatalf("status %d, erwartet 422: %s %s", status, resp.Error, resp.Data)
+	}
+	var got []models.FieldError
+	decode(t, resp, &got)
+	if len(got) != len(want) {
+		t.Fatalf("fehler %+v, erwartet %+v", got, want)
+	}
+	for i := range want {
+		if got[i].Field != want[i].Field || got[i].Code != want[i].Code {
+			t.Fatalf("fehler %+v, erwartet %+v", got, want)
+		}
+	}
+}
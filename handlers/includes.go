@@ -0,0 +1,85 @@
+package handlers
+
+import "github.com/ptmmeiningen/schichtplaner/pkg/query"
+
+// Felder, die per fields[...] angefordert werden dürfen
+var (
+	departmentFields       = []string{"id", "name", "color", "description", "location_id", "created_at", "updated_at"}
+	teamFields             = []string{"id", "department_id", "name", "color", "lead_id", "created_at", "updated_at"}
+	employeeFields         = []string{"id", "first_name", "last_name", "email", "color", "is_admin", "birth_date", "department_id", "team_id", "created_at", "updated_at"}
+	shiftTypeFields        = []string{"id", "name", "description", "color", "start_time", "end_time", "break_minutes", "breaks_paid", "created_at", "updated_at"}
+	shiftWeekFields        = []string{"id", "calendar_week", "year", "department_id", "team_id", "status", "notes", "published_at", "created_at", "updated_at"}
+	shiftDayFields         = []string{"id", "date", "shift_week_id", "shift_type_id", "employee_id", "notes", "status", "rotation_id", "break_minutes", "created_at", "updated_at"}
+	shiftTemplateFields    = []string{"id", "name", "description", "department_id", "status", "valid_from", "valid_until", "created_at", "updated_at"}
+	shiftTemplateDayFields = []string{"id", "shift_template_id", "shift_type_id", "week_day", "notes"}
+)
+
+func departmentInclude() query.Include {
+	return query.Include{
+		Preload:    "Department",
+		Resource:   "department",
+		Fields:     departmentFields,
+		ForeignKey: "department_id",
+	}
+}
+
+func teamInclude() query.Include {
+	return query.Include{
+		Preload:    "Team",
+		Resource:   "team",
+		Fields:     teamFields,
+		ForeignKey: "team_id",
+	}
+}
+
+func shiftTypeInclude() query.Include {
+	return query.Include{
+		Preload:    "ShiftType",
+		Resource:   "shift_type",
+		Fields:     shiftTypeFields,
+		ForeignKey: "shift_type_id",
+	}
+}
+
+func employeeInclude() query.Include {
+	return query.Include{
+		Preload:    "Employee",
+		Resource:   "employee",
+		Fields:     employeeFields,
+		ForeignKey: "employee_id",
+		Includes: map[string]query.Include{
+			"department": departmentInclude(),
+			"team":       teamInclude(),
+		},
+	}
+}
+
+func shiftWeekInclude() query.Include {
+	return query.Include{
+		Preload:    "ShiftWeek",
+		Resource:   "shift_week",
+		Fields:     shiftWeekFields,
+		ForeignKey: "shift_week_id",
+		Includes: map[string]query.Include{
+			"department": departmentInclude(),
+			"team":       teamInclude(),
+		},
+	}
+}
+
+// shiftDaysInclude beschreibt die Schichttage einer Woche oder eines Mitarbeiters
+func shiftDaysInclude(foreignKey, order string) query.Include {
+	return query.Include{
+		Preload:    "ShiftDays",
+		Resource:   "shift_day",
+		Fields:     shiftDayFields,
+		ForeignKey: foreignKey,
+		HasMany:    true,
+		Order:      order,
+		Includes: map[string]query.Include{
+			"shift_type": shiftTypeInclude(),
+			"employee":   employeeInclude(),
+			"shift_week": shiftWeekInclude(),
+		},
+	}
+}
@@ -0,0 +1,271 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ptmmeiningen/schichtplaner/database"
+	"github.com/ptmmeiningen/schichtplaner/models"
+	"github.com/ptmmeiningen/schichtplaner/pkg/holidays"
+	"github.com/ptmmeiningen/schichtplaner/pkg/query"
+	"github.com/ptmmeiningen/schichtplaner/pkg/responses"
+	"gorm.io/gorm"
+)
+
+// locationQuery legt die Filter und Sortierungen für Standort-Listen fest
+var locationQuery = query.Config{
+	Table: "locations",
+	Filters: map[string]query.Filter{
+		"name":          {Condition: "locations.name = ?", Parse: query.String},
+		"holiday_state": {Condition: "locations.holiday_state = ?", Parse: query.String},
+	},
+	Sorts: map[string]string{
+		"name":       "locations.name",
+		"created_at": "locations.created_at",
+	},
+	DefaultSort: "name",
+}
+
+// LocationDepartmentStats ist die Zusammenfassung einer Abteilung in den
+// Standortstatistiken
+type LocationDepartmentStats struct {
+	DepartmentID  uint          `json:"department_id"`
+	Name          string        `json:"name"`
+	EmployeeCount int64         `json:"employee_count"`
+	Summary       StaffingStats `json:"summary"`
+}
+
+// LocationStats sind die über alle Abteilungen eines Standorts summierten
+// Statistiken im Zeitraum
+type LocationStats struct {
+	LocationID    uint                      `json:"location_id"`
+	From          string                    `json:"from"`
+	To            string                    `json:"to"`
+	EmployeeCount int64                     `json:"employee_count"`
+	Summary       StaffingStats             `json:"summary"`
+	Weeks         []WeekStats               `json:"weeks"`
+	Departments   []LocationDepartmentStats `json:"departments"`
+}
+
+// @Summary Alle Standorte abrufen
+// @Description Ruft alle Standorte ab, paginiert und sortiert
+// @Tags locations
+// @Produce json
+// @Param page query int false "Seite (ab 1)"
+// @Param page_size query int false "Einträge pro Seite (max. 200)"
+// @Param cursor query string false "Cursor für die nächste Seite (statt page)"
+// @Param name query string false "Name"
+// @Param holiday_state query string false "Bundesland, z.B. BY"
+// @Param sort query string false "Sortierung, z.B. name"
+// @Success 200 {object} responses.APIResponse{data=[]models.Location}
+// @Failure 400,500 {object} responses.APIResponse
+// @Router /api/v1/locations [get]
+func HandleAllLocations(c *fiber.Ctx) error {
+	q, err := query.Parse(c, locationQuery)
+	if err != nil {
+		return c.Status(400).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	var locations []models.Location
+	meta, err := q.Find(tenantDB(c), &locations)
+	if err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+	return c.JSON(responses.PaginatedResponse(responses.MsgSuccessGet, locations, meta))
+}
+
+// @Summary Standort erstellen
+// @Description Legt einen Standort mit Anschrift, Zeitzone (IANA, z.B. Europe/Berlin) und Bundesland für die Feiertage an
+// @Tags locations
+// @Accept json
+// @Produce json
+// @Param location body models.Location true "Standortdaten"
+// @Success 201 {object} responses.APIResponse{data=models.Location}
+// @Failure 400,500 {object} responses.APIResponse
+// @Failure 422 {object} responses.APIResponse{data=[]models.FieldError}
+// @Router /api/v1/locations [post]
+func HandleCreateLocation(c *fiber.Ctx) error {
+	location := new(models.Location)
+	if err := c.BodyParser(location); err != nil {
+		return c.Status(400).JSON(responses.ErrorResponse(responses.ErrInvalidInput))
+	}
+
+	if err := validateLocation(tenantDB(c), location); err != nil {
+		return validationErrorResponse(c, err)
+	}
+
+	if err := tenantDB(c).Omit("Departments").Create(location).Error; err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	return c.Status(201).JSON(responses.SuccessResponse(responses.MsgSuccessCreate, location))
+}
+
+// @Summary Einzelnen Standort abrufen
+// @Description Ruft einen Standort mit seinen Abteilungen ab
+// @Tags locations
+// @Produce json
+// @Param id path int true "Standort-ID"
+// @Success 200 {object} responses.APIResponse{data=models.Location}
+// @Failure 404 {object} responses.APIResponse
+// @Router /api/v1/locations/{id} [get]
+func HandleGetOneLocation(c *fiber.Ctx) error {
+	var location models.Location
+
+	if err := tenantDB(c).
+		Preload("Departments", func(db *gorm.DB) *gorm.DB {
+			return db.Order("name")
+		}).
+		First(&location, c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
+	}
+
+	return c.JSON(responses.SuccessResponse(responses.MsgSuccessGet, location))
+}
+
+// @Summary Standort aktualisieren
+// @Description Aktualisiert einen Standort. Ein anderes Bundesland wirkt sich auf alle Berichte mit Feiertagen aus.
+// @Tags locations
+// @Accept json
+// @Produce json
+// @Param id path int true "Standort-ID"
+// @Param location body models.Location true "Aktualisierte Standortdaten"
+// @Success 200 {object} responses.APIResponse{data=models.Location}
+// @Failure 400,404,500 {object} responses.APIResponse
+// @Failure 422 {object} responses.APIResponse{data=[]models.FieldError}
+// @Router /api/v1/locations/{id} [put]
+func HandleUpdateLocation(c *fiber.Ctx) error {
+	var location models.Location
+
+	if err := tenantDB(c).First(&location, c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
+	}
+
+	if err := c.BodyParser(&location); err != nil {
+		return c.Status(400).JSON(responses.ErrorResponse(responses.ErrInvalidInput))
+	}
+
+	if err := validateLocation(tenantDB(c), &location); err != nil {
+		return validationErrorResponse(c, err)
+	}
+
+	if err := tenantDB(c).Omit("Departments").Save(&location).Error; err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	return c.JSON(responses.SuccessResponse(responses.MsgSuccessUpdate, location))
+}
+
+// @Summary Standort löschen
+// @Description Löscht einen Standort. Solange er Abteilungen hat, wird das Löschen verweigert.
+// @Tags locations
+// @Produce json
+// @Param id path int true "Standort-ID"
+// @Success 200 {object} responses.APIResponse
+// @Failure 404,500 {object} responses.APIResponse
+// @Failure 409 {object} responses.APIResponse{data=[]database.BlockingReference}
+// @Router /api/v1/locations/{id} [delete]
+func HandleDeleteLocation(c *fiber.Ctx) error {
+	var location models.Location
+
+	if err := tenantDB(c).First(&location, c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
+	}
+
+	tx := tenantDB(c).Begin()
+
+	if err := database.SoftDelete(tx, "locations", location.ID); err != nil {
+		tx.Rollback()
+		return deleteErrorResponse(c, err)
+	}
+
+	tx.Commit()
+
+	return c.JSON(responses.SuccessResponse(responses.MsgSuccessDelete, nil))
+}
+
+// @Summary Standortstatistiken abrufen
+// @Description Summiert die Abteilungsstatistiken aller Abteilungen eines Standorts im Zeitraum, mit Verlauf je Woche und Zusammenfassung je Abteilung. Ohne from/to werden die aktuelle und die drei folgenden Wochen ausgewertet.
+// @Tags locations
+// @Produce json
+// @Param id path int true "Standort-ID"
+// @Param from query string false "Beginn (YYYY-MM-DD)"
+// @Param to query string false "Ende einschließlich (YYYY-MM-DD)"
+// @Success 200 {object} responses.APIResponse{data=LocationStats}
+// @Failure 400,404,500 {object} responses.APIResponse
+// @Router /api/v1/locations/{id}/stats [get]
+func HandleLocationStats(c *fiber.Ctx) error {
+	var location models.Location
+	if err := tenantDB(c).First(&location, c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
+	}
+
+	from, to, err := parseStatsRange(c)
+	if err != nil {
+		return c.Status(400).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	var departments []models.Department
+	if err := tenantDB(c).Where("location_id = ?", location.ID).Order("name").Find(&departments).Error; err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	stats := LocationStats{
+		LocationID:  location.ID,
+		From:        from.Format("2006-01-02"),
+		To:          to.Format("2006-01-02"),
+		Weeks:       []WeekStats{},
+		Departments: []LocationDepartmentStats{},
+	}
+	for _, department := range departments {
+		departmentStats, err := departmentStats(tenantDB(c), department.ID, 0, from, to)
+		if err != nil {
+			return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+		}
+		stats.EmployeeCount += departmentStats.EmployeeCount
+		stats.Summary.add(departmentStats.Summary)
+		// Alle Abteilungen haben dieselben Wochen im Zeitraum
+		for i, week := range departmentStats.Weeks {
+			if i == len(stats.Weeks) {
+				stats.Weeks = append(stats.Weeks, WeekStats{Year: week.Year, CalendarWeek: week.CalendarWeek})
+			}
+			stats.Weeks[i].add(week.StaffingStats)
+		}
+		stats.Departments = append(stats.Departments, LocationDepartmentStats{
+			DepartmentID:  department.ID,
+			Name:          department.Name,
+			EmployeeCount: departmentStats.EmployeeCount,
+			Summary:       departmentStats.Summary,
+		})
+	}
+	for i := range stats.Weeks {
+		stats.Weeks[i].finish()
+	}
+	stats.Summary.finish()
+
+	return c.JSON(responses.SuccessResponse("Statistiken erfolgreich abgerufen", stats))
+}
+
+func validateLocation(db *gorm.DB, location *models.Location) error {
+	location.Name = strings.TrimSpace(location.Name)
+	if location.Timezone == "" {
+		location.Timezone = models.DefaultTimezone
+	}
+	location.HolidayState = strings.ToUpper(strings.TrimSpace(location.HolidayState))
+	errs := models.ValidateModel(location)
+
+	if !holidays.ValidState(location.HolidayState) {
+		errs.Add("holiday_state", models.CodeInvalid, "holiday_state muss ein bundesland-kürzel sein, z.B. BY")
+	}
+
+	if location.Name != "" {
+		var count int64
+		if err := db.Model(&models.Location{}).Where("name = ? AND id != ?", location.Name, location.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			errs.Add("name", models.CodeDuplicate, "name wird bereits verwendet")
+		}
+	}
+	return errs.Err()
+}
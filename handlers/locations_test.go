@@ -0,0 +1,49 @@
+package handlers_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ptmmeiningen/schichtplaner/database"
+	"github.com/ptmmeiningen/schichtplaner/database/databasetest"
+	"github.com/ptmmeiningen/schichtplaner/models"
+)
+
+func TestDeleteLocationWithDepartments(t *testing.T) {
+	app := setupApp(t)
+	location := databasetest.Location(t)
+	department := databasetest.Department(t, "Produktion")
+
+	path := fmt.Sprintf("/api/v1/locations/%d", location.ID)
+	status, resp := call(t, app, "DELETE", path, "", nil)
+	if status != 409 {
+		t.Fatalf("status %d, erwartet 409: %s", status, resp.Error)
+	}
+	var references []database.BlockingReference
+	decode(t, resp, &references)
+	if len(references) != 1 || references[0].Table != "departments" || len(references[0].IDs) != 1 || references[0].IDs[0] != department.ID {
+		t.Fatalf("verweise %+v", references)
+	}
+
+	// Derselbe Name ist an einem anderen Standort erlaubt, am selben nicht
+	status, resp = call(t, app, "POST", "/api/v1/departments", "", map[string]interface{}{"name": "Produktion", "color": "#00ff00", "location_id": location.ID})
+	expectFieldErrors(t, status, resp, models.FieldError{Field: "name", Code: models.CodeDuplicate})
+	branch := models.Location{Name: "Zweigwerk"}
+	databasetest.Create(t, &branch)
+	status, resp = call(t, app, "POST", "/api/v1/departments", "", map[string]interface{}{"name": "Produktion", "color": "#00ff00", "location_id": branch.ID})
+	if status != 201 {
+		t.Fatalf("anderer standort: status %d, %s %s", status, resp.Error, resp.Data)
+	}
+
+	// Ohne Abteilungen lässt sich der Standort löschen
+	var created models.Department
+	decode(t, resp, &created)
+	for _, id := range []uint{department.ID, created.ID} {
+		if status, resp := call(t, app, "DELETE", fmt.Sprintf("/api/v1/departments/%d", id), "", nil); status != 200 {
+			t.Fatalf("abteilung %d: status %d, %s", id, status, resp.Error)
+		}
+	}
+	if status, resp := call(t, app, "DELETE", fmt.Sprintf("/api/v1/locations/%d", branch.ID), "", nil); status != 200 {
+		t.Fatalf("status %d, %s", status, resp.Error)
+	}
+}
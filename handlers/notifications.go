@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ptmmeiningen/schichtplaner/models"
+	"github.com/ptmmeiningen/schichtplaner/pkg/query"
+	"github.com/ptmmeiningen/schichtplaner/pkg/responses"
+)
+
+// notificationQuery legt die Filter und Sortierungen für den Postausgang fest
+var notificationQuery = query.Config{
+	Table: "notifications",
+	Filters: map[string]query.Filter{
+		"status":      {Condition: "notifications.status = ?", Parse: query.String},
+		"employee_id": {Condition: "notifications.employee_id = ?", Parse: query.Int},
+		"kind":        {Condition: "notifications.kind = ?", Parse: query.String},
+	},
+	Sorts: map[string]string{
+		"created_at":      "notifications.created_at",
+		"next_attempt_at": "notifications.next_attempt_at",
+	},
+	DefaultSort: "-created_at",
+}
+
+// @Summary Benachrichtigungen abrufen
+// @Description Ruft die Einträge des Postausgangs ab, optional gefiltert nach Status und Mitarbeiter
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Param status query string false "Status (pending/sent/failed)"
+// @Param employee_id query int false "Mitarbeiter-ID"
+// @Param kind query string false "Art der Benachrichtigung"
+// @Param page query int false "Seite (ab 1)"
+// @Param page_size query int false "Einträge pro Seite (max. 200)"
+// @Param cursor query string false "Cursor für die nächste Seite (statt page)"
+// @Param sort query string false "Sortierung, z.B. -created_at"
+// @Success 200 {object} responses.APIResponse{data=[]models.Notification}
+// @Failure 400,500 {object} responses.APIResponse
+// @Router /api/v1/notifications [get]
+func HandleAllNotifications(c *fiber.Ctx) error {
+	q, err := query.Parse(c, notificationQuery)
+	if err != nil {
+		return c.Status(400).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	var notifications []models.Notification
+	meta, err := q.Find(tenantDB(c), &notifications)
+	if err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	return c.JSON(responses.PaginatedResponse(responses.MsgSuccessGet, notifications, meta))
+}
+
+// @Summary Benachrichtigung erneut senden
+// @Description Setzt eine fehlgeschlagene Benachrichtigung zurück in den Postausgang
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Param id path int true "Benachrichtigungs-ID"
+// @Success 200 {object} responses.APIResponse{data=models.Notification}
+// @Failure 400,404,500 {object} responses.APIResponse
+// @Router /api/v1/notifications/{id}/retry [post]
+func HandleRetryNotification(c *fiber.Ctx) error {
+	id := c.Params("id")
+	var notification models.Notification
+
+	if err := tenantDB(c).First(&notification, id).Error; err != nil {
+		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
+	}
+
+	if notification.Status != models.NotificationFailed {
+		return c.Status(400).JSON(responses.ErrorResponse("Nur fehlgeschlagene Benachrichtigungen können erneut gesendet werden"))
+	}
+
+	notification.Status = models.NotificationPending
+	notification.Attempts = 0
+	notification.NextAttemptAt = time.Now()
+
+	if err := tenantDB(c).Save(&notification).Error; err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	return c.JSON(responses.SuccessResponse(responses.MsgSuccessUpdate, notification))
+}
+
+// @Summary Benachrichtigungseinstellungen abrufen
+// @Description Ruft die Benachrichtigungseinstellungen eines Mitarbeiters ab
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Param id path int true "Mitarbeiter-ID"
+// @Success 200 {object} responses.APIResponse{data=models.NotificationPreference}
+// @Failure 404 {object} responses.APIResponse
+// @Router /api/v1/employees/{id}/notification-preferences [get]
+func HandleGetNotificationPreference(c *fiber.Ctx) error {
+	var employee models.Employee
+	if err := tenantDB(c).First(&employee, c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
+	}
+
+	preference := models.DefaultNotificationPreference(employee.ID)
+	tenantDB(c).Where("employee_id = ?", employee.ID).First(&preference)
+
+	return c.JSON(responses.SuccessResponse(responses.MsgSuccessGet, preference))
+}
+
+// @Summary Benachrichtigungseinstellungen aktualisieren
+// @Description Legt fest, welche Benachrichtigungen ein Mitarbeiter über welche Kanäle erhält
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Param id path int true "Mitarbeiter-ID"
+// @Param preference body models.NotificationPreference true "Benachrichtigungseinstellungen"
+// @Success 200 {object} responses.APIResponse{data=models.NotificationPreference}
+// @Failure 400,404,500 {object} responses.APIResponse
+// @Router /api/v1/employees/{id}/notification-preferences [put]
+func HandleUpdateNotificationPreference(c *fiber.Ctx) error {
+	var employee models.Employee
+	if err := tenantDB(c).First(&employee, c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
+	}
+
+	preference := models.DefaultNotificationPreference(employee.ID)
+	tenantDB(c).Where("employee_id = ?", employee.ID).First(&preference)
+
+	if err := c.BodyParser(&preference); err != nil {
+		return c.Status(400).JSON(responses.ErrorResponse(responses.ErrInvalidInput))
+	}
+	preference.EmployeeID = employee.ID
+
+	if err := tenantDB(c).Save(&preference).Error; err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	return c.JSON(responses.SuccessResponse(responses.MsgSuccessUpdate, preference))
+}
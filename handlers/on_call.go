@@ -0,0 +1,307 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ptmmeiningen/schichtplaner/database"
+	"github.com/ptmmeiningen/schichtplaner/models"
+	"github.com/ptmmeiningen/schichtplaner/pkg/query"
+	"github.com/ptmmeiningen/schichtplaner/pkg/responses"
+	"gorm.io/gorm"
+)
+
+// onCallQuery legt die Filter und Sortierungen für Rufbereitschafts-Listen fest
+var onCallQuery = query.Config{
+	Table: "on_call_assignments",
+	Filters: map[string]query.Filter{
+		"employee_id":        {Condition: "on_call_assignments.employee_id = ?", Parse: query.Int},
+		"department_id":      {Condition: "on_call_assignments.department_id = ?", Parse: query.Int},
+		"location_id":        {Condition: "on_call_assignments.department_id IN (SELECT id FROM departments WHERE location_id = ? AND deleted_at IS NULL)", Parse: query.Int},
+		"compensation_class": {Condition: "on_call_assignments.compensation_class = ?", Parse: query.String},
+		"date_from":          {Condition: "on_call_assignments.ends_at > ?", Parse: query.Date},
+		"date_to":            {Condition: "on_call_assignments.starts_at < ?", Parse: query.DateEnd},
+	},
+	Sorts: map[string]string{
+		"starts_at":  "on_call_assignments.starts_at",
+		"created_at": "on_call_assignments.created_at",
+	},
+	DefaultSort: "starts_at",
+}
+
+func preloadOnCall(db *gorm.DB) *gorm.DB {
+	return db.Preload("Callouts", func(db *gorm.DB) *gorm.DB {
+		return db.Order("started_at, id")
+	})
+}
+
+// @Summary Alle Rufbereitschaften abrufen
+// @Description Ruft alle Rufbereitschaften mit ihren Einsätzen ab. date_from und date_to liefern alle Rufbereitschaften, die den Zeitraum berühren.
+// @Tags oncall
+// @Produce json
+// @Param page query int false "Seite (ab 1)"
+// @Param page_size query int false "Einträge pro Seite (max. 200)"
+// @Param cursor query string false "Cursor für die nächste Seite (statt page)"
+// @Param employee_id query int false "Mitarbeiter-ID"
+// @Param department_id query int false "Abteilungs-ID"
+// @Param location_id query int false "Standort-ID"
+// @Param compensation_class query string false "Vergütungsklasse"
+// @Param date_from query string false "Ab Datum (YYYY-MM-DD)"
+// @Param date_to query string false "Bis Datum einschließlich (YYYY-MM-DD)"
+// @Param sort query string false "Sortierung, z.B. -starts_at"
+// @Success 200 {object} responses.APIResponse{data=[]models.OnCallAssignment}
+// @Failure 400,500 {object} responses.APIResponse
+// @Router /api/v1/oncall [get]
+func HandleAllOnCallAssignments(c *fiber.Ctx) error {
+	q, err := query.Parse(c, onCallQuery)
+	if err != nil {
+		return c.Status(400).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	var assignments []models.OnCallAssignment
+	meta, err := q.Find(tenantDB(c), &assignments, preloadOnCall)
+	if err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+	return c.JSON(responses.PaginatedResponse(responses.MsgSuccessGet, assignments, meta))
+}
+
+// @Summary Rufbereitschaft erstellen
+// @Description Legt eine Rufbereitschaft an. Sie darf sich mit Schichten des Mitarbeiters überschneiden, aber nicht mit seinen anderen Rufbereitschaften. Einsätze werden über /oncall/{id}/callouts erfasst.
+// @Tags oncall
+// @Accept json
+// @Produce json
+// @Param assignment body models.OnCallAssignment true "Rufbereitschaftsdaten"
+// @Success 201 {object} responses.APIResponse{data=models.OnCallAssignment}
+// @Failure 400,500 {object} responses.APIResponse
+// @Failure 422 {object} responses.APIResponse{data=[]models.FieldError}
+// @Router /api/v1/oncall [post]
+func HandleCreateOnCallAssignment(c *fiber.Ctx) error {
+	assignment := new(models.OnCallAssignment)
+	if err := c.BodyParser(assignment); err != nil {
+		return c.Status(400).JSON(responses.ErrorResponse(responses.ErrInvalidInput))
+	}
+	assignment.Callouts = nil
+
+	if err := validateOnCallAssignment(tenantDB(c), assignment); err != nil {
+		return validationErrorResponse(c, err)
+	}
+
+	if err := tenantDB(c).Omit("Employee", "Department", "Callouts").Create(assignment).Error; err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	preloadOnCall(tenantDB(c)).First(assignment, assignment.ID)
+
+	return c.Status(201).JSON(responses.SuccessResponse(responses.MsgSuccessCreate, assignment))
+}
+
+// @Summary Einzelne Rufbereitschaft abrufen
+// @Description Ruft eine Rufbereitschaft mit ihren Einsätzen ab
+// @Tags oncall
+// @Produce json
+// @Param id path int true "Rufbereitschafts-ID"
+// @Success 200 {object} responses.APIResponse{data=models.OnCallAssignment}
+// @Failure 404 {object} responses.APIResponse
+// @Router /api/v1/oncall/{id} [get]
+func HandleGetOneOnCallAssignment(c *fiber.Ctx) error {
+	var assignment models.OnCallAssignment
+
+	if err := preloadOnCall(tenantDB(c)).First(&assignment, c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
+	}
+
+	return c.JSON(responses.SuccessResponse(responses.MsgSuccessGet, assignment))
+}
+
+// @Summary Rufbereitschaft aktualisieren
+// @Description Aktualisiert eine Rufbereitschaft. Bereits erfasste Einsätze müssen im neuen Zeitraum liegen.
+// @Tags oncall
+// @Accept json
+// @Produce json
+// @Param id path int true "Rufbereitschafts-ID"
+// @Param assignment body models.OnCallAssignment true "Aktualisierte Rufbereitschaftsdaten"
+// @Success 200 {object} responses.APIResponse{data=models.OnCallAssignment}
+// @Failure 400,404,500 {object} responses.APIResponse
+// @Failure 422 {object} responses.APIResponse{data=[]models.FieldError}
+// @Router /api/v1/oncall/{id} [put]
+func HandleUpdateOnCallAssignment(c *fiber.Ctx) error {
+	var assignment models.OnCallAssignment
+
+	if err := preloadOnCall(tenantDB(c)).First(&assignment, c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
+	}
+	callouts := assignment.Callouts
+
+	if err := c.BodyParser(&assignment); err != nil {
+		return c.Status(400).JSON(responses.ErrorResponse(responses.ErrInvalidInput))
+	}
+	assignment.Callouts = callouts
+
+	if err := validateOnCallAssignment(tenantDB(c), &assignment); err != nil {
+		return validationErrorResponse(c, err)
+	}
+	var errs models.ValidationErrors
+	for i := range callouts {
+		if err := errs.Merge(fmt.Sprintf("callouts[%d]", i), validateOnCallCallout(tenantDB(c), &assignment, &callouts[i])); err != nil {
+			return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+		}
+	}
+	if err := errs.Err(); err != nil {
+		return validationErrorResponse(c, err)
+	}
+
+	if err := tenantDB(c).Omit("Employee", "Department", "Callouts").Save(&assignment).Error; err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	return c.JSON(responses.SuccessResponse(responses.MsgSuccessUpdate, assignment))
+}
+
+// @Summary Rufbereitschaft löschen
+// @Description Löscht eine Rufbereitschaft samt ihren Einsätzen
+// @Tags oncall
+// @Produce json
+// @Param id path int true "Rufbereitschafts-ID"
+// @Success 200 {object} responses.APIResponse
+// @Failure 404,409,500 {object} responses.APIResponse
+// @Router /api/v1/oncall/{id} [delete]
+func HandleDeleteOnCallAssignment(c *fiber.Ctx) error {
+	var assignment models.OnCallAssignment
+
+	if err := tenantDB(c).First(&assignment, c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
+	}
+
+	tx := tenantDB(c).Begin()
+
+	if err := database.SoftDelete(tx, "on_call_assignments", assignment.ID); err != nil {
+		tx.Rollback()
+		return deleteErrorResponse(c, err)
+	}
+
+	tx.Commit()
+
+	return c.JSON(responses.SuccessResponse(responses.MsgSuccessDelete, nil))
+}
+
+// @Summary Einsatz erfassen
+// @Description Erfasst einen Einsatz, also tatsächlich geleistete Arbeit während der Rufbereitschaft. Der Einsatz muss innerhalb der Rufbereitschaft liegen und darf sich nicht mit anderen Einsätzen überschneiden.
+// @Tags oncall
+// @Accept json
+// @Produce json
+// @Param id path int true "Rufbereitschafts-ID"
+// @Param callout body models.OnCallCallout true "Einsatzdaten"
+// @Success 201 {object} responses.APIResponse{data=models.OnCallCallout}
+// @Failure 400,404,500 {object} responses.APIResponse
+// @Failure 422 {object} responses.APIResponse{data=[]models.FieldError}
+// @Router /api/v1/oncall/{id}/callouts [post]
+func HandleCreateOnCallCallout(c *fiber.Ctx) error {
+	var assignment models.OnCallAssignment
+	if err := tenantDB(c).First(&assignment, c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
+	}
+
+	callout := new(models.OnCallCallout)
+	if err := c.BodyParser(callout); err != nil {
+		return c.Status(400).JSON(responses.ErrorResponse(responses.ErrInvalidInput))
+	}
+	callout.OnCallAssignmentID = assignment.ID
+
+	if err := validateOnCallCallout(tenantDB(c), &assignment, callout); err != nil {
+		return validationErrorResponse(c, err)
+	}
+
+	if err := tenantDB(c).Create(callout).Error; err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	return c.Status(201).JSON(responses.SuccessResponse(responses.MsgSuccessCreate, callout))
+}
+
+// @Summary Einsatz löschen
+// @Description Löscht einen erfassten Einsatz
+// @Tags oncall
+// @Produce json
+// @Param id path int true "Rufbereitschafts-ID"
+// @Param calloutId path int true "Einsatz-ID"
+// @Success 200 {object} responses.APIResponse
+// @Failure 404,500 {object} responses.APIResponse
+// @Router /api/v1/oncall/{id}/callouts/{calloutId} [delete]
+func HandleDeleteOnCallCallout(c *fiber.Ctx) error {
+	var callout models.OnCallCallout
+	if err := tenantDB(c).
+		Where("on_call_assignment_id = ?", c.Params("id")).
+		First(&callout, c.Params("calloutId")).Error; err != nil {
+		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
+	}
+
+	tx := tenantDB(c).Begin()
+
+	if err := database.SoftDelete(tx, "on_call_callouts", callout.ID); err != nil {
+		tx.Rollback()
+		return deleteErrorResponse(c, err)
+	}
+
+	tx.Commit()
+
+	return c.JSON(responses.SuccessResponse(responses.MsgSuccessDelete, nil))
+}
+
+func validateOnCallAssignment(db *gorm.DB, assignment *models.OnCallAssignment) error {
+	assignment.CompensationClass = strings.TrimSpace(assignment.CompensationClass)
+	errs := models.ValidateModel(assignment)
+
+	if assignment.EmployeeID != 0 {
+		if err := db.First(&models.Employee{}, assignment.EmployeeID).Error; err != nil {
+			errs.Add("employee_id", models.CodeNotFound, "mitarbeiter nicht gefunden")
+		}
+	}
+	if assignment.DepartmentID != 0 {
+		if err := db.First(&models.Department{}, assignment.DepartmentID).Error; err != nil {
+			errs.Add("department_id", models.CodeNotFound, "abteilung nicht gefunden")
+		}
+	}
+	if errs.Has("starts_at") || errs.Has("ends_at") {
+		return errs.Err()
+	}
+
+	// Überschneidungen mit Schichten sind gewollt, mit anderen
+	// Rufbereitschaften desselben Mitarbeiters nicht
+	var overlapping models.OnCallAssignment
+	err := db.Where("employee_id = ? AND id != ? AND starts_at < ? AND ends_at > ?",
+		assignment.EmployeeID, assignment.ID, assignment.EndsAt, assignment.StartsAt).
+		First(&overlapping).Error
+	if err == nil {
+		errs.Addf("starts_at", models.CodeConflict, "überschneidet sich mit rufbereitschaft %d (%s – %s)", overlapping.ID,
+			overlapping.StartsAt.Format("02.01.2006 15:04"), overlapping.EndsAt.Format("02.01.2006 15:04"))
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	return errs.Err()
+}
+
+func validateOnCallCallout(db *gorm.DB, assignment *models.OnCallAssignment, callout *models.OnCallCallout) error {
+	errs := models.ValidateModel(callout)
+	if len(errs) > 0 {
+		return errs.Err()
+	}
+	if callout.StartedAt.Before(assignment.StartsAt) || callout.EndedAt.After(assignment.EndsAt) {
+		errs.Add("started_at", models.CodeOutOfRange, "der einsatz muss innerhalb der rufbereitschaft liegen")
+	}
+
+	// Sich überschneidende Einsätze würden doppelt abgerechnet
+	var overlapping models.OnCallCallout
+	err := db.Where("on_call_assignment_id = ? AND id != ? AND started_at < ? AND ended_at > ?",
+		assignment.ID, callout.ID, callout.EndedAt, callout.StartedAt).
+		First(&overlapping).Error
+	if err == nil {
+		errs.Addf("started_at", models.CodeConflict, "überschneidet sich mit einsatz %d (%s – %s)", overlapping.ID,
+			overlapping.StartedAt.Format("02.01.2006 15:04"), overlapping.EndedAt.Format("02.01.2006 15:04"))
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	return errs.Err()
+}
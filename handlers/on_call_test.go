@@ -0,0 +1,90 @@
+package handlers_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ptmmeiningen/schichtplaner/database/databasetest"
+	"github.com/ptmmeiningen/schichtplaner/models"
+)
+
+// createOnCall legt eine Rufbereitschaft über die API an
+func createOnCall(t *testing.T, app *fiber.App, employee models.Employee, departmentID uint, class string, startsAt, endsAt time.Time) models.OnCallAssignment {
+	t.Helper()
+	status, resp := call(t, app, "POST", "/api/v1/oncall", "", map[string]interface{}{
+		"employee_id": employee.ID, "department_id": departmentID, "compensation_class": class,
+		"starts_at": startsAt, "ends_at": endsAt,
+	})
+	if status != 201 {
+		t.Fatalf("rufbereitschaft: status %d, %s %s", status, resp.Error, resp.Data)
+	}
+	var assignment models.OnCallAssignment
+	decode(t, resp, &assignment)
+	return assignment
+}
+
+// createCallout erfasst einen Einsatz über die API
+func createCallout(t *testing.T, app *fiber.App, assignment models.OnCallAssignment, startedAt, endedAt time.Time) (int, apiResponse) {
+	t.Helper()
+	return call(t, app, "POST", fmt.Sprintf("/api/v1/oncall/%d/callouts", assignment.ID), "", map[string]interface{}{
+		"started_at": startedAt, "ended_at": endedAt,
+	})
+}
+
+func TestOnCallCalloutsMustNotOverlap(t *testing.T) {
+	app := setupApp(t)
+	department := databasetest.Department(t, "Produktion")
+	anna := databasetest.Employee(t, "anna@example.org", department.ID)
+	bert := databasetest.Employee(t, "bert@example.org", department.ID)
+
+	at := func(hour, minute int) time.Time { return time.Date(2030, 1, 7, hour, minute, 0, 0, time.UTC) }
+	assignment := createOnCall(t, app, anna, department.ID, "standard", at(0, 0), at(12, 0))
+	other := createOnCall(t, app, bert, department.ID, "standard", at(0, 0), at(12, 0))
+
+	if status, resp := createCallout(t, app, assignment, at(2, 0), at(3, 30)); status != 201 {
+		t.Fatalf("status %d, %s %s", status, resp.Error, resp.Data)
+	}
+
+	conflict := models.FieldError{Field: "started_at", Code: models.CodeConflict}
+	status, resp := createCallout(t, app, assignment, at(3, 0), at(4, 0))
+	expectFieldErrors(t, status, resp, conflict)
+	status, resp = createCallout(t, app, assignment, at(1, 0), at(5, 0))
+	expectFieldErrors(t, status, resp, conflict)
+
+	// Direkt anschließende Einsätze und Einsätze anderer Rufbereitschaften
+	// überschneiden sich nicht
+	if status, resp := createCallout(t, app, assignment, at(3, 30), at(4, 0)); status != 201 {
+		t.Fatalf("anschließend: status %d, %s %s", status, resp.Error, resp.Data)
+	}
+	if status, resp := createCallout(t, app, other, at(2, 0), at(3, 30)); status != 201 {
+		t.Fatalf("andere rufbereitschaft: status %d, %s %s", status, resp.Error, resp.Data)
+	}
+
+	// Einsätze im Papierkorb zählen nicht
+	var stored models.OnCallAssignment
+	status, resp = call(t, app, "GET", fmt.Sprintf("/api/v1/oncall/%d", assignment.ID), "", nil)
+	if status != 200 {
+		t.Fatalf("status %d, %s", status, resp.Error)
+	}
+	decode(t, resp, &stored)
+	if len(stored.Callouts) != 2 {
+		t.Fatalf("einsätze %+v", stored.Callouts)
+	}
+	status, resp = call(t, app, "DELETE", fmt.Sprintf("/api/v1/oncall/%d/callouts/%d", assignment.ID, stored.Callouts[0].ID), "", nil)
+	if status != 200 {
+		t.Fatalf("status %d, %s", status, resp.Error)
+	}
+	if status, resp := createCallout(t, app, assignment, at(3, 0), at(3, 30)); status != 201 {
+		t.Fatalf("nach dem löschen: status %d, %s %s", status, resp.Error, resp.Data)
+	}
+}
+
+// addCallout erfasst einen Einsatz, der gültig sein muss
+func addCallout(t *testing.T, app *fiber.App, assignment models.OnCallAssignment, startedAt, endedAt time.Time) {
+	t.Helper()
+	if status, resp := createCallout(t, app, assignment, startedAt, endedAt); status != 201 {
+		t.Fatalf("einsatz: status %d, %s %s", status, resp.Error, resp.Data)
+	}
+}
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ptmmeiningen/schichtplaner/models"
+	"github.com/ptmmeiningen/schichtplaner/pkg/responses"
+)
+
+// PayrollEntry sind die abrechnungsrelevanten Zeiten eines Mitarbeiters.
+// Abwesenheiten zählen nicht zu den Stunden, sondern als Tage je Status.
+type PayrollEntry struct {
+	EmployeeID   uint               `json:"employee_id"`
+	Name         string             `json:"name"`
+	Shifts       int                `json:"shifts"`
+	NetHours     float64            `json:"net_hours"`
+	PaidHours    float64            `json:"paid_hours"`
+	AbsenceDays  map[string]int     `json:"absence_days"`
+	OnCallHours  map[string]float64 `json:"on_call_hours"` // je Vergütungsklasse
+	Callouts     int                `json:"callouts"`
+	CalloutHours float64            `json:"callout_hours"`
+}
+
+// PayrollReport ist der Abrechnungsexport einer Abteilung im Zeitraum
+type PayrollReport struct {
+	DepartmentID uint           `json:"department_id"`
+	From         string         `json:"from"`
+	To           string         `json:"to"`
+	Employees    []PayrollEntry `json:"employees"`
+}
+
+// @Summary Abrechnungsexport abrufen
+// @Description Summiert je Mitarbeiter Arbeitszeit und bezahlte Zeit der Schichten, Abwesenheitstage, Rufbereitschaftsstunden je Vergütungsklasse und Einsätze im Zeitraum. Rufbereitschaften zählen nur mit dem Anteil im Zeitraum, Einsätze nach ihrem Beginn. Mit format=csv wird eine CSV-Datei geliefert.
+// @Tags reports
+// @Produce json,text/csv
+// @Param department query int true "Abteilungs-ID"
+// @Param from query string true "Beginn (YYYY-MM-DD)"
+// @Param to query string true "Ende einschließlich (YYYY-MM-DD)"
+// @Param format query string false "json (Standard) oder csv"
+// @Success 200 {object} responses.APIResponse{data=PayrollReport}
+// @Failure 400,404,500 {object} responses.APIResponse
+// @Router /api/v1/reports/payroll [get]
+func HandlePayrollReport(c *fiber.Ctx) error {
+	var department models.Department
+	if err := tenantDB(c).First(&department, c.QueryInt("department")).Error; err != nil {
+		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
+	}
+
+	from, to, err := parseReportRange(c)
+	if err != nil {
+		return c.Status(400).JSON(responses.ErrorResponse(err.Error()))
+	}
+	until := to.AddDate(0, 0, 1)
+
+	format := c.Query("format", "json")
+	if format != "json" && format != "csv" {
+		return c.Status(400).JSON(responses.ErrorResponse("format muss json oder csv sein"))
+	}
+
+	var shiftDays []models.ShiftDay
+	if err := tenantDB(c).
+		Joins("JOIN shift_weeks ON shift_weeks.id = shift_days.shift_week_id AND shift_weeks.deleted_at IS NULL").
+		Where("shift_weeks.department_id = ? AND shift_days.employee_id IS NOT NULL", department.ID).
+		Where("shift_days.date >= ? AND shift_days.date < ?", from, until).
+		Find(&shiftDays).Error; err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	var assignments []models.OnCallAssignment
+	if err := preloadOnCall(tenantDB(c)).
+		Where("department_id = ? AND starts_at < ? AND ends_at > ?", department.ID, until, from).
+		Find(&assignments).Error; err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	shiftTypes, err := shiftTypesByID(tenantDB(c))
+	if err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	involved := map[uint]int{}
+	for _, day := range shiftDays {
+		involved[*day.EmployeeID]++
+	}
+	for _, assignment := range assignments {
+		involved[assignment.EmployeeID]++
+	}
+
+	var employees []models.Employee
+	if err := tenantDB(c).Unscoped().
+		Where("(department_id = ? AND deleted_at IS NULL) OR id IN ?", department.ID, mapKeys(involved)).
+		Order("last_name, first_name, id").
+		Find(&employees).Error; err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	entries := map[uint]*PayrollEntry{}
+	report := PayrollReport{
+		DepartmentID: department.ID,
+		From:         from.Format("2006-01-02"),
+		To:           to.Format("2006-01-02"),
+		Employees:    make([]PayrollEntry, len(employees)),
+	}
+	for i, employee := range employees {
+		report.Employees[i] = PayrollEntry{
+			EmployeeID:  employee.ID,
+			Name:        employee.FirstName + " " + employee.LastName,
+			AbsenceDays: map[string]int{},
+			OnCallHours: map[string]float64{},
+		}
+		entries[employee.ID] = &report.Employees[i]
+	}
+
+	for _, day := range shiftDays {
+		entry := entries[*day.EmployeeID]
+		if isAbsence(day.Status) {
+			entry.AbsenceDays[day.Status]++
+			continue
+		}
+		shiftType := shiftTypes[day.ShiftTypeID]
+		duration := day.Duration(&shiftType)
+		entry.Shifts++
+		entry.NetHours += duration.Net.Hours()
+		entry.PaidHours += duration.Paid.Hours()
+	}
+	for _, assignment := range assignments {
+		entry := entries[assignment.EmployeeID]
+		entry.OnCallHours[assignment.CompensationClass] += assignment.Overlap(from, until).Hours()
+		for _, callout := range assignment.Callouts {
+			if callout.StartedAt.Before(from) || !callout.StartedAt.Before(until) {
+				continue
+			}
+			entry.Callouts++
+			entry.CalloutHours += callout.Duration().Hours()
+		}
+	}
+
+	if format == "csv" {
+		data, err := payrollCSV(&report)
+		if err != nil {
+			return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+		}
+		c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+		c.Set(fiber.HeaderContentDisposition, fmt.Sprintf("attachment; filename=\"abrechnung-%d-%s-%s.csv\"", department.ID, report.From, report.To))
+		return c.Send(data)
+	}
+
+	return c.JSON(responses.SuccessResponse(responses.MsgSuccessGet, report))
+}
+
+// payrollCSV schreibt den Export mit einer Spalte je Abwesenheitsstatus und
+// je vorkommender Vergütungsklasse
+func payrollCSV(report *PayrollReport) ([]byte, error) {
+	classSet := map[string]bool{}
+	for _, entry := range report.Employees {
+		for class := range entry.OnCallHours {
+			classSet[class] = true
+		}
+	}
+	classes := make([]string, 0, len(classSet))
+	for class := range classSet {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+
+	header := []string{"employee_id", "name", "shifts", "net_hours", "paid_hours"}
+	for _, status := range models.AbsenceStatuses {
+		header = append(header, status+"_days")
+	}
+	for _, class := range classes {
+		header = append(header, "on_call_hours_"+class)
+	}
+	header = append(header, "callouts", "callout_hours")
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(header); err != nil {
+		return nil, err
+	}
+	for _, entry := range report.Employees {
+		row := []string{
+			strconv.FormatUint(uint64(entry.EmployeeID), 10),
+			entry.Name,
+			strconv.Itoa(entry.Shifts),
+			formatHours(entry.NetHours),
+			formatHours(entry.PaidHours),
+		}
+		for _, status := range models.AbsenceStatuses {
+			row = append(row, strconv.Itoa(entry.AbsenceDays[status]))
+		}
+		for _, class := range classes {
+			row = append(row, formatHours(entry.OnCallHours[class]))
+		}
+		row = append(row, strconv.Itoa(entry.Callouts), formatHours(entry.CalloutHours))
+		if err := w.Write(row); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+func formatHours(hours float64) string {
+	return strconv.FormatFloat(hours, 'f', 2, 64)
+}
@@ -0,0 +1,119 @@
+package handlers_test
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ptmmeiningen/schichtplaner/database/databasetest"
+	"github.com/ptmmeiningen/schichtplaner/handlers"
+	"github.com/ptmmeiningen/schichtplaner/models"
+)
+
+func TestPayrollReport(t *testing.T) {
+	app := setupApp(t)
+	department := databasetest.Department(t, "Produktion")
+	// Früh: 7,5 Stunden netto und bezahlt, Tag: 7,5 netto und 8 bezahlt
+	early := databasetest.ShiftType(t, "Früh", "06:00", "14:00")
+	day := databasetest.ShiftType(t, "Tag", "08:00", "16:00")
+	day.BreaksPaid = true
+	databasetest.Save(t, &day)
+	anna := databasetest.Employee(t, "anna@example.org", department.ID)
+	bert := databasetest.Employee(t, "bert@example.org", department.ID)
+
+	monday := time.Date(2030, 1, 7, 0, 0, 0, 0, time.UTC)
+	week := models.ShiftWeek{Year: 2030, CalendarWeek: 2, Status: models.StatusPublished, DepartmentID: &department.ID}
+	databasetest.Create(t, &week)
+	next := models.ShiftWeek{Year: 2030, CalendarWeek: 3, Status: models.StatusDraft, DepartmentID: &department.ID}
+	databasetest.Create(t, &next)
+	shift := func(shiftWeek models.ShiftWeek, employee models.Employee, shiftType models.ShiftType, day int, status string) {
+		databasetest.Create(t, &models.ShiftDay{
+			Date: monday.AddDate(0, 0, day), ShiftWeekID: &shiftWeek.ID, ShiftTypeID: shiftType.ID, EmployeeID: &employee.ID, Status: status,
+		})
+	}
+	shift(week, anna, early, 0, models.ShiftDayPlanned)
+	shift(week, anna, day, 1, models.ShiftDayPlanned)
+	shift(week, anna, early, 2, models.ShiftDaySick)
+	shift(week, anna, early, 3, models.ShiftDayVacation)
+	shift(week, bert, early, 0, models.ShiftDayAbsent)
+	shift(next, anna, early, 7, models.ShiftDayPlanned)
+
+	at := func(day, hour int) time.Time { return time.Date(2030, 1, day, hour, 0, 0, 0, time.UTC) }
+	// Von der Nacht zum 07.01. liegen 6 Stunden im Zeitraum, der Einsatz
+	// am Vorabend zählt nicht
+	night := createOnCall(t, app, anna, department.ID, "standard", at(6, 18), at(7, 6))
+	addCallout(t, app, night, at(6, 20), at(6, 21))
+	addCallout(t, app, night, time.Date(2030, 1, 7, 2, 0, 0, 0, time.UTC), time.Date(2030, 1, 7, 3, 30, 0, 0, time.UTC))
+	// Vom Wochenende liegen 40 Stunden im Zeitraum, der Einsatz beginnt darin
+	weekend := createOnCall(t, app, anna, department.ID, "weekend", at(12, 8), at(14, 8))
+	addCallout(t, app, weekend, at(13, 23), at(14, 1))
+	createOnCall(t, app, bert, department.ID, "standard", at(11, 16), at(11, 22))
+
+	path := fmt.Sprintf("/api/v1/reports/payroll?department=%d&from=2030-01-07&to=2030-01-13", department.ID)
+	status, resp := call(t, app, "GET", path, "", nil)
+	if status != 200 {
+		t.Fatalf("status %d: %s", status, resp.Error)
+	}
+	var report handlers.PayrollReport
+	decode(t, resp, &report)
+
+	if len(report.Employees) != 2 || report.Employees[0].EmployeeID != anna.ID || report.Employees[1].EmployeeID != bert.ID {
+		t.Fatalf("mitarbeiter %+v", report.Employees)
+	}
+	same := func(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+	annaEntry, bertEntry := report.Employees[0], report.Employees[1]
+	if annaEntry.Shifts != 2 || !same(annaEntry.NetHours, 15) || !same(annaEntry.PaidHours, 15.5) {
+		t.Errorf("anna schichten %+v", annaEntry)
+	}
+	if len(annaEntry.AbsenceDays) != 2 || annaEntry.AbsenceDays[models.ShiftDaySick] != 1 || annaEntry.AbsenceDays[models.ShiftDayVacation] != 1 {
+		t.Errorf("anna abwesenheiten %v", annaEntry.AbsenceDays)
+	}
+	if len(annaEntry.OnCallHours) != 2 || !same(annaEntry.OnCallHours["standard"], 6) || !same(annaEntry.OnCallHours["weekend"], 40) {
+		t.Errorf("anna rufbereitschaft %v", annaEntry.OnCallHours)
+	}
+	if annaEntry.Callouts != 2 || !same(annaEntry.CalloutHours, 3.5) {
+		t.Errorf("anna einsätze %d, %v stunden", annaEntry.Callouts, annaEntry.CalloutHours)
+	}
+	if bertEntry.Shifts != 0 || bertEntry.PaidHours != 0 || bertEntry.AbsenceDays[models.ShiftDayAbsent] != 1 ||
+		len(bertEntry.OnCallHours) != 1 || !same(bertEntry.OnCallHours["standard"], 6) || bertEntry.Callouts != 0 {
+		t.Errorf("bert %+v", bertEntry)
+	}
+
+	// CSV mit einer Spalte je Abwesenheitsstatus und Vergütungsklasse
+	httpResp, err := app.Test(httptest.NewRequest("GET", path+"&format=csv", nil), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if httpResp.StatusCode != 200 || !strings.HasPrefix(httpResp.Header.Get("Content-Type"), "text/csv") {
+		t.Fatalf("csv: status %d, %s", httpResp.StatusCode, httpResp.Header.Get("Content-Type"))
+	}
+	if disposition := httpResp.Header.Get("Content-Disposition"); !strings.Contains(disposition, fmt.Sprintf("abrechnung-%d-2030-01-07-2030-01-13.csv", department.ID)) {
+		t.Errorf("dateiname %q", disposition)
+	}
+	body, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(strings.NewReader(string(body))).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"employee_id", "name", "shifts", "net_hours", "paid_hours", "sick_days", "vacation_days", "absent_days", "on_call_hours_standard", "on_call_hours_weekend", "callouts", "callout_hours"},
+		{fmt.Sprint(anna.ID), "Anna Adler", "2", "15.00", "15.50", "1", "1", "0", "6.00", "40.00", "2", "3.50"},
+		{fmt.Sprint(bert.ID), "Anna Adler", "0", "0.00", "0.00", "0", "0", "1", "6.00", "0.00", "0", "0.00"},
+	}
+	if fmt.Sprint(records) != fmt.Sprint(want) {
+		t.Errorf("csv\n%v\nerwartet\n%v", records, want)
+	}
+
+	status, resp = call(t, app, "GET", path+"&format=xml", "", nil)
+	if status != 400 {
+		t.Errorf("format xml: status %d", status)
+	}
+}
@@ -0,0 +1,19 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ptmmeiningen/schichtplaner/database"
+	"github.com/ptmmeiningen/schichtplaner/pkg/responses"
+)
+
+// deleteErrorResponse antwortet mit 409 und den blockierenden Verweisen,
+// wenn das Löschen an einer RESTRICT-Regel scheitert, sonst mit 500
+func deleteErrorResponse(c *fiber.Ctx, err error) error {
+	var refErr *database.ReferenceError
+	if errors.As(err, &refErr) {
+		return c.Status(409).JSON(responses.ConflictResponse(responses.ErrReferenced, refErr.References))
+	}
+	return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+}
@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ptmmeiningen/schichtplaner/models"
+	"github.com/ptmmeiningen/schichtplaner/pkg/holidays"
+	"github.com/ptmmeiningen/schichtplaner/pkg/responses"
+)
+
+// maxReportDays begrenzt den Zeitraum eines Berichts
+const maxReportDays = 366
+
+// FairnessMetrics sind die Kennzahlen der Schichtverteilung
+type FairnessMetrics struct {
+	Shifts  float64 `json:"shifts"`
+	Weekend float64 `json:"weekend"`
+	Night   float64 `json:"night"`
+	Holiday float64 `json:"holiday"`
+	Late    float64 `json:"late"`
+	Hours   float64 `json:"hours"`
+}
+
+// EmployeeFairness sind die Kennzahlen eines Mitarbeiters und ihre
+// Abweichung vom Durchschnitt der Abteilung
+type EmployeeFairness struct {
+	EmployeeID uint            `json:"employee_id"`
+	Name       string          `json:"name"`
+	Counts     FairnessMetrics `json:"counts"`
+	Deviation  FairnessMetrics `json:"deviation"`
+}
+
+// FairnessReport ist die Schichtverteilung einer Abteilung im Zeitraum.
+// Gini ist je Kennzahl 0 bei völlig gleicher und nahe 1 bei völlig
+// ungleicher Verteilung.
+type FairnessReport struct {
+	DepartmentID  uint               `json:"department_id"`
+	From          string             `json:"from"`
+	To            string             `json:"to"`
+	Employees     []EmployeeFairness `json:"employees"`
+	Average       FairnessMetrics    `json:"average"`
+	Gini          FairnessMetrics    `json:"gini"`
+	ShiftsPerType map[uint]int       `json:"shifts_per_type"`
+}
+
+// @Summary Fairnessbericht abrufen
+// @Description Zählt je Mitarbeiter der Abteilung Wochenend-, Nacht-, Feiertags- und Spätschichten sowie Stunden im Zeitraum, mit Abweichung vom Abteilungsdurchschnitt und Gini-Koeffizient je Kennzahl. Feiertage richten sich nach dem Bundesland des Standorts. Krankheit, Urlaub und Abwesenheit zählen nicht als Schicht.
+// @Tags reports
+// @Accept json
+// @Produce json
+// @Param department query int true "Abteilungs-ID"
+// @Param from query string true "Beginn (YYYY-MM-DD)"
+// @Param to query string true "Ende einschließlich (YYYY-MM-DD)"
+// @Success 200 {object} responses.APIResponse{data=FairnessReport}
+// @Failure 400,404,500 {object} responses.APIResponse
+// @Router /api/v1/reports/fairness [get]
+func HandleFairnessReport(c *fiber.Ctx) error {
+	var department models.Department
+	if err := tenantDB(c).Preload("Location").First(&department, c.QueryInt("department")).Error; err != nil {
+		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
+	}
+
+	from, to, err := parseReportRange(c)
+	if err != nil {
+		return c.Status(400).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	var shiftDays []models.ShiftDay
+	if err := tenantDB(c).
+		Joins("JOIN shift_weeks ON shift_weeks.id = shift_days.shift_week_id AND shift_weeks.deleted_at IS NULL").
+		Where("shift_weeks.department_id = ? AND shift_days.date >= ? AND shift_days.date < ?", department.ID, from, to.AddDate(0, 0, 1)).
+		Where("shift_days.status NOT IN ?", models.AbsenceStatuses).
+		Order("shift_days.date, shift_days.id").
+		Find(&shiftDays).Error; err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+	tally := tallyShifts(shiftDays)
+
+	shiftTypes, err := shiftTypesByID(tenantDB(c))
+	if err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	// Alle Mitarbeiter der Abteilung zählen mit, auch ohne Schichten,
+	// dazu Aushilfen aus anderen Abteilungen
+	var employees []models.Employee
+	if err := tenantDB(c).
+		Where("department_id = ? OR id IN ?", department.ID, mapKeys(tally.perEmployee)).
+		Order("last_name, first_name, id").
+		Find(&employees).Error; err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	counts := map[uint]*FairnessMetrics{}
+	for _, employee := range employees {
+		counts[employee.ID] = &FairnessMetrics{Shifts: float64(tally.perEmployee[employee.ID])}
+	}
+	for _, day := range shiftDays {
+		if day.EmployeeID == nil || counts[*day.EmployeeID] == nil {
+			continue
+		}
+		metrics := counts[*day.EmployeeID]
+		shiftType := shiftTypes[day.ShiftTypeID]
+		if weekday := day.Date.Weekday(); weekday == time.Saturday || weekday == time.Sunday {
+			metrics.Weekend++
+		}
+		if holidays.IsHoliday(day.Date, department.HolidayState()) {
+			metrics.Holiday++
+		}
+		if shiftType.IsNight() {
+			metrics.Night++
+		}
+		if shiftType.IsLate() {
+			metrics.Late++
+		}
+		metrics.Hours += day.Duration(&shiftType).Paid.Hours()
+	}
+
+	report := FairnessReport{
+		DepartmentID:  department.ID,
+		From:          from.Format("2006-01-02"),
+		To:            to.Format("2006-01-02"),
+		Employees:     []EmployeeFairness{},
+		ShiftsPerType: tally.perType,
+	}
+
+	values := map[string][]float64{}
+	for _, employee := range employees {
+		metrics := counts[employee.ID]
+		for name, value := range metrics.fields() {
+			values[name] = append(values[name], *value)
+		}
+	}
+	for name, column := range values {
+		*report.Average.fields()[name] = mean(column)
+		*report.Gini.fields()[name] = gini(column)
+	}
+
+	for _, employee := range employees {
+		metrics := *counts[employee.ID]
+		entry := EmployeeFairness{
+			EmployeeID: employee.ID,
+			Name:       employee.FirstName + " " + employee.LastName,
+			Counts:     metrics,
+		}
+		deviation := entry.Deviation.fields()
+		for name, value := range metrics.fields() {
+			*deviation[name] = *value - *report.Average.fields()[name]
+		}
+		report.Employees = append(report.Employees, entry)
+	}
+
+	return c.JSON(responses.SuccessResponse(responses.MsgSuccessGet, report))
+}
+
+// fields liefert die Kennzahlen nach Namen, damit Durchschnitt, Abweichung
+// und Gini für alle gleich berechnet werden
+func (m *FairnessMetrics) fields() map[string]*float64 {
+	return map[string]*float64{
+		"shifts":  &m.Shifts,
+		"weekend": &m.Weekend,
+		"night":   &m.Night,
+		"holiday": &m.Holiday,
+		"late":    &m.Late,
+		"hours":   &m.Hours,
+	}
+}
+
+// parseReportRange liest from und to (einschließlich) als Datum
+func parseReportRange(c *fiber.Ctx) (time.Time, time.Time, error) {
+	from, err := time.Parse("2006-01-02", c.Query("from"))
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("from im format YYYY-MM-DD erwartet")
+	}
+	to, err := time.Parse("2006-01-02", c.Query("to"))
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("to im format YYYY-MM-DD erwartet")
+	}
+	if to.Before(from) {
+		return time.Time{}, time.Time{}, fmt.Errorf("to darf nicht vor from liegen")
+	}
+	if to.Sub(from) >= maxReportDays*24*time.Hour {
+		return time.Time{}, time.Time{}, fmt.Errorf("der zeitraum darf höchstens %d tage umfassen", maxReportDays)
+	}
+	return from, to, nil
+}
+
+func mapKeys(m map[uint]int) []uint {
+	keys := make([]uint, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
+
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, value := range values {
+		sum += value
+	}
+	return sum / float64(len(values))
+}
+
+// gini berechnet den Gini-Koeffizienten als mittlere absolute Differenz
+// aller Paare geteilt durch den doppelten Mittelwert
+func gini(values []float64) float64 {
+	average := mean(values)
+	if average == 0 {
+		return 0
+	}
+	diff := 0.0
+	for _, a := range values {
+		for _, b := range values {
+			diff += math.Abs(a - b)
+		}
+	}
+	n := float64(len(values))
+	return diff / (2 * n * n * average)
+}
@@ -0,0 +1,106 @@
+package handlers_test
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ptmmeiningen/schichtplaner/database/databasetest"
+	"github.com/ptmmeiningen/schichtplaner/handlers"
+	"github.com/ptmmeiningen/schichtplaner/models"
+)
+
+func TestGini(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		want   float64
+	}{
+		{name: "leer", values: nil, want: 0},
+		{name: "alle null", values: []float64{0, 0, 0}, want: 0},
+		{name: "ein wert", values: []float64{5}, want: 0},
+		{name: "gleich verteilt", values: []float64{2, 2, 2, 2}, want: 0},
+		{name: "völlig ungleich aus zwei", values: []float64{0, 4}, want: 0.5},
+		{name: "völlig ungleich aus vier", values: []float64{0, 0, 0, 8}, want: 0.75},
+		{name: "gemischt", values: []float64{3, 1, 0}, want: 0.5},
+	}
+	for _, test := range tests {
+		if got := handlers.Gini(test.values); math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("%s: gini %v, erwartet %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestFairnessReport(t *testing.T) {
+	app := setupApp(t)
+	department := databasetest.Department(t, "Produktion")
+	early := databasetest.ShiftType(t, "Früh", "06:00", "14:00")
+	late := databasetest.ShiftType(t, "Spät", "14:00", "22:00")
+	night := databasetest.ShiftType(t, "Nacht", "22:00", "06:00")
+	anna := databasetest.Employee(t, "anna@example.org", department.ID)
+	bert := databasetest.Employee(t, "bert@example.org", department.ID)
+	carl := databasetest.Employee(t, "carl@example.org", department.ID)
+
+	// KW 1/2030 beginnt am Montag, 31.12.2029; der 01.01. ist Neujahr
+	monday := time.Date(2029, 12, 31, 0, 0, 0, 0, time.UTC)
+	week := models.ShiftWeek{Year: 2030, CalendarWeek: 1, Status: models.StatusPublished, DepartmentID: &department.ID}
+	databasetest.Create(t, &week)
+	shift := func(employee models.Employee, shiftType models.ShiftType, day int, status string) {
+		databasetest.Create(t, &models.ShiftDay{
+			Date: monday.AddDate(0, 0, day), ShiftWeekID: &week.ID, ShiftTypeID: shiftType.ID, EmployeeID: &employee.ID, Status: status,
+		})
+	}
+	shift(anna, early, 1, models.ShiftDayPlanned)
+	shift(anna, late, 3, models.ShiftDayPlanned)
+	shift(anna, night, 5, models.ShiftDayPlanned)
+	shift(bert, early, 2, models.ShiftDayPlanned)
+	// Abwesenheiten zählen weder als Schicht noch als Stunden
+	shift(bert, early, 1, models.ShiftDaySick)
+	shift(bert, night, 5, models.ShiftDayVacation)
+	shift(carl, late, 3, models.ShiftDayAbsent)
+
+	status, resp := call(t, app, "GET", fmt.Sprintf("/api/v1/reports/fairness?department=%d&from=2029-12-31&to=2030-01-06", department.ID), "", nil)
+	if status != 200 {
+		t.Fatalf("status %d: %s", status, resp.Error)
+	}
+	var report handlers.FairnessReport
+	decode(t, resp, &report)
+
+	hours := func(shiftTypes ...models.ShiftType) float64 {
+		total := 0.0
+		for _, shiftType := range shiftTypes {
+			total += (&models.ShiftDay{}).Duration(&shiftType).Paid.Hours()
+		}
+		return total
+	}
+	want := map[uint]handlers.FairnessMetrics{
+		anna.ID: {Shifts: 3, Weekend: 1, Night: 1, Holiday: 1, Late: 1, Hours: hours(early, late, night)},
+		bert.ID: {Shifts: 1, Hours: hours(early)},
+		carl.ID: {},
+	}
+	if len(report.Employees) != len(want) {
+		t.Fatalf("mitarbeiter %+v", report.Employees)
+	}
+	for _, employee := range report.Employees {
+		if employee.Counts != want[employee.EmployeeID] {
+			t.Errorf("mitarbeiter %d: %+v, erwartet %+v", employee.EmployeeID, employee.Counts, want[employee.EmployeeID])
+		}
+		if deviation := employee.Counts.Shifts - 4.0/3.0; math.Abs(employee.Deviation.Shifts-deviation) > 1e-9 {
+			t.Errorf("mitarbeiter %d: abweichung %v, erwartet %v", employee.EmployeeID, employee.Deviation.Shifts, deviation)
+		}
+	}
+
+	if math.Abs(report.Average.Shifts-4.0/3.0) > 1e-9 || math.Abs(report.Gini.Shifts-0.5) > 1e-9 {
+		t.Errorf("schichten: durchschnitt %v, gini %v", report.Average.Shifts, report.Gini.Shifts)
+	}
+	// Nur Anna hat Wochenend-, Nacht-, Feiertags- und Spätschichten
+	for name, value := range map[string]float64{"weekend": report.Gini.Weekend, "night": report.Gini.Night, "holiday": report.Gini.Holiday, "late": report.Gini.Late} {
+		if math.Abs(value-2.0/3.0) > 1e-9 {
+			t.Errorf("gini %s %v, erwartet 2/3", name, value)
+		}
+	}
+	if report.ShiftsPerType[early.ID] != 2 || report.ShiftsPerType[late.ID] != 1 || report.ShiftsPerType[night.ID] != 1 {
+		t.Errorf("je schichttyp %v", report.ShiftsPerType)
+	}
+}
@@ -0,0 +1,432 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ptmmeiningen/schichtplaner/database"
+	"github.com/ptmmeiningen/schichtplaner/models"
+	"github.com/ptmmeiningen/schichtplaner/pkg/events"
+	"github.com/ptmmeiningen/schichtplaner/pkg/query"
+	"github.com/ptmmeiningen/schichtplaner/pkg/responses"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxRotationWeeks begrenzt den Zeitraum, der in einem Aufruf erzeugt wird
+const maxRotationWeeks = 104
+
+// rotationQuery legt die Filter und Sortierungen für Rotations-Listen fest
+var rotationQuery = query.Config{
+	Table: "rotations",
+	Filters: map[string]query.Filter{
+		"department_id": {Condition: "rotations.department_id = ?", Parse: query.Int},
+		"location_id":   {Condition: "rotations.department_id IN (SELECT id FROM departments WHERE location_id = ? AND deleted_at IS NULL)", Parse: query.Int},
+	},
+	Sorts: map[string]string{
+		"name":       "rotations.name",
+		"start_date": "rotations.start_date",
+		"created_at": "rotations.created_at",
+	},
+	DefaultSort: "name",
+}
+
+// RotationGenerateInput legt den Zeitraum fest, für den Schichten erzeugt werden
+type RotationGenerateInput struct {
+	From  string `json:"from" example:"2025-01-06"`
+	Until string `json:"until" example:"2025-03-30"`
+}
+
+// RotationWeekResult beschreibt, was in einer Kalenderwoche erzeugt wurde
+type RotationWeekResult struct {
+	Year         int               `json:"year"`
+	CalendarWeek int               `json:"calendar_week"`
+	ShiftWeekID  uint              `json:"shift_week_id,omitempty"`
+	Created      int               `json:"created"`
+	Removed      int64             `json:"removed"`
+	Skipped      []SkippedShiftDay `json:"skipped,omitempty"`
+	Error        string            `json:"error,omitempty"`
+}
+
+func preloadRotation(db *gorm.DB) *gorm.DB {
+	return db.
+		Preload("Slots", func(db *gorm.DB) *gorm.DB {
+			return db.Order("week, week_day")
+		}).
+		Preload("Crews", func(db *gorm.DB) *gorm.DB {
+			return db.Order("start_offset, id")
+		}).
+		Preload("Crews.Members")
+}
+
+// @Summary Alle Rotationen abrufen
+// @Description Ruft alle Rotationen mit Muster und Schichtgruppen ab
+// @Tags rotations
+// @Produce json
+// @Param page query int false "Seite (ab 1)"
+// @Param page_size query int false "Einträge pro Seite (max. 200)"
+// @Param cursor query string false "Cursor für die nächste Seite (statt page)"
+// @Param department_id query int false "Abteilungs-ID"
+// @Param location_id query int false "Standort-ID"
+// @Param sort query string false "Sortierung, z.B. -start_date"
+// @Success 200 {object} responses.APIResponse{data=[]models.Rotation}
+// @Failure 400,500 {object} responses.APIResponse
+// @Router /api/v1/rotations [get]
+func HandleAllRotations(c *fiber.Ctx) error {
+	q, err := query.Parse(c, rotationQuery)
+	if err != nil {
+		return c.Status(400).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	var rotations []models.Rotation
+	meta, err := q.Find(tenantDB(c), &rotations, preloadRotation)
+	if err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+	return c.JSON(responses.PaginatedResponse(responses.MsgSuccessGet, rotations, meta))
+}
+
+// @Summary Rotation erstellen
+// @Description Legt eine Rotation mit Zykluslänge, Muster (slots) und Schichtgruppen (crews) an. Das Startdatum muss ein Montag sein.
+// @Tags rotations
+// @Accept json
+// @Produce json
+// @Param rotation body models.Rotation true "Rotationsdaten"
+// @Success 201 {object} responses.APIResponse{data=models.Rotation}
+// @Failure 400,500 {object} responses.APIResponse
+// @Failure 422 {object} responses.APIResponse{data=[]models.FieldError}
+// @Router /api/v1/rotations [post]
+func HandleCreateRotation(c *fiber.Ctx) error {
+	rotation := new(models.Rotation)
+	if err := c.BodyParser(rotation); err != nil {
+		return c.Status(400).JSON(responses.ErrorResponse(responses.ErrInvalidInput))
+	}
+
+	if err := validateRotation(tenantDB(c), rotation); err != nil {
+		return validationErrorResponse(c, err)
+	}
+
+	if err := tenantDB(c).Omit("Department", "Slots.ShiftType", "Crews.Members.Employee").Create(rotation).Error; err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	preloadRotation(tenantDB(c)).First(rotation, rotation.ID)
+
+	return c.Status(201).JSON(responses.SuccessResponse(responses.MsgSuccessCreate, rotation))
+}
+
+// @Summary Einzelne Rotation abrufen
+// @Description Ruft eine Rotation mit Muster und Schichtgruppen ab
+// @Tags rotations
+// @Produce json
+// @Param id path int true "Rotations-ID"
+// @Success 200 {object} responses.APIResponse{data=models.Rotation}
+// @Failure 404 {object} responses.APIResponse
+// @Router /api/v1/rotations/{id} [get]
+func HandleGetOneRotation(c *fiber.Ctx) error {
+	id := c.Params("id")
+	var rotation models.Rotation
+
+	if err := preloadRotation(tenantDB(c)).First(&rotation, id).Error; err != nil {
+		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
+	}
+
+	return c.JSON(responses.SuccessResponse(responses.MsgSuccessGet, rotation))
+}
+
+// @Summary Rotation aktualisieren
+// @Description Aktualisiert eine Rotation. Muster und Schichtgruppen werden vollständig ersetzt. Bereits erzeugte Schichten ändern sich erst beim nächsten Erzeugen.
+// @Tags rotations
+// @Accept json
+// @Produce json
+// @Param id path int true "Rotations-ID"
+// @Param rotation body models.Rotation true "Aktualisierte Rotationsdaten"
+// @Success 200 {object} responses.APIResponse{data=models.Rotation}
+// @Failure 400,404,500 {object} responses.APIResponse
+// @Failure 422 {object} responses.APIResponse{data=[]models.FieldError}
+// @Router /api/v1/rotations/{id} [put]
+func HandleUpdateRotation(c *fiber.Ctx) error {
+	id := c.Params("id")
+	var rotation models.Rotation
+
+	if err := tenantDB(c).First(&rotation, id).Error; err != nil {
+		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
+	}
+
+	if err := c.BodyParser(&rotation); err != nil {
+		return c.Status(400).JSON(responses.ErrorResponse(responses.ErrInvalidInput))
+	}
+
+	if err := validateRotation(tenantDB(c), &rotation); err != nil {
+		return validationErrorResponse(c, err)
+	}
+
+	err := tenantDB(c).Transaction(func(tx *gorm.DB) error {
+		var crewIDs []uint
+		if err := tx.Model(&models.RotationCrew{}).Where("rotation_id = ?", rotation.ID).Pluck("id", &crewIDs).Error; err != nil {
+			return err
+		}
+		for _, crewID := range crewIDs {
+			if err := database.SoftDelete(tx, "rotation_crews", crewID); err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("rotation_id = ?", rotation.ID).Delete(&models.RotationSlot{}).Error; err != nil {
+			return err
+		}
+
+		for i := range rotation.Slots {
+			rotation.Slots[i].ID = 0
+			rotation.Slots[i].RotationID = rotation.ID
+		}
+		for i := range rotation.Crews {
+			rotation.Crews[i].ID = 0
+			rotation.Crews[i].RotationID = rotation.ID
+			for j := range rotation.Crews[i].Members {
+				rotation.Crews[i].Members[j].ID = 0
+			}
+		}
+		return tx.Omit("Department", "Slots.ShiftType", "Crews.Members.Employee").Save(&rotation).Error
+	})
+	if err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	preloadRotation(tenantDB(c)).First(&rotation, rotation.ID)
+
+	return c.JSON(responses.SuccessResponse(responses.MsgSuccessUpdate, rotation))
+}
+
+// @Summary Rotation löschen
+// @Description Löscht eine Rotation. Erzeugte Schichttage bleiben erhalten und verlieren den Bezug zur Rotation.
+// @Tags rotations
+// @Produce json
+// @Param id path int true "Rotations-ID"
+// @Success 200 {object} responses.APIResponse
+// @Failure 404,409,500 {object} responses.APIResponse
+// @Router /api/v1/rotations/{id} [delete]
+func HandleDeleteRotation(c *fiber.Ctx) error {
+	id := c.Params("id")
+	var rotation models.Rotation
+
+	if err := tenantDB(c).First(&rotation, id).Error; err != nil {
+		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
+	}
+
+	tx := tenantDB(c).Begin()
+
+	if err := database.SoftDelete(tx, "rotations", rotation.ID); err != nil {
+		tx.Rollback()
+		return deleteErrorResponse(c, err)
+	}
+
+	tx.Commit()
+
+	return c.JSON(responses.SuccessResponse(responses.MsgSuccessDelete, nil))
+}
+
+// @Summary Schichten aus einer Rotation erzeugen
+// @Description Erzeugt für jede Kalenderwoche im Zeitraum die Schichttage der Rotation. Es werden nur künftige Wochen im Entwurfsmodus verändert; dort werden die zuvor aus dieser Rotation erzeugten Schichttage ersetzt. Fehlende Schichtwochen werden als Entwurf angelegt, ungültige Zuordnungen ausgelassen und gemeldet.
+// @Tags rotations
+// @Accept json
+// @Produce json
+// @Param id path int true "Rotations-ID"
+// @Param range body RotationGenerateInput true "Zeitraum (YYYY-MM-DD)"
+// @Success 200 {object} responses.APIResponse{data=[]RotationWeekResult}
+// @Failure 400,404,500 {object} responses.APIResponse
+// @Router /api/v1/rotations/{id}/generate [post]
+func HandleGenerateRotation(c *fiber.Ctx) error {
+	id := c.Params("id")
+	var rotation models.Rotation
+
+	if err := preloadRotation(tenantDB(c)).First(&rotation, id).Error; err != nil {
+		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
+	}
+
+	var input RotationGenerateInput
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(responses.ErrorResponse(responses.ErrInvalidInput))
+	}
+	from, err := time.Parse("2006-01-02", input.From)
+	if err != nil {
+		return c.Status(400).JSON(responses.ErrorResponse("from im format YYYY-MM-DD erwartet"))
+	}
+	until, err := time.Parse("2006-01-02", input.Until)
+	if err != nil {
+		return c.Status(400).JSON(responses.ErrorResponse("until im format YYYY-MM-DD erwartet"))
+	}
+
+	// Nur künftige Wochen ab dem Start der Rotation werden verändert
+	start := isoWeekStart(from.ISOWeek())
+	if nextWeek := isoWeekStart(time.Now().ISOWeek()).AddDate(0, 0, 7); start.Before(nextWeek) {
+		start = nextWeek
+	}
+	if rotationStart := isoWeekStart(rotation.StartDate.ISOWeek()); start.Before(rotationStart) {
+		start = rotationStart
+	}
+	if until.Sub(start) > maxRotationWeeks*7*24*time.Hour {
+		return c.Status(400).JSON(responses.ErrorResponse(fmt.Sprintf("es können höchstens %d wochen auf einmal erzeugt werden", maxRotationWeeks)))
+	}
+
+	results := []RotationWeekResult{}
+	var created []models.ShiftWeek
+
+	err = tenantDB(c).Transaction(func(tx *gorm.DB) error {
+		for monday := start; !monday.After(until); monday = monday.AddDate(0, 0, 7) {
+			result, week, err := generateRotationWeek(tx, rotation, monday)
+			if err != nil {
+				return err
+			}
+			if week != nil {
+				created = append(created, *week)
+			}
+			results = append(results, *result)
+		}
+		return nil
+	})
+	if err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	for _, week := range created {
+		events.Publish(tenantID(c), events.ShiftWeekCreated, week.DepartmentID, week)
+	}
+
+	return c.JSON(responses.SuccessResponse(responses.MsgSuccessCreate, results))
+}
+
+// generateRotationWeek ersetzt die Schichttage der Rotation in der Woche ab
+// monday. Existiert die Schichtwoche noch nicht, wird sie als Entwurf
+// angelegt und zurückgegeben.
+func generateRotationWeek(tx *gorm.DB, rotation models.Rotation, monday time.Time) (*RotationWeekResult, *models.ShiftWeek, error) {
+	year, calendarWeek := monday.ISOWeek()
+	result := &RotationWeekResult{Year: year, CalendarWeek: calendarWeek}
+
+	var shiftWeek models.ShiftWeek
+	var created *models.ShiftWeek
+	err := tx.Scopes(sameTeam(nil)).
+		Where("department_id = ? AND year = ? AND calendar_week = ?", rotation.DepartmentID, year, calendarWeek).
+		First(&shiftWeek).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		// Ist die Woche bereits je Team geplant, gibt es keine Woche für die
+		// ganze Abteilung
+		var teamWeeks int64
+		if err := tx.Model(&models.ShiftWeek{}).
+			Where("department_id = ? AND year = ? AND calendar_week = ? AND team_id IS NOT NULL", rotation.DepartmentID, year, calendarWeek).
+			Count(&teamWeeks).Error; err != nil {
+			return nil, nil, err
+		}
+		if teamWeeks > 0 {
+			result.Error = "diese kalenderwoche ist bereits für einzelne teams geplant"
+			return result, nil, nil
+		}
+		shiftWeek = models.ShiftWeek{
+			CalendarWeek: calendarWeek,
+			Year:         year,
+			DepartmentID: &rotation.DepartmentID,
+			Status:       models.StatusDraft,
+		}
+		if err := tx.Omit(clause.Associations).Create(&shiftWeek).Error; err != nil {
+			return nil, nil, err
+		}
+		created = &shiftWeek
+	case err != nil:
+		return nil, nil, err
+	case shiftWeek.Status != models.StatusDraft:
+		result.ShiftWeekID = shiftWeek.ID
+		result.Error = responses.ErrDraftOnly
+		return result, nil, nil
+	}
+	result.ShiftWeekID = shiftWeek.ID
+
+	// Die ersetzten Tage werden endgültig gelöscht, damit sie weder den
+	// Papierkorb füllen noch beim Wiederherstellen der Woche zurückkehren
+	removed := tx.Unscoped().Where("shift_week_id = ? AND rotation_id = ?", shiftWeek.ID, rotation.ID).Delete(&models.ShiftDay{})
+	if removed.Error != nil {
+		return nil, nil, removed.Error
+	}
+	result.Removed = removed.RowsAffected
+
+	for _, crew := range rotation.Crews {
+		cycleWeek, ok := rotation.CycleWeek(monday, crew)
+		if !ok {
+			continue
+		}
+		for _, slot := range rotation.Slots {
+			if slot.Week != cycleWeek {
+				continue
+			}
+			for _, member := range crew.Members {
+				employeeID := member.EmployeeID
+				shiftDay := models.ShiftDay{
+					Date:        monday.AddDate(0, 0, slot.WeekDay),
+					ShiftWeekID: &shiftWeek.ID,
+					ShiftTypeID: slot.ShiftTypeID,
+					EmployeeID:  &employeeID,
+					RotationID:  &rotation.ID,
+				}
+				err := validateShiftDay(tx, &shiftDay)
+				if err == nil {
+					err = checkHardPreferences(tx, &shiftDay)
+				}
+				if err != nil {
+					result.Skipped = append(result.Skipped, SkippedShiftDay{
+						Date:       shiftDay.Date,
+						EmployeeID: shiftDay.EmployeeID,
+						Reason:     err.Error(),
+					})
+					continue
+				}
+				if err := tx.Omit(clause.Associations).Create(&shiftDay).Error; err != nil {
+					return nil, nil, err
+				}
+				result.Created++
+			}
+		}
+	}
+	return result, created, nil
+}
+
+func validateRotation(db *gorm.DB, rotation *models.Rotation) error {
+	if !rotation.StartDate.IsZero() {
+		rotation.StartDate = time.Date(rotation.StartDate.Year(), rotation.StartDate.Month(), rotation.StartDate.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	errs := models.ValidateModel(rotation)
+
+	if rotation.DepartmentID != 0 {
+		var department models.Department
+		if err := db.First(&department, rotation.DepartmentID).Error; err != nil {
+			errs.Add("department_id", models.CodeNotFound, "abteilung nicht gefunden")
+		}
+	}
+
+	for i, slot := range rotation.Slots {
+		if slot.ShiftTypeID == 0 {
+			continue
+		}
+		var shiftType models.ShiftType
+		if err := db.First(&shiftType, slot.ShiftTypeID).Error; err != nil {
+			errs.Addf(fmt.Sprintf("slots[%d].shift_type_id", i), models.CodeNotFound, "schichttyp %d nicht gefunden", slot.ShiftTypeID)
+		}
+	}
+
+	for i, crew := range rotation.Crews {
+		for j, member := range crew.Members {
+			if member.EmployeeID == 0 {
+				continue
+			}
+			field := fmt.Sprintf("crews[%d].members[%d].employee_id", i, j)
+			var employee models.Employee
+			if err := db.First(&employee, member.EmployeeID).Error; err != nil {
+				errs.Addf(field, models.CodeNotFound, "mitarbeiter %d nicht gefunden", member.EmployeeID)
+			} else if employee.DepartmentID == nil || *employee.DepartmentID != rotation.DepartmentID {
+				errs.Addf(field, models.CodeMismatch, "mitarbeiter %d gehört nicht zur abteilung der rotation", member.EmployeeID)
+			}
+		}
+	}
+
+	return errs.Err()
+}
@@ -0,0 +1,135 @@
+package handlers_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ptmmeiningen/schichtplaner/database"
+	"github.com/ptmmeiningen/schichtplaner/database/databasetest"
+	"github.com/ptmmeiningen/schichtplaner/handlers"
+	"github.com/ptmmeiningen/schichtplaner/models"
+	"github.com/ptmmeiningen/schichtplaner/pkg/responses"
+)
+
+func TestGenerateRotation(t *testing.T) {
+	app := setupApp(t)
+	department := databasetest.Department(t, "Produktion")
+	early := databasetest.ShiftType(t, "Früh", "06:00", "14:00")
+	late := databasetest.ShiftType(t, "Spät", "14:00", "22:00")
+	anna := databasetest.Employee(t, "anna@example.org", department.ID)
+	bert := databasetest.Employee(t, "bert@example.org", department.ID)
+
+	// KW 2 bis 4/2030; KW 3 ist mit einem Handeintrag geplant, KW 4 veröffentlicht
+	monday := time.Date(2030, 1, 7, 0, 0, 0, 0, time.UTC)
+	planned := models.ShiftWeek{Year: 2030, CalendarWeek: 3, Status: models.StatusDraft, DepartmentID: &department.ID}
+	databasetest.Create(t, &planned)
+	manual := models.ShiftDay{Date: monday.AddDate(0, 0, 8), ShiftWeekID: &planned.ID, ShiftTypeID: late.ID, EmployeeID: &bert.ID}
+	databasetest.Create(t, &manual)
+	published := models.ShiftWeek{Year: 2030, CalendarWeek: 4, Status: models.StatusPublished, DepartmentID: &department.ID}
+	databasetest.Create(t, &published)
+
+	// Anna hat dienstags verbindlich frei
+	tuesday := 1
+	databasetest.Create(t, &models.ShiftPreference{EmployeeID: anna.ID, Strength: models.PreferenceHard, Kind: models.PreferenceAvoid, WeekDay: &tuesday})
+
+	// Zykluswoche 0: Mo und Di Früh, Zykluswoche 1: Mo Spät.
+	// Gruppe B läuft eine Woche versetzt.
+	status, resp := call(t, app, "POST", "/api/v1/rotations", "", map[string]interface{}{
+		"name": "Wechselschicht", "department_id": department.ID, "cycle_weeks": 2, "start_date": monday,
+		"slots": []map[string]interface{}{
+			{"week": 0, "week_day": 0, "shift_type_id": early.ID},
+			{"week": 0, "week_day": 1, "shift_type_id": early.ID},
+			{"week": 1, "week_day": 0, "shift_type_id": late.ID},
+		},
+		"crews": []map[string]interface{}{
+			{"name": "A", "start_offset": 0, "members": []map[string]interface{}{{"employee_id": anna.ID}}},
+			{"name": "B", "start_offset": 1, "members": []map[string]interface{}{{"employee_id": bert.ID}}},
+		},
+	})
+	if status != 201 {
+		t.Fatalf("status %d, erwartet 201: %s %s", status, resp.Error, resp.Data)
+	}
+	var rotation models.Rotation
+	decode(t, resp, &rotation)
+
+	// Ein Tag der Rotation in der veröffentlichten Woche bleibt unangetastet
+	stale := models.ShiftDay{Date: monday.AddDate(0, 0, 14), ShiftWeekID: &published.ID, ShiftTypeID: early.ID, EmployeeID: &anna.ID, RotationID: &rotation.ID}
+	databasetest.Create(t, &stale)
+
+	generate := func() []handlers.RotationWeekResult {
+		t.Helper()
+		status, resp := call(t, app, "POST", fmt.Sprintf("/api/v1/rotations/%d/generate", rotation.ID), "", map[string]interface{}{
+			"from": "2030-01-07", "until": "2030-01-27",
+		})
+		if status != 200 {
+			t.Fatalf("status %d, erwartet 200: %s %s", status, resp.Error, resp.Data)
+		}
+		var results []handlers.RotationWeekResult
+		decode(t, resp, &results)
+		if len(results) != 3 {
+			t.Fatalf("%d wochen, erwartet 3: %+v", len(results), results)
+		}
+		return results
+	}
+
+	type shift struct {
+		date      string
+		employee  uint
+		shiftType uint
+	}
+	rotationDays := func() []shift {
+		t.Helper()
+		var days []models.ShiftDay
+		database.GetDB().Unscoped().Where("rotation_id = ?", rotation.ID).Order("date, employee_id").Find(&days)
+		result := make([]shift, len(days))
+		for i, day := range days {
+			if day.DeletedAt.Valid {
+				t.Fatalf("schichttag %d liegt im papierkorb", day.ID)
+			}
+			result[i] = shift{day.Date.Format("2006-01-02"), *day.EmployeeID, day.ShiftTypeID}
+		}
+		return result
+	}
+	want := []shift{
+		{"2030-01-07", anna.ID, early.ID},
+		{"2030-01-07", bert.ID, late.ID},
+		{"2030-01-14", anna.ID, late.ID},
+		{"2030-01-14", bert.ID, early.ID},
+		{"2030-01-21", anna.ID, early.ID},
+	}
+
+	for run, removed := range []int64{0, 2} {
+		results := generate()
+
+		// KW 2: Anna Früh, am Dienstag verbindlich frei; Bert versetzt Spät
+		if r := results[0]; r.CalendarWeek != 2 || r.ShiftWeekID == 0 || r.Created != 2 || r.Removed != removed || len(r.Skipped) != 1 ||
+			*r.Skipped[0].EmployeeID != anna.ID || !strings.Contains(r.Skipped[0].Reason, "verbindlichen wunsch") {
+			t.Errorf("lauf %d, kw 2: %+v", run, r)
+		}
+		// KW 3: Bert hat am Dienstag schon eine Schicht
+		if r := results[1]; r.CalendarWeek != 3 || r.ShiftWeekID != planned.ID || r.Created != 2 || r.Removed != removed || len(r.Skipped) != 1 ||
+			*r.Skipped[0].EmployeeID != bert.ID || !strings.Contains(r.Skipped[0].Reason, "bereits eine schicht") {
+			t.Errorf("lauf %d, kw 3: %+v", run, r)
+		}
+		// KW 4 ist veröffentlicht und wird nur gemeldet
+		if r := results[2]; r.CalendarWeek != 4 || r.ShiftWeekID != published.ID || r.Error != responses.ErrDraftOnly || r.Created != 0 || r.Removed != 0 {
+			t.Errorf("lauf %d, kw 4: %+v", run, r)
+		}
+
+		if got := rotationDays(); fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("lauf %d: schichttage %v, erwartet %v", run, got, want)
+		}
+	}
+
+	var count int64
+	database.GetDB().Model(&models.ShiftDay{}).Where("id = ?", manual.ID).Count(&count)
+	if count != 1 {
+		t.Fatal("handeintrag wurde beim erneuten erzeugen entfernt")
+	}
+	database.GetDB().Model(&models.ShiftWeek{}).Where("year = 2030 AND calendar_week = 2").Count(&count)
+	if count != 1 {
+		t.Fatalf("%d schichtwochen für kw 2, erwartet 1", count)
+	}
+}
@@ -1,46 +1,88 @@
 package handlers
 
 import (
+	"encoding/json"
 	"fmt"
+	"log"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/ptmmeiningen/schichtplaner/database"
 	"github.com/ptmmeiningen/schichtplaner/models"
+	"github.com/ptmmeiningen/schichtplaner/notifications"
+	"github.com/ptmmeiningen/schichtplaner/pkg/events"
+	"github.com/ptmmeiningen/schichtplaner/pkg/query"
 	"github.com/ptmmeiningen/schichtplaner/pkg/responses"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// shiftDayQuery legt die Filter und Sortierungen für Schichttag-Listen fest
+var shiftDayQuery = query.Config{
+	Table:    "shift_days",
+	Resource: "shift_day",
+	Fields:   shiftDayFields,
+	Filters: map[string]query.Filter{
+		"date_from":     {Condition: "shift_days.date >= ?", Parse: query.Date},
+		"date_to":       {Condition: "shift_days.date < ?", Parse: query.DateEnd},
+		"department_id": {Condition: "shift_days.shift_week_id IN (SELECT id FROM shift_weeks WHERE department_id = ? AND deleted_at IS NULL)", Parse: query.Int},
+		"employee_id":   {Condition: "shift_days.employee_id = ?", Parse: query.Int},
+		"location_id":   {Condition: "shift_days.shift_week_id IN (SELECT shift_weeks.id FROM shift_weeks JOIN departments ON departments.id = shift_weeks.department_id WHERE departments.location_id = ? AND shift_weeks.deleted_at IS NULL AND departments.deleted_at IS NULL)", Parse: query.Int},
+		"shift_type_id": {Condition: "shift_days.shift_type_id = ?", Parse: query.Int},
+		"shift_week_id": {Condition: "shift_days.shift_week_id = ?", Parse: query.Int},
+		"status":        {Condition: "shift_days.status = ?", Parse: query.String},
+		"team_id":       {Condition: "shift_days.shift_week_id IN (SELECT id FROM shift_weeks WHERE team_id = ? AND deleted_at IS NULL)", Parse: query.Int},
+	},
+	Sorts: map[string]string{
+		"date":          "shift_days.date",
+		"employee_id":   "shift_days.employee_id",
+		"shift_type_id": "shift_days.shift_type_id",
+		"status":        "shift_days.status",
+		"created_at":    "shift_days.created_at",
+	},
+	DefaultSort: "-date",
+	Includes: map[string]query.Include{
+		"shift_week": shiftWeekInclude(),
+		"shift_type": shiftTypeInclude(),
+		"employee":   employeeInclude(),
+	},
+	DefaultIncludes: []string{"shift_week.department", "shift_type", "employee"},
+}
+
 // @Summary Alle Schichttage abrufen
-// @Description Ruft alle Schichttage mit relevanten Beziehungen ab
+// @Description Ruft alle Schichttage mit relevanten Beziehungen ab, paginiert, gefiltert und sortiert
 // @Tags shiftdays
 // @Accept json
 // @Produce json
+// @Param page query int false "Seite (ab 1)"
+// @Param page_size query int false "Einträge pro Seite (max. 200)"
+// @Param cursor query string false "Cursor für die nächste Seite (statt page)"
+// @Param date_from query string false "Ab Datum (YYYY-MM-DD)"
+// @Param date_to query string false "Bis Datum einschließlich (YYYY-MM-DD)"
+// @Param department_id query int false "Abteilungs-ID"
+// @Param location_id query int false "Standort-ID"
+// @Param team_id query int false "Team-ID"
+// @Param employee_id query int false "Mitarbeiter-ID"
+// @Param shift_type_id query int false "Schichttyp-ID"
+// @Param shift_week_id query int false "Schichtwoche-ID"
+// @Param status query string false "Status"
+// @Param sort query string false "Sortierung, z.B. -date,employee_id"
+// @Param include query string false "Beziehungen, z.B. shift_type,employee,shift_week.department"
+// @Param fields[shift_day] query string false "Felder des Schichttags, z.B. id,date"
+// @Param fields[employee] query string false "Felder des Mitarbeiters, z.B. id,first_name,last_name"
 // @Success 200 {object} responses.APIResponse{data=[]models.ShiftDay}
-// @Failure 500 {object} responses.APIResponse
+// @Failure 400,500 {object} responses.APIResponse
 // @Router /api/v1/shiftdays [get]
 func HandleAllShiftDays(c *fiber.Ctx) error {
-	var shiftDays []models.ShiftDay
-	result := database.GetDB().
-		Preload("ShiftWeek", func(db *gorm.DB) *gorm.DB {
-			return db.Select("id, calendar_week, year, department_id, status").
-				Order("year DESC, calendar_week DESC")
-		}).
-		Preload("ShiftType", func(db *gorm.DB) *gorm.DB {
-			return db.Select("id, name, description, color").
-				Order("name")
-		}).
-		Preload("Employee", func(db *gorm.DB) *gorm.DB {
-			return db.Select("id, first_name, last_name, email, color, department_id").
-				Order("last_name, first_name")
-		}).
-		Preload("ShiftWeek.Department").
-		Order("date DESC").
-		Find(&shiftDays)
+	q, err := query.Parse(c, shiftDayQuery)
+	if err != nil {
+		return c.Status(400).JSON(responses.ErrorResponse(err.Error()))
+	}
 
-	if result.Error != nil {
-		return c.Status(500).JSON(responses.ErrorResponse(result.Error.Error()))
+	var shiftDays []models.ShiftDay
+	meta, err := q.Find(tenantDB(c), &shiftDays)
+	if err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
 	}
-	return c.JSON(responses.SuccessResponse(responses.MsgSuccessGet, shiftDays))
+	return c.JSON(responses.PaginatedResponse(responses.MsgSuccessGet, q.Shape(shiftDays), meta))
 }
 
 // @Summary Schichttag erstellen
@@ -51,6 +93,7 @@ func HandleAllShiftDays(c *fiber.Ctx) error {
 // @Param shiftday body models.ShiftDay true "Schichttag-Daten"
 // @Success 201 {object} responses.APIResponse{data=models.ShiftDay}
 // @Failure 400 {object} responses.APIResponse
+// @Failure 422 {object} responses.APIResponse{data=[]models.FieldError}
 // @Router /api/v1/shiftdays [post]
 func HandleCreateShiftDay(c *fiber.Ctx) error {
 	shiftDay := new(models.ShiftDay)
@@ -59,7 +102,7 @@ func HandleCreateShiftDay(c *fiber.Ctx) error {
 	}
 
 	var shiftWeek models.ShiftWeek
-	if err := database.GetDB().First(&shiftWeek, shiftDay.ShiftWeekID).Error; err != nil {
+	if err := tenantDB(c).First(&shiftWeek, shiftDay.ShiftWeekID).Error; err != nil {
 		return c.Status(404).JSON(responses.ErrorResponse("Schichtwoche nicht gefunden"))
 	}
 
@@ -67,21 +110,24 @@ func HandleCreateShiftDay(c *fiber.Ctx) error {
 		return c.Status(400).JSON(responses.ErrorResponse(responses.ErrDraftOnly))
 	}
 
-	if err := validateShiftDay(shiftDay); err != nil {
-		return c.Status(400).JSON(responses.ErrorResponse(err.Error()))
+	if err := validateShiftDay(tenantDB(c), shiftDay); err != nil {
+		return validationErrorResponse(c, err)
 	}
 
-	result := database.GetDB().Create(&shiftDay)
+	result := tenantDB(c).Create(&shiftDay)
 	if result.Error != nil {
 		return c.Status(500).JSON(responses.ErrorResponse(result.Error.Error()))
 	}
 
-	database.GetDB().
+	tenantDB(c).
 		Preload("ShiftWeek.Department").
 		Preload("ShiftType").
 		Preload("Employee").
 		First(&shiftDay, shiftDay.ID)
 
+	events.Publish(tenantID(c), events.ShiftDayCreated, shiftDay.ShiftWeek.DepartmentID, shiftDay)
+	notifyLeaveDecision(*shiftDay, models.ShiftDayPlanned)
+
 	return c.Status(201).JSON(responses.SuccessResponse(responses.MsgSuccessCreate, shiftDay))
 }
 
@@ -91,28 +137,31 @@ func HandleCreateShiftDay(c *fiber.Ctx) error {
 // @Accept json
 // @Produce json
 // @Param id path int true "Schichttag-ID"
+// @Param include query string false "Beziehungen, z.B. shift_type,employee,shift_week.department"
+// @Param fields[shift_day] query string false "Felder des Schichttags, z.B. id,date"
 // @Success 200 {object} responses.APIResponse{data=models.ShiftDay}
-// @Failure 404 {object} responses.APIResponse
+// @Failure 400,404 {object} responses.APIResponse
 // @Router /api/v1/shiftdays/{id} [get]
 func HandleGetOneShiftDay(c *fiber.Ctx) error {
 	id := c.Params("id")
 	var shiftDay models.ShiftDay
 
-	result := database.GetDB().
-		Preload("ShiftWeek.Department").
-		Preload("ShiftType").
-		Preload("Employee").
-		First(&shiftDay, id)
+	q, err := query.Parse(c, shiftDayQuery)
+	if err != nil {
+		return c.Status(400).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	result := q.Preload(tenantDB(c)).First(&shiftDay, id)
 
 	if result.Error != nil {
 		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
 	}
 
-	return c.JSON(responses.SuccessResponse(responses.MsgSuccessGet, shiftDay))
+	return c.JSON(responses.SuccessResponse(responses.MsgSuccessGet, q.Shape(shiftDay)))
 }
 
 // @Summary Schichttag aktualisieren
-// @Description Aktualisiert einen bestehenden Schichttag
+// @Description Aktualisiert einen bestehenden Schichttag. Wird eine Abwesenheit (sick, vacation, absent) eingetragen oder aufgehoben, erhält der Mitarbeiter eine Benachrichtigung.
 // @Tags shiftdays
 // @Accept json
 // @Produce json
@@ -120,12 +169,13 @@ func HandleGetOneShiftDay(c *fiber.Ctx) error {
 // @Param shiftday body models.ShiftDay true "Aktualisierte Schichttag-Daten"
 // @Success 200 {object} responses.APIResponse{data=models.ShiftDay}
 // @Failure 400,404 {object} responses.APIResponse
+// @Failure 422 {object} responses.APIResponse{data=[]models.FieldError}
 // @Router /api/v1/shiftdays/{id} [put]
 func HandleUpdateShiftDay(c *fiber.Ctx) error {
 	id := c.Params("id")
 	var shiftDay models.ShiftDay
 
-	if err := database.GetDB().
+	if err := tenantDB(c).
 		Preload("ShiftWeek").
 		First(&shiftDay, id).Error; err != nil {
 		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
@@ -135,24 +185,38 @@ func HandleUpdateShiftDay(c *fiber.Ctx) error {
 		return c.Status(400).JSON(responses.ErrorResponse(responses.ErrDraftOnly))
 	}
 
+	previousEmployeeID, previousStatus := copyID(shiftDay.EmployeeID), shiftDay.Status
+
 	if err := c.BodyParser(&shiftDay); err != nil {
 		return c.Status(400).JSON(responses.ErrorResponse(responses.ErrInvalidInput))
 	}
 
-	if err := validateShiftDay(&shiftDay); err != nil {
-		return c.Status(400).JSON(responses.ErrorResponse(err.Error()))
+	// Auch die Zielwoche muss ein Entwurf sein
+	if err := checkDraftShiftWeek(tenantDB(c), shiftDay.ShiftWeekID); err != nil {
+		return validationErrorResponse(c, err)
 	}
 
-	if err := database.GetDB().Save(&shiftDay).Error; err != nil {
+	if err := validateShiftDay(tenantDB(c), &shiftDay); err != nil {
+		return validationErrorResponse(c, err)
+	}
+
+	if err := tenantDB(c).Save(&shiftDay).Error; err != nil {
 		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
 	}
 
-	database.GetDB().
+	tenantDB(c).
 		Preload("ShiftWeek.Department").
 		Preload("ShiftType").
 		Preload("Employee").
 		First(&shiftDay, id)
 
+	events.Publish(tenantID(c), events.ShiftDayUpdated, shiftDay.ShiftWeek.DepartmentID, shiftDay)
+
+	if shiftDay.ShiftWeek.WasPublished() {
+		notifyShiftChanged(shiftDay, previousEmployeeID)
+	}
+	notifyLeaveDecision(shiftDay, previousStatus)
+
 	return c.JSON(responses.SuccessResponse(responses.MsgSuccessUpdate, shiftDay))
 }
 
@@ -169,7 +233,7 @@ func HandleDeleteShiftDay(c *fiber.Ctx) error {
 	id := c.Params("id")
 	var shiftDay models.ShiftDay
 
-	if err := database.GetDB().
+	if err := tenantDB(c).
 		Preload("ShiftWeek").
 		First(&shiftDay, id).Error; err != nil {
 		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
@@ -179,38 +243,274 @@ func HandleDeleteShiftDay(c *fiber.Ctx) error {
 		return c.Status(400).JSON(responses.ErrorResponse(responses.ErrDraftOnly))
 	}
 
-	if err := database.GetDB().Delete(&shiftDay).Error; err != nil {
+	if err := tenantDB(c).Delete(&shiftDay).Error; err != nil {
 		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
 	}
 
+	events.Publish(tenantID(c), events.ShiftDayDeleted, shiftDay.ShiftWeek.DepartmentID, fiber.Map{"id": shiftDay.ID})
+
+	if shiftDay.ShiftWeek.WasPublished() && shiftDay.EmployeeID != nil {
+		if err := notifications.NotifyShiftChanged(shiftDay, *shiftDay.EmployeeID, true); err != nil {
+			log.Printf("Fehler beim Benachrichtigen über Schichttag %d: %v", shiftDay.ID, err)
+		}
+	}
+
 	return c.JSON(responses.SuccessResponse(responses.MsgSuccessDelete, nil))
 }
 
+// maxBulkOperations begrenzt die Anzahl der Operationen je Sammelaufruf
+const maxBulkOperations = 500
+
+// Operationen eines Sammelaufrufs
+const (
+	BulkCreate = "create"
+	BulkUpdate = "update"
+	BulkDelete = "delete"
+)
+
+// BulkShiftDayOperation ist eine einzelne Operation eines Sammelaufrufs.
+// shift_day enthält bei create den neuen Schichttag, bei update die zu
+// ändernden Felder.
+type BulkShiftDayOperation struct {
+	Op       string          `json:"op" example:"create"`
+	ID       uint            `json:"id,omitempty"`
+	ShiftDay json.RawMessage `json:"shift_day,omitempty" swaggertype:"object"`
+}
+
+// BulkShiftDayInput enthält die Operationen in der Reihenfolge ihrer Ausführung
+type BulkShiftDayInput struct {
+	Operations []BulkShiftDayOperation `json:"operations"`
+}
+
+// BulkShiftDayResult ist das Ergebnis einer Operation
+type BulkShiftDayResult struct {
+	Index    int                     `json:"index"`
+	Op       string                  `json:"op"`
+	ID       uint                    `json:"id,omitempty"`
+	Success  bool                    `json:"success"`
+	Errors   models.ValidationErrors `json:"errors,omitempty"`
+	ShiftDay *models.ShiftDay        `json:"shift_day,omitempty"`
+}
+
+// bulkChange merkt sich eine ausgeführte Operation für Ereignisse und
+// Benachrichtigungen nach dem Commit
+type bulkChange struct {
+	op                 string
+	shiftDay           models.ShiftDay
+	previousEmployeeID *uint
+	previousStatus     string
+}
+
+// @Summary Schichttage gesammelt bearbeiten
+// @Description Führt create-, update- und delete-Operationen der Reihe nach in einer Transaktion aus. Jede Operation wird wie ein einzelner Aufruf geprüft, auch gegen die vorherigen Operationen. Ist eine ungültig, wird keine ausgeführt und die Antwort enthält das Ergebnis jeder Operation, Fehlerfelder mit Pfad wie operations[3].employee_id.
+// @Tags shiftdays
+// @Accept json
+// @Produce json
+// @Param operations body BulkShiftDayInput true "Operationen"
+// @Success 200 {object} responses.APIResponse{data=[]BulkShiftDayResult}
+// @Failure 400,500 {object} responses.APIResponse
+// @Failure 422 {object} responses.APIResponse{data=[]BulkShiftDayResult} "Abgelehnte Operationen, bei zu vielen Operationen []models.FieldError"
+// @Router /api/v1/shiftdays/bulk [post]
+func HandleBulkShiftDays(c *fiber.Ctx) error {
+	var input BulkShiftDayInput
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(responses.ErrorResponse(responses.ErrInvalidInput))
+	}
+	if len(input.Operations) == 0 || len(input.Operations) > maxBulkOperations {
+		return validationErrorResponse(c, models.NewValidationError("operations", models.CodeOutOfRange, fmt.Sprintf("es sind 1 bis %d operationen erlaubt", maxBulkOperations)))
+	}
+
+	results := make([]BulkShiftDayResult, len(input.Operations))
+	changes := make([]bulkChange, 0, len(input.Operations))
+	failed := false
+
+	tx := tenantDB(c).Begin()
+	for i, operation := range input.Operations {
+		results[i] = BulkShiftDayResult{Index: i, Op: operation.Op, ID: operation.ID}
+
+		// Jede Operation läuft in einem eigenen Sicherungspunkt, damit eine
+		// fehlgeschlagene Anweisung die Prüfung der übrigen nicht abbricht
+		savepoint := fmt.Sprintf("bulk_%d", i)
+		if err := tx.SavePoint(savepoint).Error; err != nil {
+			tx.Rollback()
+			return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+		}
+
+		change, err := applyBulkOperation(tx, operation)
+		if err != nil {
+			tx.RollbackTo(savepoint)
+			if err := results[i].Errors.Merge(fmt.Sprintf("operations[%d]", i), err); err != nil {
+				tx.Rollback()
+				return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+			}
+			failed = true
+			continue
+		}
+		results[i].Success = true
+		results[i].ID = change.shiftDay.ID
+		changes = append(changes, *change)
+	}
+
+	if failed {
+		tx.Rollback()
+		// Zurückgerollte Schichttage haben keine ID
+		for i := range results {
+			if results[i].Op == BulkCreate {
+				results[i].ID = 0
+			}
+		}
+		return c.Status(422).JSON(responses.ConflictResponse(responses.ErrBulkRejected, results))
+	}
+	if err := tx.Commit().Error; err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	for i, change := range changes {
+		if change.op == BulkDelete {
+			events.Publish(tenantID(c), events.ShiftDayDeleted, change.shiftDay.ShiftWeek.DepartmentID, fiber.Map{"id": change.shiftDay.ID})
+			if change.shiftDay.ShiftWeek.WasPublished() && change.shiftDay.EmployeeID != nil {
+				if err := notifications.NotifyShiftChanged(change.shiftDay, *change.shiftDay.EmployeeID, true); err != nil {
+					log.Printf("Fehler beim Benachrichtigen über Schichttag %d: %v", change.shiftDay.ID, err)
+				}
+			}
+			continue
+		}
+
+		shiftDay := change.shiftDay
+		tenantDB(c).
+			Preload("ShiftWeek.Department").
+			Preload("ShiftType").
+			Preload("Employee").
+			First(&shiftDay, shiftDay.ID)
+		results[i].ShiftDay = &shiftDay
+
+		if change.op == BulkCreate {
+			events.Publish(tenantID(c), events.ShiftDayCreated, shiftDay.ShiftWeek.DepartmentID, shiftDay)
+			notifyLeaveDecision(shiftDay, models.ShiftDayPlanned)
+			continue
+		}
+		events.Publish(tenantID(c), events.ShiftDayUpdated, shiftDay.ShiftWeek.DepartmentID, shiftDay)
+		if shiftDay.ShiftWeek.WasPublished() {
+			notifyShiftChanged(shiftDay, change.previousEmployeeID)
+		}
+		notifyLeaveDecision(shiftDay, change.previousStatus)
+	}
+
+	return c.JSON(responses.SuccessResponse(responses.MsgBulkApplied, results))
+}
+
+// applyBulkOperation prüft und schreibt eine Operation innerhalb von tx.
+// Es gelten dieselben Regeln wie für die einzelnen Endpunkte. Abgelehnte
+// Operationen liefern models.ValidationErrors mit Feldern der Operation.
+func applyBulkOperation(tx *gorm.DB, operation BulkShiftDayOperation) (*bulkChange, error) {
+	switch operation.Op {
+	case BulkCreate:
+		var shiftDay models.ShiftDay
+		if err := json.Unmarshal(operation.ShiftDay, &shiftDay); err != nil {
+			return nil, models.NewValidationError("shift_day", models.CodeInvalid, "ungültiger schichttag")
+		}
+		shiftDay.ID = 0
+
+		if err := checkDraftShiftWeek(tx, shiftDay.ShiftWeekID); err != nil {
+			return nil, err
+		}
+		if err := validateShiftDay(tx, &shiftDay); err != nil {
+			return nil, err
+		}
+		if err := tx.Omit(clause.Associations).Create(&shiftDay).Error; err != nil {
+			return nil, err
+		}
+		return &bulkChange{op: BulkCreate, shiftDay: shiftDay}, nil
+
+	case BulkUpdate, BulkDelete:
+		if operation.ID == 0 {
+			return nil, models.NewValidationError("id", models.CodeRequired, "id ist erforderlich")
+		}
+		var shiftDay models.ShiftDay
+		if err := tx.Preload("ShiftWeek").First(&shiftDay, operation.ID).Error; err != nil {
+			return nil, models.NewValidationError("id", models.CodeNotFound, "schichttag nicht gefunden")
+		}
+		if !shiftDay.CanBeModified() {
+			return nil, models.NewValidationError("id", models.CodeNotAllowed, "schichtwoche ist nicht im entwurfsmodus")
+		}
+
+		if operation.Op == BulkDelete {
+			if err := tx.Delete(&shiftDay).Error; err != nil {
+				return nil, err
+			}
+			return &bulkChange{op: BulkDelete, shiftDay: shiftDay}, nil
+		}
+
+		previousEmployeeID, previousStatus := copyID(shiftDay.EmployeeID), shiftDay.Status
+		if err := json.Unmarshal(operation.ShiftDay, &shiftDay); err != nil {
+			return nil, models.NewValidationError("shift_day", models.CodeInvalid, "ungültiger schichttag")
+		}
+		shiftDay.ID = operation.ID
+		// Auch die Zielwoche muss ein Entwurf sein
+		if err := checkDraftShiftWeek(tx, shiftDay.ShiftWeekID); err != nil {
+			return nil, err
+		}
+		if err := validateShiftDay(tx, &shiftDay); err != nil {
+			return nil, err
+		}
+		if err := tx.Omit(clause.Associations).Save(&shiftDay).Error; err != nil {
+			return nil, err
+		}
+		return &bulkChange{op: BulkUpdate, shiftDay: shiftDay, previousEmployeeID: previousEmployeeID, previousStatus: previousStatus}, nil
+	}
+
+	return nil, models.NewValidationError("op", models.CodeInvalid, "op muss create, update oder delete sein")
+}
+
+// checkDraftShiftWeek prüft, ob die Schichtwoche existiert und ein Entwurf ist
+func checkDraftShiftWeek(db *gorm.DB, shiftWeekID *uint) error {
+	if shiftWeekID == nil {
+		return nil
+	}
+	var shiftWeek models.ShiftWeek
+	if err := db.First(&shiftWeek, *shiftWeekID).Error; err != nil {
+		return models.NewValidationError("shift_week_id", models.CodeNotFound, "schichtwoche nicht gefunden")
+	}
+	if shiftWeek.Status != models.StatusDraft {
+		return models.NewValidationError("shift_week_id", models.CodeNotAllowed, "schichtwoche ist nicht im entwurfsmodus")
+	}
+	return nil
+}
+
 // @Summary Schichttage nach Woche abrufen
 // @Description Ruft alle Schichttage einer bestimmten Woche ab
 // @Tags shiftdays
 // @Accept json
 // @Produce json
 // @Param id path int true "Schichtwoche-ID"
+// @Param page query int false "Seite (ab 1)"
+// @Param page_size query int false "Einträge pro Seite (max. 200)"
+// @Param cursor query string false "Cursor für die nächste Seite (statt page)"
+// @Param sort query string false "Sortierung, Standard: date"
+// @Param include query string false "Beziehungen, z.B. shift_type,employee,shift_week.department"
+// @Param fields[shift_day] query string false "Felder des Schichttags, z.B. id,date"
+// @Param fields[employee] query string false "Felder des Mitarbeiters, z.B. id,first_name,last_name"
 // @Success 200 {object} responses.APIResponse{data=[]models.ShiftDay}
-// @Failure 404 {object} responses.APIResponse
+// @Failure 400,500 {object} responses.APIResponse
 // @Router /api/v1/shiftdays/week/{id} [get]
 func HandleGetShiftDaysByWeek(c *fiber.Ctx) error {
 	weekID := c.Params("id")
-	var shiftDays []models.ShiftDay
 
-	result := database.GetDB().
-		Where("shift_week_id = ?", weekID).
-		Preload("ShiftType").
-		Preload("Employee").
-		Order("date").
-		Find(&shiftDays)
+	config := shiftDayQuery
+	config.DefaultSort = "date"
+	config.DefaultIncludes = []string{"shift_type", "employee"}
+	q, err := query.Parse(c, config)
+	if err != nil {
+		return c.Status(400).JSON(responses.ErrorResponse(err.Error()))
+	}
 
-	if result.Error != nil {
-		return c.Status(500).JSON(responses.ErrorResponse(result.Error.Error()))
+	var shiftDays []models.ShiftDay
+	meta, err := q.Find(tenantDB(c).Where("shift_days.shift_week_id = ?", weekID), &shiftDays)
+	if err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
 	}
 
-	return c.JSON(responses.SuccessResponse(responses.MsgSuccessGet, shiftDays))
+	return c.JSON(responses.PaginatedResponse(responses.MsgSuccessGet, q.Shape(shiftDays), meta))
 }
 
 // @Summary Schichttage eines Mitarbeiters abrufen
@@ -219,25 +519,35 @@ func HandleGetShiftDaysByWeek(c *fiber.Ctx) error {
 // @Accept json
 // @Produce json
 // @Param id path int true "Mitarbeiter-ID"
+// @Param page query int false "Seite (ab 1)"
+// @Param page_size query int false "Einträge pro Seite (max. 200)"
+// @Param cursor query string false "Cursor für die nächste Seite (statt page)"
+// @Param date_from query string false "Ab Datum (YYYY-MM-DD)"
+// @Param date_to query string false "Bis Datum einschließlich (YYYY-MM-DD)"
+// @Param sort query string false "Sortierung, Standard: -date"
+// @Param include query string false "Beziehungen, z.B. shift_type,employee,shift_week.department"
+// @Param fields[shift_day] query string false "Felder des Schichttags, z.B. id,date"
+// @Param fields[employee] query string false "Felder des Mitarbeiters, z.B. id,first_name,last_name"
 // @Success 200 {object} responses.APIResponse{data=[]models.ShiftDay}
-// @Failure 404 {object} responses.APIResponse
+// @Failure 400,500 {object} responses.APIResponse
 // @Router /api/v1/shiftdays/employee/{id} [get]
 func HandleGetEmployeeShiftDays(c *fiber.Ctx) error {
 	employeeID := c.Params("id")
-	var shiftDays []models.ShiftDay
 
-	result := database.GetDB().
-		Where("employee_id = ?", employeeID).
-		Preload("ShiftWeek").
-		Preload("ShiftType").
-		Order("date DESC").
-		Find(&shiftDays)
+	config := shiftDayQuery
+	config.DefaultIncludes = []string{"shift_week", "shift_type"}
+	q, err := query.Parse(c, config)
+	if err != nil {
+		return c.Status(400).JSON(responses.ErrorResponse(err.Error()))
+	}
 
-	if result.Error != nil {
-		return c.Status(500).JSON(responses.ErrorResponse(result.Error.Error()))
+	var shiftDays []models.ShiftDay
+	meta, err := q.Find(tenantDB(c).Where("shift_days.employee_id = ?", employeeID), &shiftDays)
+	if err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
 	}
 
-	return c.JSON(responses.SuccessResponse(responses.MsgSuccessGet, shiftDays))
+	return c.JSON(responses.PaginatedResponse(responses.MsgSuccessGet, q.Shape(shiftDays), meta))
 }
 
 // @Summary Schichttage einer Abteilung abrufen
@@ -246,66 +556,137 @@ func HandleGetEmployeeShiftDays(c *fiber.Ctx) error {
 // @Accept json
 // @Produce json
 // @Param id path int true "Abteilungs-ID"
+// @Param page query int false "Seite (ab 1)"
+// @Param page_size query int false "Einträge pro Seite (max. 200)"
+// @Param cursor query string false "Cursor für die nächste Seite (statt page)"
+// @Param date_from query string false "Ab Datum (YYYY-MM-DD)"
+// @Param date_to query string false "Bis Datum einschließlich (YYYY-MM-DD)"
+// @Param sort query string false "Sortierung, Standard: -date"
+// @Param include query string false "Beziehungen, z.B. shift_type,employee,shift_week.department"
+// @Param fields[shift_day] query string false "Felder des Schichttags, z.B. id,date"
+// @Param fields[employee] query string false "Felder des Mitarbeiters, z.B. id,first_name,last_name"
 // @Success 200 {object} responses.APIResponse{data=[]models.ShiftDay}
-// @Failure 404 {object} responses.APIResponse
+// @Failure 400,500 {object} responses.APIResponse
 // @Router /api/v1/shiftdays/department/{id} [get]
 func HandleGetDepartmentShiftDays(c *fiber.Ctx) error {
 	departmentID := c.Params("id")
+
+	config := shiftDayQuery
+	config.DefaultIncludes = []string{"shift_week", "shift_type", "employee"}
+	q, err := query.Parse(c, config)
+	if err != nil {
+		return c.Status(400).JSON(responses.ErrorResponse(err.Error()))
+	}
+
 	var shiftDays []models.ShiftDay
+	weeks := tenantDB(c).Model(&models.ShiftWeek{}).Select("id").Where("department_id = ?", departmentID)
+	db := tenantDB(c).Where("shift_days.shift_week_id IN (?)", weeks)
 
-	result := database.GetDB().
-		Joins("JOIN shift_weeks ON shift_days.shift_week_id = shift_weeks.id").
-		Where("shift_weeks.department_id = ?", departmentID).
-		Preload("ShiftWeek").
-		Preload("ShiftType").
-		Preload("Employee").
-		Order("date DESC").
-		Find(&shiftDays)
+	meta, err := q.Find(db, &shiftDays)
+	if err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
 
-	if result.Error != nil {
-		return c.Status(500).JSON(responses.ErrorResponse(result.Error.Error()))
+	return c.JSON(responses.PaginatedResponse(responses.MsgSuccessGet, q.Shape(shiftDays), meta))
+}
+
+// copyID kopiert einen optionalen Verweis. BodyParser und json.Unmarshal
+// schreiben in einen vorhandenen Zeiger, ohne Kopie ginge der alte Wert
+// verloren.
+func copyID(id *uint) *uint {
+	if id == nil {
+		return nil
+	}
+	value := *id
+	return &value
+}
+
+// notifyShiftChanged benachrichtigt alten und neuen Mitarbeiter über eine geänderte Schicht
+func notifyShiftChanged(shiftDay models.ShiftDay, previousEmployeeID *uint) {
+	if previousEmployeeID != nil && (shiftDay.EmployeeID == nil || *shiftDay.EmployeeID != *previousEmployeeID) {
+		if err := notifications.NotifyShiftChanged(shiftDay, *previousEmployeeID, true); err != nil {
+			log.Printf("Fehler beim Benachrichtigen über Schichttag %d: %v", shiftDay.ID, err)
+		}
+	}
+
+	if shiftDay.EmployeeID != nil {
+		if err := notifications.NotifyShiftChanged(shiftDay, *shiftDay.EmployeeID, false); err != nil {
+			log.Printf("Fehler beim Benachrichtigen über Schichttag %d: %v", shiftDay.ID, err)
+		}
 	}
+}
 
-	return c.JSON(responses.SuccessResponse(responses.MsgSuccessGet, shiftDays))
+// notifyLeaveDecision benachrichtigt den Mitarbeiter, wenn für seinen
+// Schichttag eine Abwesenheit eingetragen, geändert oder aufgehoben wurde
+func notifyLeaveDecision(shiftDay models.ShiftDay, previousStatus string) {
+	if shiftDay.Status == previousStatus || (!isAbsence(shiftDay.Status) && !isAbsence(previousStatus)) {
+		return
+	}
+	if err := notifications.NotifyLeaveDecision(shiftDay, !isAbsence(shiftDay.Status)); err != nil {
+		log.Printf("Fehler beim Benachrichtigen über Schichttag %d: %v", shiftDay.ID, err)
+	}
 }
 
-func validateShiftDay(shiftDay *models.ShiftDay) error {
-	if shiftDay.Date.IsZero() {
-		return fmt.Errorf("datum ist erforderlich")
+// validateShiftDay prüft einen Schichttag gegen db, in Transaktionen gegen tx,
+// damit dort bereits geschriebene Schichttage berücksichtigt werden
+func validateShiftDay(db *gorm.DB, shiftDay *models.ShiftDay) error {
+	errs := models.ValidateModel(shiftDay)
+
+	var shiftType models.ShiftType
+	if shiftDay.ShiftTypeID != 0 {
+		if err := db.First(&shiftType, shiftDay.ShiftTypeID).Error; err != nil {
+			errs.Add("shift_type_id", models.CodeNotFound, "schichttyp nicht gefunden")
+		} else if err := errs.Merge("", models.ValidateBreakMinutes("break_minutes", shiftDay.BreakMinutes, shiftType.Duration())); err != nil {
+			return err
+		}
 	}
 
 	if shiftDay.ShiftWeekID == nil {
-		return fmt.Errorf("schichtwoche ist erforderlich")
+		return errs.Err()
+	}
+	var shiftWeek models.ShiftWeek
+	if err := db.First(&shiftWeek, shiftDay.ShiftWeekID).Error; err != nil {
+		errs.Add("shift_week_id", models.CodeNotFound, "schichtwoche nicht gefunden")
+		return errs.Err()
 	}
 
-	if shiftDay.ShiftTypeID == 0 {
-		return fmt.Errorf("schichttyp ist erforderlich")
+	if !shiftDay.Date.IsZero() {
+		year, week := shiftDay.Date.ISOWeek()
+		if year != shiftWeek.Year || week != shiftWeek.CalendarWeek {
+			errs.Add("date", models.CodeOutOfRange, "datum liegt außerhalb der schichtwoche")
+		}
 	}
 
-	var shiftWeek models.ShiftWeek
-	if err := database.GetDB().First(&shiftWeek, shiftDay.ShiftWeekID).Error; err != nil {
-		return fmt.Errorf("schichtwoche nicht gefunden")
+	if shiftDay.EmployeeID == nil {
+		return errs.Err()
+	}
+	var employee models.Employee
+	if err := db.First(&employee, shiftDay.EmployeeID).Error; err != nil {
+		errs.Add("employee_id", models.CodeNotFound, "mitarbeiter nicht gefunden")
+		return errs.Err()
 	}
 
-	year, week := shiftDay.Date.ISOWeek()
-	if year != shiftWeek.Year || week != shiftWeek.CalendarWeek {
-		return fmt.Errorf("datum liegt außerhalb der schichtwoche")
+	if !shiftDay.ValidateEmployeeDepartment(&employee, &shiftWeek) {
+		errs.Add("employee_id", models.CodeMismatch, "mitarbeiter muss zur gleichen abteilung wie die schichtwoche gehören")
+	} else if !shiftDay.ValidateEmployeeTeam(&employee, &shiftWeek) {
+		errs.Add("employee_id", models.CodeMismatch, "mitarbeiter muss zum team der schichtwoche gehören")
+	}
+	// Die weiteren Regeln brauchen ein gültiges Datum und einen Schichttyp
+	if len(errs) > 0 {
+		return errs.Err()
 	}
 
-	if shiftDay.EmployeeID != nil {
-		var employee models.Employee
-		if err := database.GetDB().First(&employee, shiftDay.EmployeeID).Error; err != nil {
-			return fmt.Errorf("mitarbeiter nicht gefunden")
-		}
+	if shiftDay.HasConflict(db) {
+		errs.Add("employee_id", models.CodeConflict, "mitarbeiter hat bereits eine schicht an diesem tag")
+	}
 
-		if !shiftDay.ValidateEmployeeDepartment(&employee, &shiftWeek) {
-			return fmt.Errorf("mitarbeiter muss zur gleichen abteilung wie die schichtwoche gehören")
-		}
+	if err := errs.Merge("", validateContractForShift(db, shiftDay)); err != nil {
+		return err
+	}
 
-		if shiftDay.HasConflict(database.GetDB()) {
-			return fmt.Errorf("mitarbeiter hat bereits eine schicht an diesem tag")
-		}
+	if err := errs.Merge("", checkYouthProtection(db, &employee, shiftDay)); err != nil {
+		return err
 	}
 
-	return nil
+	return errs.Err()
 }
@@ -0,0 +1,310 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ptmmeiningen/schichtplaner/database"
+	"github.com/ptmmeiningen/schichtplaner/database/databasetest"
+	"github.com/ptmmeiningen/schichtplaner/handlers"
+	"github.com/ptmmeiningen/schichtplaner/models"
+	"github.com/ptmmeiningen/schichtplaner/notifications"
+)
+
+func TestBulkShiftDaysRejectsWithFieldPaths(t *testing.T) {
+	app := setupApp(t)
+	department := databasetest.Department(t, "Produktion")
+	other := databasetest.Department(t, "Verwaltung")
+	shiftType := databasetest.ShiftType(t, "Früh", "06:00", "14:00")
+	employee := databasetest.Employee(t, "anna@example.org", department.ID)
+	stranger := databasetest.Employee(t, "bert@example.org", other.ID)
+	draft := models.ShiftWeek{Year: 2030, CalendarWeek: 2, Status: models.StatusDraft, DepartmentID: &department.ID}
+	databasetest.Create(t, &draft)
+	published := models.ShiftWeek{Year: 2030, CalendarWeek: 3, Status: models.StatusPublished, DepartmentID: &department.ID}
+	databasetest.Create(t, &published)
+
+	monday := time.Date(2030, 1, 7, 0, 0, 0, 0, time.UTC)
+	shiftDay := func(weekID, employeeID uint, date time.Time) map[string]interface{} {
+		return map[string]interface{}{"date": date, "shift_week_id": weekID, "shift_type_id": shiftType.ID, "employee_id": employeeID}
+	}
+
+	status, resp := call(t, app, "POST", "/api/v1/shiftdays/bulk", "", map[string]interface{}{
+		"operations": []map[string]interface{}{
+			{"op": "create", "shift_day": shiftDay(draft.ID, employee.ID, monday)},
+			{"op": "create", "shift_day": shiftDay(draft.ID, stranger.ID, monday)},
+			{"op": "create", "shift_day": shiftDay(published.ID, employee.ID, monday.AddDate(0, 0, 7))},
+			{"op": "update"},
+			{"op": "delete", "id": 9999},
+			{"op": "move"},
+		},
+	})
+	if status != 422 {
+		t.Fatalf("status %d, erwartet 422: %s", status, resp.Data)
+	}
+
+	var results []handlers.BulkShiftDayResult
+	decode(t, resp, &results)
+	want := []struct {
+		success bool
+		field   string
+		code    string
+	}{
+		{success: true},
+		{field: "operations[1].employee_id", code: models.CodeMismatch},
+		{field: "operations[2].shift_week_id", code: models.CodeNotAllowed},
+		{field: "operations[3].id", code: models.CodeRequired},
+		{field: "operations[4].id", code: models.CodeNotFound},
+		{field: "operations[5].op", code: models.CodeInvalid},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("%d ergebnisse, erwartet %d", len(results), len(want))
+	}
+	for i, w := range want {
+		result := results[i]
+		if result.Index != i || result.Success != w.success {
+			t.Errorf("operation %d: %+v", i, result)
+			continue
+		}
+		if w.success {
+			if result.ID != 0 || len(result.Errors) != 0 {
+				t.Errorf("operation %d: zurückgerollte anlage meldet %+v", i, result)
+			}
+			continue
+		}
+		if len(result.Errors) != 1 || result.Errors[0].Field != w.field || result.Errors[0].Code != w.code {
+			t.Errorf("operation %d: fehler %+v, erwartet %s %s", i, result.Errors, w.field, w.code)
+		}
+	}
+
+	var count int64
+	database.GetDB().Model(&models.ShiftDay{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("%d schichttage trotz abgelehnter operationen angelegt", count)
+	}
+
+	// Dieselbe gültige Operation allein wird ausgeführt
+	status, resp = call(t, app, "POST", "/api/v1/shiftdays/bulk", "", map[string]interface{}{
+		"operations": []map[string]interface{}{
+			{"op": "create", "shift_day": shiftDay(draft.ID, employee.ID, monday)},
+		},
+	})
+	if status != 200 {
+		t.Fatalf("status %d, erwartet 200: %s %s", status, resp.Error, resp.Data)
+	}
+	var createdResults []handlers.BulkShiftDayResult
+	decode(t, resp, &createdResults)
+	created := createdResults[0].ID
+
+	// Eine Änderung darf den Tag nicht in eine veröffentlichte Woche verschieben
+	status, resp = call(t, app, "POST", "/api/v1/shiftdays/bulk", "", map[string]interface{}{
+		"operations": []map[string]interface{}{
+			{"op": "update", "id": created, "shift_day": shiftDay(published.ID, employee.ID, monday.AddDate(0, 0, 7))},
+		},
+	})
+	if status != 422 {
+		t.Fatalf("status %d, erwartet 422: %s", status, resp.Data)
+	}
+	var moved []handlers.BulkShiftDayResult
+	decode(t, resp, &moved)
+	if len(moved) != 1 || len(moved[0].Errors) != 1 || moved[0].Errors[0].Field != "operations[0].shift_week_id" || moved[0].Errors[0].Code != models.CodeNotAllowed {
+		t.Fatalf("ergebnisse %+v", moved)
+	}
+	status, resp = call(t, app, "PUT", fmt.Sprintf("/api/v1/shiftdays/%d", created), "", shiftDay(published.ID, employee.ID, monday.AddDate(0, 0, 7)))
+	expectFieldErrors(t, status, resp, models.FieldError{Field: "shift_week_id", Code: models.CodeNotAllowed})
+
+	var stored models.ShiftDay
+	database.GetDB().First(&stored, created)
+	if stored.ShiftWeekID == nil || *stored.ShiftWeekID != draft.ID {
+		t.Fatalf("schichttag in woche %v verschoben", stored.ShiftWeekID)
+	}
+
+	status, resp = call(t, app, "POST", "/api/v1/shiftdays/bulk", "", map[string]interface{}{"operations": []interface{}{}})
+	expectFieldErrors(t, status, resp, models.FieldError{Field: "operations", Code: models.CodeOutOfRange})
+}
+
+// discardChannel versendet nichts. Registriert sorgt er dafür, dass
+// Benachrichtigungen im Postausgang landen.
+type discardChannel struct{}
+
+func (discardChannel) Name() string                     { return models.ChannelEmail }
+func (discardChannel) Send(notifications.Message) error { return nil }
+
+func TestShiftDayAbsenceNotifiesEmployee(t *testing.T) {
+	app := setupApp(t)
+	notifications.RegisterChannel(discardChannel{})
+	department := databasetest.Department(t, "Produktion")
+	shiftType := databasetest.ShiftType(t, "Früh", "06:00", "14:00")
+	employee := databasetest.Employee(t, "anna@example.org", department.ID)
+	optedOut := databasetest.Employee(t, "bert@example.org", department.ID)
+	preference := models.DefaultNotificationPreference(optedOut.ID)
+	preference.Decisions = false
+	databasetest.Create(t, &preference)
+	week := models.ShiftWeek{Year: 2030, CalendarWeek: 2, Status: models.StatusDraft, DepartmentID: &department.ID}
+	databasetest.Create(t, &week)
+
+	monday := time.Date(2030, 1, 7, 0, 0, 0, 0, time.UTC)
+	createShiftDay := func(employee models.Employee, date time.Time, dayStatus string) models.ShiftDay {
+		t.Helper()
+		status, resp := call(t, app, "POST", "/api/v1/shiftdays", "", map[string]interface{}{
+			"date": date, "shift_week_id": week.ID, "shift_type_id": shiftType.ID, "employee_id": employee.ID, "status": dayStatus,
+		})
+		if status != 201 {
+			t.Fatalf("status %d, %s %s", status, resp.Error, resp.Data)
+		}
+		var shiftDay models.ShiftDay
+		decode(t, resp, &shiftDay)
+		return shiftDay
+	}
+	updateStatus := func(shiftDay models.ShiftDay, dayStatus string) {
+		t.Helper()
+		path := fmt.Sprintf("/api/v1/shiftdays/%d", shiftDay.ID)
+		if status, resp := call(t, app, "PUT", path, "", map[string]string{"status": dayStatus}); status != 200 {
+			t.Fatalf("status %d, %s %s", status, resp.Error, resp.Data)
+		}
+	}
+	expectSubjects := func(employee models.Employee, want ...string) {
+		t.Helper()
+		var subjects []string
+		database.GetDB().Model(&models.Notification{}).
+			Where("employee_id = ? AND kind = ?", employee.ID, models.NotificationLeaveDecision).
+			Order("id").
+			Pluck("subject", &subjects)
+		if strings.Join(subjects, "|") != strings.Join(want, "|") {
+			t.Fatalf("betreffs %q, erwartet %q", subjects, want)
+		}
+	}
+
+	planned := createShiftDay(employee, monday, models.ShiftDayPlanned)
+	expectSubjects(employee)
+
+	updateStatus(planned, models.ShiftDayVacation)
+	// Wechsel zwischen zwei Abwesenheiten
+	updateStatus(planned, models.ShiftDaySick)
+	updateStatus(planned, models.ShiftDayPlanned)
+	createShiftDay(employee, monday.AddDate(0, 0, 1), models.ShiftDayVacation)
+	expectSubjects(employee,
+		"Urlaub am 07.01.2030 eingetragen",
+		"Krankheit am 07.01.2030 eingetragen",
+		"Abwesenheit am 07.01.2030 aufgehoben",
+		"Urlaub am 08.01.2030 eingetragen",
+	)
+
+	status, resp := call(t, app, "POST", "/api/v1/shiftdays/bulk", "", map[string]interface{}{
+		"operations": []map[string]interface{}{
+			{"op": "update", "id": planned.ID, "shift_day": map[string]string{"status": models.ShiftDayAbsent}},
+		},
+	})
+	if status != 200 {
+		t.Fatalf("status %d, %s %s", status, resp.Error, resp.Data)
+	}
+	var notification models.Notification
+	if err := database.GetDB().Where("kind = ?", models.NotificationLeaveDecision).Last(&notification).Error; err != nil {
+		t.Fatal(err)
+	}
+	if notification.Subject != "Abwesenheit am 07.01.2030 eingetragen" || !strings.Contains(notification.BodyText, "Die Schicht Früh entfällt für dich.") {
+		t.Fatalf("benachrichtigung %q: %s", notification.Subject, notification.BodyText)
+	}
+
+	updateStatus(createShiftDay(optedOut, monday, models.ShiftDayPlanned), models.ShiftDayVacation)
+	expectSubjects(optedOut)
+}
+
+func TestShiftDayReassignmentNotifiesBothEmployees(t *testing.T) {
+	app := setupApp(t)
+	notifications.RegisterChannel(discardChannel{})
+	department := databasetest.Department(t, "Produktion")
+	shiftType := databasetest.ShiftType(t, "Früh", "06:00", "14:00")
+	anna := databasetest.Employee(t, "anna@example.org", department.ID)
+	bert := databasetest.Employee(t, "bert@example.org", department.ID)
+	carl := databasetest.Employee(t, "carl@example.org", department.ID)
+	// Veröffentlicht und zur Änderung wieder in den Entwurf gesetzt
+	publishedAt := time.Now()
+	week := models.ShiftWeek{Year: 2030, CalendarWeek: 2, Status: models.StatusDraft, DepartmentID: &department.ID, PublishedAt: &publishedAt}
+	databasetest.Create(t, &week)
+
+	monday := time.Date(2030, 1, 7, 0, 0, 0, 0, time.UTC)
+	shiftDay := models.ShiftDay{Date: monday, ShiftWeekID: &week.ID, ShiftTypeID: shiftType.ID, EmployeeID: &anna.ID}
+	databasetest.Create(t, &shiftDay)
+
+	expectNotified := func(employee models.Employee, want int64) {
+		t.Helper()
+		var count int64
+		database.GetDB().Model(&models.Notification{}).
+			Where("employee_id = ? AND kind = ?", employee.ID, models.NotificationShiftChanged).
+			Count(&count)
+		if count != want {
+			t.Fatalf("%s: %d benachrichtigungen, erwartet %d", employee.Email, count, want)
+		}
+	}
+
+	path := fmt.Sprintf("/api/v1/shiftdays/%d", shiftDay.ID)
+	if status, resp := call(t, app, "PUT", path, "", map[string]interface{}{"employee_id": bert.ID}); status != 200 {
+		t.Fatalf("status %d, %s %s", status, resp.Error, resp.Data)
+	}
+	expectNotified(anna, 1)
+	expectNotified(bert, 1)
+
+	status, resp := call(t, app, "POST", "/api/v1/shiftdays/bulk", "", map[string]interface{}{
+		"operations": []map[string]interface{}{
+			{"op": "update", "id": shiftDay.ID, "shift_day": map[string]interface{}{"employee_id": carl.ID}},
+		},
+	})
+	if status != 200 {
+		t.Fatalf("status %d, %s %s", status, resp.Error, resp.Data)
+	}
+	expectNotified(bert, 2)
+	expectNotified(carl, 1)
+}
+
+func TestShiftDayIncludesAndFields(t *testing.T) {
+	app := setupApp(t)
+	department := databasetest.Department(t, "Produktion")
+	shiftType := databasetest.ShiftType(t, "Früh", "06:00", "14:00")
+	employee := databasetest.Employee(t, "anna@example.org", department.ID)
+	week := models.ShiftWeek{Year: 2030, CalendarWeek: 2, Status: models.StatusDraft, DepartmentID: &department.ID}
+	databasetest.Create(t, &week)
+	databasetest.Create(t, &models.ShiftDay{Date: time.Date(2030, 1, 7, 0, 0, 0, 0, time.UTC), ShiftWeekID: &week.ID, ShiftTypeID: shiftType.ID, EmployeeID: &employee.ID})
+
+	for _, path := range []string{
+		"/api/v1/shiftdays?include=rotation",
+		"/api/v1/shiftdays?include=shift_week.location",
+		"/api/v1/shiftdays?fields[shift_day]=id,password",
+		"/api/v1/shiftdays?fields[employee]=id,password",
+	} {
+		if status, resp := call(t, app, "GET", path, "", nil); status != 400 {
+			t.Errorf("%s: status %d, erwartet 400: %s", path, status, resp.Error)
+		}
+	}
+
+	status, resp := call(t, app, "GET", "/api/v1/shiftdays?include=shift_week.department,employee&fields[shift_day]=id,date&fields[employee]=id,last_name&fields[department]=name", "", nil)
+	if status != 200 {
+		t.Fatalf("status %d, %s", status, resp.Error)
+	}
+	var shiftDays []map[string]json.RawMessage
+	decode(t, resp, &shiftDays)
+	if len(shiftDays) != 1 {
+		t.Fatalf("%d schichttage", len(shiftDays))
+	}
+	var shiftDay struct {
+		ID        uint `json:"id"`
+		ShiftWeek struct {
+			ID         uint                       `json:"id"`
+			Department map[string]json.RawMessage `json:"department"`
+		} `json:"shift_week"`
+		Employee map[string]json.RawMessage `json:"employee"`
+	}
+	raw, _ := json.Marshal(shiftDays[0])
+	json.Unmarshal(raw, &shiftDay)
+	if len(shiftDays[0]) != 4 || shiftDays[0]["notes"] != nil || shiftDays[0]["shift_type"] != nil {
+		t.Fatalf("schichttag enthält %s", raw)
+	}
+	if shiftDay.ShiftWeek.ID != week.ID || string(shiftDay.ShiftWeek.Department["name"]) != `"Produktion"` || len(shiftDay.ShiftWeek.Department) != 1 {
+		t.Fatalf("schichtwoche %s", raw)
+	}
+	if len(shiftDay.Employee) != 2 || shiftDay.Employee["email"] != nil {
+		t.Fatalf("mitarbeiter %s", raw)
+	}
+}
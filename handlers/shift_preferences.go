@@ -0,0 +1,310 @@
+package handlers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ptmmeiningen/schichtplaner/database"
+	"github.com/ptmmeiningen/schichtplaner/models"
+	"github.com/ptmmeiningen/schichtplaner/pkg/responses"
+	"gorm.io/gorm"
+)
+
+// @Summary Schichtwünsche eines Mitarbeiters abrufen
+// @Description Ruft alle Schichtwünsche eines Mitarbeiters ab
+// @Tags shiftpreferences
+// @Accept json
+// @Produce json
+// @Param id path int true "Mitarbeiter-ID"
+// @Success 200 {object} responses.APIResponse{data=[]models.ShiftPreference}
+// @Failure 404,500 {object} responses.APIResponse
+// @Router /api/v1/employees/{id}/shift-preferences [get]
+func HandleAllShiftPreferences(c *fiber.Ctx) error {
+	var employee models.Employee
+	if err := tenantDB(c).First(&employee, c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
+	}
+
+	var preferences []models.ShiftPreference
+	if err := tenantDB(c).
+		Preload("ShiftType").
+		Where("employee_id = ?", employee.ID).
+		Order("date, week_day, id").
+		Find(&preferences).Error; err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	return c.JSON(responses.SuccessResponse(responses.MsgSuccessGet, preferences))
+}
+
+// @Summary Schichtwunsch erstellen
+// @Description Legt einen Schichtwunsch an, z.B. "keine Nacht am Freitag" (hard, avoid, week_day 4, Nachtschicht), "lieber Frühschicht" (soft, prefer) oder "frei am 24.12." (hard, avoid, date). Harte Wünsche werden bei automatischer Planung eingehalten.
+// @Tags shiftpreferences
+// @Accept json
+// @Produce json
+// @Param id path int true "Mitarbeiter-ID"
+// @Param preference body models.ShiftPreference true "Schichtwunsch"
+// @Success 201 {object} responses.APIResponse{data=models.ShiftPreference}
+// @Failure 400,404,500 {object} responses.APIResponse
+// @Failure 422 {object} responses.APIResponse{data=[]models.FieldError}
+// @Router /api/v1/employees/{id}/shift-preferences [post]
+func HandleCreateShiftPreference(c *fiber.Ctx) error {
+	var employee models.Employee
+	if err := tenantDB(c).First(&employee, c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
+	}
+
+	preference := new(models.ShiftPreference)
+	if err := c.BodyParser(preference); err != nil {
+		return c.Status(400).JSON(responses.ErrorResponse(responses.ErrInvalidInput))
+	}
+	preference.EmployeeID = employee.ID
+
+	if err := validateShiftPreference(tenantDB(c), preference); err != nil {
+		return validationErrorResponse(c, err)
+	}
+
+	if err := tenantDB(c).Omit("Employee", "ShiftType").Create(preference).Error; err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	return c.Status(201).JSON(responses.SuccessResponse(responses.MsgSuccessCreate, preference))
+}
+
+// @Summary Schichtwunsch aktualisieren
+// @Description Aktualisiert einen Schichtwunsch eines Mitarbeiters
+// @Tags shiftpreferences
+// @Accept json
+// @Produce json
+// @Param id path int true "Mitarbeiter-ID"
+// @Param preferenceId path int true "Wunsch-ID"
+// @Param preference body models.ShiftPreference true "Aktualisierter Schichtwunsch"
+// @Success 200 {object} responses.APIResponse{data=models.ShiftPreference}
+// @Failure 400,404,500 {object} responses.APIResponse
+// @Failure 422 {object} responses.APIResponse{data=[]models.FieldError}
+// @Router /api/v1/employees/{id}/shift-preferences/{preferenceId} [put]
+func HandleUpdateShiftPreference(c *fiber.Ctx) error {
+	var preference models.ShiftPreference
+	if err := tenantDB(c).
+		Where("employee_id = ?", c.Params("id")).
+		First(&preference, c.Params("preferenceId")).Error; err != nil {
+		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
+	}
+
+	employeeID := preference.EmployeeID
+	preference.WeekDay = nil
+	preference.Date = nil
+	preference.ShiftTypeID = nil
+	if err := c.BodyParser(&preference); err != nil {
+		return c.Status(400).JSON(responses.ErrorResponse(responses.ErrInvalidInput))
+	}
+	preference.EmployeeID = employeeID
+
+	if err := validateShiftPreference(tenantDB(c), &preference); err != nil {
+		return validationErrorResponse(c, err)
+	}
+
+	if err := tenantDB(c).Omit("Employee", "ShiftType").Save(&preference).Error; err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	return c.JSON(responses.SuccessResponse(responses.MsgSuccessUpdate, preference))
+}
+
+// @Summary Schichtwunsch löschen
+// @Description Löscht einen Schichtwunsch eines Mitarbeiters
+// @Tags shiftpreferences
+// @Accept json
+// @Produce json
+// @Param id path int true "Mitarbeiter-ID"
+// @Param preferenceId path int true "Wunsch-ID"
+// @Success 200 {object} responses.APIResponse
+// @Failure 404,500 {object} responses.APIResponse
+// @Router /api/v1/employees/{id}/shift-preferences/{preferenceId} [delete]
+func HandleDeleteShiftPreference(c *fiber.Ctx) error {
+	var preference models.ShiftPreference
+	if err := tenantDB(c).
+		Where("employee_id = ?", c.Params("id")).
+		First(&preference, c.Params("preferenceId")).Error; err != nil {
+		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
+	}
+
+	tx := tenantDB(c).Begin()
+
+	if err := database.SoftDelete(tx, "shift_preferences", preference.ID); err != nil {
+		tx.Rollback()
+		return deleteErrorResponse(c, err)
+	}
+
+	tx.Commit()
+
+	return c.JSON(responses.SuccessResponse(responses.MsgSuccessDelete, nil))
+}
+
+func validateShiftPreference(db *gorm.DB, preference *models.ShiftPreference) error {
+	if preference.Strength == "" {
+		preference.Strength = models.PreferenceSoft
+	}
+	if preference.Date != nil {
+		date := time.Date(preference.Date.Year(), preference.Date.Month(), preference.Date.Day(), 0, 0, 0, 0, time.UTC)
+		preference.Date = &date
+	}
+	errs := models.ValidateModel(preference)
+
+	if preference.ShiftTypeID != nil {
+		var shiftType models.ShiftType
+		if err := db.First(&shiftType, *preference.ShiftTypeID).Error; err != nil {
+			errs.Add("shift_type_id", models.CodeNotFound, "schichttyp nicht gefunden")
+		}
+	}
+
+	return errs.Err()
+}
+
+// checkHardPreferences prüft einen automatisch zugeordneten Schichttag gegen
+// die harten Wünsche des Mitarbeiters. Weiche Wünsche werden nur in der
+// Statistik der Schichtwoche ausgewertet.
+func checkHardPreferences(db *gorm.DB, shiftDay *models.ShiftDay) error {
+	if shiftDay.EmployeeID == nil {
+		return nil
+	}
+
+	var preferences []models.ShiftPreference
+	if err := db.Where("employee_id = ? AND strength = ? AND kind = ?", *shiftDay.EmployeeID, models.PreferenceHard, models.PreferenceAvoid).
+		Find(&preferences).Error; err != nil {
+		return err
+	}
+
+	for _, preference := range preferences {
+		if preference.Matches(shiftDay.Date, shiftDay.ShiftTypeID) {
+			return fmt.Errorf("widerspricht dem verbindlichen wunsch %d des mitarbeiters", preference.ID)
+		}
+	}
+	return nil
+}
+
+// PreferenceViolation ist ein Schichttag, der einem harten Wunsch widerspricht
+type PreferenceViolation struct {
+	ShiftDayID   uint `json:"shift_day_id"`
+	EmployeeID   uint `json:"employee_id"`
+	PreferenceID uint `json:"preference_id"`
+}
+
+// PreferenceFulfillment zählt erfüllte und verletzte Wünsche
+type PreferenceFulfillment struct {
+	Fulfilled int `json:"fulfilled"`
+	Violated  int `json:"violated"`
+}
+
+// WishFulfillment fasst die Erfüllung der Schichtwünsche einer Woche zusammen.
+// Ein "avoid"-Wunsch zählt je betroffenem Tag, ein "prefer"-Wunsch je
+// Schicht an einem passenden Tag.
+type WishFulfillment struct {
+	PreferenceFulfillment
+	Rate           *float64                        `json:"rate"`
+	HardViolations []PreferenceViolation           `json:"hard_violations"`
+	PerEmployee    map[uint]*PreferenceFulfillment `json:"per_employee"`
+}
+
+// evaluatePreferences wertet die Wünsche der Mitarbeiter der Abteilung und
+// aller eingeplanten Mitarbeiter für die Woche ab monday aus
+func evaluatePreferences(db *gorm.DB, shiftWeek models.ShiftWeek, monday time.Time) (*WishFulfillment, error) {
+	fulfillment := &WishFulfillment{
+		HardViolations: []PreferenceViolation{},
+		PerEmployee:    map[uint]*PreferenceFulfillment{},
+	}
+
+	shiftsByEmployee := map[uint][]models.ShiftDay{}
+	for _, day := range shiftWeek.ShiftDays {
+		if day.EmployeeID != nil {
+			shiftsByEmployee[*day.EmployeeID] = append(shiftsByEmployee[*day.EmployeeID], day)
+		}
+	}
+
+	employeeIDs := make([]uint, 0, len(shiftsByEmployee))
+	for employeeID := range shiftsByEmployee {
+		employeeIDs = append(employeeIDs, employeeID)
+	}
+	if shiftWeek.DepartmentID != nil {
+		var departmentIDs []uint
+		if err := db.Model(&models.Employee{}).Where("department_id = ?", *shiftWeek.DepartmentID).Pluck("id", &departmentIDs).Error; err != nil {
+			return nil, err
+		}
+		for _, employeeID := range departmentIDs {
+			if _, ok := shiftsByEmployee[employeeID]; !ok {
+				employeeIDs = append(employeeIDs, employeeID)
+			}
+		}
+	}
+	if len(employeeIDs) == 0 {
+		return fulfillment, nil
+	}
+
+	var preferences []models.ShiftPreference
+	if err := db.Where("employee_id IN ? AND (date IS NULL OR (date >= ? AND date < ?))", employeeIDs, monday, monday.AddDate(0, 0, 7)).
+		Order("id").
+		Find(&preferences).Error; err != nil {
+		return nil, err
+	}
+
+	for _, preference := range preferences {
+		employee := fulfillment.PerEmployee[preference.EmployeeID]
+		if employee == nil {
+			employee = &PreferenceFulfillment{}
+			fulfillment.PerEmployee[preference.EmployeeID] = employee
+		}
+		shifts := shiftsByEmployee[preference.EmployeeID]
+
+		if preference.Kind == models.PreferencePrefer {
+			for _, shift := range shifts {
+				if !preference.AppliesTo(shift.Date) {
+					continue
+				}
+				if preference.Matches(shift.Date, shift.ShiftTypeID) {
+					employee.Fulfilled++
+				} else {
+					employee.Violated++
+				}
+			}
+			continue
+		}
+
+		for i := 0; i < 7; i++ {
+			date := monday.AddDate(0, 0, i)
+			if !preference.AppliesTo(date) {
+				continue
+			}
+			violated := false
+			for _, shift := range shifts {
+				if shift.Date.Format("2006-01-02") != date.Format("2006-01-02") || !preference.Matches(shift.Date, shift.ShiftTypeID) {
+					continue
+				}
+				violated = true
+				if preference.IsHard() {
+					fulfillment.HardViolations = append(fulfillment.HardViolations, PreferenceViolation{
+						ShiftDayID:   shift.ID,
+						EmployeeID:   preference.EmployeeID,
+						PreferenceID: preference.ID,
+					})
+				}
+			}
+			if violated {
+				employee.Violated++
+			} else {
+				employee.Fulfilled++
+			}
+		}
+	}
+
+	for _, employee := range fulfillment.PerEmployee {
+		fulfillment.Fulfilled += employee.Fulfilled
+		fulfillment.Violated += employee.Violated
+	}
+	if total := fulfillment.Fulfilled + fulfillment.Violated; total > 0 {
+		rate := float64(fulfillment.Fulfilled) / float64(total)
+		fulfillment.Rate = &rate
+	}
+
+	return fulfillment, nil
+}
@@ -0,0 +1,126 @@
+package handlers_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ptmmeiningen/schichtplaner/database/databasetest"
+	"github.com/ptmmeiningen/schichtplaner/handlers"
+	"github.com/ptmmeiningen/schichtplaner/models"
+)
+
+func TestShiftPreferences(t *testing.T) {
+	app := setupApp(t)
+	department := databasetest.Department(t, "Produktion")
+	early := databasetest.ShiftType(t, "Früh", "06:00", "14:00")
+	night := databasetest.ShiftType(t, "Nacht", "22:00", "06:00")
+	anna := databasetest.Employee(t, "anna@example.org", department.ID)
+	bert := databasetest.Employee(t, "bert@example.org", department.ID)
+	carl := databasetest.Employee(t, "carl@example.org", department.ID)
+
+	// KW 2/2030 beginnt am Montag, 07.01.
+	monday := time.Date(2030, 1, 7, 0, 0, 0, 0, time.UTC)
+	week := models.ShiftWeek{Year: 2030, CalendarWeek: 2, Status: models.StatusDraft, DepartmentID: &department.ID}
+	databasetest.Create(t, &week)
+	shift := func(employee models.Employee, shiftType models.ShiftType, day int) models.ShiftDay {
+		shiftDay := models.ShiftDay{Date: monday.AddDate(0, 0, day), ShiftWeekID: &week.ID, ShiftTypeID: shiftType.ID, EmployeeID: &employee.ID}
+		databasetest.Create(t, &shiftDay)
+		return shiftDay
+	}
+	shift(anna, early, 0)
+	shift(anna, night, 2)
+	shift(anna, early, 4)
+	shift(bert, night, 0)
+	bertNight := shift(bert, night, 1)
+
+	weekDay := func(day int) *int { return &day }
+	date := func(value time.Time) *time.Time { return &value }
+	preference := func(employee models.Employee, strength, kind string, apply func(*models.ShiftPreference)) models.ShiftPreference {
+		p := models.ShiftPreference{EmployeeID: employee.ID, Strength: strength, Kind: kind}
+		apply(&p)
+		databasetest.Create(t, &p)
+		return p
+	}
+	// Wochentag 4 ist Freitag: ein Tag, verletzt
+	preference(anna, models.PreferenceSoft, models.PreferenceAvoid, func(p *models.ShiftPreference) { p.WeekDay = weekDay(4) })
+	// Wochentag 6 ist Sonntag: ein Tag, erfüllt
+	preference(anna, models.PreferenceSoft, models.PreferenceAvoid, func(p *models.ShiftPreference) { p.WeekDay = weekDay(6) })
+	// Je Schicht: Montag und Freitag erfüllt, Mittwoch verletzt
+	preference(anna, models.PreferenceSoft, models.PreferencePrefer, func(p *models.ShiftPreference) { p.ShiftTypeID = &early.ID })
+	// Verbindlich keine Nacht am Dienstag: ein Tag, verletzt. Die Nacht am
+	// Montag fällt nicht darunter.
+	noNight := preference(bert, models.PreferenceHard, models.PreferenceAvoid, func(p *models.ShiftPreference) {
+		p.ShiftTypeID = &night.ID
+		p.WeekDay = weekDay(1)
+	})
+	// Wochentag 0 ist Montag: Carl ist ohne Schicht, aber in der Abteilung
+	preference(carl, models.PreferenceSoft, models.PreferenceAvoid, func(p *models.ShiftPreference) { p.WeekDay = weekDay(0) })
+	// Wünsche für andere Wochen zählen nicht
+	preference(carl, models.PreferenceSoft, models.PreferenceAvoid, func(p *models.ShiftPreference) { p.Date = date(monday.AddDate(0, 0, 7)) })
+	// Anna hat am Montag der Folgewoche verbindlich frei
+	preference(anna, models.PreferenceHard, models.PreferenceAvoid, func(p *models.ShiftPreference) { p.Date = date(monday.AddDate(0, 0, 7)) })
+
+	t.Run("erfüllung", func(t *testing.T) {
+		status, resp := call(t, app, "GET", fmt.Sprintf("/api/v1/shiftweeks/%d/stats", week.ID), "", nil)
+		if status != 200 {
+			t.Fatalf("status %d: %s", status, resp.Error)
+		}
+		var stats struct {
+			WishFulfillment handlers.WishFulfillment `json:"wish_fulfillment"`
+		}
+		decode(t, resp, &stats)
+		wishes := stats.WishFulfillment
+
+		want := map[uint]handlers.PreferenceFulfillment{
+			anna.ID: {Fulfilled: 3, Violated: 2},
+			bert.ID: {Fulfilled: 0, Violated: 1},
+			carl.ID: {Fulfilled: 1, Violated: 0},
+		}
+		if len(wishes.PerEmployee) != len(want) {
+			t.Fatalf("je mitarbeiter %v", wishes.PerEmployee)
+		}
+		for employeeID, w := range want {
+			if got := wishes.PerEmployee[employeeID]; got == nil || *got != w {
+				t.Errorf("mitarbeiter %d: %+v, erwartet %+v", employeeID, got, w)
+			}
+		}
+		if wishes.Fulfilled != 4 || wishes.Violated != 3 || wishes.Rate == nil || *wishes.Rate != 4.0/7.0 {
+			t.Errorf("gesamt %+v, quote %v", wishes.PreferenceFulfillment, wishes.Rate)
+		}
+		if len(wishes.HardViolations) != 1 || wishes.HardViolations[0] != (handlers.PreferenceViolation{
+			ShiftDayID: bertNight.ID, EmployeeID: bert.ID, PreferenceID: noNight.ID,
+		}) {
+			t.Errorf("harte verstöße %+v", wishes.HardViolations)
+		}
+	})
+
+	t.Run("harte wünsche beim kopieren", func(t *testing.T) {
+		status, resp := call(t, app, "POST", fmt.Sprintf("/api/v1/shiftweeks/%d/copy", week.ID), "", map[string]interface{}{
+			"year": 2030, "calendar_week": 3,
+		})
+		if status != 201 {
+			t.Fatalf("status %d: %s %s", status, resp.Error, resp.Data)
+		}
+		var result handlers.ShiftWeekCopyResult
+		decode(t, resp, &result)
+		if len(result.Weeks) != 1 {
+			t.Fatalf("wochen %+v", result.Weeks)
+		}
+		copied := result.Weeks[0]
+		if copied.Copied != 3 || len(copied.Skipped) != 2 {
+			t.Fatalf("kopiert %d, ausgelassen %+v", copied.Copied, copied.Skipped)
+		}
+		skipped := map[string]uint{}
+		for _, day := range copied.Skipped {
+			if !strings.Contains(day.Reason, "verbindlichen wunsch") {
+				t.Errorf("grund %q", day.Reason)
+			}
+			skipped[day.Date.Format("2006-01-02")] = *day.EmployeeID
+		}
+		if skipped["2030-01-14"] != anna.ID || skipped["2030-01-15"] != bert.ID {
+			t.Errorf("ausgelassen %v", skipped)
+		}
+	})
+}
@@ -1,9 +1,13 @@
 package handlers
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/ptmmeiningen/schichtplaner/database"
 	"github.com/ptmmeiningen/schichtplaner/models"
+	"github.com/ptmmeiningen/schichtplaner/pkg/query"
 	"github.com/ptmmeiningen/schichtplaner/pkg/responses"
 	"gorm.io/gorm"
 )
@@ -16,6 +20,7 @@ import (
 // @Param template body models.ShiftTemplate true "Template Details"
 // @Success 200 {object} responses.APIResponse{data=models.ShiftTemplate}
 // @Failure 400,500 {object} responses.APIResponse
+// @Failure 422 {object} responses.APIResponse{data=[]models.FieldError}
 // @Router /shifttemplates [post]
 func HandleCreateShiftTemplate(c *fiber.Ctx) error {
 	var template models.ShiftTemplate
@@ -24,89 +29,143 @@ func HandleCreateShiftTemplate(c *fiber.Ctx) error {
 		return c.Status(400).JSON(responses.ErrorResponse(responses.ErrInvalidInput))
 	}
 
-	if !template.Validate() {
-		return c.Status(400).JSON(responses.ErrorResponse("Ungültige Datumsangaben"))
+	template.ID = 0
+
+	if err := validateShiftTemplate(tenantDB(c), &template); err != nil {
+		return validationErrorResponse(c, err)
 	}
 
-	if err := database.GetDB().Create(&template).Error; err != nil {
+	if err := tenantDB(c).Create(&template).Error; err != nil {
 		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
 	}
 
 	return c.JSON(responses.SuccessResponse(responses.MsgSuccessCreate, template))
 }
 
+// shiftTemplateQuery legt die Filter und Sortierungen für Template-Listen fest
+var shiftTemplateQuery = query.Config{
+	Table:    "shift_templates",
+	Resource: "shift_template",
+	Fields:   shiftTemplateFields,
+	Filters: map[string]query.Filter{
+		"department_id": {Condition: "shift_templates.department_id = ?", Parse: query.Int},
+		"location_id":   {Condition: "shift_templates.department_id IN (SELECT id FROM departments WHERE location_id = ? AND deleted_at IS NULL)", Parse: query.Int},
+		"status":        {Condition: "shift_templates.status = ?", Parse: query.String},
+	},
+	Sorts: map[string]string{
+		"name":       "shift_templates.name",
+		"valid_from": "shift_templates.valid_from",
+		"created_at": "shift_templates.created_at",
+	},
+	DefaultSort: "-created_at",
+	Includes: map[string]query.Include{
+		"department": departmentInclude(),
+		"shift_days": {
+			Preload:    "ShiftDays",
+			Resource:   "shift_template_day",
+			Fields:     shiftTemplateDayFields,
+			ForeignKey: "shift_template_id",
+			HasMany:    true,
+			Order:      "week_day ASC",
+			Includes: map[string]query.Include{
+				"shift_type": shiftTypeInclude(),
+			},
+		},
+	},
+	DefaultIncludes: []string{"department", "shift_days.shift_type"},
+}
+
 // @Summary Listet alle Schicht-Templates
-// @Description Gibt alle verfügbaren Schicht-Templates zurück
+// @Description Gibt alle verfügbaren Schicht-Templates zurück, paginiert, gefiltert und sortiert
 // @Tags ShiftTemplates
 // @Produce json
+// @Param page query int false "Seite (ab 1)"
+// @Param page_size query int false "Einträge pro Seite (max. 200)"
+// @Param cursor query string false "Cursor für die nächste Seite (statt page)"
+// @Param department_id query int false "Abteilungs-ID"
+// @Param location_id query int false "Standort-ID"
+// @Param status query string false "Status (draft/active/inactive)"
+// @Param sort query string false "Sortierung, z.B. -created_at"
+// @Param include query string false "Beziehungen, z.B. department,shift_days.shift_type"
+// @Param fields[shift_template] query string false "Felder des Templates, z.B. id,name,status"
 // @Success 200 {object} responses.APIResponse{data=[]models.ShiftTemplate}
-// @Failure 500 {object} responses.APIResponse
+// @Failure 400,500 {object} responses.APIResponse
 // @Router /shifttemplates [get]
 func HandleAllShiftTemplates(c *fiber.Ctx) error {
-	var templates []models.ShiftTemplate
-
-	result := database.GetDB().
-		Preload("Department").
-		Preload("ShiftDays", func(db *gorm.DB) *gorm.DB {
-			return db.Order("week_day ASC")
-		}).
-		Preload("ShiftDays.ShiftType").
-		Order("created_at DESC").
-		Find(&templates)
+	q, err := query.Parse(c, shiftTemplateQuery)
+	if err != nil {
+		return c.Status(400).JSON(responses.ErrorResponse(err.Error()))
+	}
 
-	if result.Error != nil {
-		return c.Status(500).JSON(responses.ErrorResponse(result.Error.Error()))
+	var templates []models.ShiftTemplate
+	meta, err := q.Find(tenantDB(c), &templates)
+	if err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
 	}
 
-	return c.JSON(responses.SuccessResponse(responses.MsgSuccessGet, templates))
+	return c.JSON(responses.PaginatedResponse(responses.MsgSuccessGet, q.Shape(templates), meta))
 }
 
 // @Summary Gibt ein einzelnes Template zurück
 // @Description Zeigt detaillierte Informationen zu einem spezifischen Schicht-Template
 // @Tags ShiftTemplates
 // @Param id path int true "Template ID"
+// @Param include query string false "Beziehungen, z.B. department,shift_days.shift_type"
+// @Param fields[shift_template] query string false "Felder des Templates, z.B. id,name,status"
 // @Produce json
 // @Success 200 {object} responses.APIResponse{data=models.ShiftTemplate}
-// @Failure 404 {object} responses.APIResponse
+// @Failure 400,404 {object} responses.APIResponse
 // @Router /shifttemplates/{id} [get]
 func HandleGetOneShiftTemplate(c *fiber.Ctx) error {
 	id := c.Params("id")
 	var template models.ShiftTemplate
 
-	result := database.GetDB().
-		Preload("Department").
-		Preload("ShiftDays.ShiftType").
-		First(&template, id)
+	q, err := query.Parse(c, shiftTemplateQuery)
+	if err != nil {
+		return c.Status(400).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	result := q.Preload(tenantDB(c)).First(&template, id)
 
 	if result.Error != nil {
 		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
 	}
 
-	return c.JSON(responses.SuccessResponse(responses.MsgSuccessGet, template))
+	return c.JSON(responses.SuccessResponse(responses.MsgSuccessGet, q.Shape(template)))
 }
 
 // @Summary Gibt Templates einer Abteilung zurück
 // @Description Listet alle Schicht-Templates für eine bestimmte Abteilung
 // @Tags ShiftTemplates
 // @Param id path int true "Abteilungs-ID"
+// @Param page query int false "Seite (ab 1)"
+// @Param page_size query int false "Einträge pro Seite (max. 200)"
+// @Param cursor query string false "Cursor für die nächste Seite (statt page)"
+// @Param status query string false "Status (draft/active/inactive)"
+// @Param sort query string false "Sortierung, z.B. -created_at"
+// @Param include query string false "Beziehungen, z.B. department,shift_days.shift_type"
+// @Param fields[shift_template] query string false "Felder des Templates, z.B. id,name,status"
 // @Produce json
 // @Success 200 {object} responses.APIResponse{data=[]models.ShiftTemplate}
-// @Failure 500 {object} responses.APIResponse
+// @Failure 400,500 {object} responses.APIResponse
 // @Router /shifttemplates/department/{id} [get]
 func HandleGetDepartmentShiftTemplates(c *fiber.Ctx) error {
 	departmentID := c.Params("id")
-	var templates []models.ShiftTemplate
 
-	result := database.GetDB().
-		Where("department_id = ?", departmentID).
-		Preload("ShiftDays.ShiftType").
-		Find(&templates)
+	config := shiftTemplateQuery
+	config.DefaultIncludes = []string{"shift_days.shift_type"}
+	q, err := query.Parse(c, config)
+	if err != nil {
+		return c.Status(400).JSON(responses.ErrorResponse(err.Error()))
+	}
 
-	if result.Error != nil {
-		return c.Status(500).JSON(responses.ErrorResponse(result.Error.Error()))
+	var templates []models.ShiftTemplate
+	meta, err := q.Find(tenantDB(c).Where("shift_templates.department_id = ?", departmentID), &templates)
+	if err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
 	}
 
-	return c.JSON(responses.SuccessResponse(responses.MsgSuccessGet, templates))
+	return c.JSON(responses.PaginatedResponse(responses.MsgSuccessGet, q.Shape(templates), meta))
 }
 
 // @Summary Aktualisiert ein Template
@@ -119,13 +178,14 @@ func HandleGetDepartmentShiftTemplates(c *fiber.Ctx) error {
 // @Success 200 {object} responses.APIResponse{data=models.ShiftTemplate}
 // @Failure 400 {object} responses.APIResponse
 // @Failure 404 {object} responses.APIResponse
+// @Failure 422 {object} responses.APIResponse{data=[]models.FieldError}
 // @Failure 500 {object} responses.APIResponse
 // @Router /shifttemplates/{id} [put]
 func HandleUpdateShiftTemplate(c *fiber.Ctx) error {
 	id := c.Params("id")
 	var template models.ShiftTemplate
 
-	if err := database.GetDB().First(&template, id).Error; err != nil {
+	if err := tenantDB(c).First(&template, id).Error; err != nil {
 		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
 	}
 
@@ -137,7 +197,11 @@ func HandleUpdateShiftTemplate(c *fiber.Ctx) error {
 		return c.Status(400).JSON(responses.ErrorResponse(responses.ErrInvalidInput))
 	}
 
-	if err := database.GetDB().Save(&template).Error; err != nil {
+	if err := validateShiftTemplate(tenantDB(c), &template); err != nil {
+		return validationErrorResponse(c, err)
+	}
+
+	if err := tenantDB(c).Save(&template).Error; err != nil {
 		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
 	}
 
@@ -158,7 +222,7 @@ func HandleDeleteShiftTemplate(c *fiber.Ctx) error {
 	id := c.Params("id")
 	var template models.ShiftTemplate
 
-	if err := database.GetDB().First(&template, id).Error; err != nil {
+	if err := tenantDB(c).First(&template, id).Error; err != nil {
 		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
 	}
 
@@ -166,16 +230,11 @@ func HandleDeleteShiftTemplate(c *fiber.Ctx) error {
 		return c.Status(400).JSON(responses.ErrorResponse("Template kann nicht gelöscht werden"))
 	}
 
-	tx := database.GetDB().Begin()
-
-	if err := tx.Where("shift_template_id = ?", id).Delete(&models.ShiftTemplateDay{}).Error; err != nil {
-		tx.Rollback()
-		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
-	}
+	tx := tenantDB(c).Begin()
 
-	if err := tx.Delete(&template).Error; err != nil {
+	if err := database.SoftDelete(tx, "shift_templates", template.ID); err != nil {
 		tx.Rollback()
-		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+		return deleteErrorResponse(c, err)
 	}
 
 	tx.Commit()
@@ -193,13 +252,14 @@ func HandleDeleteShiftTemplate(c *fiber.Ctx) error {
 // @Success 200 {object} responses.APIResponse{data=models.ShiftTemplate}
 // @Failure 400 {object} responses.APIResponse
 // @Failure 404 {object} responses.APIResponse
+// @Failure 422 {object} responses.APIResponse{data=[]models.FieldError}
 // @Failure 500 {object} responses.APIResponse
 // @Router /shifttemplates/{id}/status [put]
 func HandleUpdateShiftTemplateStatus(c *fiber.Ctx) error {
 	id := c.Params("id")
 	var template models.ShiftTemplate
 
-	if err := database.GetDB().First(&template, id).Error; err != nil {
+	if err := tenantDB(c).First(&template, id).Error; err != nil {
 		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
 	}
 
@@ -212,9 +272,40 @@ func HandleUpdateShiftTemplateStatus(c *fiber.Ctx) error {
 	}
 
 	template.Status = input.Status
-	if err := database.GetDB().Save(&template).Error; err != nil {
+	if err := validateShiftTemplate(tenantDB(c), &template); err != nil {
+		return validationErrorResponse(c, err)
+	}
+
+	if err := tenantDB(c).Save(&template).Error; err != nil {
 		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
 	}
 
 	return c.JSON(responses.SuccessResponse(responses.MsgSuccessUpdate, template))
 }
+
+// validateShiftTemplate prüft eine Vorlage samt ihrer Tage. Neue Vorlagen
+// müssen in der Zukunft beginnen.
+func validateShiftTemplate(db *gorm.DB, template *models.ShiftTemplate) error {
+	errs := models.ValidateModel(template)
+
+	if template.ID == 0 && !template.ValidFrom.IsZero() && !template.ValidFrom.After(time.Now()) {
+		errs.Add("valid_from", models.CodeOutOfRange, "gültig ab muss in der zukunft liegen")
+	}
+
+	if template.DepartmentID != 0 {
+		if err := db.First(&models.Department{}, template.DepartmentID).Error; err != nil {
+			errs.Add("department_id", models.CodeNotFound, "abteilung nicht gefunden")
+		}
+	}
+
+	for i, day := range template.ShiftDays {
+		if day.ShiftTypeID == 0 {
+			continue
+		}
+		if err := db.First(&models.ShiftType{}, day.ShiftTypeID).Error; err != nil {
+			errs.Addf(fmt.Sprintf("shift_days[%d].shift_type_id", i), models.CodeNotFound, "schichttyp %d nicht gefunden", day.ShiftTypeID)
+		}
+	}
+
+	return errs.Err()
+}
@@ -1,30 +1,49 @@
 package handlers
 
 import (
-	"time"
-
 	"github.com/gofiber/fiber/v2"
 	"github.com/ptmmeiningen/schichtplaner/database"
 	"github.com/ptmmeiningen/schichtplaner/models"
+	"github.com/ptmmeiningen/schichtplaner/pkg/events"
+	"github.com/ptmmeiningen/schichtplaner/pkg/query"
 	"github.com/ptmmeiningen/schichtplaner/pkg/responses"
 )
 
+// shiftTypeQuery legt die Sortierungen für Schichttyp-Listen fest
+var shiftTypeQuery = query.Config{
+	Table: "shift_types",
+	Sorts: map[string]string{
+		"name":       "shift_types.name",
+		"start_time": "shift_types.start_time",
+		"created_at": "shift_types.created_at",
+	},
+	DefaultSort: "name",
+}
+
 // @Summary Alle Schichttypen abrufen
-// @Description Ruft alle Schichttypen ab
+// @Description Ruft alle Schichttypen ab, paginiert und sortiert
 // @Tags shifttypes
 // @Accept json
 // @Produce json
+// @Param page query int false "Seite (ab 1)"
+// @Param page_size query int false "Einträge pro Seite (max. 200)"
+// @Param cursor query string false "Cursor für die nächste Seite (statt page)"
+// @Param sort query string false "Sortierung, z.B. start_time"
 // @Success 200 {object} responses.APIResponse{data=[]models.ShiftType}
-// @Failure 500 {object} responses.APIResponse
+// @Failure 400,500 {object} responses.APIResponse
 // @Router /api/v1/shifttypes [get]
 func HandleAllShiftTypes(c *fiber.Ctx) error {
-	var shiftTypes []models.ShiftType
-	result := database.GetDB().Order("name").Find(&shiftTypes)
+	q, err := query.Parse(c, shiftTypeQuery)
+	if err != nil {
+		return c.Status(400).JSON(responses.ErrorResponse(err.Error()))
+	}
 
-	if result.Error != nil {
-		return c.Status(500).JSON(responses.ErrorResponse(result.Error.Error()))
+	var shiftTypes []models.ShiftType
+	meta, err := q.Find(tenantDB(c), &shiftTypes)
+	if err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
 	}
-	return c.JSON(responses.SuccessResponse(responses.MsgSuccessGet, shiftTypes))
+	return c.JSON(responses.PaginatedResponse(responses.MsgSuccessGet, shiftTypes, meta))
 }
 
 // @Summary Schichttyp erstellen
@@ -35,6 +54,7 @@ func HandleAllShiftTypes(c *fiber.Ctx) error {
 // @Param shifttype body models.ShiftType true "Schichttyp-Daten"
 // @Success 201 {object} responses.APIResponse{data=models.ShiftType}
 // @Failure 400 {object} responses.APIResponse
+// @Failure 422 {object} responses.APIResponse{data=[]models.FieldError}
 // @Failure 500 {object} responses.APIResponse
 // @Router /api/v1/shifttypes [post]
 func HandleCreateShiftType(c *fiber.Ctx) error {
@@ -43,16 +63,17 @@ func HandleCreateShiftType(c *fiber.Ctx) error {
 		return c.Status(400).JSON(responses.ErrorResponse(responses.ErrInvalidInput))
 	}
 
-	// Validiere Zeitformat (HH:MM)
-	if !isValidTimeFormat(shiftType.StartTime) || !isValidTimeFormat(shiftType.EndTime) {
-		return c.Status(400).JSON(responses.ErrorResponse("Ungültiges Zeitformat. Bitte HH:MM verwenden"))
+	if err := shiftType.Validate(); err != nil {
+		return validationErrorResponse(c, err)
 	}
 
-	result := database.GetDB().Create(&shiftType)
+	result := tenantDB(c).Create(&shiftType)
 	if result.Error != nil {
 		return c.Status(500).JSON(responses.ErrorResponse(result.Error.Error()))
 	}
 
+	events.Publish(tenantID(c), events.ShiftTypeCreated, nil, shiftType)
+
 	return c.Status(201).JSON(responses.SuccessResponse(responses.MsgSuccessCreate, shiftType))
 }
 
@@ -69,7 +90,7 @@ func HandleGetOneShiftType(c *fiber.Ctx) error {
 	id := c.Params("id")
 	var shiftType models.ShiftType
 
-	if err := database.GetDB().First(&shiftType, id).Error; err != nil {
+	if err := tenantDB(c).First(&shiftType, id).Error; err != nil {
 		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
 	}
 
@@ -86,13 +107,14 @@ func HandleGetOneShiftType(c *fiber.Ctx) error {
 // @Success 200 {object} responses.APIResponse{data=models.ShiftType}
 // @Failure 400 {object} responses.APIResponse
 // @Failure 404 {object} responses.APIResponse
+// @Failure 422 {object} responses.APIResponse{data=[]models.FieldError}
 // @Failure 500 {object} responses.APIResponse
 // @Router /api/v1/shifttypes/{id} [put]
 func HandleUpdateShiftType(c *fiber.Ctx) error {
 	id := c.Params("id")
 	var shiftType models.ShiftType
 
-	if err := database.GetDB().First(&shiftType, id).Error; err != nil {
+	if err := tenantDB(c).First(&shiftType, id).Error; err != nil {
 		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
 	}
 
@@ -100,45 +122,48 @@ func HandleUpdateShiftType(c *fiber.Ctx) error {
 		return c.Status(400).JSON(responses.ErrorResponse(responses.ErrInvalidInput))
 	}
 
-	// Validiere Zeitformat (HH:MM)
-	if !isValidTimeFormat(shiftType.StartTime) || !isValidTimeFormat(shiftType.EndTime) {
-		return c.Status(400).JSON(responses.ErrorResponse("Ungültiges Zeitformat. Bitte HH:MM verwenden"))
+	if err := shiftType.Validate(); err != nil {
+		return validationErrorResponse(c, err)
 	}
 
-	if err := database.GetDB().Save(&shiftType).Error; err != nil {
+	if err := tenantDB(c).Save(&shiftType).Error; err != nil {
 		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
 	}
 
+	events.Publish(tenantID(c), events.ShiftTypeUpdated, nil, shiftType)
+
 	return c.JSON(responses.SuccessResponse(responses.MsgSuccessUpdate, shiftType))
 }
 
 // @Summary Schichttyp löschen
-// @Description Löscht einen Schichttyp
+// @Description Löscht einen Schichttyp, sofern ihn kein Schichttag und keine Vorlage mehr verwendet
 // @Tags shifttypes
 // @Accept json
 // @Produce json
 // @Param id path int true "Schichttyp-ID"
 // @Success 200 {object} responses.APIResponse
 // @Failure 404 {object} responses.APIResponse
+// @Failure 409 {object} responses.APIResponse{data=[]database.BlockingReference} "Schichttyp wird noch verwendet"
 // @Failure 500 {object} responses.APIResponse
 // @Router /api/v1/shifttypes/{id} [delete]
 func HandleDeleteShiftType(c *fiber.Ctx) error {
 	id := c.Params("id")
 	var shiftType models.ShiftType
 
-	if err := database.GetDB().First(&shiftType, id).Error; err != nil {
+	if err := tenantDB(c).First(&shiftType, id).Error; err != nil {
 		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
 	}
 
-	if err := database.GetDB().Delete(&shiftType).Error; err != nil {
-		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	tx := tenantDB(c).Begin()
+
+	if err := database.SoftDelete(tx, "shift_types", shiftType.ID); err != nil {
+		tx.Rollback()
+		return deleteErrorResponse(c, err)
 	}
 
-	return c.JSON(responses.SuccessResponse(responses.MsgSuccessDelete, nil))
-}
+	tx.Commit()
 
-// Hilfsfunktion zur Validierung des Zeitformats (HH:MM)
-func isValidTimeFormat(timeStr string) bool {
-	_, err := time.Parse("15:04", timeStr)
-	return err == nil
+	events.Publish(tenantID(c), events.ShiftTypeDeleted, nil, fiber.Map{"id": shiftType.ID})
+
+	return c.JSON(responses.SuccessResponse(responses.MsgSuccessDelete, nil))
 }
@@ -1,46 +1,85 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
+	"log"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/ptmmeiningen/schichtplaner/database"
 	"github.com/ptmmeiningen/schichtplaner/models"
+	"github.com/ptmmeiningen/schichtplaner/notifications"
+	"github.com/ptmmeiningen/schichtplaner/pkg/events"
+	"github.com/ptmmeiningen/schichtplaner/pkg/query"
 	"github.com/ptmmeiningen/schichtplaner/pkg/responses"
 	"gorm.io/gorm"
 )
 
+// shiftWeekQuery legt die Filter und Sortierungen für Schichtwochen-Listen fest.
+// date_from/date_to werden auf Kalenderwochen (jahr*100+kw) abgebildet.
+var shiftWeekQuery = query.Config{
+	Table:    "shift_weeks",
+	Resource: "shift_week",
+	Fields:   shiftWeekFields,
+	Filters: map[string]query.Filter{
+		"date_from":     {Condition: "(shift_weeks.year * 100 + shift_weeks.calendar_week) >= ?", Parse: query.ISOWeek},
+		"date_to":       {Condition: "(shift_weeks.year * 100 + shift_weeks.calendar_week) <= ?", Parse: query.ISOWeek},
+		"department_id": {Condition: "shift_weeks.department_id = ?", Parse: query.Int},
+		"status":        {Condition: "shift_weeks.status = ?", Parse: query.String},
+		"location_id":   {Condition: "shift_weeks.department_id IN (SELECT id FROM departments WHERE location_id = ? AND deleted_at IS NULL)", Parse: query.Int},
+		"team_id":       {Condition: "shift_weeks.team_id = ?", Parse: query.Int},
+		"year":          {Condition: "shift_weeks.year = ?", Parse: query.Int},
+	},
+	Sorts: map[string]string{
+		"year":          "shift_weeks.year",
+		"calendar_week": "shift_weeks.calendar_week",
+		"status":        "shift_weeks.status",
+		"created_at":    "shift_weeks.created_at",
+	},
+	DefaultSort: "-year,-calendar_week",
+	Includes: map[string]query.Include{
+		"department": departmentInclude(),
+		"team":       teamInclude(),
+		"shift_days": shiftDaysInclude("shift_week_id", "date"),
+	},
+	DefaultIncludes: []string{"department", "shift_days.shift_type", "shift_days.employee"},
+}
+
 // @Summary Alle Schichtwochen abrufen
-// @Description Ruft alle Schichtwochen mit relevanten Beziehungen ab
+// @Description Ruft alle Schichtwochen mit relevanten Beziehungen ab, paginiert, gefiltert und sortiert
 // @Tags shiftweeks
 // @Accept json
 // @Produce json
+// @Param page query int false "Seite (ab 1)"
+// @Param page_size query int false "Einträge pro Seite (max. 200)"
+// @Param cursor query string false "Cursor für die nächste Seite (statt page)"
+// @Param date_from query string false "Ab Datum (YYYY-MM-DD)"
+// @Param date_to query string false "Bis Datum einschließlich (YYYY-MM-DD)"
+// @Param department_id query int false "Abteilungs-ID"
+// @Param location_id query int false "Standort-ID"
+// @Param team_id query int false "Team-ID"
+// @Param status query string false "Status (draft/published/archived)"
+// @Param year query int false "Jahr"
+// @Param sort query string false "Sortierung, z.B. -year,-calendar_week"
+// @Param include query string false "Beziehungen, z.B. department,shift_days.shift_type,shift_days.employee"
+// @Param fields[shift_week] query string false "Felder der Schichtwoche, z.B. id,calendar_week,year,status"
+// @Param fields[shift_day] query string false "Felder der Schichttage, z.B. id,date,employee_id"
 // @Success 200 {object} responses.APIResponse{data=[]models.ShiftWeek}
-// @Failure 500 {object} responses.APIResponse
+// @Failure 400,500 {object} responses.APIResponse
 // @Router /api/v1/shiftweeks [get]
 func HandleAllShiftWeeks(c *fiber.Ctx) error {
-	var shiftWeeks []models.ShiftWeek
-	result := database.GetDB().
-		Preload("Department", func(db *gorm.DB) *gorm.DB {
-			return db.Select("id, name, description, color").
-				Order("name")
-		}).
-		Preload("ShiftDays", func(db *gorm.DB) *gorm.DB {
-			return db.Select("id, date, shift_type_id, employee_id, shift_week_id").
-				Order("date DESC")
-		}).
-		Preload("ShiftDays.ShiftType").
-		Preload("ShiftDays.Employee", func(db *gorm.DB) *gorm.DB {
-			return db.Select("id, first_name, last_name, email, color, department_id").
-				Order("last_name, first_name")
-		}).
-		Order("year DESC, calendar_week DESC").
-		Find(&shiftWeeks)
+	q, err := query.Parse(c, shiftWeekQuery)
+	if err != nil {
+		return c.Status(400).JSON(responses.ErrorResponse(err.Error()))
+	}
 
-	if result.Error != nil {
-		return c.Status(500).JSON(responses.ErrorResponse(result.Error.Error()))
+	var shiftWeeks []models.ShiftWeek
+	meta, err := q.Find(tenantDB(c), &shiftWeeks)
+	if err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
 	}
-	return c.JSON(responses.SuccessResponse(responses.MsgSuccessGet, shiftWeeks))
+	return c.JSON(responses.PaginatedResponse(responses.MsgSuccessGet, q.Shape(shiftWeeks), meta))
 }
 
 // @Summary Schichtwoche erstellen
@@ -51,6 +90,7 @@ func HandleAllShiftWeeks(c *fiber.Ctx) error {
 // @Param shiftweek body models.ShiftWeek true "Schichtwoche-Daten"
 // @Success 201 {object} responses.APIResponse{data=models.ShiftWeek}
 // @Failure 400 {object} responses.APIResponse
+// @Failure 422 {object} responses.APIResponse{data=[]models.FieldError}
 // @Router /api/v1/shiftweeks [post]
 func HandleCreateShiftWeek(c *fiber.Ctx) error {
 	shiftWeek := new(models.ShiftWeek)
@@ -58,20 +98,22 @@ func HandleCreateShiftWeek(c *fiber.Ctx) error {
 		return c.Status(400).JSON(responses.ErrorResponse(responses.ErrInvalidInput))
 	}
 
-	if err := validateShiftWeek(shiftWeek); err != nil {
-		return c.Status(400).JSON(responses.ErrorResponse(err.Error()))
+	if err := validateShiftWeek(tenantDB(c), shiftWeek); err != nil {
+		return validationErrorResponse(c, err)
 	}
 
 	shiftWeek.Status = models.StatusDraft
-	result := database.GetDB().Create(&shiftWeek)
+	result := tenantDB(c).Create(&shiftWeek)
 	if result.Error != nil {
 		return c.Status(500).JSON(responses.ErrorResponse(result.Error.Error()))
 	}
 
-	database.GetDB().
+	tenantDB(c).
 		Preload("Department").
 		First(&shiftWeek, shiftWeek.ID)
 
+	events.Publish(tenantID(c), events.ShiftWeekCreated, shiftWeek.DepartmentID, shiftWeek)
+
 	return c.Status(201).JSON(responses.SuccessResponse(responses.MsgSuccessCreate, shiftWeek))
 }
 
@@ -81,31 +123,31 @@ func HandleCreateShiftWeek(c *fiber.Ctx) error {
 // @Accept json
 // @Produce json
 // @Param id path int true "Schichtwoche-ID"
+// @Param include query string false "Beziehungen, z.B. department,shift_days.shift_type,shift_days.employee"
+// @Param fields[shift_week] query string false "Felder der Schichtwoche, z.B. id,calendar_week,year,status"
 // @Success 200 {object} responses.APIResponse{data=models.ShiftWeek}
-// @Failure 404 {object} responses.APIResponse
+// @Failure 400,404 {object} responses.APIResponse
 // @Router /api/v1/shiftweeks/{id} [get]
 func HandleGetOneShiftWeek(c *fiber.Ctx) error {
 	id := c.Params("id")
 	var shiftWeek models.ShiftWeek
 
-	result := database.GetDB().
-		Preload("Department").
-		Preload("ShiftDays", func(db *gorm.DB) *gorm.DB {
-			return db.Order("date")
-		}).
-		Preload("ShiftDays.ShiftType").
-		Preload("ShiftDays.Employee").
-		First(&shiftWeek, id)
+	q, err := query.Parse(c, shiftWeekQuery)
+	if err != nil {
+		return c.Status(400).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	result := q.Preload(tenantDB(c)).First(&shiftWeek, id)
 
 	if result.Error != nil {
 		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
 	}
 
-	return c.JSON(responses.SuccessResponse(responses.MsgSuccessGet, shiftWeek))
+	return c.JSON(responses.SuccessResponse(responses.MsgSuccessGet, q.Shape(shiftWeek)))
 }
 
 // @Summary Schichtwoche aktualisieren
-// @Description Aktualisiert eine bestehende Schichtwoche
+// @Description Aktualisiert eine bestehende Schichtwoche im Entwurfsmodus. Der Status wird über /shiftweeks/{id}/status geändert. Kalenderwoche, Jahr, Abteilung und Team lassen sich nur ändern, solange die Woche keine Schichttage hat.
 // @Tags shiftweeks
 // @Accept json
 // @Produce json
@@ -113,37 +155,57 @@ func HandleGetOneShiftWeek(c *fiber.Ctx) error {
 // @Param shiftweek body models.ShiftWeek true "Aktualisierte Schichtwoche-Daten"
 // @Success 200 {object} responses.APIResponse{data=models.ShiftWeek}
 // @Failure 400,404 {object} responses.APIResponse
+// @Failure 422 {object} responses.APIResponse{data=[]models.FieldError}
 // @Router /api/v1/shiftweeks/{id} [put]
 func HandleUpdateShiftWeek(c *fiber.Ctx) error {
 	id := c.Params("id")
 	var shiftWeek models.ShiftWeek
 
-	if err := database.GetDB().First(&shiftWeek, id).Error; err != nil {
+	if err := tenantDB(c).First(&shiftWeek, id).Error; err != nil {
 		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
 	}
 
 	if shiftWeek.Status != models.StatusDraft {
 		return c.Status(400).JSON(responses.ErrorResponse(responses.ErrDraftOnly))
 	}
+	previousStatus, publishedAt := shiftWeek.Status, shiftWeek.PublishedAt
+	previous := models.ShiftWeek{
+		Year:         shiftWeek.Year,
+		CalendarWeek: shiftWeek.CalendarWeek,
+		DepartmentID: copyID(shiftWeek.DepartmentID),
+		TeamID:       copyID(shiftWeek.TeamID),
+	}
 
 	if err := c.BodyParser(&shiftWeek); err != nil {
 		return c.Status(400).JSON(responses.ErrorResponse(responses.ErrInvalidInput))
 	}
 
-	if err := validateShiftWeek(&shiftWeek); err != nil {
-		return c.Status(400).JSON(responses.ErrorResponse(err.Error()))
+	// Veröffentlicht wird nur über den Status-Endpunkt mit seinen Prüfungen
+	// und Benachrichtigungen
+	if shiftWeek.Status != previousStatus {
+		return validationErrorResponse(c, models.NewValidationError("status", models.CodeNotAllowed, "status wird über /shiftweeks/{id}/status geändert"))
 	}
+	shiftWeek.PublishedAt = publishedAt
 
-	if err := database.GetDB().Save(&shiftWeek).Error; err != nil {
+	if err := validateShiftWeek(tenantDB(c), &shiftWeek); err != nil {
+		return validationErrorResponse(c, err)
+	}
+	if err := checkShiftWeekScope(tenantDB(c), &previous, &shiftWeek); err != nil {
+		return validationErrorResponse(c, err)
+	}
+
+	if err := tenantDB(c).Save(&shiftWeek).Error; err != nil {
 		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
 	}
 
-	database.GetDB().
+	tenantDB(c).
 		Preload("Department").
 		Preload("ShiftDays.ShiftType").
 		Preload("ShiftDays.Employee").
 		First(&shiftWeek, id)
 
+	events.Publish(tenantID(c), events.ShiftWeekUpdated, shiftWeek.DepartmentID, shiftWeek)
+
 	return c.JSON(responses.SuccessResponse(responses.MsgSuccessUpdate, shiftWeek))
 }
 
@@ -160,7 +222,7 @@ func HandleDeleteShiftWeek(c *fiber.Ctx) error {
 	id := c.Params("id")
 	var shiftWeek models.ShiftWeek
 
-	if err := database.GetDB().First(&shiftWeek, id).Error; err != nil {
+	if err := tenantDB(c).First(&shiftWeek, id).Error; err != nil {
 		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
 	}
 
@@ -168,20 +230,17 @@ func HandleDeleteShiftWeek(c *fiber.Ctx) error {
 		return c.Status(400).JSON(responses.ErrorResponse(responses.ErrDraftOnly))
 	}
 
-	tx := database.GetDB().Begin()
+	tx := tenantDB(c).Begin()
 
-	if err := tx.Where("shift_week_id = ?", id).Delete(&models.ShiftDay{}).Error; err != nil {
+	if err := database.SoftDelete(tx, "shift_weeks", shiftWeek.ID); err != nil {
 		tx.Rollback()
-		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
-	}
-
-	if err := tx.Delete(&shiftWeek).Error; err != nil {
-		tx.Rollback()
-		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+		return deleteErrorResponse(c, err)
 	}
 
 	tx.Commit()
 
+	events.Publish(tenantID(c), events.ShiftWeekDeleted, shiftWeek.DepartmentID, fiber.Map{"id": shiftWeek.ID})
+
 	return c.JSON(responses.SuccessResponse(responses.MsgSuccessDelete, nil))
 }
 
@@ -191,45 +250,54 @@ func HandleDeleteShiftWeek(c *fiber.Ctx) error {
 // @Accept json
 // @Produce json
 // @Param id path int true "Abteilungs-ID"
+// @Param page query int false "Seite (ab 1)"
+// @Param page_size query int false "Einträge pro Seite (max. 200)"
+// @Param cursor query string false "Cursor für die nächste Seite (statt page)"
+// @Param date_from query string false "Ab Datum (YYYY-MM-DD)"
+// @Param date_to query string false "Bis Datum einschließlich (YYYY-MM-DD)"
+// @Param status query string false "Status (draft/published/archived)"
+// @Param sort query string false "Sortierung, Standard: -year,-calendar_week"
+// @Param include query string false "Beziehungen, z.B. department,shift_days.shift_type,shift_days.employee"
+// @Param fields[shift_week] query string false "Felder der Schichtwoche, z.B. id,calendar_week,year,status"
+// @Param fields[shift_day] query string false "Felder der Schichttage, z.B. id,date,employee_id"
 // @Success 200 {object} responses.APIResponse{data=[]models.ShiftWeek}
-// @Failure 404 {object} responses.APIResponse
+// @Failure 400,500 {object} responses.APIResponse
 // @Router /api/v1/shiftweeks/department/{id} [get]
 func HandleGetDepartmentShiftWeeks(c *fiber.Ctx) error {
 	departmentID := c.Params("id")
-	var shiftWeeks []models.ShiftWeek
 
-	result := database.GetDB().
-		Where("department_id = ?", departmentID).
-		Preload("ShiftDays", func(db *gorm.DB) *gorm.DB {
-			return db.Order("date")
-		}).
-		Preload("ShiftDays.ShiftType").
-		Preload("ShiftDays.Employee").
-		Order("year DESC, calendar_week DESC").
-		Find(&shiftWeeks)
+	config := shiftWeekQuery
+	config.DefaultIncludes = []string{"shift_days.shift_type", "shift_days.employee"}
+	q, err := query.Parse(c, config)
+	if err != nil {
+		return c.Status(400).JSON(responses.ErrorResponse(err.Error()))
+	}
 
-	if result.Error != nil {
-		return c.Status(500).JSON(responses.ErrorResponse(result.Error.Error()))
+	var shiftWeeks []models.ShiftWeek
+	meta, err := q.Find(tenantDB(c).Where("shift_weeks.department_id = ?", departmentID), &shiftWeeks)
+	if err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
 	}
 
-	return c.JSON(responses.SuccessResponse(responses.MsgSuccessGet, shiftWeeks))
+	return c.JSON(responses.PaginatedResponse(responses.MsgSuccessGet, q.Shape(shiftWeeks), meta))
 }
 
 // @Summary Status einer Schichtwoche aktualisieren
-// @Description Aktualisiert den Status einer Schichtwoche (draft/published/archived)
+// @Description Aktualisiert den Status einer Schichtwoche (draft/published/archived). Vor dem Veröffentlichen werden die Schichten minderjähriger Mitarbeiter nach dem Jugendarbeitsschutzgesetz geprüft.
 // @Tags shiftweeks
 // @Accept json
 // @Produce json
 // @Param id path int true "Schichtwoche-ID"
 // @Param status body string true "Neuer Status"
 // @Success 200 {object} responses.APIResponse{data=models.ShiftWeek}
-// @Failure 400,404 {object} responses.APIResponse
+// @Failure 400,404,500 {object} responses.APIResponse
+// @Failure 422 {object} responses.APIResponse{data=[]models.FieldError}
 // @Router /api/v1/shiftweeks/{id}/status [put]
 func HandleUpdateShiftWeekStatus(c *fiber.Ctx) error {
 	id := c.Params("id")
 	var shiftWeek models.ShiftWeek
 
-	if err := database.GetDB().First(&shiftWeek, id).Error; err != nil {
+	if err := tenantDB(c).First(&shiftWeek, id).Error; err != nil {
 		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
 	}
 
@@ -241,32 +309,52 @@ func HandleUpdateShiftWeekStatus(c *fiber.Ctx) error {
 		return c.Status(400).JSON(responses.ErrorResponse(responses.ErrInvalidInput))
 	}
 
+	previousStatus := shiftWeek.Status
+	shiftWeek.Status = input.Status
 	if !shiftWeek.IsValidStatus() {
-		return c.Status(400).JSON(responses.ErrorResponse("Ungültiger Status"))
+		return validationErrorResponse(c, models.NewValidationError("status", models.CodeInvalid, "status muss draft, published oder archived sein"))
 	}
 
-	shiftWeek.Status = input.Status
-	if err := database.GetDB().Save(&shiftWeek).Error; err != nil {
+	publishing := shiftWeek.Status == models.StatusPublished && previousStatus != models.StatusPublished
+	if publishing {
+		if err := checkWeekYouthProtection(tenantDB(c), &shiftWeek); err != nil {
+			return validationErrorResponse(c, err)
+		}
+	}
+	if publishing && !shiftWeek.WasPublished() {
+		now := time.Now()
+		shiftWeek.PublishedAt = &now
+	}
+
+	if err := tenantDB(c).Save(&shiftWeek).Error; err != nil {
 		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
 	}
 
+	if publishing {
+		if err := notifications.NotifyWeekPublished(shiftWeek); err != nil {
+			log.Printf("Fehler beim Benachrichtigen über Schichtwoche %d: %v", shiftWeek.ID, err)
+		}
+	}
+
+	events.Publish(tenantID(c), events.ShiftWeekStatusEvent(shiftWeek.Status), shiftWeek.DepartmentID, shiftWeek)
+
 	return c.JSON(responses.SuccessResponse(responses.MsgSuccessUpdate, shiftWeek))
 }
 
 // @Summary Statistiken einer Schichtwoche abrufen
-// @Description Ruft statistische Daten zu einer Schichtwoche ab
+// @Description Ruft statistische Daten zu einer Schichtwoche ab, einschließlich der Erfüllung der Schichtwünsche (wish_fulfillment)
 // @Tags shiftweeks
 // @Accept json
 // @Produce json
 // @Param id path int true "Schichtwoche-ID"
 // @Success 200 {object} responses.APIResponse
-// @Failure 404 {object} responses.APIResponse
+// @Failure 404,500 {object} responses.APIResponse
 // @Router /api/v1/shiftweeks/{id}/stats [get]
 func HandleShiftWeekStats(c *fiber.Ctx) error {
 	id := c.Params("id")
 	var shiftWeek models.ShiftWeek
 
-	if err := database.GetDB().
+	if err := tenantDB(c).
 		Preload("ShiftDays").
 		Preload("ShiftDays.Employee").
 		Preload("ShiftDays.ShiftType").
@@ -274,55 +362,356 @@ func HandleShiftWeekStats(c *fiber.Ctx) error {
 		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
 	}
 
+	tally := tallyShifts(shiftWeek.ShiftDays)
 	stats := map[string]interface{}{
 		"total_shifts":        len(shiftWeek.ShiftDays),
-		"assigned_shifts":     0,
-		"unassigned_shifts":   0,
-		"shifts_per_type":     make(map[uint]int),
-		"shifts_per_employee": make(map[uint]int),
+		"assigned_shifts":     tally.assigned,
+		"unassigned_shifts":   tally.unassigned,
+		"shifts_per_type":     tally.perType,
+		"shifts_per_employee": tally.perEmployee,
 	}
 
-	for _, day := range shiftWeek.ShiftDays {
+	wishes, err := evaluatePreferences(tenantDB(c), shiftWeek, isoWeekStart(shiftWeek.Year, shiftWeek.CalendarWeek))
+	if err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+	stats["wish_fulfillment"] = wishes
+
+	return c.JSON(responses.SuccessResponse(responses.MsgSuccessGet, stats))
+}
+
+// shiftTally zählt Schichttage je Mitarbeiter und je Schichttyp
+type shiftTally struct {
+	assigned    int
+	unassigned  int
+	perType     map[uint]int
+	perEmployee map[uint]int
+}
+
+func tallyShifts(days []models.ShiftDay) shiftTally {
+	tally := shiftTally{perType: make(map[uint]int), perEmployee: make(map[uint]int)}
+	for _, day := range days {
 		if day.EmployeeID != nil {
-			stats["assigned_shifts"] = stats["assigned_shifts"].(int) + 1
-			stats["shifts_per_employee"].(map[uint]int)[*day.EmployeeID]++
+			tally.assigned++
+			tally.perEmployee[*day.EmployeeID]++
 		} else {
-			stats["unassigned_shifts"] = stats["unassigned_shifts"].(int) + 1
+			tally.unassigned++
 		}
-		stats["shifts_per_type"].(map[uint]int)[day.ShiftTypeID]++
+		tally.perType[day.ShiftTypeID]++
 	}
+	return tally
+}
 
-	return c.JSON(responses.SuccessResponse(responses.MsgSuccessGet, stats))
+// maxCopyWeeks begrenzt die Anzahl der Wochen, die in einem Aufruf kopiert werden
+const maxCopyWeeks = 12
+
+// ShiftWeekCopyInput beschreibt Ziel und Umfang einer Kopie
+type ShiftWeekCopyInput struct {
+	Year         int   `json:"year" example:"2025"`
+	CalendarWeek int   `json:"calendar_week" example:"3"`
+	DepartmentID *uint `json:"department_id,omitempty"`
+	Weeks        int   `json:"weeks,omitempty" example:"1"`
+}
+
+// SkippedShiftDay ist ein Schichttag, der beim Kopieren ausgelassen wurde
+type SkippedShiftDay struct {
+	SourceID   uint      `json:"source_id,omitempty"`
+	Date       time.Time `json:"date"`
+	EmployeeID *uint     `json:"employee_id"`
+	Reason     string    `json:"reason" example:"mitarbeiter nicht gefunden"`
+}
+
+// CopiedShiftWeek ist das Ergebnis der Kopie einer einzelnen Woche
+type CopiedShiftWeek struct {
+	SourceID  uint              `json:"source_id"`
+	ShiftWeek models.ShiftWeek  `json:"shift_week"`
+	Copied    int               `json:"copied"`
+	Skipped   []SkippedShiftDay `json:"skipped"`
+}
+
+// ShiftWeekCopyResult fasst alle kopierten Wochen zusammen. Fehlende
+// Quellwochen im Bereich werden als jahr-Wkw aufgeführt.
+type ShiftWeekCopyResult struct {
+	Weeks         []CopiedShiftWeek `json:"weeks"`
+	MissingSource []string          `json:"missing_source"`
 }
 
-func validateShiftWeek(shiftWeek *models.ShiftWeek) error {
-	if shiftWeek.CalendarWeek < 1 || shiftWeek.CalendarWeek > 53 {
-		return fmt.Errorf("kalenderwoche muss zwischen 1 und 53 liegen")
+// @Summary Schichtwoche kopieren
+// @Description Kopiert eine Schichtwoche als Entwurf in die Zielwoche, optional in eine andere Abteilung. Schichttage werden auf denselben Wochentag verschoben, ungültige Zuordnungen werden ausgelassen und gemeldet. Mit weeks werden die folgenden Wochen der Abteilung bzw. des Teams in die folgenden Zielwochen kopiert. Das Team bleibt nur innerhalb derselben Abteilung erhalten.
+// @Tags shiftweeks
+// @Accept json
+// @Produce json
+// @Param id path int true "Schichtwoche-ID"
+// @Param copy body ShiftWeekCopyInput true "Zielwoche"
+// @Success 201 {object} responses.APIResponse{data=ShiftWeekCopyResult}
+// @Failure 400,404,500 {object} responses.APIResponse
+// @Failure 422 {object} responses.APIResponse{data=[]models.FieldError}
+// @Router /api/v1/shiftweeks/{id}/copy [post]
+func HandleCopyShiftWeek(c *fiber.Ctx) error {
+	id := c.Params("id")
+	var source models.ShiftWeek
+
+	if err := tenantDB(c).First(&source, id).Error; err != nil {
+		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
 	}
 
-	if shiftWeek.Year < 2000 {
-		return fmt.Errorf("jahr muss nach 2000 liegen")
+	var input ShiftWeekCopyInput
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(responses.ErrorResponse(responses.ErrInvalidInput))
+	}
+	if input.Weeks == 0 {
+		input.Weeks = 1
+	}
+	if input.DepartmentID == nil {
+		input.DepartmentID = source.DepartmentID
 	}
 
-	if shiftWeek.DepartmentID == nil {
-		return fmt.Errorf("abteilung ist erforderlich")
+	var inputErrs models.ValidationErrors
+	if input.Weeks < 1 || input.Weeks > maxCopyWeeks {
+		inputErrs.Addf("weeks", models.CodeOutOfRange, "weeks muss zwischen 1 und %d liegen", maxCopyWeeks)
+	}
+	targetStart := isoWeekStart(input.Year, input.CalendarWeek)
+	if input.CalendarWeek < 1 || input.CalendarWeek > 53 {
+		inputErrs.Add("calendar_week", models.CodeOutOfRange, "kalenderwoche muss zwischen 1 und 53 liegen")
+	} else if year, week := targetStart.ISOWeek(); year != input.Year || week != input.CalendarWeek {
+		inputErrs.Addf("calendar_week", models.CodeInvalid, "das jahr %d hat keine kalenderwoche %d", input.Year, input.CalendarWeek)
+	}
+	if err := inputErrs.Err(); err != nil {
+		return validationErrorResponse(c, err)
 	}
 
+	sourceStart := isoWeekStart(source.Year, source.CalendarWeek)
+	result := ShiftWeekCopyResult{Weeks: []CopiedShiftWeek{}, MissingSource: []string{}}
+
+	var sources, targets []models.ShiftWeek
+	for i := 0; i < input.Weeks; i++ {
+		sourceYear, sourceWeek := sourceStart.AddDate(0, 0, 7*i).ISOWeek()
+		targetYear, targetWeek := targetStart.AddDate(0, 0, 7*i).ISOWeek()
+
+		var week models.ShiftWeek
+		err := tenantDB(c).
+			Preload("ShiftDays", func(db *gorm.DB) *gorm.DB {
+				return db.Order("date, id")
+			}).
+			Scopes(sameTeam(source.TeamID)).
+			Where("department_id = ? AND year = ? AND calendar_week = ?", source.DepartmentID, sourceYear, sourceWeek).
+			First(&week).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			result.MissingSource = append(result.MissingSource, fmt.Sprintf("%d-W%02d", sourceYear, sourceWeek))
+			continue
+		}
+		if err != nil {
+			return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+		}
+
+		target := models.ShiftWeek{
+			CalendarWeek: targetWeek,
+			Year:         targetYear,
+			DepartmentID: input.DepartmentID,
+			Status:       models.StatusDraft,
+			Notes:        week.Notes,
+		}
+		// Teams gibt es nur innerhalb der Abteilung
+		if *input.DepartmentID == *source.DepartmentID {
+			target.TeamID = week.TeamID
+		}
+		var errs models.ValidationErrors
+		if err := errs.Merge("", validateShiftWeek(tenantDB(c), &target)); err != nil {
+			return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+		}
+		if len(errs) > 0 {
+			for j := range errs {
+				errs[j].Message = fmt.Sprintf("%d-W%02d: %s", targetYear, targetWeek, errs[j].Message)
+			}
+			return validationErrorResponse(c, errs)
+		}
+		sources = append(sources, week)
+		targets = append(targets, target)
+	}
+
+	err := tenantDB(c).Transaction(func(tx *gorm.DB) error {
+		for i := range sources {
+			copied, err := copyShiftWeek(tx, sources[i], targets[i])
+			if err != nil {
+				return err
+			}
+			result.Weeks = append(result.Weeks, *copied)
+		}
+		return nil
+	})
+	if err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	for i := range result.Weeks {
+		copied := &result.Weeks[i]
+		tenantDB(c).
+			Preload("Department").
+			Preload("ShiftDays.ShiftType").
+			Preload("ShiftDays.Employee").
+			First(&copied.ShiftWeek, copied.ShiftWeek.ID)
+
+		events.Publish(tenantID(c), events.ShiftWeekCreated, copied.ShiftWeek.DepartmentID, copied.ShiftWeek)
+	}
+
+	return c.Status(201).JSON(responses.SuccessResponse(responses.MsgSuccessCreate, result))
+}
+
+// copyShiftWeek legt die Entwurfswoche target an und kopiert alle Schichttage
+// von source dorthin, die dort gültig sind. Die übrigen werden gemeldet.
+func copyShiftWeek(tx *gorm.DB, source, target models.ShiftWeek) (*CopiedShiftWeek, error) {
+	if err := tx.Create(&target).Error; err != nil {
+		return nil, err
+	}
+
+	offset := int(isoWeekStart(target.Year, target.CalendarWeek).Sub(isoWeekStart(source.Year, source.CalendarWeek)).Hours() / 24)
+	copied := &CopiedShiftWeek{SourceID: source.ID, ShiftWeek: target, Skipped: []SkippedShiftDay{}}
+
+	for _, day := range source.ShiftDays {
+		shiftDay := models.ShiftDay{
+			Date:         day.Date.AddDate(0, 0, offset),
+			ShiftWeekID:  &target.ID,
+			ShiftTypeID:  day.ShiftTypeID,
+			EmployeeID:   day.EmployeeID,
+			Notes:        day.Notes,
+			BreakMinutes: day.BreakMinutes,
+		}
+		err := validateShiftDay(tx, &shiftDay)
+		if err == nil {
+			err = checkHardPreferences(tx, &shiftDay)
+		}
+		if err != nil {
+			copied.Skipped = append(copied.Skipped, SkippedShiftDay{
+				SourceID:   day.ID,
+				Date:       shiftDay.Date,
+				EmployeeID: day.EmployeeID,
+				Reason:     err.Error(),
+			})
+			continue
+		}
+		if err := tx.Create(&shiftDay).Error; err != nil {
+			return nil, err
+		}
+		copied.Copied++
+	}
+	return copied, nil
+}
+
+// isoWeekStart liefert den Montag der ISO-Kalenderwoche
+func isoWeekStart(year, week int) time.Time {
+	// Der 4. Januar liegt immer in Kalenderwoche 1
+	jan4 := time.Date(year, time.January, 4, 0, 0, 0, 0, time.UTC)
+	weekday := int(jan4.Weekday()+6) % 7
+	return jan4.AddDate(0, 0, 7*(week-1)-weekday)
+}
+
+// weekConflict liefert die Meldung, wenn eine Schichtwoche mit teamID neben
+// einer vorhandenen Woche derselben Kalenderwoche nicht geplant werden kann
+func weekConflict(existingTeamID, teamID *uint) string {
+	switch {
+	case existingTeamID == nil && teamID == nil:
+		return "es existiert bereits eine schichtwoche für diese kalenderwoche in dieser abteilung"
+	case existingTeamID == nil:
+		return "diese kalenderwoche ist bereits für die ganze abteilung geplant"
+	case teamID == nil:
+		return "diese kalenderwoche ist bereits für einzelne teams geplant"
+	case *existingTeamID == *teamID:
+		return "es existiert bereits eine schichtwoche für diese kalenderwoche in diesem team"
+	}
+	return ""
+}
+
+func validateShiftWeek(db *gorm.DB, shiftWeek *models.ShiftWeek) error {
+	var errs models.ValidationErrors
+	if shiftWeek.TeamID != nil {
+		var team models.Team
+		if err := db.First(&team, *shiftWeek.TeamID).Error; err != nil {
+			errs.Add("team_id", models.CodeNotFound, "team nicht gefunden")
+		} else if shiftWeek.DepartmentID == nil {
+			shiftWeek.DepartmentID = &team.DepartmentID
+		} else if *shiftWeek.DepartmentID != team.DepartmentID {
+			errs.Add("team_id", models.CodeMismatch, "team gehört nicht zur abteilung der schichtwoche")
+		}
+	}
+	shiftWeek.Team = nil
+	errs = append(errs, models.ValidateModel(shiftWeek)...)
+
+	if shiftWeek.DepartmentID == nil {
+		return errs.Err()
+	}
 	var department models.Department
-	if err := database.GetDB().First(&department, shiftWeek.DepartmentID).Error; err != nil {
-		return fmt.Errorf("abteilung nicht gefunden")
+	if err := db.First(&department, shiftWeek.DepartmentID).Error; err != nil {
+		errs.Add("department_id", models.CodeNotFound, "abteilung nicht gefunden")
+	}
+	if len(errs) > 0 {
+		return errs.Err()
 	}
 
-	var existingWeek models.ShiftWeek
-	if err := database.GetDB().
+	// Eine Kalenderwoche wird entweder für die ganze Abteilung oder je Team
+	// geplant, sonst könnten Mitarbeiter doppelt eingeplant werden
+	var existingWeeks []models.ShiftWeek
+	if err := db.
 		Where("department_id = ? AND id != ? AND year = ? AND calendar_week = ?",
 			shiftWeek.DepartmentID,
 			shiftWeek.ID,
 			shiftWeek.Year,
 			shiftWeek.CalendarWeek).
-		First(&existingWeek).Error; err == nil {
-		return fmt.Errorf("es existiert bereits eine schichtwoche für diese kalenderwoche in dieser abteilung")
+		Find(&existingWeeks).Error; err != nil {
+		return err
 	}
+	for _, existing := range existingWeeks {
+		if message := weekConflict(existing.TeamID, shiftWeek.TeamID); message != "" {
+			errs.Add("calendar_week", models.CodeConflict, message)
+			break
+		}
+	}
+	return errs.Err()
+}
 
-	return nil
+// checkShiftWeekScope lehnt Änderungen an Kalenderwoche, Abteilung und Team
+// ab, solange die Woche Schichttage hat. Die Tage wurden gegen die bisherigen
+// Werte geprüft und lägen sonst außerhalb der Woche oder des Teams.
+func checkShiftWeekScope(db *gorm.DB, previous, shiftWeek *models.ShiftWeek) error {
+	var days int64
+	if err := db.Model(&models.ShiftDay{}).Where("shift_week_id = ?", shiftWeek.ID).Count(&days).Error; err != nil {
+		return err
+	}
+	if days == 0 {
+		return nil
+	}
+
+	var errs models.ValidationErrors
+	const message = "kann nicht geändert werden, solange die woche schichttage hat"
+	if shiftWeek.Year != previous.Year {
+		errs.Add("year", models.CodeNotAllowed, message)
+	}
+	if shiftWeek.CalendarWeek != previous.CalendarWeek {
+		errs.Add("calendar_week", models.CodeNotAllowed, message)
+	}
+	if !sameID(shiftWeek.DepartmentID, previous.DepartmentID) {
+		errs.Add("department_id", models.CodeNotAllowed, message)
+	}
+	if !sameID(shiftWeek.TeamID, previous.TeamID) {
+		errs.Add("team_id", models.CodeNotAllowed, message)
+	}
+	return errs.Err()
+}
+
+// sameID vergleicht zwei optionale Verweise
+func sameID(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// sameTeam schränkt Schichtwochen auf das Team ein, ohne Team auf die Wochen
+// der ganzen Abteilung
+func sameTeam(teamID *uint) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if teamID == nil {
+			return db.Where("team_id IS NULL")
+		}
+		return db.Where("team_id = ?", *teamID)
+	}
 }
@@ -0,0 +1,125 @@
+package handlers_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ptmmeiningen/schichtplaner/database/databasetest"
+	"github.com/ptmmeiningen/schichtplaner/models"
+)
+
+func TestCopyShiftWeekValidatesInput(t *testing.T) {
+	app := setupApp(t)
+	department := databasetest.Department(t, "Produktion")
+	week := models.ShiftWeek{Year: 2030, CalendarWeek: 2, Status: models.StatusDraft, DepartmentID: &department.ID}
+	databasetest.Create(t, &week)
+	path := fmt.Sprintf("/api/v1/shiftweeks/%d/copy", week.ID)
+
+	tests := []struct {
+		name  string
+		input map[string]interface{}
+		want  []models.FieldError
+	}{
+		{
+			name:  "zu viele Wochen",
+			input: map[string]interface{}{"year": 2030, "calendar_week": 3, "weeks": 100},
+			want:  []models.FieldError{{Field: "weeks", Code: models.CodeOutOfRange}},
+		},
+		{
+			name:  "Kalenderwoche außerhalb",
+			input: map[string]interface{}{"year": 2030, "calendar_week": 54},
+			want:  []models.FieldError{{Field: "calendar_week", Code: models.CodeOutOfRange}},
+		},
+		{
+			name:  "Jahr ohne KW 53",
+			input: map[string]interface{}{"year": 2030, "calendar_week": 53},
+			want:  []models.FieldError{{Field: "calendar_week", Code: models.CodeInvalid}},
+		},
+		{
+			name:  "mehrere Fehler",
+			input: map[string]interface{}{"year": 2030, "calendar_week": 0, "weeks": -1},
+			want: []models.FieldError{
+				{Field: "weeks", Code: models.CodeOutOfRange},
+				{Field: "calendar_week", Code: models.CodeOutOfRange},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			status, resp := call(t, app, "POST", path, "", test.input)
+			expectFieldErrors(t, status, resp, test.want...)
+		})
+	}
+
+	status, resp := call(t, app, "POST", path, "", map[string]interface{}{"year": 2030, "calendar_week": 3})
+	if status != 201 {
+		t.Fatalf("gültige Kopie: status %d, %s %s", status, resp.Error, resp.Data)
+	}
+}
+
+func TestUpdateShiftWeekKeepsScopeWithDays(t *testing.T) {
+	app := setupApp(t)
+	department := databasetest.Department(t, "Produktion")
+	other := databasetest.Department(t, "Versand")
+	team := models.Team{DepartmentID: department.ID, Name: "Linie A"}
+	databasetest.Create(t, &team)
+	early := databasetest.ShiftType(t, "Früh", "06:00", "14:00")
+	anna := databasetest.Employee(t, "anna@example.org", department.ID)
+	anna.TeamID = &team.ID
+	databasetest.Save(t, &anna)
+
+	// KW 2/2030 beginnt am Montag, 07.01.
+	week := models.ShiftWeek{Year: 2030, CalendarWeek: 2, Status: models.StatusDraft, DepartmentID: &department.ID, TeamID: &team.ID}
+	databasetest.Create(t, &week)
+	databasetest.Create(t, &models.ShiftDay{
+		Date: time.Date(2030, 1, 7, 0, 0, 0, 0, time.UTC), ShiftWeekID: &week.ID, ShiftTypeID: early.ID, EmployeeID: &anna.ID,
+	})
+	path := fmt.Sprintf("/api/v1/shiftweeks/%d", week.ID)
+
+	tests := []struct {
+		name  string
+		input map[string]interface{}
+		want  []models.FieldError
+	}{
+		{
+			name:  "Kalenderwoche",
+			input: map[string]interface{}{"calendar_week": 3},
+			want:  []models.FieldError{{Field: "calendar_week", Code: models.CodeNotAllowed}},
+		},
+		{
+			name:  "Jahr",
+			input: map[string]interface{}{"year": 2031},
+			want:  []models.FieldError{{Field: "year", Code: models.CodeNotAllowed}},
+		},
+		{
+			name:  "Team entfernt",
+			input: map[string]interface{}{"team_id": nil},
+			want:  []models.FieldError{{Field: "team_id", Code: models.CodeNotAllowed}},
+		},
+		{
+			name:  "andere Abteilung",
+			input: map[string]interface{}{"department_id": other.ID, "team_id": nil},
+			want: []models.FieldError{
+				{Field: "department_id", Code: models.CodeNotAllowed},
+				{Field: "team_id", Code: models.CodeNotAllowed},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			status, resp := call(t, app, "PUT", path, "", test.input)
+			expectFieldErrors(t, status, resp, test.want...)
+		})
+	}
+
+	// Ohne Schichttage lässt sich die Woche verschieben
+	empty := models.ShiftWeek{Year: 2030, CalendarWeek: 5, Status: models.StatusDraft, DepartmentID: &department.ID, TeamID: &team.ID}
+	databasetest.Create(t, &empty)
+	status, resp := call(t, app, "PUT", fmt.Sprintf("/api/v1/shiftweeks/%d", empty.ID), "", map[string]interface{}{
+		"calendar_week": 6, "department_id": other.ID, "team_id": nil,
+	})
+	if status != 200 {
+		t.Fatalf("leere woche: status %d, %s %s", status, resp.Error, resp.Data)
+	}
+}
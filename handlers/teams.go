@@ -0,0 +1,268 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ptmmeiningen/schichtplaner/database"
+	"github.com/ptmmeiningen/schichtplaner/models"
+	"github.com/ptmmeiningen/schichtplaner/pkg/query"
+	"github.com/ptmmeiningen/schichtplaner/pkg/responses"
+	"gorm.io/gorm"
+)
+
+// teamQuery legt die Filter und Sortierungen für Team-Listen fest
+var teamQuery = query.Config{
+	Table:    "teams",
+	Resource: "team",
+	Fields:   teamFields,
+	Filters: map[string]query.Filter{
+		"department_id": {Condition: "teams.department_id = ?", Parse: query.Int},
+		"location_id":   {Condition: "teams.department_id IN (SELECT id FROM departments WHERE location_id = ? AND deleted_at IS NULL)", Parse: query.Int},
+		"name":          {Condition: "teams.name = ?", Parse: query.String},
+	},
+	Sorts: map[string]string{
+		"name":       "teams.name",
+		"created_at": "teams.created_at",
+	},
+	DefaultSort: "name",
+	Includes: map[string]query.Include{
+		"department": departmentInclude(),
+		"lead": {
+			Preload:    "Lead",
+			Resource:   "employee",
+			Fields:     employeeFields,
+			ForeignKey: "lead_id",
+		},
+		"employees": {
+			Preload:    "Employees",
+			Resource:   "employee",
+			Fields:     employeeFields,
+			ForeignKey: "team_id",
+			HasMany:    true,
+			Order:      "last_name, first_name",
+		},
+	},
+	DefaultIncludes: []string{"lead"},
+}
+
+// @Summary Alle Teams abrufen
+// @Description Ruft alle Teams ab, paginiert, gefiltert und sortiert
+// @Tags teams
+// @Produce json
+// @Param page query int false "Seite (ab 1)"
+// @Param page_size query int false "Einträge pro Seite (max. 200)"
+// @Param cursor query string false "Cursor für die nächste Seite (statt page)"
+// @Param department_id query int false "Abteilungs-ID"
+// @Param location_id query int false "Standort-ID"
+// @Param name query string false "Name"
+// @Param sort query string false "Sortierung, z.B. name"
+// @Param include query string false "Beziehungen, z.B. department,lead,employees"
+// @Param fields[team] query string false "Felder des Teams, z.B. id,name,lead_id"
+// @Success 200 {object} responses.APIResponse{data=[]models.Team}
+// @Failure 400,500 {object} responses.APIResponse
+// @Router /api/v1/teams [get]
+func HandleAllTeams(c *fiber.Ctx) error {
+	q, err := query.Parse(c, teamQuery)
+	if err != nil {
+		return c.Status(400).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	var teams []models.Team
+	meta, err := q.Find(tenantDB(c), &teams)
+	if err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+	return c.JSON(responses.PaginatedResponse(responses.MsgSuccessGet, q.Shape(teams), meta))
+}
+
+// @Summary Team erstellen
+// @Description Legt ein Team in einer Abteilung an. Der Name muss je Abteilung eindeutig sein, die Teamleitung muss zur Abteilung gehören.
+// @Tags teams
+// @Accept json
+// @Produce json
+// @Param team body models.Team true "Teamdaten"
+// @Success 201 {object} responses.APIResponse{data=models.Team}
+// @Failure 400,500 {object} responses.APIResponse
+// @Failure 422 {object} responses.APIResponse{data=[]models.FieldError}
+// @Router /api/v1/teams [post]
+func HandleCreateTeam(c *fiber.Ctx) error {
+	team := new(models.Team)
+	if err := c.BodyParser(team); err != nil {
+		return c.Status(400).JSON(responses.ErrorResponse(responses.ErrInvalidInput))
+	}
+	team.ID = 0
+
+	if err := validateTeam(tenantDB(c), team); err != nil {
+		return validationErrorResponse(c, err)
+	}
+
+	if err := tenantDB(c).Omit("Employees").Create(team).Error; err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	tenantDB(c).Preload("Lead").First(team, team.ID)
+
+	return c.Status(201).JSON(responses.SuccessResponse(responses.MsgSuccessCreate, team))
+}
+
+// @Summary Einzelnes Team abrufen
+// @Description Ruft ein Team mit Teamleitung und Mitarbeitern ab
+// @Tags teams
+// @Produce json
+// @Param id path int true "Team-ID"
+// @Param include query string false "Beziehungen, z.B. department,lead,employees"
+// @Param fields[team] query string false "Felder des Teams, z.B. id,name,lead_id"
+// @Success 200 {object} responses.APIResponse{data=models.Team}
+// @Failure 400,404 {object} responses.APIResponse
+// @Router /api/v1/teams/{id} [get]
+func HandleGetOneTeam(c *fiber.Ctx) error {
+	var team models.Team
+
+	config := teamQuery
+	config.DefaultIncludes = []string{"lead", "employees"}
+	q, err := query.Parse(c, config)
+	if err != nil {
+		return c.Status(400).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	if err := q.Preload(tenantDB(c)).First(&team, c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
+	}
+
+	return c.JSON(responses.SuccessResponse(responses.MsgSuccessGet, q.Shape(team)))
+}
+
+// @Summary Team aktualisieren
+// @Description Aktualisiert Name, Farbe oder Teamleitung eines Teams. Ein Team kann nicht in eine andere Abteilung verschoben werden.
+// @Tags teams
+// @Accept json
+// @Produce json
+// @Param id path int true "Team-ID"
+// @Param team body models.Team true "Aktualisierte Teamdaten"
+// @Success 200 {object} responses.APIResponse{data=models.Team}
+// @Failure 400,404,500 {object} responses.APIResponse
+// @Failure 422 {object} responses.APIResponse{data=[]models.FieldError}
+// @Router /api/v1/teams/{id} [put]
+func HandleUpdateTeam(c *fiber.Ctx) error {
+	var team models.Team
+
+	if err := tenantDB(c).First(&team, c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
+	}
+	id, departmentID := team.ID, team.DepartmentID
+
+	if err := c.BodyParser(&team); err != nil {
+		return c.Status(400).JSON(responses.ErrorResponse(responses.ErrInvalidInput))
+	}
+	team.ID = id
+	// Mitarbeiter und Schichtwochen des Teams hängen an der Abteilung
+	if team.DepartmentID != departmentID {
+		return validationErrorResponse(c, models.NewValidationError("department_id", models.CodeNotAllowed, "die abteilung eines teams kann nicht geändert werden"))
+	}
+
+	if err := validateTeam(tenantDB(c), &team); err != nil {
+		return validationErrorResponse(c, err)
+	}
+
+	if err := tenantDB(c).Omit("Employees").Save(&team).Error; err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	tenantDB(c).Preload("Lead").First(&team, team.ID)
+
+	return c.JSON(responses.SuccessResponse(responses.MsgSuccessUpdate, team))
+}
+
+// @Summary Team löschen
+// @Description Löscht ein Team samt seinen Schichtwochen. Die Mitarbeiter bleiben in der Abteilung, gehören aber keinem Team mehr an.
+// @Tags teams
+// @Produce json
+// @Param id path int true "Team-ID"
+// @Success 200 {object} responses.APIResponse
+// @Failure 404,500 {object} responses.APIResponse
+// @Failure 409 {object} responses.APIResponse{data=[]database.BlockingReference}
+// @Router /api/v1/teams/{id} [delete]
+func HandleDeleteTeam(c *fiber.Ctx) error {
+	var team models.Team
+
+	if err := tenantDB(c).First(&team, c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
+	}
+
+	tx := tenantDB(c).Begin()
+
+	if err := database.SoftDelete(tx, "teams", team.ID); err != nil {
+		tx.Rollback()
+		return deleteErrorResponse(c, err)
+	}
+
+	tx.Commit()
+
+	return c.JSON(responses.SuccessResponse(responses.MsgSuccessDelete, nil))
+}
+
+// @Summary Teamstatistiken abrufen
+// @Description Wie die Abteilungsstatistiken, aber nur mit den Mitarbeitern und Schichtwochen des Teams. Die Schichtvorlagen gelten für die ganze Abteilung, der Bedarf und damit die Abdeckung bleiben daher leer. Ohne from/to werden die aktuelle und die drei folgenden Wochen ausgewertet.
+// @Tags teams
+// @Produce json
+// @Param id path int true "Team-ID"
+// @Param from query string false "Beginn (YYYY-MM-DD)"
+// @Param to query string false "Ende einschließlich (YYYY-MM-DD)"
+// @Success 200 {object} responses.APIResponse{data=DepartmentStats}
+// @Failure 400,404,500 {object} responses.APIResponse
+// @Router /api/v1/teams/{id}/stats [get]
+func HandleTeamStats(c *fiber.Ctx) error {
+	var team models.Team
+	if err := tenantDB(c).First(&team, c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
+	}
+
+	from, to, err := parseStatsRange(c)
+	if err != nil {
+		return c.Status(400).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	stats, err := departmentStats(tenantDB(c), team.DepartmentID, team.ID, from, to)
+	if err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	return c.JSON(responses.SuccessResponse("Statistiken erfolgreich abgerufen", stats))
+}
+
+func validateTeam(db *gorm.DB, team *models.Team) error {
+	team.Name = strings.TrimSpace(team.Name)
+	team.Department = nil
+	team.Lead = nil
+	team.Employees = nil
+	errs := models.ValidateModel(team)
+	if team.DepartmentID == 0 {
+		return errs.Err()
+	}
+
+	if err := db.First(&models.Department{}, team.DepartmentID).Error; err != nil {
+		errs.Add("department_id", models.CodeNotFound, "abteilung nicht gefunden")
+		return errs.Err()
+	}
+
+	if team.LeadID != nil {
+		var lead models.Employee
+		if err := db.First(&lead, *team.LeadID).Error; err != nil {
+			errs.Add("lead_id", models.CodeNotFound, "teamleitung nicht gefunden")
+		} else if lead.DepartmentID == nil || *lead.DepartmentID != team.DepartmentID {
+			errs.Add("lead_id", models.CodeMismatch, "teamleitung muss zur abteilung des teams gehören")
+		}
+	}
+
+	var count int64
+	if err := db.Model(&models.Team{}).
+		Where("department_id = ? AND name = ? AND id != ?", team.DepartmentID, team.Name, team.ID).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		errs.Add("name", models.CodeDuplicate, "name ist in dieser abteilung bereits vergeben")
+	}
+	return errs.Err()
+}
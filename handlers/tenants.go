@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"os"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ptmmeiningen/schichtplaner/database"
+	"github.com/ptmmeiningen/schichtplaner/models"
+	"github.com/ptmmeiningen/schichtplaner/pkg/responses"
+	"gorm.io/gorm"
+)
+
+// ProvisionedTenant ist ein neu angelegter Mandant samt API-Token. Der Token
+// wird nur bei der Anlage und beim Erneuern ausgeliefert.
+type ProvisionedTenant struct {
+	models.Tenant
+	Token string `json:"token" example:"3f9c..."`
+}
+
+// tenantDB liefert die Datenbank eingeschränkt auf den Mandanten der Anfrage
+func tenantDB(c *fiber.Ctx) *gorm.DB {
+	return database.ForTenant(c.UserContext())
+}
+
+// tenantID liefert den Mandanten der Anfrage
+func tenantID(c *fiber.Ctx) uint {
+	if id, ok := database.TenantFromContext(c.UserContext()); ok {
+		return id
+	}
+	return models.DefaultTenantID
+}
+
+// ResolveTenant ordnet die Anfrage einem Mandanten zu. Ohne Mandantenbetrieb
+// ist das immer der Standardmandant. Sonst zählt zuerst der API-Token aus
+// "Authorization: Bearer <token>", danach die Subdomain unterhalb von
+// TENANT_DOMAIN, etwa schwester.schichtplaner.example für TENANT_DOMAIN=
+// schichtplaner.example. Die Subdomain ist nur hinter einem Reverse Proxy
+// sinnvoll, der den Host-Header prüft.
+func ResolveTenant(c *fiber.Ctx) error {
+	if !database.MultiTenant() {
+		c.SetUserContext(database.WithTenant(c.UserContext(), models.DefaultTenantID))
+		return c.Next()
+	}
+
+	var tenant models.Tenant
+	query := database.GetDB().Where("active = ?", true)
+	if token, ok := bearerToken(c); ok {
+		query = query.Where("token_hash = ?", hashToken(token))
+	} else if slug, ok := subdomain(c.Hostname()); ok {
+		query = query.Where("slug = ?", slug)
+	} else {
+		return c.Status(401).JSON(responses.ErrorResponse(responses.ErrUnknownTenant))
+	}
+	if err := query.First(&tenant).Error; err != nil {
+		return c.Status(401).JSON(responses.ErrorResponse(responses.ErrUnknownTenant))
+	}
+
+	c.SetUserContext(database.WithTenant(c.UserContext(), tenant.ID))
+	return c.Next()
+}
+
+// RequireAdmin schützt die Mandantenverwaltung mit ADMIN_TOKEN. Ohne
+// ADMIN_TOKEN ist sie nur ohne Mandantenbetrieb erreichbar.
+func RequireAdmin(c *fiber.Ctx) error {
+	if os.Getenv("ADMIN_TOKEN") == "" && !database.MultiTenant() {
+		return c.Next()
+	}
+	return RequireAdminToken(c)
+}
+
+// RequireAdminToken verlangt immer ADMIN_TOKEN als Bearer-Token. Ohne
+// ADMIN_TOKEN bleiben die Routes gesperrt.
+func RequireAdminToken(c *fiber.Ctx) error {
+	expected := os.Getenv("ADMIN_TOKEN")
+	token, ok := bearerToken(c)
+	if expected == "" || !ok || subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+		return c.Status(403).JSON(responses.ErrorResponse(responses.ErrPermission))
+	}
+	return c.Next()
+}
+
+// @Summary Alle Mandanten abrufen
+// @Description Listet alle Mandanten. Benötigt ADMIN_TOKEN.
+// @Tags admin
+// @Produce json
+// @Success 200 {object} responses.APIResponse{data=[]models.Tenant}
+// @Failure 403,500 {object} responses.APIResponse
+// @Router /api/v1/admin/tenants [get]
+func HandleAllTenants(c *fiber.Ctx) error {
+	var tenants []models.Tenant
+	if err := database.GetDB().Order("id").Find(&tenants).Error; err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	return c.JSON(responses.SuccessResponse(responses.MsgSuccessGet, tenants))
+}
+
+// @Summary Mandant anlegen
+// @Description Legt einen Mandanten mit einem Standardstandort an und liefert einmalig seinen API-Token. Der Slug dient auch als Subdomain. Benötigt ADMIN_TOKEN.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param tenant body models.Tenant true "Name und Slug"
+// @Success 201 {object} responses.APIResponse{data=ProvisionedTenant}
+// @Failure 400,403,500 {object} responses.APIResponse
+// @Failure 422 {object} responses.APIResponse{data=[]models.FieldError}
+// @Router /api/v1/admin/tenants [post]
+func HandleCreateTenant(c *fiber.Ctx) error {
+	tenant := new(models.Tenant)
+	if err := c.BodyParser(tenant); err != nil {
+		return c.Status(400).JSON(responses.ErrorResponse(responses.ErrInvalidInput))
+	}
+	tenant.ID = 0
+	tenant.Active = true
+
+	if err := validateTenant(database.GetDB(), tenant); err != nil {
+		return validationErrorResponse(c, err)
+	}
+
+	token, err := newTenantToken()
+	if err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+	hash := hashToken(token)
+	tenant.TokenHash = &hash
+
+	err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(tenant).Error; err != nil {
+			return err
+		}
+		// Abteilungen brauchen einen Standort
+		location := models.Location{Name: "Hauptstandort"}
+		return tx.WithContext(database.WithTenant(c.UserContext(), tenant.ID)).
+			Omit("Departments").
+			Create(&location).Error
+	})
+	if err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	return c.Status(201).JSON(responses.SuccessResponse(responses.MsgSuccessCreate, ProvisionedTenant{Tenant: *tenant, Token: token}))
+}
+
+// @Summary Einzelnen Mandanten abrufen
+// @Description Ruft einen Mandanten ab. Benötigt ADMIN_TOKEN.
+// @Tags admin
+// @Produce json
+// @Param id path int true "Mandanten-ID"
+// @Success 200 {object} responses.APIResponse{data=models.Tenant}
+// @Failure 403,404 {object} responses.APIResponse
+// @Router /api/v1/admin/tenants/{id} [get]
+func HandleGetOneTenant(c *fiber.Ctx) error {
+	var tenant models.Tenant
+	if err := database.GetDB().First(&tenant, c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
+	}
+
+	return c.JSON(responses.SuccessResponse(responses.MsgSuccessGet, tenant))
+}
+
+// @Summary Mandant aktualisieren
+// @Description Ändert Name, Slug oder Aktivierung eines Mandanten. Deaktivierte Mandanten werden bei jeder Anfrage abgewiesen, ihre Daten bleiben erhalten. Benötigt ADMIN_TOKEN.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "Mandanten-ID"
+// @Param tenant body models.Tenant true "Aktualisierte Mandantendaten"
+// @Success 200 {object} responses.APIResponse{data=models.Tenant}
+// @Failure 400,403,404,500 {object} responses.APIResponse
+// @Failure 422 {object} responses.APIResponse{data=[]models.FieldError}
+// @Router /api/v1/admin/tenants/{id} [put]
+func HandleUpdateTenant(c *fiber.Ctx) error {
+	var tenant models.Tenant
+	if err := database.GetDB().First(&tenant, c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
+	}
+	id, tokenHash := tenant.ID, tenant.TokenHash
+
+	if err := c.BodyParser(&tenant); err != nil {
+		return c.Status(400).JSON(responses.ErrorResponse(responses.ErrInvalidInput))
+	}
+	tenant.ID, tenant.TokenHash = id, tokenHash
+
+	if err := validateTenant(database.GetDB(), &tenant); err != nil {
+		return validationErrorResponse(c, err)
+	}
+
+	if err := database.GetDB().Save(&tenant).Error; err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	return c.JSON(responses.SuccessResponse(responses.MsgSuccessUpdate, tenant))
+}
+
+// @Summary API-Token eines Mandanten erneuern
+// @Description Erzeugt einen neuen API-Token, der bisherige wird sofort ungültig. Der Token wird nur in dieser Antwort ausgeliefert. Benötigt ADMIN_TOKEN.
+// @Tags admin
+// @Produce json
+// @Param id path int true "Mandanten-ID"
+// @Success 200 {object} responses.APIResponse{data=ProvisionedTenant}
+// @Failure 403,404,500 {object} responses.APIResponse
+// @Router /api/v1/admin/tenants/{id}/token [post]
+func HandleRotateTenantToken(c *fiber.Ctx) error {
+	var tenant models.Tenant
+	if err := database.GetDB().First(&tenant, c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
+	}
+
+	token, err := newTenantToken()
+	if err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+	hash := hashToken(token)
+	tenant.TokenHash = &hash
+
+	if err := database.GetDB().Save(&tenant).Error; err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	return c.JSON(responses.SuccessResponse("Token erfolgreich erneuert", ProvisionedTenant{Tenant: tenant, Token: token}))
+}
+
+func validateTenant(db *gorm.DB, tenant *models.Tenant) error {
+	tenant.Name = strings.TrimSpace(tenant.Name)
+	tenant.Slug = strings.ToLower(strings.TrimSpace(tenant.Slug))
+	errs := models.ValidateModel(tenant)
+
+	var count int64
+	if err := db.Model(&models.Tenant{}).Where("slug = ? AND id != ?", tenant.Slug, tenant.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		errs.Add("slug", models.CodeDuplicate, "slug wird bereits verwendet")
+	}
+	return errs.Err()
+}
+
+func bearerToken(c *fiber.Ctx) (string, bool) {
+	token, found := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+	token = strings.TrimSpace(token)
+	return token, found && token != ""
+}
+
+// subdomain liefert den Slug aus einem Host unterhalb von TENANT_DOMAIN
+func subdomain(host string) (string, bool) {
+	domain := strings.ToLower(strings.TrimSpace(os.Getenv("TENANT_DOMAIN")))
+	if domain == "" {
+		return "", false
+	}
+	slug, found := strings.CutSuffix(strings.ToLower(host), "."+domain)
+	if !found || slug == "" || strings.Contains(slug, ".") {
+		return "", false
+	}
+	return slug, true
+}
+
+func newTenantToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
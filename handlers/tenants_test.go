@@ -0,0 +1,537 @@
+package handlers_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ptmmeiningen/schichtplaner/database"
+	"github.com/ptmmeiningen/schichtplaner/handlers"
+	"github.com/ptmmeiningen/schichtplaner/models"
+	"github.com/ptmmeiningen/schichtplaner/webhooks"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const adminToken = "geheim"
+
+// tenantData ist ein Mandant mit Token und je einem Datensatz der
+// wichtigsten Typen
+type tenantData struct {
+	id         uint
+	token      string
+	location   models.Location
+	department models.Department
+	shiftType  models.ShiftType
+	employee   models.Employee
+	week       models.ShiftWeek
+	day        models.ShiftDay
+}
+
+// forTenant liefert die Datenbank eingeschränkt auf den Mandanten
+func forTenant(tenantID uint) *gorm.DB {
+	return database.ForTenant(database.WithTenant(context.Background(), tenantID))
+}
+
+// setupTenants startet die App im Mandantenbetrieb mit zwei Mandanten, deren
+// Datensätze gleich heißen
+func setupTenants(t *testing.T) (*fiber.App, tenantData, tenantData) {
+	t.Helper()
+	t.Setenv("MULTI_TENANT", "true")
+	t.Setenv("ADMIN_TOKEN", adminToken)
+	app := setupApp(t)
+	return app, createTenant(t, app, "nord"), createTenant(t, app, "sued")
+}
+
+// createTenant legt einen Mandanten über die API an und füllt ihn direkt in
+// der Datenbank
+func createTenant(t *testing.T, app *fiber.App, slug string) tenantData {
+	t.Helper()
+	status, resp := call(t, app, "POST", "/api/v1/admin/tenants", adminToken, map[string]string{"name": slug, "slug": slug})
+	if status != 201 {
+		t.Fatalf("mandant %s: status %d, %s %s", slug, status, resp.Error, resp.Data)
+	}
+	var provisioned handlers.ProvisionedTenant
+	decode(t, resp, &provisioned)
+
+	tenant := tenantData{id: provisioned.ID, token: provisioned.Token}
+	create := func(value interface{}) {
+		t.Helper()
+		if err := forTenant(tenant.id).Omit(clause.Associations).Create(value).Error; err != nil {
+			t.Fatalf("mandant %s: anlegen von %T: %v", slug, value, err)
+		}
+	}
+
+	if err := forTenant(tenant.id).First(&tenant.location).Error; err != nil {
+		t.Fatal(err)
+	}
+	tenant.department = models.Department{Name: "Produktion", Color: "#0000ff", LocationID: tenant.location.ID}
+	create(&tenant.department)
+	tenant.shiftType = models.ShiftType{Name: "Früh", Color: "#00ff00", StartTime: "06:00", EndTime: "14:00"}
+	create(&tenant.shiftType)
+	tenant.employee = models.Employee{FirstName: "Anna", LastName: "Adler", Email: "anna@example.org", Password: "geheim123", Color: "#ff0000", DepartmentID: &tenant.department.ID}
+	create(&tenant.employee)
+	tenant.week = models.ShiftWeek{Year: 2030, CalendarWeek: 2, Status: models.StatusDraft, DepartmentID: &tenant.department.ID}
+	create(&tenant.week)
+	tenant.day = models.ShiftDay{Date: time.Date(2030, 1, 7, 0, 0, 0, 0, time.UTC), ShiftWeekID: &tenant.week.ID, ShiftTypeID: tenant.shiftType.ID, EmployeeID: &tenant.employee.ID}
+	create(&tenant.day)
+	return tenant
+}
+
+func TestUnknownTenantRejected(t *testing.T) {
+	app, a, _ := setupTenants(t)
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "ohne Token"},
+		{name: "unbekannter Token", token: "unbekannt"},
+		{name: "Admin-Token", token: adminToken},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if status, resp := call(t, app, "GET", "/api/v1/employees", test.token, nil); status != 401 {
+				t.Fatalf("status %d, erwartet 401: %s", status, resp.Error)
+			}
+		})
+	}
+
+	t.Run("deaktivierter Mandant", func(t *testing.T) {
+		if status, _ := call(t, app, "GET", "/api/v1/employees", a.token, nil); status != 200 {
+			t.Fatalf("aktiver mandant: status %d", status)
+		}
+		path := fmt.Sprintf("/api/v1/admin/tenants/%d", a.id)
+		if status, resp := call(t, app, "PUT", path, adminToken, map[string]bool{"active": false}); status != 200 {
+			t.Fatalf("deaktivieren: status %d, %s %s", status, resp.Error, resp.Data)
+		}
+		for _, path := range []string{"/api/v1/employees", "/api/v1/events", "/api/v1/trash?entity=employees"} {
+			if status, resp := call(t, app, "GET", path, a.token, nil); status != 401 {
+				t.Fatalf("%s: status %d, erwartet 401: %s", path, status, resp.Error)
+			}
+		}
+	})
+}
+
+func TestTenantCannotAccessOtherTenantByID(t *testing.T) {
+	app, a, b := setupTenants(t)
+
+	paths := []struct {
+		path string
+		id   uint
+		body map[string]interface{}
+	}{
+		{path: "/api/v1/locations", id: a.location.ID, body: map[string]interface{}{"name": "Übernommen"}},
+		{path: "/api/v1/departments", id: a.department.ID, body: map[string]interface{}{"name": "Übernommen", "color": "#0000ff", "location_id": b.location.ID}},
+		{path: "/api/v1/shifttypes", id: a.shiftType.ID, body: map[string]interface{}{"name": "Übernommen", "color": "#00ff00", "start_time": "06:00", "end_time": "14:00"}},
+		{path: "/api/v1/employees", id: a.employee.ID, body: map[string]interface{}{"first_name": "Bert", "last_name": "Bauer", "email": "bert@example.org", "color": "#ff0000"}},
+		{path: "/api/v1/shiftweeks", id: a.week.ID, body: map[string]interface{}{"year": 2030, "calendar_week": 5, "status": models.StatusDraft, "department_id": b.department.ID}},
+		{path: "/api/v1/shiftdays", id: a.day.ID, body: map[string]interface{}{"date": b.day.Date, "shift_week_id": b.week.ID, "shift_type_id": b.shiftType.ID, "employee_id": b.employee.ID}},
+	}
+	for _, p := range paths {
+		t.Run(p.path, func(t *testing.T) {
+			path := fmt.Sprintf("%s/%d", p.path, p.id)
+			for _, method := range []string{"GET", "PUT", "DELETE"} {
+				if status, resp := call(t, app, method, path, b.token, p.body); status != 404 {
+					t.Fatalf("%s %s: status %d, erwartet 404: %s %s", method, path, status, resp.Error, resp.Data)
+				}
+			}
+			if status, resp := call(t, app, "POST", path+"/restore", b.token, nil); status != 404 {
+				t.Fatalf("POST %s/restore: status %d, erwartet 404: %s", path, status, resp.Error)
+			}
+
+			// Die Liste enthält nur den gleichnamigen Datensatz des eigenen
+			// Mandanten
+			status, resp := call(t, app, "GET", p.path, b.token, nil)
+			if status != 200 {
+				t.Fatalf("GET %s: status %d, %s", p.path, status, resp.Error)
+			}
+			var list []struct {
+				ID uint `json:"id"`
+			}
+			decode(t, resp, &list)
+			for _, item := range list {
+				if item.ID == p.id {
+					t.Fatalf("GET %s liefert %d von mandant %d", p.path, p.id, a.id)
+				}
+			}
+		})
+	}
+
+	// Der Datensatz von A ist unverändert
+	status, resp := call(t, app, "GET", fmt.Sprintf("/api/v1/shifttypes/%d", a.shiftType.ID), a.token, nil)
+	if status != 200 {
+		t.Fatalf("status %d, %s", status, resp.Error)
+	}
+	var shiftType models.ShiftType
+	decode(t, resp, &shiftType)
+	if shiftType.Name != a.shiftType.Name {
+		t.Fatalf("name %q, erwartet %q", shiftType.Name, a.shiftType.Name)
+	}
+}
+
+func TestTenantCannotReferenceOtherTenant(t *testing.T) {
+	app, a, b := setupTenants(t)
+
+	tests := []struct {
+		name  string
+		path  string
+		input map[string]interface{}
+		field string
+	}{
+		{
+			name:  "Schichttyp",
+			path:  "/api/v1/shiftdays",
+			input: map[string]interface{}{"date": b.day.Date.AddDate(0, 0, 1), "shift_week_id": b.week.ID, "shift_type_id": a.shiftType.ID, "employee_id": b.employee.ID},
+			field: "shift_type_id",
+		},
+		{
+			name:  "Mitarbeiter",
+			path:  "/api/v1/shiftdays",
+			input: map[string]interface{}{"date": b.day.Date.AddDate(0, 0, 1), "shift_week_id": b.week.ID, "shift_type_id": b.shiftType.ID, "employee_id": a.employee.ID},
+			field: "employee_id",
+		},
+		{
+			name:  "Abteilung des Mitarbeiters",
+			path:  "/api/v1/employees",
+			input: map[string]interface{}{"first_name": "Bert", "last_name": "Bauer", "email": "bert@example.org", "password": "geheim123", "color": "#ff0000", "department_id": a.department.ID},
+			field: "department_id",
+		},
+		{
+			name:  "Abteilung der Schichtwoche",
+			path:  "/api/v1/shiftweeks",
+			input: map[string]interface{}{"year": 2030, "calendar_week": 5, "status": models.StatusDraft, "department_id": a.department.ID},
+			field: "department_id",
+		},
+		{
+			name:  "Standort der Abteilung",
+			path:  "/api/v1/departments",
+			input: map[string]interface{}{"name": "Versand", "color": "#0000ff", "location_id": a.location.ID},
+			field: "location_id",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			status, resp := call(t, app, "POST", test.path, b.token, test.input)
+			expectFieldErrors(t, status, resp, models.FieldError{Field: test.field, Code: models.CodeNotFound})
+		})
+	}
+
+	t.Run("Schichtwoche", func(t *testing.T) {
+		status, resp := call(t, app, "POST", "/api/v1/shiftdays", b.token, map[string]interface{}{
+			"date": b.day.Date.AddDate(0, 0, 1), "shift_week_id": a.week.ID, "shift_type_id": b.shiftType.ID, "employee_id": b.employee.ID,
+		})
+		if status != 404 {
+			t.Fatalf("status %d, erwartet 404: %s %s", status, resp.Error, resp.Data)
+		}
+	})
+
+	t.Run("Änderung", func(t *testing.T) {
+		path := fmt.Sprintf("/api/v1/shiftdays/%d", b.day.ID)
+		status, resp := call(t, app, "PUT", path, b.token, map[string]interface{}{
+			"date": b.day.Date, "shift_week_id": b.week.ID, "shift_type_id": a.shiftType.ID, "employee_id": b.employee.ID,
+		})
+		expectFieldErrors(t, status, resp, models.FieldError{Field: "shift_type_id", Code: models.CodeNotFound})
+	})
+}
+
+func TestTenantBulkShiftDays(t *testing.T) {
+	app, a, b := setupTenants(t)
+
+	status, resp := call(t, app, "POST", "/api/v1/shiftdays/bulk", b.token, map[string]interface{}{
+		"operations": []map[string]interface{}{
+			{"op": "update", "id": a.day.ID, "shift_day": map[string]interface{}{"date": a.day.Date, "shift_week_id": b.week.ID, "shift_type_id": b.shiftType.ID, "employee_id": b.employee.ID}},
+			{"op": "delete", "id": a.day.ID},
+			{"op": "create", "shift_day": map[string]interface{}{"date": a.day.Date, "shift_week_id": a.week.ID, "shift_type_id": b.shiftType.ID, "employee_id": b.employee.ID}},
+		},
+	})
+	if status != 422 {
+		t.Fatalf("status %d, erwartet 422: %s %s", status, resp.Error, resp.Data)
+	}
+	var results []handlers.BulkShiftDayResult
+	decode(t, resp, &results)
+	want := []string{"operations[0].id", "operations[1].id", "operations[2].shift_week_id"}
+	if len(results) != len(want) {
+		t.Fatalf("%d ergebnisse, erwartet %d", len(results), len(want))
+	}
+	for i, field := range want {
+		errs := results[i].Errors
+		if len(errs) != 1 || errs[0].Field != field || errs[0].Code != models.CodeNotFound {
+			t.Errorf("operation %d: fehler %+v, erwartet %s %s", i, errs, field, models.CodeNotFound)
+		}
+	}
+
+	var day models.ShiftDay
+	if err := forTenant(a.id).First(&day, a.day.ID).Error; err != nil {
+		t.Fatalf("schichttag von mandant %d: %v", a.id, err)
+	}
+	if *day.ShiftWeekID != a.week.ID || day.ShiftTypeID != a.shiftType.ID {
+		t.Fatalf("schichttag von mandant %d geändert: %+v", a.id, day)
+	}
+}
+
+func TestTenantCopyShiftWeek(t *testing.T) {
+	app, a, b := setupTenants(t)
+
+	path := fmt.Sprintf("/api/v1/shiftweeks/%d/copy", a.week.ID)
+	if status, resp := call(t, app, "POST", path, b.token, map[string]interface{}{"year": 2030, "calendar_week": 3}); status != 404 {
+		t.Fatalf("fremde woche: status %d, erwartet 404: %s %s", status, resp.Error, resp.Data)
+	}
+
+	path = fmt.Sprintf("/api/v1/shiftweeks/%d/copy", b.week.ID)
+	status, resp := call(t, app, "POST", path, b.token, map[string]interface{}{"year": 2030, "calendar_week": 3, "department_id": a.department.ID})
+	expectFieldErrors(t, status, resp, models.FieldError{Field: "department_id", Code: models.CodeNotFound})
+
+	status, resp = call(t, app, "POST", path, b.token, map[string]interface{}{"year": 2030, "calendar_week": 3})
+	if status != 201 {
+		t.Fatalf("eigene woche: status %d, %s %s", status, resp.Error, resp.Data)
+	}
+	for _, tenant := range []tenantData{a, b} {
+		var count int64
+		forTenant(tenant.id).Model(&models.ShiftDay{}).Count(&count)
+		want := int64(1)
+		if tenant.id == b.id {
+			want = 2
+		}
+		if count != want {
+			t.Fatalf("mandant %d: %d schichttage, erwartet %d", tenant.id, count, want)
+		}
+	}
+}
+
+func TestTenantTrash(t *testing.T) {
+	app, a, b := setupTenants(t)
+
+	deleteEmployee := func(tenant tenantData) {
+		t.Helper()
+		path := fmt.Sprintf("/api/v1/employees/%d", tenant.employee.ID)
+		if status, resp := call(t, app, "DELETE", path, tenant.token, nil); status != 200 {
+			t.Fatalf("mandant %d: status %d, %s %s", tenant.id, status, resp.Error, resp.Data)
+		}
+	}
+	trash := func(tenant tenantData) []models.Employee {
+		t.Helper()
+		status, resp := call(t, app, "GET", "/api/v1/trash?entity=employees", tenant.token, nil)
+		if status != 200 {
+			t.Fatalf("mandant %d: status %d, %s", tenant.id, status, resp.Error)
+		}
+		var employees []models.Employee
+		decode(t, resp, &employees)
+		return employees
+	}
+
+	// Schichttage verweisen per SET NULL auf den Mitarbeiter
+	deleteEmployee(a)
+	if employees := trash(b); len(employees) != 0 {
+		t.Fatalf("papierkorb von mandant %d enthält %+v", b.id, employees)
+	}
+	if employees := trash(a); len(employees) != 1 || employees[0].ID != a.employee.ID {
+		t.Fatalf("papierkorb von mandant %d: %+v", a.id, employees)
+	}
+
+	restore := fmt.Sprintf("/api/v1/employees/%d/restore", a.employee.ID)
+	if status, resp := call(t, app, "POST", restore, b.token, nil); status != 404 {
+		t.Fatalf("fremde wiederherstellung: status %d, erwartet 404: %s", status, resp.Error)
+	}
+
+	// Die Aufbewahrungsfrist gilt für alle Mandanten gleich: Das ältere
+	// Löschen von A wird endgültig, das jüngere von B nicht
+	cutoff := time.Now()
+	time.Sleep(10 * time.Millisecond)
+	deleteEmployee(b)
+	if err := forTenant(a.id).Unscoped().Model(&models.Employee{}).
+		Where("id = ?", a.employee.ID).
+		Update("deleted_at", cutoff.Add(-time.Hour)).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := database.Purge(cutoff); err != nil {
+		t.Fatal(err)
+	}
+	if employees := trash(a); len(employees) != 0 {
+		t.Fatalf("papierkorb von mandant %d nach dem leeren: %+v", a.id, employees)
+	}
+	if employees := trash(b); len(employees) != 1 || employees[0].ID != b.employee.ID {
+		t.Fatalf("papierkorb von mandant %d nach dem leeren: %+v", b.id, employees)
+	}
+	if status, resp := call(t, app, "POST", restore, a.token, nil); status != 404 {
+		t.Fatalf("endgültig gelöscht: status %d, erwartet 404: %s", status, resp.Error)
+	}
+	restore = fmt.Sprintf("/api/v1/employees/%d/restore", b.employee.ID)
+	if status, resp := call(t, app, "POST", restore, b.token, nil); status != 200 {
+		t.Fatalf("eigene wiederherstellung: status %d, %s %s", status, resp.Error, resp.Data)
+	}
+}
+
+// setupWebhooks verbindet die Webhooks nur einmal mit dem Ereignissystem
+var setupWebhooks sync.Once
+
+func TestTenantWebhookFanOut(t *testing.T) {
+	app, a, b := setupTenants(t)
+	setupWebhooks.Do(webhooks.Setup)
+
+	subscriptions := map[uint]*models.WebhookSubscription{}
+	for _, tenant := range []tenantData{a, b} {
+		subscription := &models.WebhookSubscription{URL: "https://example.org/hook", Secret: "geheim", Active: true}
+		if err := forTenant(tenant.id).Create(subscription).Error; err != nil {
+			t.Fatal(err)
+		}
+		subscriptions[tenant.id] = subscription
+	}
+
+	status, resp := call(t, app, "POST", "/api/v1/shiftdays", a.token, map[string]interface{}{
+		"date": a.day.Date.AddDate(0, 0, 1), "shift_week_id": a.week.ID, "shift_type_id": a.shiftType.ID, "employee_id": a.employee.ID,
+	})
+	if status != 201 {
+		t.Fatalf("status %d, %s %s", status, resp.Error, resp.Data)
+	}
+
+	for _, tenant := range []tenantData{a, b} {
+		var deliveries []models.WebhookDelivery
+		if err := database.GetDB().Where("subscription_id = ?", subscriptions[tenant.id].ID).Find(&deliveries).Error; err != nil {
+			t.Fatal(err)
+		}
+		want := 0
+		if tenant.id == a.id {
+			want = 1
+		}
+		if len(deliveries) != want {
+			t.Fatalf("mandant %d: %d zustellungen, erwartet %d", tenant.id, len(deliveries), want)
+		}
+		for _, delivery := range deliveries {
+			if delivery.TenantID != tenant.id {
+				t.Fatalf("zustellung an mandant %d gehört zu mandant %d", tenant.id, delivery.TenantID)
+			}
+		}
+	}
+}
+
+// openEventStream abonniert den Ereignisstrom der App unter addr
+func openEventStream(t *testing.T, addr, token string) *bufio.Reader {
+	t.Helper()
+	req, err := http.NewRequest("GET", "http://"+addr+"/api/v1/events", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	if resp.StatusCode != 200 {
+		t.Fatalf("status %d", resp.StatusCode)
+	}
+
+	stream := bufio.NewReader(resp.Body)
+	if line, err := stream.ReadString('\n'); err != nil || line != ": verbunden\n" {
+		t.Fatalf("begrüßung %q: %v", line, err)
+	}
+	return stream
+}
+
+// nextEvent liest das nächste Ereignis aus dem Strom und liefert Typ und
+// ID des geänderten Datensatzes
+func nextEvent(t *testing.T, stream *bufio.Reader) (string, uint) {
+	t.Helper()
+	var eventType string
+	for {
+		line, err := stream.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			eventType = strings.TrimSpace(strings.TrimPrefix(line, "event: "))
+		case strings.HasPrefix(line, "data: "):
+			var event struct {
+				Data struct {
+					ID uint `json:"id"`
+				} `json:"data"`
+			}
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event); err != nil {
+				t.Fatal(err)
+			}
+			return eventType, event.Data.ID
+		}
+	}
+}
+
+func TestTenantEventStream(t *testing.T) {
+	app, a, b := setupTenants(t)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go app.Listener(listener)
+	t.Cleanup(func() { app.ShutdownWithTimeout(time.Second) })
+
+	streams := map[uint]*bufio.Reader{}
+	for _, tenant := range []tenantData{a, b} {
+		streams[tenant.id] = openEventStream(t, listener.Addr().String(), tenant.token)
+	}
+
+	// Beide Mandanten legen einen Schichttyp an. Erreicht das Ereignis von A
+	// den Strom von B, liest B es vor dem eigenen.
+	created := map[uint]uint{}
+	for _, tenant := range []tenantData{a, b} {
+		status, resp := call(t, app, "POST", "/api/v1/shifttypes", tenant.token, map[string]interface{}{
+			"name": "Spät", "color": "#00ff00", "start_time": "14:00", "end_time": "22:00",
+		})
+		if status != 201 {
+			t.Fatalf("mandant %d: status %d, %s %s", tenant.id, status, resp.Error, resp.Data)
+		}
+		var shiftType models.ShiftType
+		decode(t, resp, &shiftType)
+		created[tenant.id] = shiftType.ID
+	}
+
+	for _, tenant := range []tenantData{a, b} {
+		done := make(chan struct{})
+		var eventType string
+		var id uint
+		go func() {
+			defer close(done)
+			eventType, id = nextEvent(t, streams[tenant.id])
+		}()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("mandant %d: kein ereignis", tenant.id)
+		}
+		if eventType != "shifttype.created" || id != created[tenant.id] {
+			t.Fatalf("mandant %d: ereignis %s für %d, erwartet shifttype.created für %d", tenant.id, eventType, id, created[tenant.id])
+		}
+	}
+}
+
+func TestTenantNotificationDedupe(t *testing.T) {
+	_, a, b := setupTenants(t)
+
+	key := "week_published:1:1"
+	for _, tenant := range []tenantData{a, b} {
+		notification := models.Notification{
+			EmployeeID: tenant.employee.ID, Channel: models.ChannelEmail, Kind: models.NotificationWeekPublished,
+			Recipient: tenant.employee.Email, Subject: "Dienstplan", DedupeKey: &key, NextAttemptAt: time.Now(),
+		}
+		if err := forTenant(tenant.id).Create(&notification).Error; err != nil {
+			t.Fatalf("mandant %d: %v", tenant.id, err)
+		}
+	}
+
+	duplicate := models.Notification{
+		EmployeeID: a.employee.ID, Channel: models.ChannelEmail, Kind: models.NotificationWeekPublished,
+		Recipient: a.employee.Email, Subject: "Dienstplan", DedupeKey: &key, NextAttemptAt: time.Now(),
+	}
+	if err := forTenant(a.id).Create(&duplicate).Error; err == nil {
+		t.Fatal("doppelter dedupe_key im selben mandanten angelegt")
+	}
+}
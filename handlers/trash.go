@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ptmmeiningen/schichtplaner/database"
+	"github.com/ptmmeiningen/schichtplaner/models"
+	"github.com/ptmmeiningen/schichtplaner/pkg/query"
+	"github.com/ptmmeiningen/schichtplaner/pkg/responses"
+	"gorm.io/gorm"
+)
+
+// trashEntity verbindet den Namen im Papierkorb mit Tabelle und Modell
+type trashEntity struct {
+	table string
+	model func() interface{}
+	list  func() interface{}
+}
+
+// trashEntities sind die Datensätze, die über den Papierkorb gelistet und
+// wiederhergestellt werden können. Die Namen entsprechen den API-Pfaden.
+var trashEntities = map[string]trashEntity{
+	"locations":      {table: "locations", model: func() interface{} { return &models.Location{} }, list: func() interface{} { return &[]models.Location{} }},
+	"departments":    {table: "departments", model: func() interface{} { return &models.Department{} }, list: func() interface{} { return &[]models.Department{} }},
+	"teams":          {table: "teams", model: func() interface{} { return &models.Team{} }, list: func() interface{} { return &[]models.Team{} }},
+	"employees":      {table: "employees", model: func() interface{} { return &models.Employee{} }, list: func() interface{} { return &[]models.Employee{} }},
+	"shifttypes":     {table: "shift_types", model: func() interface{} { return &models.ShiftType{} }, list: func() interface{} { return &[]models.ShiftType{} }},
+	"shifttemplates": {table: "shift_templates", model: func() interface{} { return &models.ShiftTemplate{} }, list: func() interface{} { return &[]models.ShiftTemplate{} }},
+	"shiftweeks":     {table: "shift_weeks", model: func() interface{} { return &models.ShiftWeek{} }, list: func() interface{} { return &[]models.ShiftWeek{} }},
+	"shiftdays":      {table: "shift_days", model: func() interface{} { return &models.ShiftDay{} }, list: func() interface{} { return &[]models.ShiftDay{} }},
+	"rotations":      {table: "rotations", model: func() interface{} { return &models.Rotation{} }, list: func() interface{} { return &[]models.Rotation{} }},
+	"oncall":         {table: "on_call_assignments", model: func() interface{} { return &models.OnCallAssignment{} }, list: func() interface{} { return &[]models.OnCallAssignment{} }},
+	"webhooks":       {table: "webhook_subscriptions", model: func() interface{} { return &models.WebhookSubscription{} }, list: func() interface{} { return &[]models.WebhookSubscription{} }},
+}
+
+func trashQuery(table string) query.Config {
+	return query.Config{
+		Table:  table,
+		Params: []string{"entity"},
+		Sorts: map[string]string{
+			"deleted_at": table + ".deleted_at",
+			"created_at": table + ".created_at",
+		},
+		DefaultSort: "-deleted_at",
+	}
+}
+
+// @Summary Papierkorb abrufen
+// @Description Listet die gelöschten Datensätze eines Typs, zuletzt gelöschte zuerst. Nach TRASH_RETENTION_DAYS werden sie endgültig gelöscht.
+// @Tags trash
+// @Produce json
+// @Param entity query string true "Typ (locations, departments, teams, employees, shifttypes, shifttemplates, shiftweeks, shiftdays, rotations, oncall, webhooks)"
+// @Param page query int false "Seite (ab 1)"
+// @Param page_size query int false "Einträge pro Seite (max. 200)"
+// @Param cursor query string false "Cursor für die nächste Seite (statt page)"
+// @Param sort query string false "Sortierung, Standard: -deleted_at"
+// @Success 200 {object} responses.APIResponse
+// @Failure 400,500 {object} responses.APIResponse
+// @Router /api/v1/trash [get]
+func HandleTrash(c *fiber.Ctx) error {
+	entity, ok := trashEntities[c.Query("entity")]
+	if !ok {
+		names := make([]string, 0, len(trashEntities))
+		for name := range trashEntities {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return c.Status(400).JSON(responses.ErrorResponse("entity muss einer der Werte " + strings.Join(names, ", ") + " sein"))
+	}
+
+	q, err := query.Parse(c, trashQuery(entity.table))
+	if err != nil {
+		return c.Status(400).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	records := entity.list()
+	deleted := tenantDB(c).Unscoped().Where(entity.table + ".deleted_at IS NOT NULL")
+	meta, err := q.Find(deleted, records)
+	if err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+	return c.JSON(responses.PaginatedResponse(responses.MsgSuccessGet, records, meta))
+}
+
+// restoreRecord stellt einen Datensatz aus dem Papierkorb wieder her
+func restoreRecord(c *fiber.Ctx, entity string) error {
+	id, err := c.ParamsInt("id")
+	if err != nil || id < 1 {
+		return c.Status(400).JSON(responses.ErrorResponse(responses.ErrInvalidInput))
+	}
+	table := trashEntities[entity].table
+
+	tx := tenantDB(c).Begin()
+
+	err = database.Restore(tx, table, uint(id))
+	if err != nil {
+		tx.Rollback()
+		var parentErr *database.ParentDeletedError
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
+		case errors.Is(err, database.ErrNotDeleted):
+			return c.Status(400).JSON(responses.ErrorResponse(err.Error()))
+		case errors.Is(err, database.ErrRestoreConflict):
+			return c.Status(409).JSON(responses.ErrorResponse(err.Error()))
+		case errors.As(err, &parentErr):
+			return c.Status(409).JSON(responses.ConflictResponse(responses.ErrParentDeleted, parentErr.Parents))
+		}
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	record := trashEntities[entity].model()
+	if err := tenantDB(c).First(record, id).Error; err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+	return c.JSON(responses.SuccessResponse(responses.MsgSuccessRestore, record))
+}
+
+// @Summary Standort wiederherstellen
+// @Description Stellt einen gelöschten Standort wieder her
+// @Tags trash
+// @Produce json
+// @Param id path int true "Standort-ID"
+// @Success 200 {object} responses.APIResponse{data=models.Location}
+// @Failure 400,404,409,500 {object} responses.APIResponse
+// @Router /api/v1/locations/{id}/restore [post]
+func HandleRestoreLocation(c *fiber.Ctx) error {
+	return restoreRecord(c, "locations")
+}
+
+// @Summary Team wiederherstellen
+// @Description Stellt ein gelöschtes Team samt mitgelöschten Schichtwochen wieder her. Mitarbeiter werden dem Team nicht wieder zugeordnet.
+// @Tags trash
+// @Produce json
+// @Param id path int true "Team-ID"
+// @Success 200 {object} responses.APIResponse{data=models.Team}
+// @Failure 400,404,409,500 {object} responses.APIResponse
+// @Router /api/v1/teams/{id}/restore [post]
+func HandleRestoreTeam(c *fiber.Ctx) error {
+	return restoreRecord(c, "teams")
+}
+
+// @Summary Abteilung wiederherstellen
+// @Description Stellt eine gelöschte Abteilung samt mitgelöschten Schichtwochen und Vorlagen wieder her
+// @Tags trash
+// @Produce json
+// @Param id path int true "Abteilungs-ID"
+// @Success 200 {object} responses.APIResponse{data=models.Department}
+// @Failure 400,404,409,500 {object} responses.APIResponse
+// @Router /api/v1/departments/{id}/restore [post]
+func HandleRestoreDepartment(c *fiber.Ctx) error {
+	return restoreRecord(c, "departments")
+}
+
+// @Summary Mitarbeiter wiederherstellen
+// @Description Stellt einen gelöschten Mitarbeiter wieder her. Ist seine Abteilung gelöscht, wird die Zuordnung entfernt.
+// @Tags trash
+// @Produce json
+// @Param id path int true "Mitarbeiter-ID"
+// @Success 200 {object} responses.APIResponse{data=models.Employee}
+// @Failure 400,404,409,500 {object} responses.APIResponse
+// @Router /api/v1/employees/{id}/restore [post]
+func HandleRestoreEmployee(c *fiber.Ctx) error {
+	return restoreRecord(c, "employees")
+}
+
+// @Summary Schichttyp wiederherstellen
+// @Description Stellt einen gelöschten Schichttyp wieder her
+// @Tags trash
+// @Produce json
+// @Param id path int true "Schichttyp-ID"
+// @Success 200 {object} responses.APIResponse{data=models.ShiftType}
+// @Failure 400,404,409,500 {object} responses.APIResponse
+// @Router /api/v1/shifttypes/{id}/restore [post]
+func HandleRestoreShiftType(c *fiber.Ctx) error {
+	return restoreRecord(c, "shifttypes")
+}
+
+// @Summary Schichtvorlage wiederherstellen
+// @Description Stellt eine gelöschte Schichtvorlage samt ihrer Tage wieder her
+// @Tags trash
+// @Produce json
+// @Param id path int true "Vorlagen-ID"
+// @Success 200 {object} responses.APIResponse{data=models.ShiftTemplate}
+// @Failure 400,404,409,500 {object} responses.APIResponse
+// @Router /api/v1/shifttemplates/{id}/restore [post]
+func HandleRestoreShiftTemplate(c *fiber.Ctx) error {
+	return restoreRecord(c, "shifttemplates")
+}
+
+// @Summary Schichtwoche wiederherstellen
+// @Description Stellt eine gelöschte Schichtwoche samt der mit ihr gelöschten Schichttage wieder her
+// @Tags trash
+// @Produce json
+// @Param id path int true "Schichtwoche-ID"
+// @Success 200 {object} responses.APIResponse{data=models.ShiftWeek}
+// @Failure 400,404,409,500 {object} responses.APIResponse
+// @Router /api/v1/shiftweeks/{id}/restore [post]
+func HandleRestoreShiftWeek(c *fiber.Ctx) error {
+	return restoreRecord(c, "shiftweeks")
+}
+
+// @Summary Schichttag wiederherstellen
+// @Description Stellt einen gelöschten Schichttag wieder her, sofern Schichtwoche und Schichttyp noch bestehen
+// @Tags trash
+// @Produce json
+// @Param id path int true "Schichttag-ID"
+// @Success 200 {object} responses.APIResponse{data=models.ShiftDay}
+// @Failure 400,404,409,500 {object} responses.APIResponse
+// @Router /api/v1/shiftdays/{id}/restore [post]
+func HandleRestoreShiftDay(c *fiber.Ctx) error {
+	return restoreRecord(c, "shiftdays")
+}
+
+// @Summary Webhook wiederherstellen
+// @Description Stellt ein gelöschtes Webhook-Abonnement samt Zustellprotokoll wieder her
+// @Tags trash
+// @Produce json
+// @Param id path int true "Webhook-ID"
+// @Success 200 {object} responses.APIResponse{data=models.WebhookSubscription}
+// @Failure 400,404,409,500 {object} responses.APIResponse
+// @Router /api/v1/webhooks/{id}/restore [post]
+func HandleRestoreWebhook(c *fiber.Ctx) error {
+	return restoreRecord(c, "webhooks")
+}
+
+// @Summary Rotation wiederherstellen
+// @Description Stellt eine gelöschte Rotation samt Muster und Schichtgruppen wieder her
+// @Tags trash
+// @Produce json
+// @Param id path int true "Rotations-ID"
+// @Success 200 {object} responses.APIResponse{data=models.Rotation}
+// @Failure 400,404,409,500 {object} responses.APIResponse
+// @Router /api/v1/rotations/{id}/restore [post]
+func HandleRestoreRotation(c *fiber.Ctx) error {
+	return restoreRecord(c, "rotations")
+}
+
+// @Summary Rufbereitschaft wiederherstellen
+// @Description Stellt eine gelöschte Rufbereitschaft samt Einsätzen wieder her
+// @Tags trash
+// @Produce json
+// @Param id path int true "Rufbereitschafts-ID"
+// @Success 200 {object} responses.APIResponse{data=models.OnCallAssignment}
+// @Failure 400,404,409,500 {object} responses.APIResponse
+// @Router /api/v1/oncall/{id}/restore [post]
+func HandleRestoreOnCall(c *fiber.Ctx) error {
+	return restoreRecord(c, "oncall")
+}
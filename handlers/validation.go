@@ -0,0 +1,19 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ptmmeiningen/schichtplaner/models"
+	"github.com/ptmmeiningen/schichtplaner/pkg/responses"
+)
+
+// validationErrorResponse antwortet bei Validierungsfehlern mit 422 und allen
+// Fehlern je Feld, bei anderen Fehlern, etwa der Datenbank, mit 500
+func validationErrorResponse(c *fiber.Ctx, err error) error {
+	var validationErrors models.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		return c.Status(422).JSON(responses.ValidationResponse(validationErrors))
+	}
+	return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+}
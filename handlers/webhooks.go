@@ -0,0 +1,304 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ptmmeiningen/schichtplaner/database"
+	"github.com/ptmmeiningen/schichtplaner/models"
+	"github.com/ptmmeiningen/schichtplaner/pkg/events"
+	"github.com/ptmmeiningen/schichtplaner/pkg/query"
+	"github.com/ptmmeiningen/schichtplaner/pkg/responses"
+	"github.com/ptmmeiningen/schichtplaner/webhooks"
+)
+
+// webhookQuery legt die Sortierungen für Webhook-Listen fest
+var webhookQuery = query.Config{
+	Table: "webhook_subscriptions",
+	Filters: map[string]query.Filter{
+		"active": {Condition: "webhook_subscriptions.active = ?", Parse: query.Bool},
+	},
+	Sorts: map[string]string{
+		"url":        "webhook_subscriptions.url",
+		"created_at": "webhook_subscriptions.created_at",
+	},
+	DefaultSort: "-created_at",
+}
+
+// webhookDeliveryQuery legt die Filter und Sortierungen für das Zustellprotokoll fest
+var webhookDeliveryQuery = query.Config{
+	Table: "webhook_deliveries",
+	Filters: map[string]query.Filter{
+		"status":     {Condition: "webhook_deliveries.status = ?", Parse: query.String},
+		"event_type": {Condition: "webhook_deliveries.event_type = ?", Parse: query.String},
+	},
+	Sorts: map[string]string{
+		"created_at":      "webhook_deliveries.created_at",
+		"next_attempt_at": "webhook_deliveries.next_attempt_at",
+	},
+	DefaultSort: "-created_at",
+}
+
+// @Summary Alle Webhooks abrufen
+// @Description Ruft alle Webhook-Abonnements ab (ohne Signaturgeheimnis)
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param page query int false "Seite (ab 1)"
+// @Param page_size query int false "Einträge pro Seite (max. 200)"
+// @Param cursor query string false "Cursor für die nächste Seite (statt page)"
+// @Param active query bool false "Nur aktive bzw. inaktive Webhooks"
+// @Param sort query string false "Sortierung, z.B. -created_at"
+// @Success 200 {object} responses.APIResponse{data=[]models.WebhookSubscription}
+// @Failure 400,500 {object} responses.APIResponse
+// @Router /api/v1/webhooks [get]
+func HandleAllWebhooks(c *fiber.Ctx) error {
+	q, err := query.Parse(c, webhookQuery)
+	if err != nil {
+		return c.Status(400).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	var subscriptions []models.WebhookSubscription
+	meta, err := q.Find(tenantDB(c), &subscriptions)
+	if err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	for i := range subscriptions {
+		subscriptions[i].Secret = ""
+	}
+
+	return c.JSON(responses.PaginatedResponse(responses.MsgSuccessGet, subscriptions, meta))
+}
+
+// @Summary Webhook erstellen
+// @Description Legt ein Webhook-Abonnement an. Ohne Angabe wird ein Signaturgeheimnis erzeugt und einmalig zurückgegeben. Die URL muss https verwenden und darf nicht auf interne Adressen zeigen, mit WEBHOOK_ALLOWED_HOSTS nur auf freigegebene Hosts.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param webhook body models.WebhookSubscription true "Webhook-Daten"
+// @Success 201 {object} responses.APIResponse{data=models.WebhookSubscription}
+// @Failure 400,500 {object} responses.APIResponse
+// @Failure 422 {object} responses.APIResponse{data=[]models.FieldError}
+// @Router /api/v1/webhooks [post]
+func HandleCreateWebhook(c *fiber.Ctx) error {
+	subscription := new(models.WebhookSubscription)
+	subscription.Active = true
+	if err := c.BodyParser(subscription); err != nil {
+		return c.Status(400).JSON(responses.ErrorResponse(responses.ErrInvalidInput))
+	}
+
+	if err := validateWebhook(subscription); err != nil {
+		return validationErrorResponse(c, err)
+	}
+
+	if subscription.Secret == "" {
+		secret, err := webhooks.GenerateSecret()
+		if err != nil {
+			return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+		}
+		subscription.Secret = secret
+	}
+
+	if err := tenantDB(c).Create(subscription).Error; err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	return c.Status(201).JSON(responses.SuccessResponse(responses.MsgSuccessCreate, subscription))
+}
+
+// @Summary Einzelnen Webhook abrufen
+// @Description Ruft ein Webhook-Abonnement ab (ohne Signaturgeheimnis)
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param id path int true "Webhook-ID"
+// @Success 200 {object} responses.APIResponse{data=models.WebhookSubscription}
+// @Failure 404 {object} responses.APIResponse
+// @Router /api/v1/webhooks/{id} [get]
+func HandleGetOneWebhook(c *fiber.Ctx) error {
+	id := c.Params("id")
+	var subscription models.WebhookSubscription
+
+	if err := tenantDB(c).First(&subscription, id).Error; err != nil {
+		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
+	}
+
+	subscription.Secret = ""
+	return c.JSON(responses.SuccessResponse(responses.MsgSuccessGet, subscription))
+}
+
+// @Summary Webhook aktualisieren
+// @Description Aktualisiert URL, Ereignisfilter oder Status eines Webhooks. Ein leeres Geheimnis behält das bisherige.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param id path int true "Webhook-ID"
+// @Param webhook body models.WebhookSubscription true "Aktualisierte Webhook-Daten"
+// @Success 200 {object} responses.APIResponse{data=models.WebhookSubscription}
+// @Failure 400,404,500 {object} responses.APIResponse
+// @Failure 422 {object} responses.APIResponse{data=[]models.FieldError}
+// @Router /api/v1/webhooks/{id} [put]
+func HandleUpdateWebhook(c *fiber.Ctx) error {
+	id := c.Params("id")
+	var subscription models.WebhookSubscription
+
+	if err := tenantDB(c).First(&subscription, id).Error; err != nil {
+		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
+	}
+
+	secret := subscription.Secret
+	subscription.Secret = ""
+	if err := c.BodyParser(&subscription); err != nil {
+		return c.Status(400).JSON(responses.ErrorResponse(responses.ErrInvalidInput))
+	}
+	if subscription.Secret == "" {
+		subscription.Secret = secret
+	}
+
+	if err := validateWebhook(&subscription); err != nil {
+		return validationErrorResponse(c, err)
+	}
+
+	if err := tenantDB(c).Save(&subscription).Error; err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	subscription.Secret = ""
+	return c.JSON(responses.SuccessResponse(responses.MsgSuccessUpdate, subscription))
+}
+
+// @Summary Webhook löschen
+// @Description Löscht ein Webhook-Abonnement
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param id path int true "Webhook-ID"
+// @Success 200 {object} responses.APIResponse
+// @Failure 404,500 {object} responses.APIResponse
+// @Router /api/v1/webhooks/{id} [delete]
+func HandleDeleteWebhook(c *fiber.Ctx) error {
+	id := c.Params("id")
+	var subscription models.WebhookSubscription
+
+	if err := tenantDB(c).First(&subscription, id).Error; err != nil {
+		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
+	}
+
+	tx := tenantDB(c).Begin()
+
+	if err := database.SoftDelete(tx, "webhook_subscriptions", subscription.ID); err != nil {
+		tx.Rollback()
+		return deleteErrorResponse(c, err)
+	}
+
+	tx.Commit()
+
+	return c.JSON(responses.SuccessResponse(responses.MsgSuccessDelete, nil))
+}
+
+// @Summary Zustellprotokoll eines Webhooks abrufen
+// @Description Ruft alle Zustellversuche eines Webhooks ab, neueste zuerst
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param id path int true "Webhook-ID"
+// @Param status query string false "Status (pending/delivered/failed)"
+// @Param event_type query string false "Ereignistyp"
+// @Param page query int false "Seite (ab 1)"
+// @Param page_size query int false "Einträge pro Seite (max. 200)"
+// @Param cursor query string false "Cursor für die nächste Seite (statt page)"
+// @Param sort query string false "Sortierung, z.B. -created_at"
+// @Success 200 {object} responses.APIResponse{data=[]models.WebhookDelivery}
+// @Failure 400,404,500 {object} responses.APIResponse
+// @Router /api/v1/webhooks/{id}/deliveries [get]
+func HandleWebhookDeliveries(c *fiber.Ctx) error {
+	id := c.Params("id")
+	var subscription models.WebhookSubscription
+
+	if err := tenantDB(c).First(&subscription, id).Error; err != nil {
+		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
+	}
+
+	q, err := query.Parse(c, webhookDeliveryQuery)
+	if err != nil {
+		return c.Status(400).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	var deliveries []models.WebhookDelivery
+	meta, err := q.Find(tenantDB(c).Where("webhook_deliveries.subscription_id = ?", subscription.ID), &deliveries)
+	if err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	return c.JSON(responses.PaginatedResponse(responses.MsgSuccessGet, deliveries, meta))
+}
+
+// @Summary Webhook erneut zustellen
+// @Description Stellt eine protokollierte Zustellung sofort erneut zu und legt dafür einen neuen Protokolleintrag an
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param id path int true "Zustellungs-ID"
+// @Success 200 {object} responses.APIResponse{data=models.WebhookDelivery}
+// @Failure 404,500 {object} responses.APIResponse
+// @Router /api/v1/webhooks/deliveries/{id}/redeliver [post]
+func HandleRedeliverWebhook(c *fiber.Ctx) error {
+	id := c.Params("id")
+	var original models.WebhookDelivery
+
+	if err := tenantDB(c).First(&original, id).Error; err != nil {
+		return c.Status(404).JSON(responses.ErrorResponse(responses.ErrNotFound))
+	}
+
+	delivery := models.WebhookDelivery{
+		SubscriptionID: original.SubscriptionID,
+		EventType:      original.EventType,
+		Payload:        original.Payload,
+		Status:         models.WebhookPending,
+		NextAttemptAt:  time.Now(),
+	}
+	if err := tenantDB(c).Create(&delivery).Error; err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	if err := webhooks.Deliver(&delivery); err != nil {
+		return c.Status(500).JSON(responses.ErrorResponse(err.Error()))
+	}
+
+	return c.JSON(responses.SuccessResponse(responses.MsgSuccessUpdate, delivery))
+}
+
+func validateWebhook(subscription *models.WebhookSubscription) error {
+	errs := models.ValidateModel(subscription)
+	if !errs.Has("url") {
+		if err := webhooks.CheckURL(subscription.URL); err != nil {
+			errs.Add("url", models.CodeNotAllowed, err.Error())
+		}
+	}
+
+	for i, eventType := range subscription.EventTypes {
+		if !isKnownEventPattern(eventType) {
+			errs.Addf(fmt.Sprintf("event_types[%d]", i), models.CodeInvalid, "unbekannter ereignistyp: %s", eventType)
+		}
+	}
+
+	return errs.Err()
+}
+
+func isKnownEventPattern(pattern string) bool {
+	if pattern == "*" {
+		return true
+	}
+	for _, eventType := range events.Types {
+		if eventType == pattern {
+			return true
+		}
+		if strings.HasSuffix(pattern, ".*") && strings.HasPrefix(eventType, strings.TrimSuffix(pattern, "*")) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/ptmmeiningen/schichtplaner/models"
+	"gorm.io/gorm"
+)
+
+// Grenzen des Jugendarbeitsschutzgesetzes für Mitarbeiter unter 18 Jahren
+const (
+	youthMaxDailyHours = 8                // § 8 Abs. 1 JArbSchG
+	youthMinRest       = 12 * time.Hour   // § 13 JArbSchG
+	youthEarliestStart = 6 * time.Hour    // § 14 Abs. 1 JArbSchG
+	youthLatestEnd     = 20 * time.Hour   // § 14 Abs. 1 JArbSchG
+	youthLongBreak     = 60 * time.Minute // § 11 Abs. 1 JArbSchG, über 6 Stunden
+	youthShortBreak    = 30 * time.Minute // § 11 Abs. 1 JArbSchG, 4,5 bis 6 Stunden
+)
+
+// youthWorkingTime ist die Arbeitszeit ohne Pausen, mindestens ohne die
+// längeren Ruhepausen nach § 11 JArbSchG
+func youthWorkingTime(shiftDay *models.ShiftDay, shiftType *models.ShiftType) time.Duration {
+	duration := shiftDay.Duration(shiftType)
+	youthBreak := time.Duration(0)
+	switch {
+	case duration.Gross > 6*time.Hour:
+		youthBreak = youthLongBreak
+	case duration.Gross > 4*time.Hour+30*time.Minute:
+		youthBreak = youthShortBreak
+	}
+	return duration.Gross - max(duration.Break, youthBreak)
+}
+
+// checkYouthProtection prüft eine Schicht eines minderjährigen Mitarbeiters
+// auf Nachtruhe, Höchstarbeitszeit und Ruhezeit zu den Schichten am Vortag
+// und Folgetag. Verstöße werden als models.ValidationErrors mit Feld
+// employee_id und Code labour_law gemeldet, für volljährige Mitarbeiter gibt
+// es keine.
+func checkYouthProtection(db *gorm.DB, employee *models.Employee, shiftDay *models.ShiftDay) error {
+	if !employee.IsMinorOn(shiftDay.Date) || isAbsence(shiftDay.Status) {
+		return nil
+	}
+
+	var shiftType models.ShiftType
+	if err := db.First(&shiftType, shiftDay.ShiftTypeID).Error; err != nil {
+		return models.NewValidationError("shift_type_id", models.CodeNotFound, "schichttyp nicht gefunden")
+	}
+
+	var violations models.ValidationErrors
+	begin, end := shiftType.Interval(shiftDay.Date)
+	day := truncateDay(shiftDay.Date)
+	if begin.Before(day.Add(youthEarliestStart)) || end.After(day.Add(youthLatestEnd)) {
+		violations.Addf("employee_id", models.CodeLabourLaw, "jugendliche dürfen nur zwischen 6 und 20 uhr beschäftigt werden (schicht %s–%s)", shiftType.StartTime, shiftType.EndTime)
+	}
+	if hours := youthWorkingTime(shiftDay, &shiftType).Hours(); hours > youthMaxDailyHours {
+		violations.Addf("employee_id", models.CodeLabourLaw, "jugendliche dürfen höchstens %d stunden täglich arbeiten (schicht %.1f stunden ohne pausen)", youthMaxDailyHours, hours)
+	}
+
+	var neighbours []models.ShiftDay
+	if err := db.Preload("ShiftType").
+		Where("employee_id = ? AND id != ? AND date >= ? AND date < ?", employee.ID, shiftDay.ID, day.AddDate(0, 0, -1), day.AddDate(0, 0, 2)).
+		Where("status NOT IN ?", models.AbsenceStatuses).
+		Find(&neighbours).Error; err != nil {
+		return err
+	}
+	for _, neighbour := range neighbours {
+		otherBegin, otherEnd := neighbour.ShiftType.Interval(neighbour.Date)
+		var rest time.Duration
+		if otherBegin.Before(begin) {
+			rest = begin.Sub(otherEnd)
+		} else {
+			rest = otherBegin.Sub(end)
+		}
+		if rest < youthMinRest {
+			violations.Addf("employee_id", models.CodeLabourLaw, "jugendliche brauchen 12 stunden ruhezeit, zur schicht am %s liegen nur %.1f stunden", neighbour.Date.Format("02.01.2006"), rest.Hours())
+		}
+	}
+
+	return violations.Err()
+}
+
+// checkWeekYouthProtection prüft vor dem Veröffentlichen alle Schichten
+// minderjähriger Mitarbeiter einer Schichtwoche. Verstöße werden am Feld
+// status gemeldet, mit Mitarbeiter und Datum in der Meldung.
+func checkWeekYouthProtection(db *gorm.DB, shiftWeek *models.ShiftWeek) error {
+	var shiftDays []models.ShiftDay
+	if err := db.Preload("Employee").
+		Where("shift_week_id = ? AND employee_id IS NOT NULL", shiftWeek.ID).
+		Order("date, id").
+		Find(&shiftDays).Error; err != nil {
+		return err
+	}
+
+	var violations models.ValidationErrors
+	for i := range shiftDays {
+		shiftDay := &shiftDays[i]
+		var found models.ValidationErrors
+		if err := found.Merge("", checkYouthProtection(db, &shiftDay.Employee, shiftDay)); err != nil {
+			return err
+		}
+		for _, violation := range found {
+			violations.Addf("status", violation.Code, "%s %s am %s: %s", shiftDay.Employee.FirstName, shiftDay.Employee.LastName, shiftDay.Date.Format("02.01.2006"), violation.Message)
+		}
+	}
+	return violations.Err()
+}
@@ -0,0 +1,96 @@
+package handlers_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ptmmeiningen/schichtplaner/database"
+	"github.com/ptmmeiningen/schichtplaner/database/databasetest"
+	"github.com/ptmmeiningen/schichtplaner/models"
+)
+
+func TestYouthProtection(t *testing.T) {
+	app := setupApp(t)
+	department := databasetest.Department(t, "Produktion")
+	week := models.ShiftWeek{Year: 2030, CalendarWeek: 2, Status: models.StatusDraft, DepartmentID: &department.ID}
+	databasetest.Create(t, &week)
+
+	birthDate := time.Date(2014, 3, 1, 0, 0, 0, 0, time.UTC)
+	minor := databasetest.Employee(t, "azubi@example.org", department.ID)
+	minor.BirthDate = &birthDate
+	databasetest.Save(t, &minor)
+	adult := databasetest.Employee(t, "anna@example.org", department.ID)
+
+	early := databasetest.ShiftType(t, "Früh", "05:00", "12:00")
+	late := databasetest.ShiftType(t, "Spät", "14:00", "21:00")
+	long := databasetest.ShiftType(t, "Lang", "06:00", "16:00")
+	day := databasetest.ShiftType(t, "Tag", "08:00", "16:00")
+	evening := databasetest.ShiftType(t, "Abend", "12:00", "20:00")
+	morning := databasetest.ShiftType(t, "Morgen", "06:00", "14:00")
+
+	monday := time.Date(2030, 1, 7, 0, 0, 0, 0, time.UTC)
+	createShiftDay := func(employee models.Employee, shiftType models.ShiftType, date time.Time) (int, apiResponse) {
+		return call(t, app, "POST", "/api/v1/shiftdays", "", map[string]interface{}{
+			"date": date, "shift_week_id": week.ID, "shift_type_id": shiftType.ID, "employee_id": employee.ID,
+		})
+	}
+	labourLaw := models.FieldError{Field: "employee_id", Code: models.CodeLabourLaw}
+
+	t.Run("nur zwischen 6 und 20 Uhr", func(t *testing.T) {
+		status, resp := createShiftDay(minor, early, monday)
+		expectFieldErrors(t, status, resp, labourLaw)
+		status, resp = createShiftDay(minor, late, monday)
+		expectFieldErrors(t, status, resp, labourLaw)
+	})
+
+	t.Run("höchstens 8 Stunden täglich", func(t *testing.T) {
+		status, resp := createShiftDay(minor, long, monday)
+		expectFieldErrors(t, status, resp, labourLaw)
+	})
+
+	t.Run("12 Stunden Ruhezeit", func(t *testing.T) {
+		if status, resp := createShiftDay(minor, evening, monday.AddDate(0, 0, 1)); status != 201 {
+			t.Fatalf("status %d, %s %s", status, resp.Error, resp.Data)
+		}
+		// Nach 20 Uhr am Vortag liegen bis 6 Uhr nur 10 Stunden
+		status, resp := createShiftDay(minor, morning, monday.AddDate(0, 0, 2))
+		expectFieldErrors(t, status, resp, labourLaw)
+		// Bis 8 Uhr sind es 12 Stunden
+		if status, resp := createShiftDay(minor, day, monday.AddDate(0, 0, 2)); status != 201 {
+			t.Fatalf("status %d, %s %s", status, resp.Error, resp.Data)
+		}
+	})
+
+	t.Run("Volljährige nicht betroffen", func(t *testing.T) {
+		for i, shiftType := range []models.ShiftType{early, late, long} {
+			if status, resp := createShiftDay(adult, shiftType, monday.AddDate(0, 0, 2*i)); status != 201 {
+				t.Fatalf("%s: status %d, %s %s", shiftType.Name, status, resp.Error, resp.Data)
+			}
+		}
+	})
+
+	t.Run("vor dem Veröffentlichen", func(t *testing.T) {
+		// Am Handler vorbei geschriebene Schichten fallen beim
+		// Veröffentlichen auf
+		shiftDay := models.ShiftDay{Date: monday.AddDate(0, 0, 4), ShiftWeekID: &week.ID, ShiftTypeID: early.ID, EmployeeID: &minor.ID}
+		databasetest.Create(t, &shiftDay)
+
+		path := fmt.Sprintf("/api/v1/shiftweeks/%d/status", week.ID)
+		status, resp := call(t, app, "PUT", path, "", map[string]string{"status": models.StatusPublished})
+		expectFieldErrors(t, status, resp, models.FieldError{Field: "status", Code: models.CodeLabourLaw})
+
+		// Auch das Aktualisieren der Woche veröffentlicht nicht an der
+		// Prüfung vorbei
+		status, resp = call(t, app, "PUT", fmt.Sprintf("/api/v1/shiftweeks/%d", week.ID), "", map[string]interface{}{
+			"year": week.Year, "calendar_week": week.CalendarWeek, "department_id": department.ID, "status": models.StatusPublished,
+		})
+		expectFieldErrors(t, status, resp, models.FieldError{Field: "status", Code: models.CodeNotAllowed})
+
+		var stored models.ShiftWeek
+		database.GetDB().First(&stored, week.ID)
+		if stored.Status != models.StatusDraft || stored.PublishedAt != nil {
+			t.Fatalf("woche %s, veröffentlicht %v", stored.Status, stored.PublishedAt)
+		}
+	})
+}
@@ -8,6 +8,10 @@ import (
 
 type DeletedAt = gorm.DeletedAt
 
+// BaseModel enthält die gemeinsamen Felder aller Modelle. Modelle mit
+// mandantenweit eindeutigen Feldern deklarieren TenantID selbst und verdecken
+// damit das Feld aus BaseModel, damit tenant_id als erste Spalte in ihre
+// eindeutigen Indizes eingeht.
 type BaseModel struct {
 	ID        uint      `json:"id" gorm:"primarykey;autoIncrement" swaggertype:"integer"`
 	CreatedAt time.Time `json:"created_at" gorm:"not null;default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date-time"`
@@ -16,4 +20,5 @@ type BaseModel struct {
 	CreatedBy uint      `json:"created_by,omitempty" gorm:"default:null" swaggertype:"integer"`
 	UpdatedBy uint      `json:"updated_by,omitempty" gorm:"default:null" swaggertype:"integer"`
 	Version   uint      `json:"version" gorm:"default:1" swaggertype:"integer"`
+	TenantID  uint      `json:"-" gorm:"not null;default:1;index"`
 }
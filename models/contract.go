@@ -0,0 +1,69 @@
+package models
+
+import "time"
+
+// Beschäftigungsarten eines Vertrags
+const (
+	EmploymentFullTime   = "full_time"
+	EmploymentPartTime   = "part_time"
+	EmploymentMiniJob    = "mini_job"
+	EmploymentApprentice = "apprentice"
+)
+
+// Contract ist ein Abschnitt der Vertragshistorie eines Mitarbeiters. Ohne
+// ValidTo gilt der Vertrag unbefristet. MaxDaysPerWeek 0 bedeutet keine
+// Begrenzung.
+type Contract struct {
+	BaseModel
+	EmployeeID     uint       `json:"employee_id" gorm:"not null;index"`
+	Employee       Employee   `json:"employee" gorm:"constraint:OnDelete:CASCADE" swaggerignore:"true"`
+	EmploymentType string     `json:"employment_type" gorm:"type:varchar(20);not null;check:employment_type IN ('full_time','part_time','mini_job','apprentice')"`
+	ValidFrom      time.Time  `json:"valid_from" gorm:"not null"`
+	ValidTo        *time.Time `json:"valid_to"`
+	WeeklyHours    float64    `json:"weekly_hours" gorm:"not null;check:weekly_hours >= 0"`
+	MaxDaysPerWeek int        `json:"max_days_per_week" gorm:"not null;default:0;check:max_days_per_week >= 0 AND max_days_per_week <= 7"`
+}
+
+// IsActiveOn gibt an, ob der Vertrag am Datum gilt. ValidTo ist der letzte
+// Arbeitstag.
+func (c *Contract) IsActiveOn(date time.Time) bool {
+	day := date.Format("2006-01-02")
+	if day < c.ValidFrom.Format("2006-01-02") {
+		return false
+	}
+	return c.ValidTo == nil || day <= c.ValidTo.Format("2006-01-02")
+}
+
+// ContractOn liefert den Vertrag, der am Datum gilt
+func ContractOn(contracts []Contract, date time.Time) *Contract {
+	for i := range contracts {
+		if contracts[i].IsActiveOn(date) {
+			return &contracts[i]
+		}
+	}
+	return nil
+}
+
+// Validate prüft Beschäftigungsart, Gültigkeit, Wochenstunden und Arbeitstage
+func (c *Contract) Validate() error {
+	var errs ValidationErrors
+	switch c.EmploymentType {
+	case EmploymentFullTime, EmploymentPartTime, EmploymentMiniJob, EmploymentApprentice:
+	case "":
+		errs.Add("employment_type", CodeRequired, "beschäftigungsart ist erforderlich")
+	default:
+		errs.Add("employment_type", CodeInvalid, "beschäftigungsart muss full_time, part_time, mini_job oder apprentice sein")
+	}
+	if c.ValidFrom.IsZero() {
+		errs.Add("valid_from", CodeRequired, "gültig ab ist erforderlich")
+	} else if c.ValidTo != nil && c.ValidTo.Format("2006-01-02") < c.ValidFrom.Format("2006-01-02") {
+		errs.Add("valid_to", CodeOutOfRange, "gültig bis darf nicht vor gültig ab liegen")
+	}
+	if c.WeeklyHours < 0 || c.WeeklyHours > 60 {
+		errs.Add("weekly_hours", CodeOutOfRange, "wochenstunden müssen zwischen 0 und 60 liegen")
+	}
+	if c.MaxDaysPerWeek < 0 || c.MaxDaysPerWeek > 7 {
+		errs.Add("max_days_per_week", CodeOutOfRange, "maximale arbeitstage pro woche müssen zwischen 0 und 7 liegen")
+	}
+	return errs.Err()
+}
@@ -2,9 +2,32 @@ package models
 
 type Department struct {
 	BaseModel
-	Name        string      `json:"name" gorm:"not null;uniqueIndex"`
+	TenantID    uint        `json:"-" gorm:"not null;default:1;index;uniqueIndex:idx_departments_location_name,priority:1"`
+	Name        string      `json:"name" gorm:"not null;uniqueIndex:idx_departments_location_name,priority:3,where:deleted_at IS NULL"`
 	Color       string      `json:"color" gorm:"not null"`
 	Description string      `json:"description" gorm:"type:text"`
-	Employees   []Employee  `json:"employees,omitempty" swaggerignore:"true"`
-	ShiftWeeks  []ShiftWeek `json:"shift_weeks,omitempty" swaggerignore:"true"`
+	LocationID  uint        `json:"location_id" gorm:"not null;index;uniqueIndex:idx_departments_location_name,priority:2"`
+	Location    *Location   `json:"location,omitempty" swaggerignore:"true"`
+	Employees   []Employee  `json:"employees,omitempty" gorm:"constraint:OnDelete:SET NULL" swaggerignore:"true"`
+	Teams       []Team      `json:"teams,omitempty" gorm:"constraint:OnDelete:CASCADE" swaggerignore:"true"`
+	ShiftWeeks  []ShiftWeek `json:"shift_weeks,omitempty" gorm:"constraint:OnDelete:CASCADE" swaggerignore:"true"`
+}
+
+// HolidayState liefert das Bundesland des Standorts, sofern geladen
+func (d *Department) HolidayState() string {
+	if d.Location == nil {
+		return ""
+	}
+	return d.Location.HolidayState
+}
+
+// Validate prüft Name und Farbe
+func (d *Department) Validate() error {
+	var errs ValidationErrors
+	errs.requireText("name", d.Name, "name", 50)
+	if d.Name != "" && len(d.Name) < 2 {
+		errs.Add("name", CodeTooShort, "name muss mindestens 2 Zeichen lang sein")
+	}
+	errs.requireText("color", d.Color, "farbe", 0)
+	return errs.Err()
 }
@@ -1,14 +1,43 @@
 package models
 
+import "time"
+
 type Employee struct {
 	BaseModel
+	TenantID     uint       `json:"-" gorm:"not null;default:1;index;uniqueIndex:idx_employees_email,priority:1"`
 	FirstName    string     `json:"first_name" gorm:"not null"`
 	LastName     string     `json:"last_name" gorm:"not null"`
-	Email        string     `json:"email" gorm:"unique;not null"`
+	Email        string     `json:"email" gorm:"not null;uniqueIndex:idx_employees_email,priority:2,where:deleted_at IS NULL"`
 	Password     string     `json:"-" gorm:"not null"`
 	Color        string     `json:"color" gorm:"not null"`
 	IsAdmin      bool       `json:"is_admin" gorm:"default:false"`
+	BirthDate    *time.Time `json:"birth_date"`
 	DepartmentID *uint      `json:"department_id"`
 	Department   Department `json:"department"`
-	ShiftDays    []ShiftDay `json:"shift_days" swaggerignore:"true"`
+	TeamID       *uint      `json:"team_id"` // Team innerhalb der Abteilung
+	Team         *Team      `json:"team,omitempty" swaggerignore:"true"`
+	ShiftDays    []ShiftDay `json:"shift_days" gorm:"constraint:OnDelete:SET NULL" swaggerignore:"true"`
+}
+
+// IsMinorOn gibt an, ob der Mitarbeiter am Datum noch nicht 18 Jahre alt ist.
+// Ohne Geburtsdatum gilt er als volljährig.
+func (e *Employee) IsMinorOn(date time.Time) bool {
+	if e.BirthDate == nil {
+		return false
+	}
+	adult := e.BirthDate.AddDate(18, 0, 0)
+	return date.Format("2006-01-02") < adult.Format("2006-01-02")
+}
+
+// Validate prüft Namen, E-Mail, Farbe und Geburtsdatum
+func (e *Employee) Validate() error {
+	var errs ValidationErrors
+	errs.requireText("first_name", e.FirstName, "vorname", 0)
+	errs.requireText("last_name", e.LastName, "nachname", 0)
+	errs.requireText("email", e.Email, "e-mail", 0)
+	errs.requireText("color", e.Color, "farbe", 0)
+	if e.BirthDate != nil && e.BirthDate.After(time.Now()) {
+		errs.Add("birth_date", CodeOutOfRange, "geburtsdatum darf nicht in der zukunft liegen")
+	}
+	return errs.Err()
 }
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+	_ "time/tzdata" // Zeitzonen auch ohne Systemdatenbank, z.B. im Container
+)
+
+// DefaultTimezone gilt für Standorte ohne eigene Zeitzone
+const DefaultTimezone = "Europe/Berlin"
+
+// Location ist ein Standort (Werk) mit eigenen Abteilungen. Das Bundesland
+// bestimmt die Landesfeiertage, ohne Angabe gelten nur bundesweite.
+type Location struct {
+	BaseModel
+	TenantID     uint         `json:"-" gorm:"not null;default:1;index;uniqueIndex:idx_locations_name,priority:1"`
+	Name         string       `json:"name" gorm:"not null;uniqueIndex:idx_locations_name,priority:2,where:deleted_at IS NULL"`
+	Street       string       `json:"street"`
+	PostalCode   string       `json:"postal_code" gorm:"size:10"`
+	City         string       `json:"city"`
+	Timezone     string       `json:"timezone" gorm:"size:64;not null;default:'Europe/Berlin'" example:"Europe/Berlin"`
+	HolidayState string       `json:"holiday_state" gorm:"size:2" example:"TH"` // Kürzel des Bundeslands, z.B. BY
+	Departments  []Department `json:"departments,omitempty" gorm:"constraint:OnDelete:RESTRICT" swaggerignore:"true"`
+}
+
+// TimeLocation liefert die Zeitzone des Standorts, bei ungültiger Angabe UTC
+func (l *Location) TimeLocation() *time.Location {
+	name := l.Timezone
+	if name == "" {
+		name = DefaultTimezone
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// Validate prüft Name, Postleitzahl und Zeitzone. Das Bundesland prüft der
+// Handler gegen die bekannten Feiertagsregeln.
+func (l *Location) Validate() error {
+	var errs ValidationErrors
+	errs.requireText("name", l.Name, "name", 100)
+	if len(l.PostalCode) > 10 {
+		errs.Add("postal_code", CodeTooLong, "postleitzahl darf maximal 10 Zeichen lang sein")
+	}
+	if l.Timezone != "" {
+		if _, err := time.LoadLocation(l.Timezone); err != nil {
+			errs.Addf("timezone", CodeInvalid, "unbekannte zeitzone %q", l.Timezone)
+		}
+	}
+	return errs.Err()
+}
@@ -0,0 +1,101 @@
+package models
+
+import "time"
+
+const (
+	NotificationPending = "pending"
+	NotificationSent    = "sent"
+	NotificationFailed  = "failed"
+)
+
+const (
+	NotificationWeekPublished = "week_published"
+	NotificationShiftChanged  = "shift_changed"
+	NotificationLeaveDecision = "leave_decision"
+	NotificationShiftReminder = "shift_reminder"
+)
+
+const ChannelEmail = "email"
+
+// Notification ist ein Eintrag im Postausgang, der vom Worker zugestellt wird
+type Notification struct {
+	BaseModel
+	TenantID      uint       `json:"-" gorm:"not null;default:1;index;uniqueIndex:idx_notifications_dedupe_key,priority:1"`
+	EmployeeID    uint       `json:"employee_id" gorm:"not null;index"`
+	Channel       string     `json:"channel" gorm:"type:varchar(20);not null"`
+	Kind          string     `json:"kind" gorm:"type:varchar(30);not null"`
+	Recipient     string     `json:"recipient" gorm:"not null"`
+	Subject       string     `json:"subject" gorm:"not null"`
+	BodyText      string     `json:"body_text" gorm:"type:text"`
+	BodyHTML      string     `json:"body_html" gorm:"type:text"`
+	DedupeKey     *string    `json:"dedupe_key,omitempty" gorm:"uniqueIndex:idx_notifications_dedupe_key,priority:2"`
+	Status        string     `json:"status" gorm:"type:varchar(20);default:'pending';index"`
+	Attempts      int        `json:"attempts" gorm:"default:0"`
+	NextAttemptAt time.Time  `json:"next_attempt_at" gorm:"index"`
+	LastError     string     `json:"last_error,omitempty" gorm:"type:text"`
+	SentAt        *time.Time `json:"sent_at,omitempty"`
+}
+
+// NotificationPreference speichert, welche Benachrichtigungen ein Mitarbeiter erhält
+type NotificationPreference struct {
+	BaseModel
+	EmployeeID    uint `json:"employee_id" gorm:"not null;uniqueIndex"`
+	EmailEnabled  bool `json:"email_enabled" gorm:"not null"`
+	WeekPublished bool `json:"week_published" gorm:"not null"`
+	ShiftChanged  bool `json:"shift_changed" gorm:"not null"`
+	Decisions     bool `json:"decisions" gorm:"not null"`
+	Reminders     bool `json:"reminders" gorm:"not null"`
+}
+
+// DefaultNotificationPreference liefert die Voreinstellung: alles aktiviert
+func DefaultNotificationPreference(employeeID uint) NotificationPreference {
+	return NotificationPreference{
+		EmployeeID:    employeeID,
+		EmailEnabled:  true,
+		WeekPublished: true,
+		ShiftChanged:  true,
+		Decisions:     true,
+		Reminders:     true,
+	}
+}
+
+func (np *NotificationPreference) Allows(channel, kind string) bool {
+	if channel == ChannelEmail && !np.EmailEnabled {
+		return false
+	}
+
+	switch kind {
+	case NotificationWeekPublished:
+		return np.WeekPublished
+	case NotificationShiftChanged:
+		return np.ShiftChanged
+	case NotificationLeaveDecision:
+		return np.Decisions
+	case NotificationShiftReminder:
+		return np.Reminders
+	}
+	return true
+}
+
+// Validate prüft Empfänger, Kanal, Art und Betreff der Benachrichtigung
+func (n *Notification) Validate() error {
+	var errs ValidationErrors
+	if n.EmployeeID == 0 {
+		errs.Add("employee_id", CodeRequired, "mitarbeiter ist erforderlich")
+	}
+	if n.Channel != ChannelEmail {
+		errs.Add("channel", CodeInvalid, "kanal muss email sein")
+	}
+	errs.requireText("kind", n.Kind, "art", 30)
+	errs.requireText("recipient", n.Recipient, "empfänger", 0)
+	errs.requireText("subject", n.Subject, "betreff", 0)
+	return errs.Err()
+}
+
+// Validate prüft, ob die Einstellungen einem Mitarbeiter gehören
+func (np *NotificationPreference) Validate() error {
+	if np.EmployeeID == 0 {
+		return NewValidationError("employee_id", CodeRequired, "mitarbeiter ist erforderlich")
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package models
+
+import "time"
+
+// OnCallAssignment ist eine Rufbereitschaft eines Mitarbeiters. Sie darf sich
+// mit Schichten überschneiden und ist deshalb kein Schichttag.
+type OnCallAssignment struct {
+	BaseModel
+	EmployeeID        uint            `json:"employee_id" gorm:"not null;index"`
+	Employee          Employee        `json:"employee" gorm:"constraint:OnDelete:CASCADE" swaggerignore:"true"`
+	DepartmentID      uint            `json:"department_id" gorm:"not null;index"`
+	Department        Department      `json:"department" gorm:"constraint:OnDelete:CASCADE" swaggerignore:"true"`
+	StartsAt          time.Time       `json:"starts_at" gorm:"not null"`
+	EndsAt            time.Time       `json:"ends_at" gorm:"not null"`
+	CompensationClass string          `json:"compensation_class" gorm:"size:20;not null" example:"standard"`
+	Notes             string          `json:"notes" gorm:"type:text"`
+	Callouts          []OnCallCallout `json:"callouts" gorm:"constraint:OnDelete:CASCADE"`
+}
+
+// OnCallCallout ist ein Einsatz während einer Rufbereitschaft, also
+// tatsächlich geleistete Arbeit
+type OnCallCallout struct {
+	BaseModel
+	OnCallAssignmentID uint      `json:"on_call_assignment_id" gorm:"not null;index"`
+	StartedAt          time.Time `json:"started_at" gorm:"not null"`
+	EndedAt            time.Time `json:"ended_at" gorm:"not null"`
+	Description        string    `json:"description" gorm:"type:text"`
+}
+
+// Overlap liefert die Dauer der Rufbereitschaft innerhalb von [from, until)
+func (a *OnCallAssignment) Overlap(from, until time.Time) time.Duration {
+	start, end := a.StartsAt, a.EndsAt
+	if start.Before(from) {
+		start = from
+	}
+	if end.After(until) {
+		end = until
+	}
+	if !end.After(start) {
+		return 0
+	}
+	return end.Sub(start)
+}
+
+// Duration liefert die Dauer des Einsatzes
+func (c *OnCallCallout) Duration() time.Duration {
+	return c.EndedAt.Sub(c.StartedAt)
+}
+
+// MaxCompensationClassLength ist die Länge der Spalte compensation_class
+const MaxCompensationClassLength = 20
+
+// Validate prüft Mitarbeiter, Abteilung, Zeitraum und Vergütungsklasse. Die
+// Einsätze prüft der Handler gegen den Zeitraum.
+func (a *OnCallAssignment) Validate() error {
+	var errs ValidationErrors
+	if a.EmployeeID == 0 {
+		errs.Add("employee_id", CodeRequired, "mitarbeiter ist erforderlich")
+	}
+	if a.DepartmentID == 0 {
+		errs.Add("department_id", CodeRequired, "abteilung ist erforderlich")
+	}
+	errs.checkInterval("starts_at", "ends_at", a.StartsAt, a.EndsAt)
+	errs.requireText("compensation_class", a.CompensationClass, "compensation_class", MaxCompensationClassLength)
+	return errs.Err()
+}
+
+// Validate prüft Beginn und Ende des Einsatzes
+func (c *OnCallCallout) Validate() error {
+	var errs ValidationErrors
+	errs.checkInterval("started_at", "ended_at", c.StartedAt, c.EndedAt)
+	return errs.Err()
+}
+
+// checkInterval prüft, ob beide Zeitpunkte angegeben sind und das Ende nach
+// dem Beginn liegt
+func (e *ValidationErrors) checkInterval(startField, endField string, start, end time.Time) {
+	if start.IsZero() {
+		e.Add(startField, CodeRequired, startField+" ist erforderlich")
+	}
+	if end.IsZero() {
+		e.Add(endField, CodeRequired, endField+" ist erforderlich")
+	}
+	if !start.IsZero() && !end.IsZero() && !end.After(start) {
+		e.Add(endField, CodeOutOfRange, endField+" muss nach "+startField+" liegen")
+	}
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOnCallOverlap(t *testing.T) {
+	at := func(day, hour int) time.Time { return time.Date(2030, 1, day, hour, 0, 0, 0, time.UTC) }
+	// Rufbereitschaft vom 07.01. 18 Uhr bis 08.01. 6 Uhr
+	assignment := OnCallAssignment{StartsAt: at(7, 18), EndsAt: at(8, 6)}
+
+	tests := []struct {
+		name        string
+		from, until time.Time
+		want        time.Duration
+	}{
+		{name: "ganz im zeitraum", from: at(7, 0), until: at(9, 0), want: 12 * time.Hour},
+		{name: "beginn vor dem zeitraum", from: at(8, 0), until: at(9, 0), want: 6 * time.Hour},
+		{name: "ende nach dem zeitraum", from: at(7, 0), until: at(8, 0), want: 6 * time.Hour},
+		{name: "zeitraum innerhalb", from: at(7, 20), until: at(7, 22), want: 2 * time.Hour},
+		{name: "endet bei beginn des zeitraums", from: at(8, 6), until: at(9, 0)},
+		{name: "beginnt bei ende des zeitraums", from: at(7, 0), until: at(7, 18)},
+		{name: "davor", from: at(1, 0), until: at(2, 0)},
+	}
+	for _, test := range tests {
+		if got := assignment.Overlap(test.from, test.until); got != test.want {
+			t.Errorf("%s: %v, erwartet %v", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Rotation beschreibt einen Schichtwechsel über einen Zyklus von mehreren
+// Wochen, den feste Schichtgruppen mit unterschiedlichem Versatz durchlaufen
+type Rotation struct {
+	BaseModel
+	Name         string         `json:"name" gorm:"size:100;not null"`
+	Description  string         `json:"description" gorm:"type:text"`
+	DepartmentID uint           `json:"department_id" gorm:"not null"`
+	Department   Department     `json:"department" gorm:"constraint:OnDelete:CASCADE" swaggerignore:"true"`
+	CycleWeeks   int            `json:"cycle_weeks" gorm:"not null;check:cycle_weeks >= 1"`
+	StartDate    time.Time      `json:"start_date" gorm:"not null"`
+	Slots        []RotationSlot `json:"slots" gorm:"constraint:OnDelete:CASCADE"`
+	Crews        []RotationCrew `json:"crews" gorm:"constraint:OnDelete:CASCADE"`
+}
+
+// RotationSlot legt fest, welche Schicht an einem Wochentag einer
+// Zykluswoche gearbeitet wird. Week zählt ab 0, WeekDay 0 ist Montag.
+type RotationSlot struct {
+	BaseModel
+	RotationID  uint      `json:"rotation_id" gorm:"not null;index"`
+	Week        int       `json:"week" gorm:"not null;check:week >= 0"`
+	WeekDay     int       `json:"week_day" gorm:"not null;check:week_day >= 0 AND week_day <= 6"`
+	ShiftTypeID uint      `json:"shift_type_id" gorm:"not null"`
+	ShiftType   ShiftType `json:"shift_type" gorm:"constraint:OnDelete:RESTRICT" swaggerignore:"true"`
+}
+
+// RotationCrew ist eine Schichtgruppe, die den Zyklus um StartOffset Wochen
+// versetzt durchläuft
+type RotationCrew struct {
+	BaseModel
+	RotationID  uint                 `json:"rotation_id" gorm:"not null;index"`
+	Name        string               `json:"name" gorm:"size:100;not null"`
+	StartOffset int                  `json:"start_offset" gorm:"not null;default:0;check:start_offset >= 0"`
+	Members     []RotationCrewMember `json:"members" gorm:"constraint:OnDelete:CASCADE"`
+}
+
+// RotationCrewMember ordnet einen Mitarbeiter einer Schichtgruppe zu
+type RotationCrewMember struct {
+	BaseModel
+	RotationCrewID uint     `json:"rotation_crew_id" gorm:"not null;index"`
+	EmployeeID     uint     `json:"employee_id" gorm:"not null"`
+	Employee       Employee `json:"employee" gorm:"constraint:OnDelete:CASCADE" swaggerignore:"true"`
+}
+
+// CycleWeek liefert die Zykluswoche, die crew in der Woche ab monday
+// arbeitet. Vor dem Start der Rotation ist ok false.
+func (r *Rotation) CycleWeek(monday time.Time, crew RotationCrew) (int, bool) {
+	if r.CycleWeeks < 1 || monday.Before(r.StartDate) {
+		return 0, false
+	}
+	weeks := int(monday.Sub(r.StartDate).Hours()/24) / 7
+	return (weeks + crew.StartOffset) % r.CycleWeeks, true
+}
+
+// maxCycleWeeks begrenzt den Zyklus einer Rotation auf ein Jahr
+const maxCycleWeeks = 52
+
+// Validate prüft die Rotation mit ihren Schichten und Schichtgruppen. Ob
+// Schichttypen und Mitarbeiter existieren, prüft der Handler.
+func (r *Rotation) Validate() error {
+	var errs ValidationErrors
+	errs.requireText("name", r.Name, "name", 100)
+	if r.DepartmentID == 0 {
+		errs.Add("department_id", CodeRequired, "abteilung ist erforderlich")
+	}
+	cycleValid := r.CycleWeeks >= 1 && r.CycleWeeks <= maxCycleWeeks
+	if !cycleValid {
+		errs.Addf("cycle_weeks", CodeOutOfRange, "zykluslänge muss zwischen 1 und %d wochen liegen", maxCycleWeeks)
+	}
+	switch {
+	case r.StartDate.IsZero():
+		errs.Add("start_date", CodeRequired, "startdatum ist erforderlich")
+	case r.StartDate.Weekday() != time.Monday:
+		errs.Add("start_date", CodeInvalid, "startdatum muss ein montag sein")
+	}
+
+	slots := map[[2]int]bool{}
+	for i := range r.Slots {
+		slot := &r.Slots[i]
+		field := fmt.Sprintf("slots[%d]", i)
+		if err := errs.Merge(field, slot.Validate()); err != nil {
+			errs.Add(field, CodeInvalid, err.Error())
+		}
+		if cycleValid && slot.Week >= r.CycleWeeks {
+			errs.Addf(field+".week", CodeOutOfRange, "woche %d liegt außerhalb des zyklus", slot.Week)
+		}
+		key := [2]int{slot.Week, slot.WeekDay}
+		if slots[key] {
+			errs.Addf(field, CodeDuplicate, "woche %d, wochentag %d ist mehrfach belegt", slot.Week, slot.WeekDay)
+		}
+		slots[key] = true
+	}
+
+	members := map[uint]bool{}
+	for i := range r.Crews {
+		crew := &r.Crews[i]
+		field := fmt.Sprintf("crews[%d]", i)
+		if err := errs.Merge(field, crew.Validate()); err != nil {
+			errs.Add(field, CodeInvalid, err.Error())
+		}
+		if cycleValid && crew.StartOffset >= r.CycleWeeks {
+			errs.Addf(field+".start_offset", CodeOutOfRange, "versatz der schichtgruppe %s muss zwischen 0 und %d liegen", crew.Name, r.CycleWeeks-1)
+		}
+		for j, member := range crew.Members {
+			if member.EmployeeID != 0 && members[member.EmployeeID] {
+				errs.Addf(fmt.Sprintf("%s.members[%d].employee_id", field, j), CodeDuplicate, "mitarbeiter %d ist mehreren schichtgruppen zugeordnet", member.EmployeeID)
+			}
+			members[member.EmployeeID] = true
+		}
+	}
+	return errs.Err()
+}
+
+// Validate prüft Zykluswoche, Wochentag und Schichttyp
+func (s *RotationSlot) Validate() error {
+	var errs ValidationErrors
+	if s.Week < 0 {
+		errs.Addf("week", CodeOutOfRange, "woche %d liegt außerhalb des zyklus", s.Week)
+	}
+	errs.checkWeekDay("week_day", s.WeekDay)
+	if s.ShiftTypeID == 0 {
+		errs.Add("shift_type_id", CodeRequired, "schichttyp ist erforderlich")
+	}
+	return errs.Err()
+}
+
+// Validate prüft Name, Versatz und Mitglieder der Schichtgruppe
+func (c *RotationCrew) Validate() error {
+	var errs ValidationErrors
+	errs.requireText("name", c.Name, "name der schichtgruppe", 100)
+	if c.StartOffset < 0 {
+		errs.Addf("start_offset", CodeOutOfRange, "versatz der schichtgruppe %s darf nicht negativ sein", c.Name)
+	}
+	for i := range c.Members {
+		field := fmt.Sprintf("members[%d]", i)
+		if err := errs.Merge(field, c.Members[i].Validate()); err != nil {
+			errs.Add(field, CodeInvalid, err.Error())
+		}
+	}
+	return errs.Err()
+}
+
+// Validate prüft, ob ein Mitarbeiter angegeben ist
+func (m *RotationCrewMember) Validate() error {
+	if m.EmployeeID == 0 {
+		return NewValidationError("employee_id", CodeRequired, "mitarbeiter ist erforderlich")
+	}
+	return nil
+}
@@ -6,17 +6,76 @@ import (
 	"gorm.io/gorm"
 )
 
+// Status eines Schichttags. Abwesenheiten behalten die Zuordnung, damit
+// Ausfälle in der Statistik sichtbar bleiben.
+const (
+	ShiftDayPlanned  = "planned"
+	ShiftDaySick     = "sick"
+	ShiftDayVacation = "vacation"
+	ShiftDayAbsent   = "absent"
+)
+
+// AbsenceStatuses sind die Status, bei denen der Mitarbeiter nicht arbeitet
+var AbsenceStatuses = []string{ShiftDaySick, ShiftDayVacation, ShiftDayAbsent}
+
 type ShiftDay struct {
 	BaseModel
-	Date        time.Time `json:"date" gorm:"not null;uniqueIndex:idx_shift_date_employee"`
-	ShiftWeekID *uint     `json:"shift_week_id"`
-	ShiftWeek   ShiftWeek `json:"shift_week"`
-	ShiftTypeID uint      `json:"shift_type_id" gorm:"not null"`
-	ShiftType   ShiftType `json:"shift_type" swaggerignore:"true"`
-	EmployeeID  *uint     `json:"employee_id" gorm:"uniqueIndex:idx_shift_date_employee"`
-	Employee    Employee  `json:"employee" swaggerignore:"true"`
-	Notes       string    `json:"notes" gorm:"type:text"`
-	Status      string    `json:"status" gorm:"type:varchar(20);default:'planned'"`
+	TenantID     uint      `json:"-" gorm:"not null;default:1;index;uniqueIndex:idx_shift_date_employee,priority:1"`
+	Date         time.Time `json:"date" gorm:"not null;uniqueIndex:idx_shift_date_employee,priority:2,where:deleted_at IS NULL"`
+	ShiftWeekID  *uint     `json:"shift_week_id"`
+	ShiftWeek    ShiftWeek `json:"shift_week"`
+	ShiftTypeID  uint      `json:"shift_type_id" gorm:"not null"`
+	ShiftType    ShiftType `json:"shift_type" swaggerignore:"true"`
+	EmployeeID   *uint     `json:"employee_id" gorm:"uniqueIndex:idx_shift_date_employee,priority:3"`
+	Employee     Employee  `json:"employee" swaggerignore:"true"`
+	Notes        string    `json:"notes" gorm:"type:text"`
+	Status       string    `json:"status" gorm:"type:varchar(20);default:'planned'"`
+	RotationID   *uint     `json:"rotation_id,omitempty" gorm:"index"`
+	BreakMinutes *int      `json:"break_minutes,omitempty" gorm:"check:break_minutes >= 0"` // überschreibt die Pause des Schichttyps
+}
+
+// ShiftDuration teilt eine Schicht in Anwesenheit, Pause, Arbeitszeit und
+// bezahlte Zeit auf
+type ShiftDuration struct {
+	Gross time.Duration // Beginn bis Ende
+	Break time.Duration
+	Net   time.Duration // Arbeitszeit im Sinne des ArbZG, ohne Pausen
+	Paid  time.Duration // Net, bei bezahlten Pausen einschließlich Pause
+}
+
+// StatutoryBreak ist die Mindestpause nach § 4 ArbZG, vereinfachend nach der
+// Dauer der Schicht bemessen: 30 Minuten über 6 Stunden, 45 über 9 Stunden
+func StatutoryBreak(gross time.Duration) time.Duration {
+	switch {
+	case gross > 9*time.Hour:
+		return 45 * time.Minute
+	case gross > 6*time.Hour:
+		return 30 * time.Minute
+	}
+	return 0
+}
+
+// Duration berechnet die Dauer des Schichttags mit dem Schichttyp. Die Pause
+// ist die des Tags, sonst die des Schichttyps, sonst die gesetzliche
+// Mindestpause. Alle Berichte und Regelprüfungen rechnen damit.
+func (sd *ShiftDay) Duration(shiftType *ShiftType) ShiftDuration {
+	duration := ShiftDuration{Gross: shiftType.Duration()}
+	switch {
+	case sd.BreakMinutes != nil:
+		duration.Break = time.Duration(*sd.BreakMinutes) * time.Minute
+	case shiftType.BreakMinutes != nil:
+		duration.Break = time.Duration(*shiftType.BreakMinutes) * time.Minute
+	default:
+		duration.Break = StatutoryBreak(duration.Gross)
+	}
+	duration.Break = min(duration.Break, duration.Gross)
+
+	duration.Net = duration.Gross - duration.Break
+	duration.Paid = duration.Net
+	if shiftType.BreaksPaid {
+		duration.Paid = duration.Gross
+	}
+	return duration
 }
 
 func (sd *ShiftDay) CanBeModified() bool {
@@ -42,6 +101,15 @@ func (sd *ShiftDay) ValidateEmployeeDepartment(employee *Employee, shiftWeek *Sh
 	return *employee.DepartmentID == *shiftWeek.DepartmentID
 }
 
+// ValidateEmployeeTeam prüft bei Schichtwochen eines Teams, ob der
+// Mitarbeiter zum Team gehört
+func (sd *ShiftDay) ValidateEmployeeTeam(employee *Employee, shiftWeek *ShiftWeek) bool {
+	if shiftWeek == nil || shiftWeek.TeamID == nil {
+		return true
+	}
+	return employee != nil && employee.TeamID != nil && *employee.TeamID == *shiftWeek.TeamID
+}
+
 func (sd *ShiftDay) HasConflict(db *gorm.DB) bool {
 	if sd.EmployeeID == nil {
 		return false
@@ -52,3 +120,27 @@ func (sd *ShiftDay) HasConflict(db *gorm.DB) bool {
 		Count(&count)
 	return count > 0
 }
+
+// Validate prüft Datum, Schichtwoche, Schichttyp, Status und Pause. Alles, was
+// Schichtwoche, Mitarbeiter oder andere Schichten braucht, prüft der Handler.
+func (sd *ShiftDay) Validate() error {
+	var errs ValidationErrors
+	if sd.Date.IsZero() {
+		errs.Add("date", CodeRequired, "datum ist erforderlich")
+	}
+	if sd.ShiftWeekID == nil {
+		errs.Add("shift_week_id", CodeRequired, "schichtwoche ist erforderlich")
+	}
+	if sd.ShiftTypeID == 0 {
+		errs.Add("shift_type_id", CodeRequired, "schichttyp ist erforderlich")
+	}
+	switch sd.Status {
+	case "", ShiftDayPlanned, ShiftDaySick, ShiftDayVacation, ShiftDayAbsent:
+	default:
+		errs.Add("status", CodeInvalid, "status muss planned, sick, vacation oder absent sein")
+	}
+	if sd.BreakMinutes != nil && *sd.BreakMinutes < 0 {
+		errs.Add("break_minutes", CodeOutOfRange, "pause darf nicht negativ sein")
+	}
+	return errs.Err()
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func minutes(value int) *int { return &value }
+
+func TestShiftDayDuration(t *testing.T) {
+	tests := []struct {
+		name      string
+		shiftType ShiftType
+		dayBreak  *int
+		gross     time.Duration
+		breakTime time.Duration
+		paid      time.Duration
+	}{
+		{name: "genau 6 stunden ohne pause", shiftType: ShiftType{StartTime: "08:00", EndTime: "14:00"}, gross: 6 * time.Hour, paid: 6 * time.Hour},
+		{name: "über 6 stunden", shiftType: ShiftType{StartTime: "08:00", EndTime: "14:01"}, gross: 6*time.Hour + time.Minute, breakTime: 30 * time.Minute, paid: 5*time.Hour + 31*time.Minute},
+		{name: "genau 9 stunden", shiftType: ShiftType{StartTime: "07:00", EndTime: "16:00"}, gross: 9 * time.Hour, breakTime: 30 * time.Minute, paid: 8*time.Hour + 30*time.Minute},
+		{name: "über 9 stunden", shiftType: ShiftType{StartTime: "07:00", EndTime: "16:01"}, gross: 9*time.Hour + time.Minute, breakTime: 45 * time.Minute, paid: 8*time.Hour + 16*time.Minute},
+		{name: "pause des schichttyps", shiftType: ShiftType{StartTime: "06:00", EndTime: "14:00", BreakMinutes: minutes(40)}, gross: 8 * time.Hour, breakTime: 40 * time.Minute, paid: 7*time.Hour + 20*time.Minute},
+		{name: "pause des tags vor der des schichttyps", shiftType: ShiftType{StartTime: "06:00", EndTime: "14:00", BreakMinutes: minutes(40)}, dayBreak: minutes(60), gross: 8 * time.Hour, breakTime: time.Hour, paid: 7 * time.Hour},
+		{name: "pause null am tag", shiftType: ShiftType{StartTime: "06:00", EndTime: "14:00", BreakMinutes: minutes(40)}, dayBreak: minutes(0), gross: 8 * time.Hour, paid: 8 * time.Hour},
+		{name: "bezahlte pause", shiftType: ShiftType{StartTime: "06:00", EndTime: "14:00", BreaksPaid: true}, gross: 8 * time.Hour, breakTime: 30 * time.Minute, paid: 8 * time.Hour},
+		{name: "über mitternacht", shiftType: ShiftType{StartTime: "22:00", EndTime: "06:00"}, gross: 8 * time.Hour, breakTime: 30 * time.Minute, paid: 7*time.Hour + 30*time.Minute},
+		{name: "über mitternacht mit pause des tags", shiftType: ShiftType{StartTime: "20:00", EndTime: "06:00"}, dayBreak: minutes(60), gross: 10 * time.Hour, breakTime: time.Hour, paid: 9 * time.Hour},
+		{name: "pause länger als die schicht", shiftType: ShiftType{StartTime: "08:00", EndTime: "09:00"}, dayBreak: minutes(90), gross: time.Hour, breakTime: time.Hour},
+	}
+	for _, test := range tests {
+		shiftDay := ShiftDay{BreakMinutes: test.dayBreak}
+		got := shiftDay.Duration(&test.shiftType)
+		if got.Gross != test.gross || got.Break != test.breakTime || got.Net != test.gross-test.breakTime || got.Paid != test.paid {
+			t.Errorf("%s: %+v, erwartet brutto %v, pause %v, bezahlt %v", test.name, got, test.gross, test.breakTime, test.paid)
+		}
+	}
+}
+
+func TestValidateBreakMinutes(t *testing.T) {
+	tests := []struct {
+		name    string
+		minutes *int
+		gross   time.Duration
+		code    string
+	}{
+		{name: "ohne festgelegte pause", gross: 10 * time.Hour},
+		{name: "bis 6 stunden ohne pause", minutes: minutes(0), gross: 6 * time.Hour},
+		{name: "über 6 stunden ohne pause", minutes: minutes(0), gross: 6*time.Hour + time.Minute, code: CodeLabourLaw},
+		{name: "über 6 stunden 30 minuten", minutes: minutes(30), gross: 7 * time.Hour},
+		{name: "9 stunden 30 minuten", minutes: minutes(30), gross: 9 * time.Hour},
+		{name: "über 9 stunden 30 minuten", minutes: minutes(30), gross: 9*time.Hour + time.Minute, code: CodeLabourLaw},
+		{name: "über 9 stunden 45 minuten", minutes: minutes(45), gross: 10 * time.Hour},
+		{name: "negativ", minutes: minutes(-5), gross: 4 * time.Hour, code: CodeOutOfRange},
+		{name: "so lang wie die schicht", minutes: minutes(60), gross: time.Hour, code: CodeOutOfRange},
+	}
+	for _, test := range tests {
+		err := ValidateBreakMinutes("break_minutes", test.minutes, test.gross)
+		if test.code == "" {
+			if err != nil {
+				t.Errorf("%s: %v", test.name, err)
+			}
+			continue
+		}
+		var validationErrors ValidationErrors
+		if !errors.As(err, &validationErrors) || len(validationErrors) != 1 ||
+			validationErrors[0].Field != "break_minutes" || validationErrors[0].Code != test.code {
+			t.Errorf("%s: %v, erwartet %s", test.name, err, test.code)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package models
+
+import "time"
+
+// Verbindlichkeit eines Schichtwunsches
+const (
+	PreferenceHard = "hard"
+	PreferenceSoft = "soft"
+)
+
+// Art eines Schichtwunsches
+const (
+	PreferenceAvoid  = "avoid"
+	PreferencePrefer = "prefer"
+)
+
+// ShiftPreference ist ein Wunsch eines Mitarbeiters, z.B. "keine Nacht am
+// Freitag", "lieber Frühschicht" oder "frei am 24.12.". Leere Kriterien
+// gelten für alle Tage bzw. alle Schichttypen. WeekDay 0 ist Montag.
+type ShiftPreference struct {
+	BaseModel
+	EmployeeID  uint       `json:"employee_id" gorm:"not null;index"`
+	Employee    Employee   `json:"employee" gorm:"constraint:OnDelete:CASCADE" swaggerignore:"true"`
+	Strength    string     `json:"strength" gorm:"type:varchar(10);not null;default:'soft';check:strength IN ('hard','soft')"`
+	Kind        string     `json:"kind" gorm:"type:varchar(10);not null;check:kind IN ('avoid','prefer')"`
+	WeekDay     *int       `json:"week_day" gorm:"check:week_day >= 0 AND week_day <= 6"`
+	Date        *time.Time `json:"date"`
+	ShiftTypeID *uint      `json:"shift_type_id"`
+	ShiftType   *ShiftType `json:"shift_type,omitempty" gorm:"constraint:OnDelete:CASCADE" swaggerignore:"true"`
+	Note        string     `json:"note" gorm:"type:text"`
+}
+
+// IsHard gibt an, ob der Wunsch bei automatischer Planung eingehalten werden muss
+func (p *ShiftPreference) IsHard() bool {
+	return p.Strength == PreferenceHard
+}
+
+// AppliesTo prüft Datum und Wochentag des Wunsches
+func (p *ShiftPreference) AppliesTo(date time.Time) bool {
+	if p.Date != nil && p.Date.Format("2006-01-02") != date.Format("2006-01-02") {
+		return false
+	}
+	if p.WeekDay != nil && *p.WeekDay != (int(date.Weekday())+6)%7 {
+		return false
+	}
+	return true
+}
+
+// Matches prüft, ob eine Schicht an date mit shiftTypeID unter den Wunsch fällt
+func (p *ShiftPreference) Matches(date time.Time, shiftTypeID uint) bool {
+	if !p.AppliesTo(date) {
+		return false
+	}
+	return p.ShiftTypeID == nil || *p.ShiftTypeID == shiftTypeID
+}
+
+// Validate prüft Verbindlichkeit, Art und Kriterien des Wunsches
+func (p *ShiftPreference) Validate() error {
+	var errs ValidationErrors
+	if p.EmployeeID == 0 {
+		errs.Add("employee_id", CodeRequired, "mitarbeiter ist erforderlich")
+	}
+	if p.Strength != PreferenceHard && p.Strength != PreferenceSoft {
+		errs.Add("strength", CodeInvalid, "strength muss hard oder soft sein")
+	}
+	if p.Kind != PreferenceAvoid && p.Kind != PreferencePrefer {
+		errs.Add("kind", CodeInvalid, "kind muss avoid oder prefer sein")
+	}
+	// Ein harter Wunsch nach einer bestimmten Schicht ließe sich nur durch
+	// Umplanen anderer Mitarbeiter erfüllen
+	if p.Strength == PreferenceHard && p.Kind == PreferencePrefer {
+		errs.Add("kind", CodeNotAllowed, "harte wünsche können schichten nur ausschließen")
+	}
+	if p.WeekDay != nil {
+		errs.checkWeekDay("week_day", *p.WeekDay)
+	}
+	if p.WeekDay == nil && p.Date == nil && p.ShiftTypeID == nil {
+		errs.Add("", CodeRequired, "wochentag, datum oder schichttyp ist erforderlich")
+	}
+	if p.Kind == PreferencePrefer && p.ShiftTypeID == nil {
+		errs.Add("shift_type_id", CodeRequired, "ein wunsch nach einer schicht benötigt einen schichttyp")
+	}
+	return errs.Err()
+}
+
+// checkWeekDay prüft einen Wochentag, 0 ist Montag
+func (e *ValidationErrors) checkWeekDay(field string, weekDay int) {
+	if weekDay < 0 || weekDay > 6 {
+		e.Add(field, CodeOutOfRange, "wochentag muss zwischen 0 (montag) und 6 (sonntag) liegen")
+	}
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"fmt"
 	"time"
 )
 
@@ -10,9 +11,9 @@ type ShiftTemplate struct {
 	Name         string             `json:"name" gorm:"size:100;not null"`
 	Description  string             `json:"description" gorm:"type:text"`
 	DepartmentID uint               `json:"department_id" gorm:"not null"`
-	Department   Department         `json:"department"`
-	ShiftDays    []ShiftTemplateDay `json:"shift_days"`
-	Status       string             `json:"status" gorm:"type:enum('draft','active','inactive');default:'draft'"`
+	Department   Department         `json:"department" gorm:"constraint:OnDelete:CASCADE"`
+	ShiftDays    []ShiftTemplateDay `json:"shift_days" gorm:"constraint:OnDelete:CASCADE"`
+	Status       string             `json:"status" gorm:"type:varchar(20);default:'draft';check:status IN ('draft','active','inactive')"`
 	ValidFrom    time.Time          `json:"valid_from" gorm:"not null"`
 	ValidUntil   time.Time          `json:"valid_until" gorm:"not null"`
 }
@@ -22,7 +23,7 @@ type ShiftTemplateDay struct {
 	BaseModel
 	ShiftTemplateID uint      `json:"shift_template_id" gorm:"not null"`
 	ShiftTypeID     uint      `json:"shift_type_id" gorm:"not null"`
-	ShiftType       ShiftType `json:"shift_type"`
+	ShiftType       ShiftType `json:"shift_type" gorm:"constraint:OnDelete:RESTRICT"`
 	WeekDay         int       `json:"week_day" gorm:"type:int;check:week_day >= 0 AND week_day <= 6;not null"`
 	Notes           string    `json:"notes" gorm:"type:text"`
 }
@@ -35,7 +36,42 @@ func (st *ShiftTemplate) CanBeModified() bool {
 	return st.Status == "draft"
 }
 
-func (st *ShiftTemplate) Validate() bool {
-	now := time.Now()
-	return st.ValidFrom.Before(st.ValidUntil) && st.ValidFrom.After(now)
+// Validate prüft Name, Abteilung, Status, Gültigkeit und die Tage der
+// Vorlage. Dass neue Vorlagen erst künftig gelten, prüft der Handler.
+func (st *ShiftTemplate) Validate() error {
+	var errs ValidationErrors
+	errs.requireText("name", st.Name, "name", 100)
+	if st.DepartmentID == 0 {
+		errs.Add("department_id", CodeRequired, "abteilung ist erforderlich")
+	}
+	switch st.Status {
+	case "", "draft", "active", "inactive":
+	default:
+		errs.Add("status", CodeInvalid, "status muss draft, active oder inactive sein")
+	}
+	if st.ValidFrom.IsZero() {
+		errs.Add("valid_from", CodeRequired, "gültig ab ist erforderlich")
+	}
+	if st.ValidUntil.IsZero() {
+		errs.Add("valid_until", CodeRequired, "gültig bis ist erforderlich")
+	} else if !st.ValidFrom.IsZero() && !st.ValidFrom.Before(st.ValidUntil) {
+		errs.Add("valid_until", CodeOutOfRange, "gültig bis muss nach gültig ab liegen")
+	}
+	for i := range st.ShiftDays {
+		field := fmt.Sprintf("shift_days[%d]", i)
+		if err := errs.Merge(field, st.ShiftDays[i].Validate()); err != nil {
+			errs.Add(field, CodeInvalid, err.Error())
+		}
+	}
+	return errs.Err()
+}
+
+// Validate prüft Wochentag und Schichttyp
+func (std *ShiftTemplateDay) Validate() error {
+	var errs ValidationErrors
+	errs.checkWeekDay("week_day", std.WeekDay)
+	if std.ShiftTypeID == 0 {
+		errs.Add("shift_type_id", CodeRequired, "schichttyp ist erforderlich")
+	}
+	return errs.Err()
 }
@@ -1,11 +1,100 @@
 package models
 
+import "time"
+
 type ShiftType struct {
 	BaseModel
-	Name        string     `json:"name" gorm:"not null;unique"`
-	Description string     `json:"description" gorm:"type:text"`
-	Color       string     `json:"color" gorm:"not null"`
-	StartTime   string     `json:"start_time" gorm:"not null"` // Format: "HH:MM"
-	EndTime     string     `json:"end_time" gorm:"not null"`   // Format: "HH:MM"
-	ShiftDays   []ShiftDay `json:"shift_days,omitempty" swaggerignore:"true"`
+	TenantID     uint       `json:"-" gorm:"not null;default:1;index;uniqueIndex:idx_shift_types_name,priority:1"`
+	Name         string     `json:"name" gorm:"not null;uniqueIndex:idx_shift_types_name,priority:2,where:deleted_at IS NULL"`
+	Description  string     `json:"description" gorm:"type:text"`
+	Color        string     `json:"color" gorm:"not null"`
+	StartTime    string     `json:"start_time" gorm:"not null"`                    // Format: "HH:MM"
+	EndTime      string     `json:"end_time" gorm:"not null"`                      // Format: "HH:MM"
+	BreakMinutes *int       `json:"break_minutes" gorm:"check:break_minutes >= 0"` // ohne Angabe gilt die gesetzliche Mindestpause
+	BreaksPaid   bool       `json:"breaks_paid" gorm:"not null;default:false"`
+	ShiftDays    []ShiftDay `json:"shift_days,omitempty" gorm:"constraint:OnDelete:RESTRICT" swaggerignore:"true"`
+}
+
+// Nachtzeit nach § 2 Abs. 3 ArbZG: 23 bis 6 Uhr
+const (
+	nightStart = 23 * 60
+	nightEnd   = 6 * 60
+)
+
+// Duration liefert die Dauer der Schicht. Endet sie vor ihrem Beginn, geht
+// sie über Mitternacht.
+func (s *ShiftType) Duration() time.Duration {
+	start, ok := clockMinutes(s.StartTime)
+	end, ok2 := clockMinutes(s.EndTime)
+	if !ok || !ok2 {
+		return 0
+	}
+	if end <= start {
+		end += 24 * 60
+	}
+	return time.Duration(end-start) * time.Minute
+}
+
+// Interval liefert Beginn und Ende der Schicht am Datum
+func (s *ShiftType) Interval(date time.Time) (time.Time, time.Time) {
+	start, _ := clockMinutes(s.StartTime)
+	begin := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC).Add(time.Duration(start) * time.Minute)
+	return begin, begin.Add(s.Duration())
+}
+
+// IsNight gibt an, ob die Schicht Nachtarbeit ist, d.h. mehr als zwei
+// Stunden der Nachtzeit umfasst (§ 2 Abs. 4 ArbZG)
+func (s *ShiftType) IsNight() bool {
+	start, ok := clockMinutes(s.StartTime)
+	if !ok {
+		return false
+	}
+	end := start + int(s.Duration()/time.Minute)
+
+	night := 0
+	// Nachtzeiten des Vortags, des Tags und des Folgetags
+	for _, window := range [][2]int{{nightStart - 24*60, nightEnd}, {nightStart, 24*60 + nightEnd}, {24*60 + nightStart, 48*60 + nightEnd}} {
+		from, until := max(start, window[0]), min(end, window[1])
+		if until > from {
+			night += until - from
+		}
+	}
+	return night > 120
+}
+
+// IsLate gibt an, ob die Schicht eine Spätschicht ist: Beginn ab 12 Uhr
+// und keine Nachtarbeit
+func (s *ShiftType) IsLate() bool {
+	start, ok := clockMinutes(s.StartTime)
+	return ok && start >= 12*60 && !s.IsNight()
+}
+
+// clockMinutes wandelt eine Uhrzeit "HH:MM" in Minuten seit Mitternacht um
+func clockMinutes(clock string) (int, bool) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, false
+	}
+	return t.Hour()*60 + t.Minute(), true
+}
+
+// Validate prüft Name, Farbe, Uhrzeiten und Pause
+func (s *ShiftType) Validate() error {
+	var errs ValidationErrors
+	errs.requireText("name", s.Name, "name", 0)
+	errs.requireText("color", s.Color, "farbe", 0)
+	_, startValid := clockMinutes(s.StartTime)
+	if !startValid {
+		errs.Add("start_time", CodeInvalid, "ungültiges zeitformat, bitte HH:MM verwenden")
+	}
+	_, endValid := clockMinutes(s.EndTime)
+	if !endValid {
+		errs.Add("end_time", CodeInvalid, "ungültiges zeitformat, bitte HH:MM verwenden")
+	}
+	if startValid && endValid {
+		if err := errs.Merge("", ValidateBreakMinutes("break_minutes", s.BreakMinutes, s.Duration())); err != nil {
+			errs.Add("break_minutes", CodeInvalid, err.Error())
+		}
+	}
+	return errs.Err()
 }
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 const (
 	StatusDraft     = "draft"
 	StatusPublished = "published"
@@ -12,9 +14,12 @@ type ShiftWeek struct {
 	Year         int        `json:"year" gorm:"not null;index"`
 	DepartmentID *uint      `json:"department_id"`
 	Department   Department `json:"department"`
-	ShiftDays    []ShiftDay `json:"shift_days,omitempty" swaggerignore:"true"`
+	TeamID       *uint      `json:"team_id"` // ohne Team gilt die Woche für die ganze Abteilung
+	Team         *Team      `json:"team,omitempty" gorm:"constraint:OnDelete:CASCADE" swaggerignore:"true"`
+	ShiftDays    []ShiftDay `json:"shift_days,omitempty" gorm:"constraint:OnDelete:CASCADE" swaggerignore:"true"`
 	Status       string     `json:"status" gorm:"type:varchar(20);default:'draft'"`
 	Notes        string     `json:"notes" gorm:"type:text"`
+	PublishedAt  *time.Time `json:"published_at"`
 }
 
 func (sw *ShiftWeek) IsValidStatus() bool {
@@ -22,3 +27,26 @@ func (sw *ShiftWeek) IsValidStatus() bool {
 		sw.Status == StatusPublished ||
 		sw.Status == StatusArchived
 }
+
+// WasPublished gibt an, ob die Woche schon einmal veröffentlicht wurde
+func (sw *ShiftWeek) WasPublished() bool {
+	return sw.PublishedAt != nil
+}
+
+// Validate prüft Kalenderwoche, Jahr, Abteilung und Status
+func (sw *ShiftWeek) Validate() error {
+	var errs ValidationErrors
+	if sw.CalendarWeek < 1 || sw.CalendarWeek > 53 {
+		errs.Add("calendar_week", CodeOutOfRange, "kalenderwoche muss zwischen 1 und 53 liegen")
+	}
+	if sw.Year < 2000 {
+		errs.Add("year", CodeOutOfRange, "jahr muss nach 2000 liegen")
+	}
+	if sw.DepartmentID == nil {
+		errs.Add("department_id", CodeRequired, "abteilung ist erforderlich")
+	}
+	if sw.Status != "" && !sw.IsValidStatus() {
+		errs.Add("status", CodeInvalid, "status muss draft, published oder archived sein")
+	}
+	return errs.Err()
+}
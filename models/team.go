@@ -0,0 +1,26 @@
+package models
+
+// Team ist eine Gruppe innerhalb einer Abteilung, z.B. eine Schichtgruppe
+// einer Produktionslinie mit eigener Teamleitung. Schichtwochen können für
+// ein Team statt für die ganze Abteilung geplant werden.
+type Team struct {
+	BaseModel
+	TenantID     uint        `json:"-" gorm:"not null;default:1;index;uniqueIndex:idx_teams_department_name,priority:1"`
+	DepartmentID uint        `json:"department_id" gorm:"not null;index;uniqueIndex:idx_teams_department_name,priority:2"`
+	Department   *Department `json:"department,omitempty" swaggerignore:"true"`
+	Name         string      `json:"name" gorm:"not null;uniqueIndex:idx_teams_department_name,priority:3,where:deleted_at IS NULL" example:"Linie A"`
+	Color        string      `json:"color"`
+	LeadID       *uint       `json:"lead_id"` // Teamleitung, ein Mitarbeiter der Abteilung
+	Lead         *Employee   `json:"lead,omitempty" gorm:"constraint:OnDelete:SET NULL" swaggerignore:"true"`
+	Employees    []Employee  `json:"employees,omitempty" gorm:"constraint:OnDelete:SET NULL" swaggerignore:"true"`
+}
+
+// Validate prüft Name und Abteilung
+func (t *Team) Validate() error {
+	var errs ValidationErrors
+	errs.requireText("name", t.Name, "name", 50)
+	if t.DepartmentID == 0 {
+		errs.Add("department_id", CodeRequired, "abteilung ist erforderlich")
+	}
+	return errs.Err()
+}
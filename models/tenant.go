@@ -0,0 +1,36 @@
+package models
+
+import (
+	"regexp"
+	"time"
+)
+
+// slugPattern erlaubt Slugs, die als Subdomain taugen
+var slugPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// DefaultTenantID ist der Mandant, dem alle Daten aus der Zeit vor den
+// Mandanten gehören. Ohne Mandantenbetrieb arbeiten alle Anfragen mit ihm.
+const DefaultTenantID uint = 1
+
+// Tenant ist ein Mandant, z.B. eine Schwesterfirma. Anfragen werden über den
+// API-Token oder die Subdomain (Slug) einem Mandanten zugeordnet. Vom Token
+// wird nur der SHA-256-Hash gespeichert.
+type Tenant struct {
+	ID        uint      `json:"id" gorm:"primarykey;autoIncrement"`
+	CreatedAt time.Time `json:"created_at" gorm:"not null;default:CURRENT_TIMESTAMP" format:"date-time"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"not null;default:CURRENT_TIMESTAMP" format:"date-time"`
+	Name      string    `json:"name" gorm:"not null" example:"Schwesterfirma GmbH"`
+	Slug      string    `json:"slug" gorm:"size:63;not null;uniqueIndex:idx_tenants_slug" example:"schwester"`
+	TokenHash *string   `json:"-" gorm:"size:64;uniqueIndex:idx_tenants_token_hash"`
+	Active    bool      `json:"active" gorm:"not null;default:true"`
+}
+
+// Validate prüft Name und Slug
+func (t *Tenant) Validate() error {
+	var errs ValidationErrors
+	errs.requireText("name", t.Name, "name", 0)
+	if !slugPattern.MatchString(t.Slug) {
+		errs.Add("slug", CodeInvalid, "slug darf nur kleinbuchstaben, ziffern und bindestriche enthalten und muss als subdomain taugen")
+	}
+	return errs.Err()
+}
@@ -1,13 +1,143 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
 
+	"gorm.io/gorm"
+)
+
+// Fehlercodes der Validierung. Die Codes bleiben stabil, die Meldungen
+// können sich ändern.
+const (
+	CodeRequired   = "required"     // Pflichtfeld fehlt
+	CodeInvalid    = "invalid"      // Format oder Wert ungültig, z.B. unbekannter Status
+	CodeTooShort   = "too_short"    // Text zu kurz
+	CodeTooLong    = "too_long"     // Text zu lang
+	CodeOutOfRange = "out_of_range" // Zahl oder Datum außerhalb des erlaubten Bereichs
+	CodeNotFound   = "not_found"    // verwiesener Datensatz existiert nicht
+	CodeDuplicate  = "duplicate"    // Wert muss eindeutig sein
+	CodeMismatch   = "mismatch"     // Verweis passt nicht, z.B. Mitarbeiter einer anderen Abteilung
+	CodeConflict   = "conflict"     // überschneidet sich mit vorhandenen Daten
+	CodeNotAllowed = "not_allowed"  // verstößt gegen eine Planungsregel, z.B. Vertrag oder Wunsch
+	CodeLabourLaw  = "labour_law"   // verstößt gegen Arbeitsschutz, z.B. Jugendarbeitsschutz
+)
+
+// Validator wird von allen Modellen implementiert. Validate prüft die Felder
+// ohne Datenbank und liefert alle Fehler gesammelt als ValidationErrors.
 type Validator interface {
 	Validate() error
 }
 
-func ValidateModel(m Validator) error {
-	return m.Validate()
+// FieldError ist ein Validierungsfehler. Field ist der JSON-Pfad des Feldes,
+// z.B. slots[2].shift_type_id, und leer bei Fehlern des ganzen Datensatzes.
+type FieldError struct {
+	Field   string `json:"field" example:"date"`
+	Code    string `json:"code" enums:"required,invalid,too_short,too_long,out_of_range,not_found,duplicate,mismatch,conflict,not_allowed,labour_law" example:"out_of_range"`
+	Message string `json:"message" example:"datum liegt außerhalb der schichtwoche"`
+}
+
+// ValidationErrors sammelt alle Validierungsfehler eines Datensatzes
+type ValidationErrors []FieldError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, fieldError := range e {
+		messages[i] = fieldError.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Add fügt einen Fehler hinzu
+func (e *ValidationErrors) Add(field, code, message string) {
+	*e = append(*e, FieldError{Field: field, Code: code, Message: message})
+}
+
+// Addf fügt einen Fehler mit formatierter Meldung hinzu
+func (e *ValidationErrors) Addf(field, code, format string, args ...interface{}) {
+	e.Add(field, code, fmt.Sprintf(format, args...))
+}
+
+// Has gibt an, ob es für das Feld bereits einen Fehler gibt
+func (e ValidationErrors) Has(field string) bool {
+	for _, fieldError := range e {
+		if fieldError.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Merge übernimmt die Validierungsfehler aus err und stellt ihren Feldern
+// prefix voran. Andere Fehler, etwa der Datenbank, werden zurückgegeben.
+func (e *ValidationErrors) Merge(prefix string, err error) error {
+	var nested ValidationErrors
+	if !errors.As(err, &nested) {
+		return err
+	}
+	for _, fieldError := range nested {
+		switch {
+		case prefix == "":
+		case fieldError.Field == "":
+			fieldError.Field = prefix
+		default:
+			fieldError.Field = prefix + "." + fieldError.Field
+		}
+		*e = append(*e, fieldError)
+	}
+	return nil
+}
+
+// Err liefert die Fehler als error, ohne Fehler nil
+func (e ValidationErrors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// NewValidationError liefert einen einzelnen Validierungsfehler
+func NewValidationError(field, code, message string) error {
+	return ValidationErrors{{Field: field, Code: code, Message: message}}
+}
+
+// ValidateModel prüft m und liefert die Fehler zum Ergänzen um Prüfungen
+// gegen die Datenbank
+func ValidateModel(m Validator) ValidationErrors {
+	var errs ValidationErrors
+	if err := errs.Merge("", m.Validate()); err != nil {
+		errs.Add("", CodeInvalid, err.Error())
+	}
+	return errs
+}
+
+// ValidateBreakMinutes prüft eine festgelegte Pausenlänge gegen die Dauer der
+// Schicht und die gesetzliche Mindestpause
+func ValidateBreakMinutes(field string, minutes *int, gross time.Duration) error {
+	if minutes == nil {
+		return nil
+	}
+	pause := time.Duration(*minutes) * time.Minute
+	if *minutes < 0 || pause >= gross {
+		return NewValidationError(field, CodeOutOfRange, "pause muss kürzer als die schicht sein")
+	}
+	if statutory := StatutoryBreak(gross); pause < statutory {
+		return NewValidationError(field, CodeLabourLaw, fmt.Sprintf("pause muss bei einer schicht von %.1f stunden mindestens %d minuten betragen", gross.Hours(), int(statutory.Minutes())))
+	}
+	return nil
+}
+
+// requireText prüft Pflichttexte und ihre Länge, maxLength 0 heißt ohne
+// Begrenzung
+func (e *ValidationErrors) requireText(field, value, label string, maxLength int) {
+	switch {
+	case strings.TrimSpace(value) == "":
+		e.Add(field, CodeRequired, label+" ist erforderlich")
+	case maxLength > 0 && len(value) > maxLength:
+		e.Addf(field, CodeTooLong, "%s darf maximal %d Zeichen lang sein", label, maxLength)
+	}
 }
 
 func WithTransaction(db *gorm.DB, fn func(*gorm.DB) error) error {
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	WebhookPending   = "pending"
+	WebhookDelivered = "delivered"
+	WebhookFailed    = "failed"
+)
+
+// WebhookSubscription beschreibt einen externen Empfänger für Ereignisse
+type WebhookSubscription struct {
+	BaseModel
+	URL         string   `json:"url" gorm:"not null"`
+	Secret      string   `json:"secret,omitempty" gorm:"not null"`
+	EventTypes  []string `json:"event_types" gorm:"serializer:json;type:text"`
+	Description string   `json:"description" gorm:"type:text"`
+	Active      bool     `json:"active" gorm:"not null"`
+}
+
+// Matches prüft, ob das Abonnement einen Ereignistyp erhalten soll.
+// Eine leere Liste abonniert alle Ereignisse, "shiftday.*" alle Ereignisse eines Bereichs.
+func (ws *WebhookSubscription) Matches(eventType string) bool {
+	if !ws.Active {
+		return false
+	}
+	if len(ws.EventTypes) == 0 {
+		return true
+	}
+
+	for _, pattern := range ws.EventTypes {
+		if pattern == "*" || pattern == eventType {
+			return true
+		}
+		if strings.HasSuffix(pattern, ".*") && strings.HasPrefix(eventType, strings.TrimSuffix(pattern, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery protokolliert jeden Zustellversuch eines Ereignisses
+type WebhookDelivery struct {
+	BaseModel
+	SubscriptionID uint       `json:"subscription_id" gorm:"not null;index"`
+	EventType      string     `json:"event_type" gorm:"type:varchar(50);not null"`
+	Payload        string     `json:"payload" gorm:"type:text;not null"`
+	Status         string     `json:"status" gorm:"type:varchar(20);default:'pending';index"`
+	Attempts       int        `json:"attempts" gorm:"default:0"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at" gorm:"index"`
+	ResponseStatus int        `json:"response_status,omitempty"`
+	ResponseBody   string     `json:"response_body,omitempty" gorm:"type:text"`
+	LastError      string     `json:"last_error,omitempty" gorm:"type:text"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+}
+
+// Validate prüft die Adresse des Empfängers. Die Ereignistypen prüft der
+// Handler gegen die bekannten Ereignisse.
+func (ws *WebhookSubscription) Validate() error {
+	parsed, err := url.Parse(ws.URL)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return NewValidationError("url", CodeInvalid, "url muss eine gültige http- oder https-adresse sein")
+	}
+	return nil
+}
+
+// Validate prüft Abonnement und Ereignistyp der Zustellung
+func (wd *WebhookDelivery) Validate() error {
+	var errs ValidationErrors
+	if wd.SubscriptionID == 0 {
+		errs.Add("subscription_id", CodeRequired, "abonnement ist erforderlich")
+	}
+	errs.requireText("event_type", wd.EventType, "ereignistyp", 50)
+	return errs.Err()
+}
@@ -0,0 +1,126 @@
+package notifications
+
+import (
+	"fmt"
+	"mime"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ptmmeiningen/schichtplaner/models"
+)
+
+// Message ist eine fertig gerenderte Nachricht für einen Kanal
+type Message struct {
+	Recipient string
+	Subject   string
+	BodyText  string
+	BodyHTML  string
+}
+
+// Channel stellt Nachrichten über einen bestimmten Weg zu
+type Channel interface {
+	Name() string
+	Send(msg Message) error
+}
+
+var channels = map[string]Channel{}
+
+// RegisterChannel macht einen Kanal für den Worker verfügbar
+func RegisterChannel(ch Channel) {
+	channels[ch.Name()] = ch
+}
+
+func getChannel(name string) (Channel, bool) {
+	ch, ok := channels[name]
+	return ch, ok
+}
+
+func errUnknownChannel(name string) error {
+	return fmt.Errorf("kanal %s ist nicht konfiguriert", name)
+}
+
+// SMTPChannel versendet E-Mails über einen SMTP-Server
+type SMTPChannel struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// NewSMTPChannelFromEnv liest die SMTP-Konfiguration aus den Umgebungsvariablen
+func NewSMTPChannelFromEnv() (*SMTPChannel, bool) {
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		return nil, false
+	}
+
+	port := os.Getenv("SMTP_PORT")
+	if port == "" {
+		port = "25"
+	}
+
+	from := os.Getenv("SMTP_FROM")
+	if from == "" {
+		from = "schichtplaner@localhost"
+	}
+
+	return &SMTPChannel{
+		Host:     host,
+		Port:     port,
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     from,
+	}, true
+}
+
+func (s *SMTPChannel) Name() string {
+	return models.ChannelEmail
+}
+
+func (s *SMTPChannel) Send(msg Message) error {
+	var auth smtp.Auth
+	if s.Username != "" {
+		auth = smtp.PlainAuth("", s.Username, s.Password, s.Host)
+	}
+
+	addr := s.Host + ":" + s.Port
+	if err := smtp.SendMail(addr, auth, s.From, []string{msg.Recipient}, s.buildMessage(msg)); err != nil {
+		return fmt.Errorf("fehler beim e-mail-versand: %w", err)
+	}
+	return nil
+}
+
+// buildMessage erzeugt eine multipart/alternative Nachricht mit Text- und HTML-Teil
+func (s *SMTPChannel) buildMessage(msg Message) []byte {
+	boundary := fmt.Sprintf("schichtplaner-%d", time.Now().UnixNano())
+
+	var b strings.Builder
+	b.WriteString("From: " + s.From + "\r\n")
+	b.WriteString("To: " + msg.Recipient + "\r\n")
+	b.WriteString("Subject: " + encodeHeader(msg.Subject) + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: multipart/alternative; boundary=\"" + boundary + "\"\r\n")
+	b.WriteString("\r\n")
+
+	b.WriteString("--" + boundary + "\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	b.WriteString(msg.BodyText + "\r\n")
+
+	if msg.BodyHTML != "" {
+		b.WriteString("--" + boundary + "\r\n")
+		b.WriteString("Content-Type: text/html; charset=UTF-8\r\n\r\n")
+		b.WriteString(msg.BodyHTML + "\r\n")
+	}
+
+	b.WriteString("--" + boundary + "--\r\n")
+	return []byte(b.String())
+}
+
+// encodeHeader kodiert Umlaute im Betreff nach RFC 2047
+func encodeHeader(value string) string {
+	return mime.QEncoding.Encode("utf-8", value)
+}
@@ -0,0 +1,149 @@
+package notifications
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeSMTP ist ein minimaler SMTP-Server, der empfangene Nachrichten
+// sammelt und die ersten failures Zustellungen mit 451 ablehnt
+type fakeSMTP struct {
+	listener net.Listener
+	mu       sync.Mutex
+	failures int
+	messages []string
+}
+
+func newFakeSMTP(t *testing.T, failures int) *fakeSMTP {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &fakeSMTP{listener: listener, failures: failures}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+	return server
+}
+
+func (s *fakeSMTP) channel() *SMTPChannel {
+	host, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	return &SMTPChannel{Host: host, Port: port, From: "schichtplaner@example.org"}
+}
+
+func (s *fakeSMTP) received() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.messages...)
+}
+
+func (s *fakeSMTP) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 localhost ESMTP")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(command, "MAIL FROM"):
+			s.mu.Lock()
+			reject := s.failures > 0
+			if reject {
+				s.failures--
+			}
+			s.mu.Unlock()
+			if reject {
+				reply("451 vorübergehender Fehler")
+				continue
+			}
+			reply("250 OK")
+		case strings.HasPrefix(command, "RCPT TO"):
+			reply("250 OK")
+		case command == "DATA":
+			reply("354 Ende mit <CRLF>.<CRLF>")
+			var data strings.Builder
+			for {
+				line, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			s.mu.Lock()
+			s.messages = append(s.messages, data.String())
+			s.mu.Unlock()
+			reply("250 OK")
+		case command == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func TestSMTPChannelSend(t *testing.T) {
+	server := newFakeSMTP(t, 0)
+
+	err := server.channel().Send(Message{
+		Recipient: "anna@example.org",
+		Subject:   "Schichtänderung",
+		BodyText:  "Deine Schicht wurde geändert.",
+		BodyHTML:  "<p>Deine Schicht wurde geändert.</p>",
+	})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	messages := server.received()
+	if len(messages) != 1 {
+		t.Fatalf("%d Nachrichten empfangen, erwartet 1", len(messages))
+	}
+	for _, want := range []string{
+		"From: schichtplaner@example.org",
+		"To: anna@example.org",
+		"Subject: =?utf-8?q?Schicht=C3=A4nderung?=",
+		"Content-Type: multipart/alternative",
+		"Content-Type: text/plain; charset=UTF-8",
+		"Deine Schicht wurde geändert.",
+		"Content-Type: text/html; charset=UTF-8",
+		"<p>Deine Schicht wurde geändert.</p>",
+	} {
+		if !strings.Contains(messages[0], want) {
+			t.Errorf("Nachricht enthält nicht %q:\n%s", want, messages[0])
+		}
+	}
+}
+
+func TestSMTPChannelSendRejected(t *testing.T) {
+	server := newFakeSMTP(t, 1)
+
+	if err := server.channel().Send(Message{Recipient: "anna@example.org", Subject: "Test"}); err == nil {
+		t.Fatal("abgelehnte Zustellung ohne Fehler")
+	}
+	if messages := server.received(); len(messages) != 0 {
+		t.Fatalf("%d Nachrichten trotz Ablehnung empfangen", len(messages))
+	}
+}
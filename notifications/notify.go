@@ -0,0 +1,176 @@
+package notifications
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ptmmeiningen/schichtplaner/database"
+	"github.com/ptmmeiningen/schichtplaner/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// NotifyWeekPublished benachrichtigt alle in der Woche eingeplanten Mitarbeiter
+func NotifyWeekPublished(shiftWeek models.ShiftWeek) error {
+	var shiftDays []models.ShiftDay
+	if err := database.GetDB().
+		Where("shift_week_id = ? AND employee_id IS NOT NULL", shiftWeek.ID).
+		Preload("ShiftType").
+		Preload("Employee").
+		Order("date").
+		Find(&shiftDays).Error; err != nil {
+		return err
+	}
+
+	perEmployee := make(map[uint][]models.ShiftDay)
+	var order []uint
+	for _, day := range shiftDays {
+		if _, ok := perEmployee[*day.EmployeeID]; !ok {
+			order = append(order, *day.EmployeeID)
+		}
+		perEmployee[*day.EmployeeID] = append(perEmployee[*day.EmployeeID], day)
+	}
+
+	for _, employeeID := range order {
+		days := perEmployee[employeeID]
+		data := TemplateData{
+			Employee:  days[0].Employee,
+			ShiftWeek: shiftWeek,
+			ShiftDays: days,
+		}
+		key := fmt.Sprintf("week_published:%d:%d", shiftWeek.ID, employeeID)
+		if err := enqueue(models.NotificationWeekPublished, data, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NotifyShiftChanged informiert einen Mitarbeiter über eine Änderung nach der Veröffentlichung
+func NotifyShiftChanged(shiftDay models.ShiftDay, employeeID uint, removed bool) error {
+	var employee models.Employee
+	if err := database.GetDB().First(&employee, employeeID).Error; err != nil {
+		return err
+	}
+
+	if shiftDay.ShiftType.ID == 0 {
+		database.GetDB().First(&shiftDay.ShiftType, shiftDay.ShiftTypeID)
+	}
+
+	data := TemplateData{
+		Employee: employee,
+		ShiftDay: shiftDay,
+		Removed:  removed,
+	}
+	return enqueue(models.NotificationShiftChanged, data, "")
+}
+
+// NotifyLeaveDecision informiert den Mitarbeiter eines Schichttags, dass für
+// ihn eine Abwesenheit eingetragen (Urlaub, Krankheit) oder wieder aufgehoben
+// wurde. Anders als Schichtänderungen auch vor der Veröffentlichung.
+func NotifyLeaveDecision(shiftDay models.ShiftDay, removed bool) error {
+	if shiftDay.EmployeeID == nil {
+		return nil
+	}
+
+	var employee models.Employee
+	if err := database.GetDB().First(&employee, *shiftDay.EmployeeID).Error; err != nil {
+		return err
+	}
+
+	if shiftDay.ShiftType.ID == 0 {
+		database.GetDB().First(&shiftDay.ShiftType, shiftDay.ShiftTypeID)
+	}
+
+	data := TemplateData{
+		Employee: employee,
+		ShiftDay: shiftDay,
+		Removed:  removed,
+	}
+	return enqueue(models.NotificationLeaveDecision, data, "")
+}
+
+// EnqueueReminders legt Erinnerungen für alle veröffentlichten Schichten am
+// angegebenen Kalendertag an. Schichttage sind auf Mitternacht UTC gespeichert,
+// daher zählt nur das Datum von day, nicht seine Zeitzone.
+func EnqueueReminders(day time.Time) error {
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 1)
+
+	published := database.GetDB().Model(&models.ShiftWeek{}).Select("id").Where("status = ?", models.StatusPublished)
+
+	var shiftDays []models.ShiftDay
+	if err := database.GetDB().
+		Where("shift_days.shift_week_id IN (?) AND shift_days.employee_id IS NOT NULL", published).
+		Where("shift_days.date >= ? AND shift_days.date < ?", start, end).
+		Preload("ShiftType").
+		Preload("Employee").
+		Find(&shiftDays).Error; err != nil {
+		return err
+	}
+
+	for _, day := range shiftDays {
+		data := TemplateData{
+			Employee: day.Employee,
+			ShiftDay: day,
+		}
+		key := fmt.Sprintf("shift_reminder:%d:%s", day.ID, start.Format("2006-01-02"))
+		if err := enqueue(models.NotificationShiftReminder, data, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// enqueue rendert die Nachricht und legt sie für jeden aktiven Kanal im Postausgang ab
+func enqueue(kind string, data TemplateData, dedupeKey string) error {
+	if data.Employee.ID == 0 || data.Employee.Email == "" {
+		return nil
+	}
+
+	preference := loadPreference(database.GetDB(), data.Employee.ID)
+
+	msg, err := render(kind, data)
+	if err != nil {
+		return err
+	}
+
+	for name := range channels {
+		if !preference.Allows(name, kind) {
+			continue
+		}
+
+		notification := models.Notification{
+			TenantID:      data.Employee.TenantID,
+			EmployeeID:    data.Employee.ID,
+			Channel:       name,
+			Kind:          kind,
+			Recipient:     msg.Recipient,
+			Subject:       msg.Subject,
+			BodyText:      msg.BodyText,
+			BodyHTML:      msg.BodyHTML,
+			Status:        models.NotificationPending,
+			NextAttemptAt: time.Now(),
+		}
+		if dedupeKey != "" {
+			key := dedupeKey + ":" + name
+			notification.DedupeKey = &key
+		}
+
+		// Bereits vorhandene Einträge mit gleichem Schlüssel werden übersprungen
+		if err := database.GetDB().
+			Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&notification).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func loadPreference(db *gorm.DB, employeeID uint) models.NotificationPreference {
+	var preference models.NotificationPreference
+	if err := db.Where("employee_id = ?", employeeID).First(&preference).Error; err != nil {
+		return models.DefaultNotificationPreference(employeeID)
+	}
+	return preference
+}
@@ -0,0 +1,81 @@
+package notifications
+
+import (
+	"bytes"
+	"embed"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/ptmmeiningen/schichtplaner/models"
+)
+
+//go:embed templates/*
+var templateFS embed.FS
+
+var weekdays = [...]string{"Sonntag", "Montag", "Dienstag", "Mittwoch", "Donnerstag", "Freitag", "Samstag"}
+
+var absences = map[string]string{
+	models.ShiftDaySick:     "Krankheit",
+	models.ShiftDayVacation: "Urlaub",
+	models.ShiftDayAbsent:   "Abwesenheit",
+}
+
+var templateFuncs = map[string]interface{}{
+	"date": func(t time.Time) string {
+		return t.Format("02.01.2006")
+	},
+	"weekday": func(t time.Time) string {
+		return weekdays[t.Weekday()]
+	},
+	"absence": func(status string) string {
+		return absences[status]
+	},
+}
+
+// TemplateData enthält alle Werte, die in den Vorlagen verwendet werden können
+type TemplateData struct {
+	Employee  models.Employee
+	ShiftWeek models.ShiftWeek
+	ShiftDays []models.ShiftDay
+	ShiftDay  models.ShiftDay
+	Removed   bool
+}
+
+// render erzeugt Betreff, Text- und HTML-Teil für eine Benachrichtigungsart
+func render(kind string, data TemplateData) (Message, error) {
+	textTmpl, err := texttemplate.New(kind).
+		Funcs(templateFuncs).
+		ParseFS(templateFS, "templates/"+kind+".txt")
+	if err != nil {
+		return Message{}, err
+	}
+
+	var subject, text bytes.Buffer
+	if err := textTmpl.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, err
+	}
+	if err := textTmpl.ExecuteTemplate(&text, "body", data); err != nil {
+		return Message{}, err
+	}
+
+	htmlTmpl, err := htmltemplate.New(kind).
+		Funcs(templateFuncs).
+		ParseFS(templateFS, "templates/layout.html", "templates/"+kind+".html")
+	if err != nil {
+		return Message{}, err
+	}
+
+	var html bytes.Buffer
+	if err := htmlTmpl.ExecuteTemplate(&html, "layout", data); err != nil {
+		return Message{}, err
+	}
+
+	return Message{
+		Recipient: data.Employee.Email,
+		Subject:   strings.TrimSpace(subject.String()),
+		BodyText:  strings.TrimSpace(text.String()),
+		BodyHTML:  html.String(),
+	}, nil
+}
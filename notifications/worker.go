@@ -0,0 +1,94 @@
+package notifications
+
+import (
+	"log"
+	"time"
+
+	"github.com/ptmmeiningen/schichtplaner/database"
+	"github.com/ptmmeiningen/schichtplaner/models"
+)
+
+const (
+	maxAttempts = 5
+	batchSize   = 50
+)
+
+// Setup registriert die konfigurierten Kanäle
+func Setup() {
+	if smtpChannel, ok := NewSMTPChannelFromEnv(); ok {
+		RegisterChannel(smtpChannel)
+	}
+}
+
+// StartWorker verarbeitet den Postausgang und plant Erinnerungen im angegebenen Intervall
+func StartWorker(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := EnqueueReminders(time.Now().AddDate(0, 0, 1)); err != nil {
+				log.Printf("Fehler beim Anlegen der Erinnerungen: %v", err)
+			}
+			if err := ProcessOutbox(); err != nil {
+				log.Printf("Fehler beim Verarbeiten des Postausgangs: %v", err)
+			}
+		}
+	}()
+}
+
+// ProcessOutbox versendet fällige Benachrichtigungen und plant Wiederholungen bei Fehlern
+func ProcessOutbox() error {
+	var pending []models.Notification
+	if err := database.GetDB().
+		Where("status = ? AND next_attempt_at <= ?", models.NotificationPending, time.Now()).
+		Order("next_attempt_at").
+		Limit(batchSize).
+		Find(&pending).Error; err != nil {
+		return err
+	}
+
+	for i := range pending {
+		notification := &pending[i]
+		notification.Attempts++
+
+		err := deliver(notification)
+		if err == nil {
+			now := time.Now()
+			notification.Status = models.NotificationSent
+			notification.SentAt = &now
+			notification.LastError = ""
+		} else {
+			notification.LastError = err.Error()
+			if notification.Attempts >= maxAttempts {
+				notification.Status = models.NotificationFailed
+			} else {
+				notification.NextAttemptAt = time.Now().Add(backoff(notification.Attempts))
+			}
+		}
+
+		if err := database.GetDB().Save(notification).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func deliver(notification *models.Notification) error {
+	ch, ok := getChannel(notification.Channel)
+	if !ok {
+		return errUnknownChannel(notification.Channel)
+	}
+
+	return ch.Send(Message{
+		Recipient: notification.Recipient,
+		Subject:   notification.Subject,
+		BodyText:  notification.BodyText,
+		BodyHTML:  notification.BodyHTML,
+	})
+}
+
+// backoff verdoppelt die Wartezeit mit jedem Fehlversuch (2, 4, 8, 16 Minuten)
+func backoff(attempts int) time.Duration {
+	return time.Minute << attempts
+}
@@ -0,0 +1,171 @@
+package notifications
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ptmmeiningen/schichtplaner/database"
+	"github.com/ptmmeiningen/schichtplaner/database/databasetest"
+	"github.com/ptmmeiningen/schichtplaner/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// useChannel registriert ch für die Dauer des Tests
+func useChannel(t *testing.T, ch Channel) {
+	t.Helper()
+	RegisterChannel(ch)
+	t.Cleanup(func() { delete(channels, ch.Name()) })
+}
+
+// createPublishedShift legt einen Mitarbeiter mit einer veröffentlichten
+// Frühschicht an jedem der Tage an
+func createPublishedShift(t *testing.T, days ...time.Time) (models.Employee, []models.ShiftDay) {
+	t.Helper()
+	db := database.GetDB().Omit(clause.Associations).Session(&gorm.Session{})
+
+	employee := databasetest.Employee(t, "anna@example.org", 0)
+	shiftType := databasetest.ShiftType(t, "Früh", "06:00", "14:00")
+
+	var shiftDays []models.ShiftDay
+	for _, day := range days {
+		year, week := day.ISOWeek()
+		shiftWeek := models.ShiftWeek{Year: year, CalendarWeek: week, Status: models.StatusPublished}
+		if err := db.Where(shiftWeek).FirstOrCreate(&shiftWeek).Error; err != nil {
+			t.Fatal(err)
+		}
+		shiftDay := models.ShiftDay{Date: day, ShiftWeekID: &shiftWeek.ID, ShiftTypeID: shiftType.ID, EmployeeID: &employee.ID}
+		databasetest.Create(t, &shiftDay)
+		shiftDays = append(shiftDays, shiftDay)
+	}
+	return employee, shiftDays
+}
+
+// makeDue stellt alle ausstehenden Benachrichtigungen sofort fällig
+func makeDue(t *testing.T) {
+	t.Helper()
+	if err := database.GetDB().Model(&models.Notification{}).
+		Where("status = ?", models.NotificationPending).
+		Update("next_attempt_at", time.Now().Add(-time.Second)).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func loadNotifications(t *testing.T) []models.Notification {
+	t.Helper()
+	var notifications []models.Notification
+	if err := database.GetDB().Order("id").Find(&notifications).Error; err != nil {
+		t.Fatal(err)
+	}
+	return notifications
+}
+
+func TestProcessOutboxRetriesUntilSent(t *testing.T) {
+	databasetest.Setup(t)
+	server := newFakeSMTP(t, 1)
+	useChannel(t, server.channel())
+
+	employee, shiftDays := createPublishedShift(t, time.Date(2030, 1, 14, 0, 0, 0, 0, time.UTC))
+	if err := NotifyShiftChanged(shiftDays[0], employee.ID, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ProcessOutbox(); err != nil {
+		t.Fatal(err)
+	}
+	notification := loadNotifications(t)[0]
+	if notification.Status != models.NotificationPending || notification.Attempts != 1 || notification.LastError == "" {
+		t.Fatalf("nach abgelehnter Zustellung: status %s, versuche %d, fehler %q", notification.Status, notification.Attempts, notification.LastError)
+	}
+	if !notification.NextAttemptAt.After(time.Now()) {
+		t.Fatalf("nächster Versuch %v liegt nicht in der Zukunft", notification.NextAttemptAt)
+	}
+
+	// Vor Ablauf der Wartezeit wird nicht erneut zugestellt
+	if err := ProcessOutbox(); err != nil {
+		t.Fatal(err)
+	}
+	if attempts := loadNotifications(t)[0].Attempts; attempts != 1 {
+		t.Fatalf("%d Versuche vor Ablauf der Wartezeit, erwartet 1", attempts)
+	}
+
+	makeDue(t)
+	if err := ProcessOutbox(); err != nil {
+		t.Fatal(err)
+	}
+	notification = loadNotifications(t)[0]
+	if notification.Status != models.NotificationSent || notification.Attempts != 2 || notification.SentAt == nil || notification.LastError != "" {
+		t.Fatalf("nach erfolgreicher Zustellung: status %s, versuche %d, fehler %q", notification.Status, notification.Attempts, notification.LastError)
+	}
+	if messages := server.received(); len(messages) != 1 {
+		t.Fatalf("%d Nachrichten empfangen, erwartet 1", len(messages))
+	}
+}
+
+func TestProcessOutboxGivesUp(t *testing.T) {
+	databasetest.Setup(t)
+	server := newFakeSMTP(t, maxAttempts)
+	useChannel(t, server.channel())
+
+	employee, shiftDays := createPublishedShift(t, time.Date(2030, 1, 14, 0, 0, 0, 0, time.UTC))
+	if err := NotifyShiftChanged(shiftDays[0], employee.ID, true); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < maxAttempts; i++ {
+		makeDue(t)
+		if err := ProcessOutbox(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	notification := loadNotifications(t)[0]
+	if notification.Status != models.NotificationFailed || notification.Attempts != maxAttempts {
+		t.Fatalf("status %s nach %d versuchen, erwartet %s nach %d", notification.Status, notification.Attempts, models.NotificationFailed, maxAttempts)
+	}
+
+	// Aufgegebene Benachrichtigungen werden nicht mehr zugestellt
+	makeDue(t)
+	if err := ProcessOutbox(); err != nil {
+		t.Fatal(err)
+	}
+	if attempts := loadNotifications(t)[0].Attempts; attempts != maxAttempts {
+		t.Fatalf("%d Versuche nach dem Aufgeben, erwartet %d", attempts, maxAttempts)
+	}
+}
+
+func TestEnqueueRemindersUsesCalendarDay(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("Zeitzonendaten nicht verfügbar")
+	}
+	local := time.Local
+	time.Local = newYork
+	t.Cleanup(func() { time.Local = local })
+
+	databasetest.Setup(t)
+	useChannel(t, newFakeSMTP(t, 0).channel())
+
+	_, shiftDays := createPublishedShift(t,
+		time.Date(2030, 1, 14, 0, 0, 0, 0, time.UTC),
+		time.Date(2030, 1, 15, 0, 0, 0, 0, time.UTC),
+	)
+
+	// Am Abend des 14. in New York ist es in UTC schon der 15.
+	day := time.Date(2030, 1, 14, 20, 0, 0, 0, newYork)
+	for i := 0; i < 2; i++ {
+		if err := EnqueueReminders(day); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	notifications := loadNotifications(t)
+	if len(notifications) != 1 {
+		t.Fatalf("%d Erinnerungen, erwartet 1", len(notifications))
+	}
+	want := fmt.Sprintf("shift_reminder:%d:2030-01-14:%s", shiftDays[0].ID, models.ChannelEmail)
+	if notifications[0].DedupeKey == nil || *notifications[0].DedupeKey != want {
+		t.Fatalf("Erinnerung mit Schlüssel %v, erwartet %s", notifications[0].DedupeKey, want)
+	}
+}
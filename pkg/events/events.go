@@ -0,0 +1,138 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Vordefinierte Ereignistypen
+const (
+	ShiftDayCreated    = "shiftday.created"
+	ShiftDayUpdated    = "shiftday.updated"
+	ShiftDayDeleted    = "shiftday.deleted"
+	ShiftWeekCreated   = "shiftweek.created"
+	ShiftWeekUpdated   = "shiftweek.updated"
+	ShiftWeekDeleted   = "shiftweek.deleted"
+	ShiftWeekDraft     = "shiftweek.draft"
+	ShiftWeekPublished = "shiftweek.published"
+	ShiftWeekArchived  = "shiftweek.archived"
+	ShiftTypeCreated   = "shifttype.created"
+	ShiftTypeUpdated   = "shifttype.updated"
+	ShiftTypeDeleted   = "shifttype.deleted"
+	EmployeeCreated    = "employee.created"
+	EmployeeUpdated    = "employee.updated"
+	EmployeeDeleted    = "employee.deleted"
+	DepartmentCreated  = "department.created"
+	DepartmentUpdated  = "department.updated"
+	DepartmentDeleted  = "department.deleted"
+)
+
+// Types listet alle bekannten Ereignistypen
+var Types = []string{
+	ShiftDayCreated, ShiftDayUpdated, ShiftDayDeleted,
+	ShiftWeekCreated, ShiftWeekUpdated, ShiftWeekDeleted,
+	ShiftWeekDraft, ShiftWeekPublished, ShiftWeekArchived,
+	ShiftTypeCreated, ShiftTypeUpdated, ShiftTypeDeleted,
+	EmployeeCreated, EmployeeUpdated, EmployeeDeleted,
+	DepartmentCreated, DepartmentUpdated, DepartmentDeleted,
+}
+
+// Event beschreibt eine Änderung, die an verbundene Clients verteilt wird.
+// Clients und Webhooks erhalten nur Ereignisse ihres Mandanten.
+type Event struct {
+	TenantID     uint        `json:"-"`
+	Type         string      `json:"type" example:"shiftday.created"`
+	DepartmentID *uint       `json:"department_id,omitempty" example:"1"`
+	Data         interface{} `json:"data,omitempty"`
+	Timestamp    time.Time   `json:"timestamp" format:"date-time"`
+}
+
+// Broker verteilt Ereignisse an alle Abonnenten
+type Broker interface {
+	Publish(event Event)
+	Subscribe() (<-chan Event, func())
+}
+
+var (
+	broker Broker = NewMemoryBroker()
+	hooks  []func(Event)
+)
+
+func GetBroker() Broker {
+	return broker
+}
+
+func SetBroker(b Broker) {
+	broker = b
+}
+
+// OnPublish registriert eine Funktion, die jedes Ereignis synchron erhält.
+// Im Gegensatz zu Abonnenten des Brokers gehen dabei keine Ereignisse verloren.
+func OnPublish(fn func(Event)) {
+	hooks = append(hooks, fn)
+}
+
+// Publish veröffentlicht ein Ereignis eines Mandanten über den aktiven Broker
+func Publish(tenantID uint, eventType string, departmentID *uint, data interface{}) {
+	event := Event{
+		TenantID:     tenantID,
+		Type:         eventType,
+		DepartmentID: departmentID,
+		Data:         data,
+		Timestamp:    time.Now(),
+	}
+
+	broker.Publish(event)
+	for _, fn := range hooks {
+		fn(event)
+	}
+}
+
+// ShiftWeekStatusEvent liefert den Ereignistyp für einen Schichtwochen-Status
+func ShiftWeekStatusEvent(status string) string {
+	return "shiftweek." + status
+}
+
+// MemoryBroker ist ein prozessinterner Broker ohne Persistenz
+type MemoryBroker struct {
+	mu          sync.RWMutex
+	subscribers map[chan Event]struct{}
+}
+
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{
+		subscribers: make(map[chan Event]struct{}),
+	}
+}
+
+func (b *MemoryBroker) Publish(event Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for ch := range b.subscribers {
+		// Langsame Abonnenten blockieren die Handler nicht
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+func (b *MemoryBroker) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, 32)
+
+	b.mu.Lock()
+	b.subscribers[ch] = struct{}{}
+	b.mu.Unlock()
+
+	unsubscribe := func() {
+		b.mu.Lock()
+		if _, ok := b.subscribers[ch]; ok {
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+		b.mu.Unlock()
+	}
+
+	return ch, unsubscribe
+}
@@ -0,0 +1,136 @@
+package holidays
+
+import "time"
+
+// States sind die Kürzel der Bundesländer
+var States = map[string]string{
+	"BW": "Baden-Württemberg",
+	"BY": "Bayern",
+	"BE": "Berlin",
+	"BB": "Brandenburg",
+	"HB": "Bremen",
+	"HH": "Hamburg",
+	"HE": "Hessen",
+	"MV": "Mecklenburg-Vorpommern",
+	"NI": "Niedersachsen",
+	"NW": "Nordrhein-Westfalen",
+	"RP": "Rheinland-Pfalz",
+	"SL": "Saarland",
+	"SN": "Sachsen",
+	"ST": "Sachsen-Anhalt",
+	"SH": "Schleswig-Holstein",
+	"TH": "Thüringen",
+}
+
+// ValidState gibt an, ob state ein Bundesland-Kürzel oder leer ist
+func ValidState(state string) bool {
+	if state == "" {
+		return true
+	}
+	_, ok := States[state]
+	return ok
+}
+
+// Name liefert den Namen des gesetzlichen Feiertags am Datum. Ohne
+// Bundesland gelten nur die bundesweiten Feiertage. Feiertage, die nur in
+// einzelnen Gemeinden gelten (z.B. Mariä Himmelfahrt in Bayern,
+// Fronleichnam in Teilen Sachsens und Thüringens), sind nicht enthalten.
+func Name(date time.Time, state string) (string, bool) {
+	year, month, day := date.Date()
+	switch {
+	case month == time.January && day == 1:
+		return "Neujahr", true
+	case month == time.May && day == 1:
+		return "Tag der Arbeit", true
+	case month == time.October && day == 3:
+		return "Tag der Deutschen Einheit", true
+	case month == time.December && day == 25:
+		return "1. Weihnachtstag", true
+	case month == time.December && day == 26:
+		return "2. Weihnachtstag", true
+	}
+
+	easter := easterSunday(year)
+	offset := int(time.Date(year, month, day, 0, 0, 0, 0, time.UTC).Sub(easter).Hours() / 24)
+	switch offset {
+	case -2:
+		return "Karfreitag", true
+	case 1:
+		return "Ostermontag", true
+	case 39:
+		return "Christi Himmelfahrt", true
+	case 50:
+		return "Pfingstmontag", true
+	}
+
+	return stateName(state, year, month, day, offset)
+}
+
+// stateName liefert die Feiertage, die nur in einzelnen Bundesländern gelten
+func stateName(state string, year int, month time.Month, day, easterOffset int) (string, bool) {
+	in := func(states ...string) bool {
+		for _, s := range states {
+			if s == state {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch {
+	case month == time.January && day == 6 && in("BW", "BY", "ST"):
+		return "Heilige Drei Könige", true
+	case month == time.March && day == 8 && (state == "BE" && year >= 2019 || state == "MV" && year >= 2023):
+		return "Internationaler Frauentag", true
+	case easterOffset == 0 && state == "BB":
+		return "Ostersonntag", true
+	case easterOffset == 49 && state == "BB":
+		return "Pfingstsonntag", true
+	case easterOffset == 60 && in("BW", "BY", "HE", "NW", "RP", "SL"):
+		return "Fronleichnam", true
+	case month == time.August && day == 15 && state == "SL":
+		return "Mariä Himmelfahrt", true
+	case month == time.September && day == 20 && state == "TH" && year >= 2019:
+		return "Weltkindertag", true
+	case month == time.October && day == 31 && (in("BB", "MV", "SN", "ST", "TH") || in("HB", "HH", "NI", "SH") && year >= 2018):
+		return "Reformationstag", true
+	case month == time.November && day == 1 && in("BW", "BY", "NW", "RP", "SL"):
+		return "Allerheiligen", true
+	case month == time.November && state == "SN" && day == repentanceDay(year):
+		return "Buß- und Bettag", true
+	}
+	return "", false
+}
+
+// IsHoliday gibt an, ob das Datum im Bundesland ein gesetzlicher Feiertag ist
+func IsHoliday(date time.Time, state string) bool {
+	_, ok := Name(date, state)
+	return ok
+}
+
+// repentanceDay ist der Tag im November des Buß- und Bettags, der Mittwoch
+// vor dem 23. November
+func repentanceDay(year int) int {
+	nov22 := time.Date(year, time.November, 22, 0, 0, 0, 0, time.UTC)
+	return 22 - (int(nov22.Weekday())-int(time.Wednesday)+7)%7
+}
+
+// easterSunday berechnet den Ostersonntag nach der Gaußschen Osterformel
+// (anonymer gregorianischer Algorithmus)
+func easterSunday(year int) time.Time {
+	a := year % 19
+	b := year / 100
+	c := year % 100
+	d := b / 4
+	e := b % 4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i := c / 4
+	k := c % 4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+}
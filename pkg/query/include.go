@@ -0,0 +1,249 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// Include beschreibt eine Beziehung, die per ?include= geladen werden darf
+type Include struct {
+	Preload    string
+	Resource   string
+	Fields     []string
+	ForeignKey string
+	HasMany    bool
+	Order      string
+	Includes   map[string]Include
+}
+
+// selection ist der aus include und fields[...] aufgelöste Baum für eine Ebene
+type selection struct {
+	preload    string
+	fields     map[string]bool
+	relations  map[string]Include
+	children   map[string]*selection
+	foreignKey string
+	hasMany    bool
+	order      string
+	columns    []string
+}
+
+// parseSelection löst include und fields[...] gegen die Whitelist der Konfiguration auf
+func parseSelection(c *fiber.Ctx, config Config) (*selection, error) {
+	fields := make(map[string][]string)
+	c.Context().QueryArgs().VisitAll(func(key, value []byte) {
+		name := string(key)
+		if !strings.HasPrefix(name, "fields[") || !strings.HasSuffix(name, "]") {
+			return
+		}
+		resource := name[len("fields[") : len(name)-1]
+		for _, field := range strings.Split(string(value), ",") {
+			if field = strings.TrimSpace(field); field != "" {
+				fields[resource] = append(fields[resource], field)
+			}
+		}
+	})
+
+	known := map[string][]string{config.Resource: config.Fields}
+	collectResources(config.Includes, known)
+	for resource, requested := range fields {
+		allowed, ok := known[resource]
+		if !ok {
+			return nil, fmt.Errorf("unbekannte ressource in fields: %s", resource)
+		}
+		for _, field := range requested {
+			if !contains(allowed, field) {
+				return nil, fmt.Errorf("feld %s ist für %s nicht erlaubt", field, resource)
+			}
+		}
+	}
+
+	paths := config.DefaultIncludes
+	if c.Context().QueryArgs().Has("include") {
+		paths = nil
+		for _, path := range strings.Split(c.Query("include"), ",") {
+			if path = strings.TrimSpace(path); path != "" {
+				paths = append(paths, path)
+			}
+		}
+	}
+
+	root := newSelection(config.Includes, fields[config.Resource])
+	for _, path := range paths {
+		current := root
+		for _, name := range strings.Split(path, ".") {
+			include, ok := current.relations[name]
+			if !ok {
+				return nil, fmt.Errorf("include %s wird nicht unterstützt", path)
+			}
+			child, ok := current.children[name]
+			if !ok {
+				child = newSelection(include.Includes, fields[include.Resource])
+				child.preload = joinPath(current.preload, include.Preload)
+				child.foreignKey = include.ForeignKey
+				child.hasMany = include.HasMany
+				child.order = include.Order
+				current.children[name] = child
+			}
+			current = child
+		}
+	}
+
+	root.resolveColumns()
+	return root, nil
+}
+
+func newSelection(relations map[string]Include, fields []string) *selection {
+	s := &selection{
+		relations: relations,
+		children:  make(map[string]*selection),
+	}
+	if len(fields) > 0 {
+		s.fields = make(map[string]bool)
+		for _, field := range fields {
+			s.fields[field] = true
+		}
+	}
+	return s
+}
+
+// resolveColumns ergänzt die angeforderten Felder um Schlüssel, die GORM für die Preloads braucht
+func (s *selection) resolveColumns() {
+	if s.fields != nil {
+		columns := map[string]bool{"id": true}
+		for field := range s.fields {
+			columns[field] = true
+		}
+		for _, child := range s.children {
+			if !child.hasMany {
+				columns[child.foreignKey] = true
+			}
+		}
+		if s.hasMany {
+			columns[s.foreignKey] = true
+		}
+		for column := range columns {
+			s.columns = append(s.columns, column)
+		}
+		sort.Strings(s.columns)
+	}
+
+	for _, child := range s.children {
+		child.resolveColumns()
+	}
+}
+
+// apply fügt Select und Preloads für die aufgelöste Auswahl hinzu
+func (s *selection) apply(db *gorm.DB, table string) *gorm.DB {
+	if len(s.columns) > 0 {
+		qualified := make([]string, len(s.columns))
+		for i, column := range s.columns {
+			qualified[i] = table + "." + column
+		}
+		db = db.Select(qualified)
+	}
+	return s.applyPreloads(db)
+}
+
+func (s *selection) applyPreloads(db *gorm.DB) *gorm.DB {
+	for _, name := range s.childNames() {
+		child := s.children[name]
+		columns, order := child.columns, child.order
+		db = db.Preload(child.preload, func(tx *gorm.DB) *gorm.DB {
+			if len(columns) > 0 {
+				tx = tx.Select(columns)
+			}
+			if order != "" {
+				tx = tx.Order(order)
+			}
+			return tx
+		})
+		db = child.applyPreloads(db)
+	}
+	return db
+}
+
+func (s *selection) childNames() []string {
+	names := make([]string, 0, len(s.children))
+	for name := range s.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// shape entfernt nicht angeforderte Felder und nicht eingebundene Beziehungen aus dem JSON
+func (s *selection) shape(node interface{}) interface{} {
+	switch value := node.(type) {
+	case []interface{}:
+		for i := range value {
+			value[i] = s.shape(value[i])
+		}
+		return value
+	case map[string]interface{}:
+		for key, item := range value {
+			if _, isRelation := s.relations[key]; isRelation {
+				if child, ok := s.children[key]; ok {
+					value[key] = child.shape(item)
+				} else {
+					delete(value, key)
+				}
+				continue
+			}
+			if s.fields != nil && !s.fields[key] {
+				delete(value, key)
+			}
+		}
+		return value
+	}
+	return node
+}
+
+// Preload wendet include und fields auf eine Abfrage an, z.B. für First
+func (q *Query) Preload(db *gorm.DB) *gorm.DB {
+	return q.selection.apply(db, q.config.Table)
+}
+
+// Shape bereitet geladene Daten für die Antwort auf
+func (q *Query) Shape(data interface{}) interface{} {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return data
+	}
+
+	var generic interface{}
+	if err := json.Unmarshal(raw, &generic); err != nil {
+		return data
+	}
+	return q.selection.shape(generic)
+}
+
+func collectResources(includes map[string]Include, known map[string][]string) {
+	for _, include := range includes {
+		if _, ok := known[include.Resource]; !ok {
+			known[include.Resource] = include.Fields
+		}
+		collectResources(include.Includes, known)
+	}
+}
+
+func joinPath(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+package query
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// keys liefert die sortierten Schlüssel eines JSON-Objekts
+func keys(value interface{}) string {
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+// shapeFirst lädt die Tage zu target und liefert den ersten geformten Tag
+func shapeFirst(t *testing.T, target string) map[string]interface{} {
+	t.Helper()
+	monday := time.Date(2030, 1, 7, 0, 0, 0, 0, time.UTC)
+	db := setupDays(t, monday, "mo")
+
+	q, err := parse(t, target, dayConfig)
+	if err != nil {
+		t.Fatalf("%s: %v", target, err)
+	}
+	var days []testDay
+	if _, err := q.Find(db, &days); err != nil {
+		t.Fatalf("%s: %v", target, err)
+	}
+
+	// Wie in der Antwort: einmal durch JSON
+	raw, err := json.Marshal(q.Shape(days))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var shaped []map[string]interface{}
+	if err := json.Unmarshal(raw, &shaped); err != nil {
+		t.Fatal(err)
+	}
+	if len(shaped) != 1 {
+		t.Fatalf("%s: %d tage", target, len(shaped))
+	}
+	return shaped[0]
+}
+
+func TestParseSelectionRejectsUnknown(t *testing.T) {
+	for _, target := range []string{
+		"/?include=month",
+		"/?include=week.team",
+		"/?include=department",
+		"/?fields[day]=id,secret",
+		"/?fields[week]=id,department",
+		"/?fields[employee]=id",
+		"/?include=week&fields[department]=id,budget",
+	} {
+		if _, err := parse(t, target, dayConfig); err == nil {
+			t.Errorf("%s: kein fehler", target)
+		}
+	}
+}
+
+func TestShapeIncludes(t *testing.T) {
+	tests := []struct {
+		target string
+		day    string
+		week   string
+	}{
+		{target: "/", day: "date,id,name,week_id"},
+		{target: "/?include=week", day: "date,id,name,week,week_id", week: "department_id,id,number"},
+		{target: "/?include=week.department", day: "date,id,name,week,week_id", week: "department,department_id,id,number"},
+		{target: "/?include=", day: "date,id,name,week_id"},
+	}
+	for _, test := range tests {
+		day := shapeFirst(t, test.target)
+		if got := keys(day); got != test.day {
+			t.Errorf("%s: tag %s, erwartet %s", test.target, got, test.day)
+		}
+		if got := keys(day["week"]); got != test.week {
+			t.Errorf("%s: woche %s, erwartet %s", test.target, got, test.week)
+		}
+	}
+
+	day := shapeFirst(t, "/?include=week.department")
+	department := day["week"].(map[string]interface{})["department"]
+	if keys(department) != "id,name" || department.(map[string]interface{})["name"] != "Produktion" {
+		t.Fatalf("abteilung %v", department)
+	}
+}
+
+func TestShapeFields(t *testing.T) {
+	day := shapeFirst(t, "/?fields[day]=name&include=week.department&fields[week]=number&fields[department]=name")
+	if got := keys(day); got != "name,week" {
+		t.Fatalf("tag %s", got)
+	}
+	week := day["week"].(map[string]interface{})
+	if got := keys(week); got != "department,number" {
+		t.Fatalf("woche %s", got)
+	}
+	if week["number"] != float64(2) {
+		t.Fatalf("woche %v", week)
+	}
+	// Fremdschlüssel werden für die Preloads geladen, aber nicht ausgeliefert
+	department := week["department"].(map[string]interface{})
+	if got := keys(department); got != "name" || department["name"] != "Produktion" {
+		t.Fatalf("abteilung %v", department)
+	}
+}
+
+func TestSelectionColumns(t *testing.T) {
+	q, err := parse(t, "/?fields[day]=name&include=week&fields[week]=number", dayConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(q.selection.columns, ","); got != "id,name,week_id" {
+		t.Fatalf("spalten tag %s", got)
+	}
+	if got := strings.Join(q.selection.children["week"].columns, ","); got != "id,number" {
+		t.Fatalf("spalten woche %s", got)
+	}
+}
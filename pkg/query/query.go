@@ -0,0 +1,285 @@
+package query
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ptmmeiningen/schichtplaner/pkg/responses"
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 200
+)
+
+// Filter beschreibt einen erlaubten Filterparameter eines Endpunkts
+type Filter struct {
+	Condition string
+	Parse     func(string) (interface{}, error)
+}
+
+// Config legt fest, welche Filter, Sortierungen und Beziehungen ein Endpunkt
+// unterstützt. Params sind weitere Parameter, die der Handler selbst liest.
+type Config struct {
+	Table           string
+	Resource        string
+	Fields          []string
+	Params          []string
+	Filters         map[string]Filter
+	Sorts           map[string]string
+	DefaultSort     string
+	Includes        map[string]Include
+	DefaultIncludes []string
+}
+
+// Query enthält die aus der Anfrage gelesenen Parameter
+type Query struct {
+	config     Config
+	conditions []condition
+	orders     []string
+	page       int
+	pageSize   int
+	cursorMode bool
+	cursor     uint64
+	selection  *selection
+}
+
+type condition struct {
+	sql   string
+	value interface{}
+}
+
+// Parse liest page, page_size, cursor, sort und die Filter der Konfiguration.
+// Unbekannte Parameter werden abgelehnt, damit ein vertippter Filter nicht
+// stillschweigend alle Datensätze liefert.
+func Parse(c *fiber.Ctx, config Config) (*Query, error) {
+	q := &Query{
+		config:   config,
+		page:     1,
+		pageSize: DefaultPageSize,
+	}
+
+	var unknown []string
+	c.Context().QueryArgs().VisitAll(func(key, _ []byte) {
+		if name := string(key); !config.accepts(name) {
+			unknown = append(unknown, name)
+		}
+	})
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("unbekannter parameter: %s", strings.Join(unknown, ", "))
+	}
+
+	if raw := c.Query("page_size"); raw != "" {
+		size, err := strconv.Atoi(raw)
+		if err != nil || size < 1 || size > MaxPageSize {
+			return nil, fmt.Errorf("page_size muss zwischen 1 und %d liegen", MaxPageSize)
+		}
+		q.pageSize = size
+	}
+
+	if c.Context().QueryArgs().Has("cursor") {
+		q.cursorMode = true
+		if raw := c.Query("cursor"); raw != "" {
+			cursor, err := strconv.ParseUint(raw, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("ungültiger cursor")
+			}
+			q.cursor = cursor
+		}
+		if c.Query("page") != "" || c.Query("sort") != "" {
+			return nil, fmt.Errorf("cursor kann nicht mit page oder sort kombiniert werden")
+		}
+	} else if raw := c.Query("page"); raw != "" {
+		page, err := strconv.Atoi(raw)
+		if err != nil || page < 1 {
+			return nil, fmt.Errorf("page muss eine positive zahl sein")
+		}
+		q.page = page
+	}
+
+	for name, filter := range config.Filters {
+		raw := c.Query(name)
+		if raw == "" {
+			continue
+		}
+
+		value, err := filter.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("ungültiger wert für %s: %v", name, err)
+		}
+
+		q.conditions = append(q.conditions, condition{sql: filter.Condition, value: value})
+	}
+
+	sort := c.Query("sort", config.DefaultSort)
+	if q.cursorMode {
+		sort = ""
+	}
+	for _, field := range strings.Split(sort, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		direction := "ASC"
+		if strings.HasPrefix(field, "-") {
+			direction = "DESC"
+			field = field[1:]
+		}
+
+		column, ok := config.Sorts[field]
+		if !ok {
+			return nil, fmt.Errorf("sortierung nach %s wird nicht unterstützt", field)
+		}
+		q.orders = append(q.orders, column+" "+direction)
+	}
+
+	selection, err := parseSelection(c, config)
+	if err != nil {
+		return nil, err
+	}
+	q.selection = selection
+
+	return q, nil
+}
+
+// accepts gibt an, ob name ein Parameter des Endpunkts ist
+func (config Config) accepts(name string) bool {
+	switch name {
+	case "page", "page_size", "cursor", "sort", "include":
+		return true
+	}
+	if strings.HasPrefix(name, "fields[") && strings.HasSuffix(name, "]") {
+		return true
+	}
+	if _, ok := config.Filters[name]; ok {
+		return true
+	}
+	return contains(config.Params, name)
+}
+
+// Where wendet nur die Filter an, z.B. für Aggregationen
+func (q *Query) Where(db *gorm.DB) *gorm.DB {
+	for _, cond := range q.conditions {
+		db = db.Where(cond.sql, cond.value)
+	}
+	return db
+}
+
+// Find lädt eine Seite in dest inklusive der angeforderten Beziehungen.
+// Zusätzliche Scopes werden wie die Preloads erst nach der Zählung angewendet.
+func (q *Query) Find(db *gorm.DB, dest interface{}, scopes ...func(*gorm.DB) *gorm.DB) (*responses.Meta, error) {
+	filtered := q.Where(db)
+
+	var total int64
+	if err := filtered.Session(&gorm.Session{}).Model(dest).Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	idColumn := q.config.Table + ".id"
+	find := q.Preload(filtered.Session(&gorm.Session{})).Scopes(scopes...)
+	meta := &responses.Meta{Total: total, PageSize: q.pageSize}
+
+	if q.cursorMode {
+		if q.cursor > 0 {
+			find = find.Where(idColumn+" > ?", q.cursor)
+		}
+		if err := find.Order(idColumn + " ASC").Limit(q.pageSize).Find(dest).Error; err != nil {
+			return nil, err
+		}
+
+		if lastID, ok := lastID(dest); ok && q.hasMore(filtered, dest, idColumn, lastID) {
+			meta.NextCursor = strconv.FormatUint(lastID, 10)
+		}
+		return meta, nil
+	}
+
+	for _, order := range q.orders {
+		find = find.Order(order)
+	}
+	// Stabile Reihenfolge bei gleichen Sortierwerten
+	find = find.Order(idColumn)
+
+	if err := find.Offset((q.page - 1) * q.pageSize).Limit(q.pageSize).Find(dest).Error; err != nil {
+		return nil, err
+	}
+
+	meta.Page = q.page
+	return meta, nil
+}
+
+func (q *Query) hasMore(filtered *gorm.DB, dest interface{}, idColumn string, lastID uint64) bool {
+	var count int64
+	filtered.Session(&gorm.Session{}).Model(dest).Where(idColumn+" > ?", lastID).Count(&count)
+	return count > 0
+}
+
+// Int akzeptiert positive Ganzzahlen
+func Int(raw string) (interface{}, error) {
+	value, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("zahl erwartet")
+	}
+	return value, nil
+}
+
+// String übernimmt den Wert unverändert
+func String(raw string) (interface{}, error) {
+	return raw, nil
+}
+
+// Bool akzeptiert true/false bzw. 1/0
+func Bool(raw string) (interface{}, error) {
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		return nil, fmt.Errorf("true oder false erwartet")
+	}
+	return value, nil
+}
+
+// Date erwartet ein Datum im Format YYYY-MM-DD und liefert Mitternacht UTC,
+// so wie Schichttage gespeichert sind
+func Date(raw string) (interface{}, error) {
+	date, err := time.ParseInLocation("2006-01-02", raw, time.UTC)
+	if err != nil {
+		return nil, fmt.Errorf("datum im format YYYY-MM-DD erwartet")
+	}
+	return date, nil
+}
+
+// DateEnd liefert den Beginn des Folgetags, damit date_to den Tag einschließt
+func DateEnd(raw string) (interface{}, error) {
+	date, err := Date(raw)
+	if err != nil {
+		return nil, err
+	}
+	return date.(time.Time).AddDate(0, 0, 1), nil
+}
+
+// ISOWeek wandelt ein Datum in den Vergleichswert jahr*100+kalenderwoche um
+func ISOWeek(raw string) (interface{}, error) {
+	date, err := Date(raw)
+	if err != nil {
+		return nil, err
+	}
+	year, week := date.(time.Time).ISOWeek()
+	return year*100 + week, nil
+}
+
+func lastID(dest interface{}) (uint64, bool) {
+	items := reflect.Indirect(reflect.ValueOf(dest))
+	if items.Kind() != reflect.Slice || items.Len() == 0 {
+		return 0, false
+	}
+
+	id := reflect.Indirect(items.Index(items.Len() - 1)).FieldByName("ID")
+	if !id.IsValid() {
+		return 0, false
+	}
+	return id.Uint(), true
+}
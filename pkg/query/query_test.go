@@ -0,0 +1,267 @@
+package query
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/gofiber/fiber/v2"
+	"github.com/ptmmeiningen/schichtplaner/pkg/responses"
+	"gorm.io/gorm"
+)
+
+// Testmodelle: Tage gehören zu Wochen, Wochen zu Abteilungen
+type testDepartment struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+func (testDepartment) TableName() string { return "departments" }
+
+type testWeek struct {
+	ID           uint           `json:"id"`
+	Number       int            `json:"number"`
+	DepartmentID uint           `json:"department_id"`
+	Department   testDepartment `json:"department"`
+}
+
+func (testWeek) TableName() string { return "weeks" }
+
+type testDay struct {
+	ID     uint      `json:"id"`
+	Name   string    `json:"name"`
+	Date   time.Time `json:"date"`
+	WeekID uint      `json:"week_id"`
+	Week   testWeek  `json:"week"`
+}
+
+func (testDay) TableName() string { return "days" }
+
+var dayConfig = Config{
+	Table:    "days",
+	Resource: "day",
+	Fields:   []string{"id", "name", "date", "week_id"},
+	Params:   []string{"view"},
+	Filters: map[string]Filter{
+		"date_from": {Condition: "days.date >= ?", Parse: Date},
+		"date_to":   {Condition: "days.date < ?", Parse: DateEnd},
+		"week_id":   {Condition: "days.week_id = ?", Parse: Int},
+		"name":      {Condition: "days.name = ?", Parse: String},
+	},
+	Sorts: map[string]string{
+		"date": "days.date",
+		"name": "days.name",
+	},
+	DefaultSort: "date",
+	Includes: map[string]Include{
+		"week": {
+			Preload:    "Week",
+			Resource:   "week",
+			Fields:     []string{"id", "number", "department_id"},
+			ForeignKey: "week_id",
+			Includes: map[string]Include{
+				"department": {
+					Preload:    "Department",
+					Resource:   "department",
+					Fields:     []string{"id", "name"},
+					ForeignKey: "department_id",
+				},
+			},
+		},
+	},
+}
+
+// setupDays legt eine Abteilung mit einer Woche und je einem Tag ab monday an
+func setupDays(t *testing.T, monday time.Time, names ...string) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&testDepartment{}, &testWeek{}, &testDay{}); err != nil {
+		t.Fatal(err)
+	}
+
+	department := testDepartment{Name: "Produktion"}
+	db.Create(&department)
+	week := testWeek{Number: 2, DepartmentID: department.ID}
+	db.Create(&week)
+	for i, name := range names {
+		if err := db.Create(&testDay{Name: name, Date: monday.AddDate(0, 0, i), WeekID: week.ID}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+// parse ruft Parse mit den Parametern aus target auf
+func parse(t *testing.T, target string, config Config) (*Query, error) {
+	t.Helper()
+	var q *Query
+	var parseErr error
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		q, parseErr = Parse(c, config)
+		return nil
+	})
+	if _, err := app.Test(httptest.NewRequest("GET", target, nil)); err != nil {
+		t.Fatal(err)
+	}
+	return q, parseErr
+}
+
+// find parst target und lädt die passenden Tage
+func find(t *testing.T, db *gorm.DB, target string) ([]testDay, *responses.Meta) {
+	t.Helper()
+	q, err := parse(t, target, dayConfig)
+	if err != nil {
+		t.Fatalf("%s: %v", target, err)
+	}
+	var days []testDay
+	meta, err := q.Find(db, &days)
+	if err != nil {
+		t.Fatalf("%s: %v", target, err)
+	}
+	return days, meta
+}
+
+func names(days []testDay) []string {
+	result := make([]string, len(days))
+	for i, day := range days {
+		result[i] = day.Name
+	}
+	return result
+}
+
+func TestParseRejectsInvalidParameters(t *testing.T) {
+	tests := []struct {
+		target string
+		valid  bool
+	}{
+		{target: "/", valid: true},
+		{target: "/?page=2&page_size=200&sort=-name,date&view=kompakt", valid: true},
+		{target: "/?page_size=0"},
+		{target: "/?page_size=201"},
+		{target: "/?page_size=zehn"},
+		{target: "/?page=0"},
+		{target: "/?page=-1"},
+		{target: "/?cursor=abc"},
+		{target: "/?cursor=&page=2"},
+		{target: "/?cursor=&sort=name"},
+		{target: "/?sort=week_id"},
+		{target: "/?sort=-unbekannt"},
+		{target: "/?week=1"},
+		{target: "/?date_form=2030-01-07"},
+		{target: "/?week_id=eins"},
+		{target: "/?date_from=07.01.2030"},
+	}
+	for _, test := range tests {
+		_, err := parse(t, test.target, dayConfig)
+		if test.valid && err != nil {
+			t.Errorf("%s: %v", test.target, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("%s: kein fehler", test.target)
+		}
+	}
+}
+
+func TestFindPages(t *testing.T) {
+	monday := time.Date(2030, 1, 7, 0, 0, 0, 0, time.UTC)
+	db := setupDays(t, monday, "mo", "di", "mi", "do", "fr")
+
+	days, meta := find(t, db, "/?page=2&page_size=2")
+	if got := names(days); len(got) != 2 || got[0] != "mi" || got[1] != "do" {
+		t.Fatalf("seite 2: %v", got)
+	}
+	if meta.Total != 5 || meta.Page != 2 || meta.PageSize != 2 || meta.NextCursor != "" {
+		t.Fatalf("meta %+v", meta)
+	}
+
+	days, _ = find(t, db, "/?sort=-name&page_size=3")
+	if got := names(days); len(got) != 3 || got[0] != "mo" || got[1] != "mi" || got[2] != "fr" {
+		t.Fatalf("absteigend nach name: %v", got)
+	}
+
+	days, _ = find(t, db, "/?name=di")
+	if got := names(days); len(got) != 1 || got[0] != "di" {
+		t.Fatalf("filter name: %v", got)
+	}
+}
+
+func TestFindCursorRoundTrip(t *testing.T) {
+	monday := time.Date(2030, 1, 7, 0, 0, 0, 0, time.UTC)
+	db := setupDays(t, monday, "mo", "di", "mi", "do", "fr")
+
+	var seen []string
+	target := "/?cursor=&page_size=2"
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatalf("cursor endet nicht: %v", seen)
+		}
+		days, meta := find(t, db, target)
+		if meta.Total != 5 || meta.Page != 0 {
+			t.Fatalf("meta %+v", meta)
+		}
+		seen = append(seen, names(days)...)
+		if meta.NextCursor == "" {
+			break
+		}
+		target = "/?page_size=2&cursor=" + meta.NextCursor
+	}
+
+	if len(seen) != 5 || seen[0] != "mo" || seen[4] != "fr" {
+		t.Fatalf("alle seiten: %v", seen)
+	}
+}
+
+func TestDateBoundsUseUTC(t *testing.T) {
+	// Ohne UTC verschöbe die Zeitzone die Grenzen um einen Tag
+	local := time.Local
+	time.Local = time.FixedZone("UTC-5", -5*60*60)
+	t.Cleanup(func() { time.Local = local })
+
+	from, err := Date("2030-01-08")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2030, 1, 8, 0, 0, 0, 0, time.UTC); !from.(time.Time).Equal(want) || from.(time.Time).Location() != time.UTC {
+		t.Fatalf("Date %v, erwartet %v", from, want)
+	}
+	end, err := DateEnd("2030-01-09")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2030, 1, 10, 0, 0, 0, 0, time.UTC); !end.(time.Time).Equal(want) {
+		t.Fatalf("DateEnd %v, erwartet %v", end, want)
+	}
+
+	// Schichttage liegen auf Mitternacht UTC
+	monday := time.Date(2030, 1, 7, 0, 0, 0, 0, time.UTC)
+	db := setupDays(t, monday, "mo", "di", "mi", "do")
+	days, _ := find(t, db, "/?date_from=2030-01-08&date_to=2030-01-09")
+	if got := names(days); len(got) != 2 || got[0] != "di" || got[1] != "mi" {
+		t.Fatalf("di bis mi: %v", got)
+	}
+}
+
+func TestISOWeek(t *testing.T) {
+	tests := []struct {
+		date string
+		want int
+	}{
+		{date: "2030-01-07", want: 203002},
+		{date: "2027-01-01", want: 202653},
+		{date: "2024-12-30", want: 202501},
+	}
+	for _, test := range tests {
+		got, err := ISOWeek(test.date)
+		if err != nil || got != test.want {
+			t.Errorf("ISOWeek(%s) = %v, %v, erwartet %d", test.date, got, err, test.want)
+		}
+	}
+	if _, err := ISOWeek("2030-13-01"); err == nil {
+		t.Error("ungültiges datum ohne fehler")
+	}
+}
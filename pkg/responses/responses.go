@@ -1,11 +1,22 @@
 package responses
 
+import "github.com/ptmmeiningen/schichtplaner/models"
+
 // APIResponse definiert das standardisierte API-Antwortformat
 type APIResponse struct {
 	Success bool        `json:"success" example:"true"`
 	Message string      `json:"message,omitempty" example:"Operation erfolgreich"`
 	Error   string      `json:"error,omitempty" example:"Fehler bei der Verarbeitung"`
 	Data    interface{} `json:"data,omitempty"`
+	Meta    *Meta       `json:"meta,omitempty"`
+}
+
+// Meta enthält Angaben zur Paginierung von Listen
+type Meta struct {
+	Total      int64  `json:"total" example:"120"`
+	Page       int    `json:"page,omitempty" example:"1"`
+	PageSize   int    `json:"page_size" example:"50"`
+	NextCursor string `json:"next_cursor,omitempty" example:"42"`
 }
 
 // Vordefinierte Erfolgsmeldungen
@@ -14,6 +25,8 @@ const (
 	MsgSuccessCreate   = "Erfolgreich erstellt"
 	MsgSuccessUpdate   = "Erfolgreich aktualisiert"
 	MsgSuccessDelete   = "Erfolgreich gelöscht"
+	MsgSuccessRestore  = "Erfolgreich wiederhergestellt"
+	MsgBulkApplied     = "Alle Operationen erfolgreich ausgeführt"
 	MsgStatusDraft     = "Als Entwurf gespeichert"
 	MsgStatusPublished = "Erfolgreich veröffentlicht"
 	MsgStatusArchived  = "Erfolgreich archiviert"
@@ -27,7 +40,12 @@ const (
 	ErrStatusTransition = "Ungültiger Statusübergang"
 	ErrDraftOnly        = "Nur im Entwurfsmodus möglich"
 	ErrConflict         = "Konflikt mit existierenden Daten"
+	ErrReferenced       = "Datensatz wird noch verwendet"
+	ErrParentDeleted    = "Übergeordneter Datensatz ist gelöscht"
+	ErrBulkRejected     = "Keine Operation ausgeführt, mindestens eine ist ungültig"
 	ErrPermission       = "Keine Berechtigung"
+	ErrUnknownTenant    = "Mandant unbekannt oder deaktiviert"
+	ErrMaintenance      = "Eine Sicherung wird zurückgespielt, bitte später erneut versuchen"
 )
 
 // SuccessResponse erstellt eine erfolgreiche API-Antwort
@@ -39,6 +57,16 @@ func SuccessResponse(message string, data interface{}) APIResponse {
 	}
 }
 
+// PaginatedResponse erstellt eine erfolgreiche API-Antwort mit Paginierungsangaben
+func PaginatedResponse(message string, data interface{}, meta *Meta) APIResponse {
+	return APIResponse{
+		Success: true,
+		Message: message,
+		Data:    data,
+		Meta:    meta,
+	}
+}
+
 // ErrorResponse erstellt eine Fehler-API-Antwort
 func ErrorResponse(errorMessage string) APIResponse {
 	return APIResponse{
@@ -47,8 +75,10 @@ func ErrorResponse(errorMessage string) APIResponse {
 	}
 }
 
-// ValidationResponse erstellt eine Antwort für Validierungsfehler
-func ValidationResponse(validationErrors []string) APIResponse {
+// ValidationResponse erstellt eine Antwort für Validierungsfehler. data
+// enthält jeden Fehler mit Feld, Code und Meldung, die Codes sind in
+// models.FieldError beschrieben.
+func ValidationResponse(validationErrors models.ValidationErrors) APIResponse {
 	return APIResponse{
 		Success: false,
 		Error:   ErrValidation,
@@ -56,6 +86,15 @@ func ValidationResponse(validationErrors []string) APIResponse {
 	}
 }
 
+// ConflictResponse erstellt eine Fehlerantwort mit Angaben zum Konflikt
+func ConflictResponse(errorMessage string, data interface{}) APIResponse {
+	return APIResponse{
+		Success: false,
+		Error:   errorMessage,
+		Data:    data,
+	}
+}
+
 // StatusResponse erstellt eine Antwort für Statusänderungen
 func StatusResponse(status string, data interface{}) APIResponse {
 	var message string
@@ -9,64 +9,169 @@ func SetupRoutes(app *fiber.App) {
 	// API v1 routes
 	v1 := app.Group("/api/v1")
 
+	// Das Zurückspielen einer Sicherung wartet auf alle laufenden Anfragen
+	// und steht deshalb vor der Wartungssperre
+	v1.Post("/admin/backups/:name/restore", handlers.RequireAdminToken, handlers.HandleRestoreBackup)
+	v1.Use(handlers.MaintenanceGate)
+
 	// Health check
 	v1.Get("/health", handlers.HandleHealthCheck)
 
+	// Event stream
+	v1.Get("/events", handlers.ResolveTenant, handlers.HandleEvents)
+
 	// Employee routes
-	employees := v1.Group("/employees")
+	employees := v1.Group("/employees", handlers.ResolveTenant)
 	employees.Get("/", handlers.HandleAllEmployees)
 	employees.Post("/", handlers.HandleCreateEmployee)
 	employees.Get("/:id", handlers.HandleGetOneEmployee)
 	employees.Put("/:id", handlers.HandleUpdateEmployee)
 	employees.Delete("/:id", handlers.HandleDeleteEmployee)
+	employees.Post("/:id/restore", handlers.HandleRestoreEmployee)
 	employees.Get("/department/:id", handlers.HandleGetDepartmentEmployees)
+	employees.Get("/:id/notification-preferences", handlers.HandleGetNotificationPreference)
+	employees.Put("/:id/notification-preferences", handlers.HandleUpdateNotificationPreference)
+	employees.Get("/:id/contracts", handlers.HandleAllContracts)
+	employees.Post("/:id/contracts", handlers.HandleCreateContract)
+	employees.Put("/:id/contracts/:contractId", handlers.HandleUpdateContract)
+	employees.Delete("/:id/contracts/:contractId", handlers.HandleDeleteContract)
+	employees.Get("/:id/shift-preferences", handlers.HandleAllShiftPreferences)
+	employees.Post("/:id/shift-preferences", handlers.HandleCreateShiftPreference)
+	employees.Put("/:id/shift-preferences/:preferenceId", handlers.HandleUpdateShiftPreference)
+	employees.Delete("/:id/shift-preferences/:preferenceId", handlers.HandleDeleteShiftPreference)
+	employees.Get("/:id/calendar", handlers.HandleEmployeeCalendar)
+
+	// Location routes
+	locations := v1.Group("/locations", handlers.ResolveTenant)
+	locations.Get("/", handlers.HandleAllLocations)
+	locations.Post("/", handlers.HandleCreateLocation)
+	locations.Get("/:id", handlers.HandleGetOneLocation)
+	locations.Put("/:id", handlers.HandleUpdateLocation)
+	locations.Delete("/:id", handlers.HandleDeleteLocation)
+	locations.Post("/:id/restore", handlers.HandleRestoreLocation)
+	locations.Get("/:id/stats", handlers.HandleLocationStats)
 
 	// Department routes
-	departments := v1.Group("/departments")
+	departments := v1.Group("/departments", handlers.ResolveTenant)
 	departments.Get("/", handlers.HandleAllDepartments)
 	departments.Post("/", handlers.HandleCreateDepartment)
 	departments.Get("/:id", handlers.HandleGetOneDepartment)
 	departments.Put("/:id", handlers.HandleUpdateDepartment)
 	departments.Delete("/:id", handlers.HandleDeleteDepartment)
+	departments.Post("/:id/restore", handlers.HandleRestoreDepartment)
 	departments.Get("/:id/stats", handlers.HandleDepartmentStats)
 
+	// Team routes
+	teams := v1.Group("/teams", handlers.ResolveTenant)
+	teams.Get("/", handlers.HandleAllTeams)
+	teams.Post("/", handlers.HandleCreateTeam)
+	teams.Get("/:id", handlers.HandleGetOneTeam)
+	teams.Put("/:id", handlers.HandleUpdateTeam)
+	teams.Delete("/:id", handlers.HandleDeleteTeam)
+	teams.Post("/:id/restore", handlers.HandleRestoreTeam)
+	teams.Get("/:id/stats", handlers.HandleTeamStats)
+
 	// ShiftType routes
-	shiftTypes := v1.Group("/shifttypes")
+	shiftTypes := v1.Group("/shifttypes", handlers.ResolveTenant)
 	shiftTypes.Get("/", handlers.HandleAllShiftTypes)
 	shiftTypes.Post("/", handlers.HandleCreateShiftType)
 	shiftTypes.Get("/:id", handlers.HandleGetOneShiftType)
 	shiftTypes.Put("/:id", handlers.HandleUpdateShiftType)
 	shiftTypes.Delete("/:id", handlers.HandleDeleteShiftType)
+	shiftTypes.Post("/:id/restore", handlers.HandleRestoreShiftType)
 
 	// ShiftTemplate routes
-	shiftTemplates := v1.Group("/shifttemplates")
+	shiftTemplates := v1.Group("/shifttemplates", handlers.ResolveTenant)
 	shiftTemplates.Get("/", handlers.HandleAllShiftTemplates)
 	shiftTemplates.Post("/", handlers.HandleCreateShiftTemplate)
 	shiftTemplates.Get("/:id", handlers.HandleGetOneShiftTemplate)
 	shiftTemplates.Put("/:id", handlers.HandleUpdateShiftTemplate)
 	shiftTemplates.Delete("/:id", handlers.HandleDeleteShiftTemplate)
+	shiftTemplates.Post("/:id/restore", handlers.HandleRestoreShiftTemplate)
 	shiftTemplates.Get("/department/:id", handlers.HandleGetDepartmentShiftTemplates)
 	shiftTemplates.Put("/:id/status", handlers.HandleUpdateShiftTemplateStatus)
 
 	// ShiftWeek routes
-	shiftWeeks := v1.Group("/shiftweeks")
+	shiftWeeks := v1.Group("/shiftweeks", handlers.ResolveTenant)
 	shiftWeeks.Get("/", handlers.HandleAllShiftWeeks)
 	shiftWeeks.Post("/", handlers.HandleCreateShiftWeek)
 	shiftWeeks.Get("/:id", handlers.HandleGetOneShiftWeek)
 	shiftWeeks.Put("/:id", handlers.HandleUpdateShiftWeek)
 	shiftWeeks.Delete("/:id", handlers.HandleDeleteShiftWeek)
+	shiftWeeks.Post("/:id/restore", handlers.HandleRestoreShiftWeek)
 	shiftWeeks.Get("/department/:id", handlers.HandleGetDepartmentShiftWeeks)
 	shiftWeeks.Put("/:id/status", handlers.HandleUpdateShiftWeekStatus)
 	shiftWeeks.Get("/:id/stats", handlers.HandleShiftWeekStats)
+	shiftWeeks.Post("/:id/copy", handlers.HandleCopyShiftWeek)
 
 	// ShiftDay routes
-	shiftDays := v1.Group("/shiftdays")
+	shiftDays := v1.Group("/shiftdays", handlers.ResolveTenant)
 	shiftDays.Get("/", handlers.HandleAllShiftDays)
 	shiftDays.Post("/", handlers.HandleCreateShiftDay)
+	shiftDays.Post("/bulk", handlers.HandleBulkShiftDays)
 	shiftDays.Get("/:id", handlers.HandleGetOneShiftDay)
 	shiftDays.Put("/:id", handlers.HandleUpdateShiftDay)
 	shiftDays.Delete("/:id", handlers.HandleDeleteShiftDay)
+	shiftDays.Post("/:id/restore", handlers.HandleRestoreShiftDay)
 	shiftDays.Get("/week/:id", handlers.HandleGetShiftDaysByWeek)
 	shiftDays.Get("/employee/:id", handlers.HandleGetEmployeeShiftDays)
 	shiftDays.Get("/department/:id", handlers.HandleGetDepartmentShiftDays)
+
+	// Rotation routes
+	rotations := v1.Group("/rotations", handlers.ResolveTenant)
+	rotations.Get("/", handlers.HandleAllRotations)
+	rotations.Post("/", handlers.HandleCreateRotation)
+	rotations.Get("/:id", handlers.HandleGetOneRotation)
+	rotations.Put("/:id", handlers.HandleUpdateRotation)
+	rotations.Delete("/:id", handlers.HandleDeleteRotation)
+	rotations.Post("/:id/restore", handlers.HandleRestoreRotation)
+	rotations.Post("/:id/generate", handlers.HandleGenerateRotation)
+
+	// OnCall routes
+	onCall := v1.Group("/oncall", handlers.ResolveTenant)
+	onCall.Get("/", handlers.HandleAllOnCallAssignments)
+	onCall.Post("/", handlers.HandleCreateOnCallAssignment)
+	onCall.Get("/:id", handlers.HandleGetOneOnCallAssignment)
+	onCall.Put("/:id", handlers.HandleUpdateOnCallAssignment)
+	onCall.Delete("/:id", handlers.HandleDeleteOnCallAssignment)
+	onCall.Post("/:id/restore", handlers.HandleRestoreOnCall)
+	onCall.Post("/:id/callouts", handlers.HandleCreateOnCallCallout)
+	onCall.Delete("/:id/callouts/:calloutId", handlers.HandleDeleteOnCallCallout)
+
+	// Report routes
+	reports := v1.Group("/reports", handlers.ResolveTenant)
+	reports.Get("/fairness", handlers.HandleFairnessReport)
+	reports.Get("/payroll", handlers.HandlePayrollReport)
+
+	// Notification routes
+	notifications := v1.Group("/notifications", handlers.ResolveTenant)
+	notifications.Get("/", handlers.HandleAllNotifications)
+	notifications.Post("/:id/retry", handlers.HandleRetryNotification)
+
+	// Webhook routes
+	webhooks := v1.Group("/webhooks", handlers.ResolveTenant)
+	webhooks.Get("/", handlers.HandleAllWebhooks)
+	webhooks.Post("/", handlers.HandleCreateWebhook)
+	webhooks.Get("/:id", handlers.HandleGetOneWebhook)
+	webhooks.Put("/:id", handlers.HandleUpdateWebhook)
+	webhooks.Delete("/:id", handlers.HandleDeleteWebhook)
+	webhooks.Post("/:id/restore", handlers.HandleRestoreWebhook)
+	webhooks.Get("/:id/deliveries", handlers.HandleWebhookDeliveries)
+	webhooks.Post("/deliveries/:id/redeliver", handlers.HandleRedeliverWebhook)
+
+	// Trash routes
+	v1.Get("/trash", handlers.ResolveTenant, handlers.HandleTrash)
+
+	// Admin routes
+	backups := v1.Group("/admin/backups", handlers.RequireAdminToken)
+	backups.Get("/", handlers.HandleAllBackups)
+	backups.Post("/", handlers.HandleCreateBackup)
+	backups.Post("/prune", handlers.HandlePruneBackups)
+
+	admin := v1.Group("/admin", handlers.RequireAdmin)
+	admin.Get("/tenants", handlers.HandleAllTenants)
+	admin.Post("/tenants", handlers.HandleCreateTenant)
+	admin.Get("/tenants/:id", handlers.HandleGetOneTenant)
+	admin.Put("/tenants/:id", handlers.HandleUpdateTenant)
+	admin.Post("/tenants/:id/token", handlers.HandleRotateTenantToken)
 }
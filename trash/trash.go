@@ -0,0 +1,59 @@
+package trash
+
+import (
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/ptmmeiningen/schichtplaner/database"
+)
+
+// retention ist die Aufbewahrungsdauer gelöschter Datensätze im Papierkorb
+var retention = 30 * 24 * time.Hour
+
+// Setup liest die Aufbewahrungsdauer aus TRASH_RETENTION_DAYS (Standard: 30,
+// 0 schaltet das endgültige Löschen ab)
+func Setup() error {
+	if value := os.Getenv("TRASH_RETENTION_DAYS"); value != "" {
+		days, err := strconv.Atoi(value)
+		if err != nil || days < 0 {
+			return errors.New("ungültiges TRASH_RETENTION_DAYS: " + value)
+		}
+		retention = time.Duration(days) * 24 * time.Hour
+	}
+	return nil
+}
+
+// Retention liefert die aktive Aufbewahrungsdauer
+func Retention() time.Duration {
+	return retention
+}
+
+// StartWorker löscht im angegebenen Intervall abgelaufene Datensätze endgültig
+func StartWorker(interval time.Duration) {
+	if retention <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if _, err := Purge(); err != nil {
+				log.Printf("Fehler beim Leeren des Papierkorbs: %v", err)
+			}
+		}
+	}()
+}
+
+// Purge löscht alle Datensätze endgültig, die länger als die
+// Aufbewahrungsdauer im Papierkorb liegen
+func Purge() (map[string]int64, error) {
+	if retention <= 0 {
+		return map[string]int64{}, nil
+	}
+	return database.Purge(time.Now().Add(-retention))
+}
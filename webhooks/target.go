@@ -0,0 +1,134 @@
+package webhooks
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenAddress wird gemeldet, wenn ein Webhook eine interne Adresse
+// erreichen würde
+var ErrForbiddenAddress = errors.New("interne adressen sind als webhook-ziel nicht erlaubt")
+
+// sharedAddressSpace ist der Adressbereich für Carrier-Grade-NAT (RFC 6598)
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// allowInsecure erlaubt mit WEBHOOK_ALLOW_INSECURE=true http und interne
+// Adressen, etwa für Empfänger auf dem Entwicklungsrechner
+func allowInsecure() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv("WEBHOOK_ALLOW_INSECURE"))
+	return enabled
+}
+
+// allowedHosts liest WEBHOOK_ALLOWED_HOSTS, eine kommagetrennte Liste von
+// Hosts. Ein Eintrag mit führendem Punkt wie .example.org erlaubt alle
+// Subdomains. Ohne Liste ist jeder öffentliche Host erlaubt.
+func allowedHosts() []string {
+	var hosts []string
+	for _, host := range strings.Split(os.Getenv("WEBHOOK_ALLOWED_HOSTS"), ",") {
+		if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+// CheckURL prüft, ob Webhooks an rawURL zugestellt werden dürfen: nur über
+// https, nur an Hosts der Liste WEBHOOK_ALLOWED_HOSTS, sofern gesetzt, und
+// nicht an Loopback-, private oder Link-Local-Adressen. Hostnamen werden
+// erst beim Verbindungsaufbau aufgelöst und dort erneut geprüft.
+func CheckURL(rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Hostname() == "" {
+		return errors.New("url muss eine gültige http- oder https-adresse sein")
+	}
+	if allowInsecure() {
+		return nil
+	}
+
+	if parsed.Scheme != "https" {
+		return errors.New("webhooks werden nur über https zugestellt")
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(parsed.Hostname(), "."))
+	if allowed := allowedHosts(); len(allowed) > 0 && !hostAllowed(host, allowed) {
+		return fmt.Errorf("host %s ist nicht in WEBHOOK_ALLOWED_HOSTS freigegeben", host)
+	}
+
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrForbiddenAddress
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && forbiddenAddr(addr) {
+		return ErrForbiddenAddress
+	}
+	return nil
+}
+
+func hostAllowed(host string, allowed []string) bool {
+	for _, entry := range allowed {
+		if host == entry || (strings.HasPrefix(entry, ".") && strings.HasSuffix(host, entry)) {
+			return true
+		}
+	}
+	return false
+}
+
+// forbiddenAddr erkennt Adressen, die nicht ins öffentliche Internet führen
+func forbiddenAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsLoopback() ||
+		addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() ||
+		addr.IsUnspecified() ||
+		sharedAddressSpace.Contains(addr)
+}
+
+// checkDialAddress prüft die aufgelöste Zieladresse jeder Verbindung, damit
+// auch Hostnamen und Weiterleitungen auf interne Adressen abgewiesen werden
+func checkDialAddress(network, address string, _ syscall.RawConn) error {
+	if allowInsecure() {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil || forbiddenAddr(addr) {
+		return ErrForbiddenAddress
+	}
+	return nil
+}
+
+// newClient erzeugt den HTTP-Client für Zustellungen. Er nutzt keinen Proxy
+// aus der Umgebung, prüft jede Verbindung mit checkDialAddress und folgt
+// nur Weiterleitungen, die CheckURL erlaubt.
+func newClient() *http.Client {
+	dialer := &net.Dialer{Timeout: 5 * time.Second, Control: checkDialAddress}
+	return &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 5 * time.Second,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 5 {
+				return errors.New("zu viele weiterleitungen")
+			}
+			return CheckURL(req.URL.String())
+		},
+	}
+}
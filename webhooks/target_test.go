@@ -0,0 +1,126 @@
+package webhooks
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ptmmeiningen/schichtplaner/models"
+)
+
+func TestCheckURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		allowed string
+		ok      bool
+	}{
+		{url: "https://hr.example.com/hooks", ok: true},
+		{url: "https://93.184.215.14/hooks", ok: true},
+		{url: "http://hr.example.com/hooks"},
+		{url: "ftp://hr.example.com/hooks"},
+		{url: "https:///hooks"},
+		{url: "https://localhost/hooks"},
+		{url: "https://api.localhost/hooks"},
+		{url: "https://127.0.0.1/hooks"},
+		{url: "https://[::1]/hooks"},
+		{url: "https://10.1.2.3/hooks"},
+		{url: "https://172.16.0.1/hooks"},
+		{url: "https://192.168.178.1/hooks"},
+		{url: "https://169.254.169.254/latest/meta-data"},
+		{url: "https://100.64.0.1/hooks"},
+		{url: "https://0.0.0.0/hooks"},
+		{url: "https://[::ffff:127.0.0.1]/hooks"},
+		{url: "https://[fe80::1]/hooks"},
+		{url: "https://hr.example.com/hooks", allowed: "hr.example.com, chat.example.com", ok: true},
+		{url: "https://chat.example.com/hooks", allowed: ".example.com", ok: true},
+		{url: "https://evil.example.org/hooks", allowed: "hr.example.com,.example.com"},
+	}
+	for _, test := range tests {
+		t.Run(test.url+" "+test.allowed, func(t *testing.T) {
+			t.Setenv("WEBHOOK_ALLOWED_HOSTS", test.allowed)
+			err := CheckURL(test.url)
+			if test.ok && err != nil {
+				t.Fatalf("abgelehnt: %v", err)
+			}
+			if !test.ok && err == nil {
+				t.Fatal("nicht abgelehnt")
+			}
+		})
+	}
+}
+
+func TestCheckURLAllowInsecure(t *testing.T) {
+	t.Setenv("WEBHOOK_ALLOW_INSECURE", "true")
+	if err := CheckURL("http://127.0.0.1:9000/hooks"); err != nil {
+		t.Fatalf("mit WEBHOOK_ALLOW_INSECURE abgelehnt: %v", err)
+	}
+}
+
+func TestClientRejectsInternalAddressAtDial(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer server.Close()
+
+	// Die URL umgeht CheckURL, wie es ein Hostname mit interner Adresse täte
+	_, err := client.Get(server.URL)
+	if !errors.Is(err, ErrForbiddenAddress) {
+		t.Fatalf("Fehler %v, erwartet %v", err, ErrForbiddenAddress)
+	}
+	if requests != 0 {
+		t.Fatalf("%d Anfragen beim Empfänger angekommen", requests)
+	}
+}
+
+func TestClientRejectsRedirectToInternalAddress(t *testing.T) {
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer internal.Close()
+	redirect := httptest.NewServer(http.RedirectHandler(internal.URL, http.StatusFound))
+	defer redirect.Close()
+
+	req, err := http.NewRequest(http.MethodGet, redirect.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.CheckRedirect(req, []*http.Request{req}); err == nil {
+		t.Fatal("weiterleitung auf eine interne adresse nicht abgelehnt")
+	}
+}
+
+func TestSendSignsPayload(t *testing.T) {
+	t.Setenv("WEBHOOK_ALLOW_INSECURE", "true")
+
+	var header http.Header
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		raw, _ := io.ReadAll(r.Body)
+		body = string(raw)
+	}))
+	defer server.Close()
+
+	subscription := &models.WebhookSubscription{URL: server.URL, Secret: "geheim"}
+	delivery := &models.WebhookDelivery{EventType: "shift_day.created", Payload: `{"id":1}`}
+	status, _, err := send(subscription, delivery)
+	if err != nil || status != http.StatusOK {
+		t.Fatalf("status %d, fehler %v", status, err)
+	}
+
+	timestamp := header.Get("X-Schichtplaner-Timestamp")
+	if want := "sha256=" + Sign("geheim", timestamp, delivery.Payload); header.Get("X-Schichtplaner-Signature") != want {
+		t.Fatalf("signatur %s, erwartet %s", header.Get("X-Schichtplaner-Signature"), want)
+	}
+	if body != delivery.Payload || header.Get("X-Schichtplaner-Event") != delivery.EventType {
+		t.Fatalf("body %s, ereignis %s", body, header.Get("X-Schichtplaner-Event"))
+	}
+}
+
+func TestSendRejectsForbiddenURL(t *testing.T) {
+	subscription := &models.WebhookSubscription{URL: "https://169.254.169.254/latest/meta-data", Secret: "geheim"}
+	if _, _, err := send(subscription, &models.WebhookDelivery{Payload: "{}"}); !errors.Is(err, ErrForbiddenAddress) {
+		t.Fatalf("Fehler %v, erwartet %v", err, ErrForbiddenAddress)
+	}
+}
@@ -0,0 +1,190 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ptmmeiningen/schichtplaner/database"
+	"github.com/ptmmeiningen/schichtplaner/models"
+	"github.com/ptmmeiningen/schichtplaner/pkg/events"
+)
+
+const (
+	maxAttempts     = 8
+	batchSize       = 50
+	maxResponseBody = 2048
+)
+
+var client = newClient()
+
+// Setup verbindet die Webhooks mit dem Ereignissystem
+func Setup() {
+	events.OnPublish(func(event events.Event) {
+		if err := Enqueue(event); err != nil {
+			log.Printf("Fehler beim Anlegen der Webhook-Zustellungen für %s: %v", event.Type, err)
+		}
+	})
+}
+
+// StartWorker stellt ausstehende Webhooks im angegebenen Intervall zu
+func StartWorker(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := ProcessDeliveries(); err != nil {
+				log.Printf("Fehler beim Zustellen der Webhooks: %v", err)
+			}
+		}
+	}()
+}
+
+// GenerateSecret erzeugt ein zufälliges Signaturgeheimnis
+func GenerateSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// Enqueue legt für jedes passende Abonnement des Mandanten eine Zustellung an
+func Enqueue(event events.Event) error {
+	db := database.ForTenant(database.WithTenant(context.Background(), event.TenantID))
+
+	var subscriptions []models.WebhookSubscription
+	if err := db.Where("active = ?", true).Find(&subscriptions).Error; err != nil {
+		return err
+	}
+
+	var payload []byte
+	for _, subscription := range subscriptions {
+		if !subscription.Matches(event.Type) {
+			continue
+		}
+
+		if payload == nil {
+			var err error
+			if payload, err = json.Marshal(event); err != nil {
+				return err
+			}
+		}
+
+		delivery := models.WebhookDelivery{
+			SubscriptionID: subscription.ID,
+			EventType:      event.Type,
+			Payload:        string(payload),
+			Status:         models.WebhookPending,
+			NextAttemptAt:  time.Now(),
+		}
+		if err := db.Create(&delivery).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ProcessDeliveries versendet fällige Zustellungen und plant Wiederholungen bei Fehlern
+func ProcessDeliveries() error {
+	var pending []models.WebhookDelivery
+	if err := database.GetDB().
+		Where("status = ? AND next_attempt_at <= ?", models.WebhookPending, time.Now()).
+		Order("next_attempt_at").
+		Limit(batchSize).
+		Find(&pending).Error; err != nil {
+		return err
+	}
+
+	for i := range pending {
+		if err := Deliver(&pending[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Deliver führt einen Zustellversuch aus und speichert das Ergebnis im Protokoll
+func Deliver(delivery *models.WebhookDelivery) error {
+	var subscription models.WebhookSubscription
+	if err := database.GetDB().First(&subscription, delivery.SubscriptionID).Error; err != nil {
+		delivery.Status = models.WebhookFailed
+		delivery.LastError = "abonnement nicht gefunden"
+		return database.GetDB().Save(delivery).Error
+	}
+
+	delivery.Attempts++
+	status, body, err := send(&subscription, delivery)
+	delivery.ResponseStatus = status
+	delivery.ResponseBody = body
+
+	if err == nil {
+		now := time.Now()
+		delivery.Status = models.WebhookDelivered
+		delivery.DeliveredAt = &now
+		delivery.LastError = ""
+	} else {
+		delivery.LastError = err.Error()
+		if delivery.Attempts >= maxAttempts {
+			delivery.Status = models.WebhookFailed
+		} else {
+			delivery.Status = models.WebhookPending
+			delivery.NextAttemptAt = time.Now().Add(backoff(delivery.Attempts))
+		}
+	}
+
+	return database.GetDB().Save(delivery).Error
+}
+
+func send(subscription *models.WebhookSubscription, delivery *models.WebhookDelivery) (int, string, error) {
+	if err := CheckURL(subscription.URL); err != nil {
+		return 0, "", err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequest(http.MethodPost, subscription.URL, bytes.NewBufferString(delivery.Payload))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Schichtplaner-Webhooks/1.0")
+	req.Header.Set("X-Schichtplaner-Event", delivery.EventType)
+	req.Header.Set("X-Schichtplaner-Delivery", strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set("X-Schichtplaner-Timestamp", timestamp)
+	req.Header.Set("X-Schichtplaner-Signature", "sha256="+Sign(subscription.Secret, timestamp, delivery.Payload))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, string(body), fmt.Errorf("empfänger antwortete mit status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, string(body), nil
+}
+
+// Sign berechnet die HMAC-SHA256-Signatur über "<timestamp>.<payload>"
+func Sign(secret, timestamp, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// backoff verdoppelt die Wartezeit mit jedem Fehlversuch, beginnend bei 30 Sekunden
+func backoff(attempts int) time.Duration {
+	return 30 * time.Second << (attempts - 1)
+}